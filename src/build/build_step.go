@@ -229,7 +229,7 @@ func buildTarget(state *core.BuildState, target *core.BuildTarget, runRemotely b
 			// changes from the build metadata and check if we need to build the target again
 			if target.BuildCouldModifyTarget() {
 				// needsBuilding checks that the metadata file exists so this is safe
-				metadata, err = loadTargetMetadata(target)
+				metadata, err = LoadTargetMetadata(target)
 				if err != nil {
 					return fmt.Errorf("failed to load build metadata for %s: %w", target.Label, err)
 				}
@@ -363,6 +363,11 @@ func buildTarget(state *core.BuildState, target *core.BuildTarget, runRemotely b
 			target.SetState(core.BuiltRemotely)
 			state.LogBuildResult(target, core.TargetBuilt, "Built remotely")
 		}
+		// The remote client keeps its own record of this, but we store it too so it's available
+		// to things like plz query weight that only look at the output directory.
+		if err := StoreTargetMetadata(target, metadata); err != nil {
+			return fmt.Errorf("failed to store target build metadata for %s: %w", target.Label, err)
+		}
 		if state.ShouldDownload(target) {
 			if err := state.EnsureDownloaded(target); err != nil {
 				return err
@@ -437,7 +442,7 @@ func addOutDirOutsFromMetadata(target *core.BuildTarget, md *core.BuildMetadata)
 func retrieveFromCache(cache core.Cache, target *core.BuildTarget, cacheKey []byte, files []string) *core.BuildMetadata {
 	files = append(files, target.TargetBuildMetadataFileName())
 	if ok := cache.Retrieve(target, cacheKey, files); ok {
-		md, err := loadTargetMetadata(target)
+		md, err := LoadTargetMetadata(target)
 		if err != nil {
 			log.Debugf("failed to retrieve %s build metadata from cache: %v", target.Label, err)
 			return nil
@@ -1214,7 +1219,9 @@ func build(state *core.BuildState, target *core.BuildTarget, inputHash []byte) (
 	if err != nil {
 		return nil, err
 	} else if workerCmd == "" {
+		start := time.Now()
 		metadata.Stdout, err = runBuildCommand(state, target, localCmd, inputHash)
+		metadata.Duration = time.Since(start)
 		return metadata, err
 	}
 	return nil, fmt.Errorf("Persistent workers are no longer supported, found worker command: %s", workerCmd)
@@ -133,7 +133,7 @@ func TestOutputDir(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string{"file7"}, target.Outputs())
 
-	md, err := loadTargetMetadata(target)
+	md, err := LoadTargetMetadata(target)
 	require.NoError(t, err)
 
 	assert.Len(t, md.OutputDirOuts, 1)
@@ -168,7 +168,7 @@ func TestOutputDirDoubleStar(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string{"foo"}, target.Outputs())
 
-	md, err := loadTargetMetadata(target)
+	md, err := LoadTargetMetadata(target)
 	require.NoError(t, err)
 
 	assert.Len(t, md.OutputDirOuts, 1)
@@ -451,7 +451,7 @@ func TestBuildMetadatafileIsCreated(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, target.BuildCouldModifyTarget())
 	assert.True(t, fs.FileExists(filepath.Join(target.OutDir(), target.TargetBuildMetadataFileName())))
-	md, err := loadTargetMetadata(target)
+	md, err := LoadTargetMetadata(target)
 	require.NoError(t, err)
 	assert.Equal(t, stdOut, string(md.Stdout))
 }
@@ -669,3 +669,18 @@ func TestMain(m *testing.M) {
 	}
 	os.Exit(m.Run())
 }
+
+func TestRuntimeHashTestSelection(t *testing.T) {
+	state, target := newState("//package1:target_test")
+	target.Test = new(core.TestFields)
+	full, err := RuntimeHash(state, target, 1)
+	require.NoError(t, err)
+	state.TestSelections = map[core.BuildLabel][]string{target.Label: {"TestOne"}}
+	partial, err := RuntimeHash(state, target, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, full, partial, "Running a subset of test cases should have a different runtime hash")
+	state.TestSelections = map[core.BuildLabel][]string{target.Label: {"TestTwo"}}
+	other, err := RuntimeHash(state, target, 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, partial, other)
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/thought-machine/please/src/core"
 	"github.com/thought-machine/please/src/fs"
@@ -366,8 +367,8 @@ func targetBuildMetadataFileName(target *core.BuildTarget) string {
 	return filepath.Join(target.OutDir(), target.TargetBuildMetadataFileName())
 }
 
-// loadTargetMetadata retrieves the target metadata from a file in the output directory of this target
-func loadTargetMetadata(target *core.BuildTarget) (*core.BuildMetadata, error) {
+// LoadTargetMetadata retrieves the target metadata from a file in the output directory of this target
+func LoadTargetMetadata(target *core.BuildTarget) (*core.BuildMetadata, error) {
 	file, err := os.Open(targetBuildMetadataFileName(target))
 	if err != nil {
 		return nil, err
@@ -440,6 +441,14 @@ func RuntimeHash(state *core.BuildState, target *core.BuildTarget, testRun int)
 	hash := append(RuleHash(state, target, true, false), RuleHash(state, target, true, true)...)
 	hash = append(hash, state.Hashes.Config...)
 	h := sha1.New()
+	if shards := target.NumTestShards(); shards > 1 {
+		// Each shard runs different tests, so they need to be distinguished.
+		fmt.Fprintf(h, "shard %d of %d", target.TestShard(testRun), shards)
+	}
+	if selection := state.TestSelection(target.Label); len(selection) > 0 {
+		// Running a subset of the test cases gives different results, so they're cached separately.
+		fmt.Fprintf(h, "tests %s", strings.Join(selection, " "))
+	}
 	for source := range core.IterRuntimeFiles(state.Graph, target, true, target.TestDir(testRun)) {
 		result, err := state.PathHasher.Hash(source.Src, false, true, false)
 		if err != nil {
@@ -63,13 +63,16 @@ var KnownFields = map[string]bool{
 	"Test.tools":      true,
 	"Test.namedTools": true,
 	"Test.Outputs":    true,
+	"Test.Shards":     true,
 
 	// These don't need to be hashed
-	"Test.NoOutput":   true,
-	"Test.NoCoverage": true,
-	"Test.Timeout":    true,
-	"Test.Flakiness":  true,
-	"Test.Results":    true, // Recall that unsuccessful test results aren't cached...
+	"Test.NoOutput":      true,
+	"Test.NoCoverage":    true,
+	"Test.Timeout":       true,
+	"Test.Flakiness":     true,
+	"Test.Results":       true, // Recall that unsuccessful test results aren't cached...
+	"Test.ResultsFormat": true, // Only affects how we parse the results, which we do again when retrieving them.
+	"Test.Resources":     true, // Only affects when the test runs.
 
 	// Debug fields don't contribute to any hash
 	"Debug":            true,
@@ -106,6 +109,7 @@ var KnownFields = map[string]bool{
 	"mutex":                  true,
 	"dependenciesRegistered": true,
 	"finishedBuilding":       true,
+	"CallStack":              true,
 
 	// Used to save the rule hash rather than actually being hashed itself.
 	"RuleHash": true,
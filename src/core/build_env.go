@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -159,7 +160,7 @@ func TestEnvironment(state *BuildState, target *BuildTarget, testDir string, run
 	env["TMP_DIR"] = testDir
 	env["TMPDIR"] = testDir
 	env["HOME"] = testDir
-	env["TEST_ARGS"] = strings.Join(state.TestArgs, ",")
+	env["TEST_ARGS"] = strings.Join(state.TestSelection(target.Label), ",")
 	env["RESULTS_FILE"] = resultsFile
 	// We shouldn't really have specific things like this here, but it really is just easier to set it.
 	env["GTEST_OUTPUT"] = "xml:" + resultsFile
@@ -176,14 +177,20 @@ func TestEnvironment(state *BuildState, target *BuildTarget, testDir string, run
 	if target.HasLabel("cc") {
 		env["GCNO_DIR"] = filepath.Join(RepoRoot, GenDir, target.Label.PackageName)
 	}
+	if shards := target.NumTestShards(); shards > 1 {
+		// These follow the same protocol as Bazel, so test runners that support that will shard automatically.
+		env["TEST_TOTAL_SHARDS"] = strconv.Itoa(shards)
+		env["TEST_SHARD_INDEX"] = strconv.Itoa(target.TestShard(run))
+		env["TEST_SHARD_STATUS_FILE"] = filepath.Join(testDir, TestShardStatusFile)
+	}
 	if state.DebugFailingTests {
 		env["DEBUG_TEST_FAILURE"] = "true"
 	}
 	if target.Test.Sandbox && len(state.Config.Sandbox.Dir) > 0 {
 		env["SANDBOX_DIRS"] = strings.Join(state.Config.Sandbox.Dir, ",")
 	}
-	if len(state.TestArgs) > 0 {
-		env["TESTS"] = strings.Join(state.TestArgs, " ")
+	if selection := state.TestSelection(target.Label); len(selection) > 0 {
+		env["TESTS"] = strings.Join(selection, " ")
 	}
 	return withUserProvidedEnv(target, env)
 }
@@ -117,6 +117,22 @@ func TestExecEnvironmentTestTarget(t *testing.T) {
 	assert.Equal(t, env["TEST"], "out_file1")
 }
 
+func TestTestEnvironmentSharded(t *testing.T) {
+	state := NewDefaultBuildState()
+	target := NewBuildTarget(NewBuildLabel("pkg", "t"))
+	target.Test = &TestFields{}
+
+	env := TestEnvironment(state, target, "/path/to/test/dir", 1)
+	assert.NotContains(t, env, "TEST_TOTAL_SHARDS")
+	assert.NotContains(t, env, "TEST_SHARD_INDEX")
+
+	target.Test.Shards = 4
+	env = TestEnvironment(state, target, "/path/to/test/dir", 7)
+	assert.Equal(t, "4", env["TEST_TOTAL_SHARDS"])
+	assert.Equal(t, "2", env["TEST_SHARD_INDEX"])
+	assert.Equal(t, "/path/to/test/dir/test.shard_status", env["TEST_SHARD_STATUS_FILE"])
+}
+
 func TestExecEnvironmentDebugTarget(t *testing.T) {
 	t.Setenv("TERM", "my-term")
 
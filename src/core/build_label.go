@@ -622,3 +622,13 @@ func (slice BuildLabels) String() string {
 	}
 	return strings.Join(s, ", ")
 }
+
+// Includes returns true if any of the labels in this slice include the given one.
+func (slice BuildLabels) Includes(label BuildLabel) bool {
+	for _, l := range slice {
+		if l.Includes(label) {
+			return true
+		}
+	}
+	return false
+}
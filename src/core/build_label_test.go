@@ -51,6 +51,17 @@ func TestIncludesSubpackages(t *testing.T) {
 	assert.True(t, label1.Includes(label2))
 }
 
+func TestBuildLabelsIncludes(t *testing.T) {
+	labels := BuildLabels{
+		{PackageName: "src/core", Name: "all"},
+		{PackageName: "third_party", Name: "..."},
+	}
+	assert.True(t, labels.Includes(BuildLabel{PackageName: "src/core", Name: "core"}))
+	assert.True(t, labels.Includes(BuildLabel{PackageName: "third_party/go", Name: "testify"}))
+	assert.False(t, labels.Includes(BuildLabel{PackageName: "src/core/test", Name: "test"}))
+	assert.False(t, BuildLabels{}.Includes(BuildLabel{PackageName: "src/core", Name: "core"}))
+}
+
 func TestLabelParent(t *testing.T) {
 	label := BuildLabel{PackageName: "src/core", Name: "core"}
 	assert.Equal(t, label, label.Parent())
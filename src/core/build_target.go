@@ -52,6 +52,9 @@ const TestResultsFile = "test.results"
 // This is similarly defined via an environment variable.
 const CoverageFile = "test.coverage"
 
+// TestShardStatusFile is the file that sharded tests touch to indicate that they support sharding.
+const TestShardStatusFile = "test.shard_status"
+
 // tempOutputSuffix is the suffix we attach to temporary outputs to avoid name clashes.
 const tempOutputSuffix = ".out"
 
@@ -76,6 +79,13 @@ type TestFields struct {
 	Outputs []string `name:"test_outputs"`
 	// Flakiness of test, ie. number of times we will rerun it before giving up. 1 is the default.
 	Flakiness uint8 `name:"flaky"`
+	// Number of shards to split the test into, each of which is run as a separate process.
+	// Zero or one means that it isn't sharded.
+	Shards uint16 `name:"test_shards" print:"omitempty"`
+	// The format that the test writes its results in. If empty it's detected automatically.
+	ResultsFormat string `name:"test_results_format"`
+	// Resources (declared in the config) that the test holds while it runs.
+	Resources []string `name:"test_resources"`
 	// True if the test action is sandboxed.
 	Sandbox bool `name:"test_sandbox"`
 	// True if the target is a test and has no output file.
@@ -241,6 +251,9 @@ type BuildTarget struct {
 	IsTextFile bool `print:"false"`
 	// Marks that the target was added in a post-build function.
 	AddedPostBuild bool `print:"false"`
+	// The chain of build language calls that created this target, innermost first (so the first is always
+	// the call to build_rule). Only recorded if BuildState.RecordProvenance is set.
+	CallStack []CallSite `name:"provenance"`
 	// If true, the interactive progress display will try to infer the target's progress
 	// via some heuristics on its output.
 	showProgress atomic.Bool `name:"progress"`
@@ -271,6 +284,9 @@ type BuildMetadata struct {
 	Test bool
 	// True if the results were retrieved from a cache, false if we ran the full build action.
 	Cached bool
+	// How long the build action took to run when it was last actually built (i.e. not retrieved from a cache).
+	// Zero if it isn't known.
+	Duration time.Duration
 	// VersionTag is an integer representing the version of this cache object. If this doesn't match the
 	// expected version above, Please will not use this cached metadata.
 	VersionTag int
@@ -298,6 +314,7 @@ type depInfo struct {
 	internal bool           // is it an internal dependency (that is not picked up implicitly by transitive searches)
 	source   bool           // is it implicit because it's a source (not true if it's a dependency too)
 	data     bool           // is it a data item for a test
+	site     *CallSite      // where it was declared, if provenance is being recorded
 }
 
 // OutputDirectory is an output directory for the build rule. It may have a suffix of /** which means that we should
@@ -453,7 +470,22 @@ func (target *BuildTarget) CompleteRun(state *BuildState) bool {
 	defer target.mutex.Unlock()
 
 	target.completedRuns++
-	return target.completedRuns == state.NumTestRuns
+	return int(target.completedRuns) == state.numTestTasks(target)
+}
+
+// NumTestShards returns the number of shards this target's tests are split into. It's always at least 1.
+func (target *BuildTarget) NumTestShards() int {
+	if target.Test == nil || target.Test.Shards <= 1 {
+		return 1
+	}
+	return int(target.Test.Shards)
+}
+
+// TestShard returns the (zero-based) shard that the given test run is for.
+// Each shard of a sharded test is run separately, so runs are numbered consecutively across all shards
+// (i.e. for a test with 3 shards, runs 1-3 are the shards of the first run, runs 4-6 the second, etc).
+func (target *BuildTarget) TestShard(run int) int {
+	return (run - 1) % target.NumTestShards()
 }
 
 // TestResultsFile returns the output results file for tests for this target.
@@ -466,6 +498,30 @@ func (target *BuildTarget) CoverageFile() string {
 	return filepath.Join(target.OutDir(), ".test_coverage_"+target.Label.Name)
 }
 
+// ShardResultsFile returns the output results file for a single shard of this target's tests.
+// If it isn't sharded, this is the same as TestResultsFile.
+func (target *BuildTarget) ShardResultsFile(shard int) string {
+	return target.shardFile(target.TestResultsFile(), shard)
+}
+
+// ShardCoverageFile returns the output coverage file for a single shard of this target's tests.
+// If it isn't sharded, this is the same as CoverageFile.
+func (target *BuildTarget) ShardCoverageFile(shard int) string {
+	return target.shardFile(target.CoverageFile(), shard)
+}
+
+// ShardTestOutput returns the location of one of the test's additional outputs for a single shard.
+func (target *BuildTarget) ShardTestOutput(output string, shard int) string {
+	return target.shardFile(filepath.Join(target.OutDir(), output), shard)
+}
+
+func (target *BuildTarget) shardFile(filename string, shard int) string {
+	if target.NumTestShards() == 1 {
+		return filename
+	}
+	return fmt.Sprintf("%s_shard_%d", filename, shard)
+}
+
 // AddTestResults adds results to the target
 func (target *BuildTarget) AddTestResults(results TestSuite) {
 	target.mutex.Lock()
@@ -479,17 +535,19 @@ func (target *BuildTarget) AddTestResults(results TestSuite) {
 	target.Test.Results.Collapse(results)
 }
 
-// StartTestSuite sets the initial properties on the result test suite
-func (target *BuildTarget) StartTestSuite() {
+// StartTestSuite sets the initial properties on the result test suite.
+// quarantined indicates whether the test is quarantined (see Configuration.IsQuarantined).
+func (target *BuildTarget) StartTestSuite(quarantined bool) {
 	target.mutex.Lock()
 	defer target.mutex.Unlock()
 
 	// If the results haven't been set yet, set them
 	if target.Test.Results == nil {
 		target.Test.Results = &TestSuite{
-			Package:   strings.ReplaceAll(target.Label.PackageName, "/", "."),
-			Name:      target.Label.Name,
-			Timestamp: time.Now().Format(time.RFC3339),
+			Package:     strings.ReplaceAll(target.Label.PackageName, "/", "."),
+			Name:        target.Label.Name,
+			Timestamp:   time.Now().Format(time.RFC3339),
+			Quarantined: quarantined,
 		}
 	}
 }
@@ -915,6 +973,8 @@ func (target *BuildTarget) CheckDependencyVisibility(state *BuildState) error {
 		dep := state.Graph.TargetOrDie(*d.declared)
 		if !target.CanSee(state, dep) {
 			return fmt.Errorf("Target %s isn't visible to %s", dep.Label, target.Label)
+		} else if err := target.checkDependencyPolicy(state, dep); err != nil {
+			return err
 		} else if dep.TestOnly && !(target.IsTest() || target.TestOnly) {
 			if target.Label.isExperimental(state) {
 				log.Info("Test-only restrictions suppressed for %s since %s is in the experimental tree", dep.Label, target.Label)
@@ -926,6 +986,21 @@ func (target *BuildTarget) CheckDependencyVisibility(state *BuildState) error {
 	return nil
 }
 
+// checkDependencyPolicy checks that a dependency of this target doesn't break the repo's policy, if it has one.
+func (target *BuildTarget) checkDependencyPolicy(state *BuildState, dep *BuildTarget) error {
+	if state.Policy == nil {
+		return nil
+	}
+	for _, v := range state.Policy.Check(target.Label, dep.Label) {
+		if v.Exception == "" && v.Report {
+			log.Warning("%s", v.Error())
+		} else if !v.Allowed() {
+			return v
+		}
+	}
+	return nil
+}
+
 // CheckDuplicateOutputs checks if any of the outputs of this target duplicate one another.
 // Returns an error if so, or nil if all's well.
 func (target *BuildTarget) CheckDuplicateOutputs() error {
@@ -969,6 +969,35 @@ func TestCheckLicences(t *testing.T) {
 	assert.Equal(t, "", accepted)
 }
 
+func TestTestShards(t *testing.T) {
+	target := makeTarget1("//src/core:test", "")
+	target.Test = &TestFields{}
+	assert.Equal(t, 1, target.NumTestShards())
+	assert.Equal(t, 0, target.TestShard(1))
+	assert.Equal(t, target.TestResultsFile(), target.ShardResultsFile(0))
+	assert.Equal(t, target.CoverageFile(), target.ShardCoverageFile(0))
+
+	target.Test.Shards = 3
+	assert.Equal(t, 3, target.NumTestShards())
+	assert.Equal(t, 0, target.TestShard(1))
+	assert.Equal(t, 2, target.TestShard(3))
+	assert.Equal(t, 0, target.TestShard(4))
+	assert.Equal(t, target.TestResultsFile()+"_shard_1", target.ShardResultsFile(1))
+	assert.Equal(t, target.CoverageFile()+"_shard_2", target.ShardCoverageFile(2))
+	assert.Equal(t, "plz-out/gen/src/core/out.txt_shard_0", target.ShardTestOutput("out.txt", 0))
+}
+
+func TestCompleteRunSharded(t *testing.T) {
+	state := NewDefaultBuildState()
+	state.NumTestRuns = 2
+	target := makeTarget1("//src/core:test", "")
+	target.Test = &TestFields{Shards: 3}
+	for i := 0; i < 5; i++ {
+		assert.False(t, target.CompleteRun(state))
+	}
+	assert.True(t, target.CompleteRun(state))
+}
+
 func makeTarget1(label, visibility string, deps ...*BuildTarget) *BuildTarget {
 	target := NewBuildTarget(ParseBuildLabel(label, ""))
 	if visibility == "PUBLIC" {
@@ -496,6 +496,7 @@ type Configuration struct {
 		BuildDefsDir       []string     `help:"Directory to look in when prompted for help topics that aren't known internally." example:"build_defs"`
 		NumThreads         int          `help:"Number of parallel parse operations to run.\nIs overridden by the --num_threads command line flag." example:"6"`
 		GitFunctions       bool         `help:"Activates built-in functions git_branch, git_commit, git_show and git_state. If disabled they will not be usable at parse time."`
+		Cache              bool         `help:"Caches the results of parsing BUILD files in plz-out, and reloads them on subsequent runs when the BUILD file, its subincludes and the config are unchanged. Packages that use pre- or post-build functions or other non-deterministic builtins are never cached."`
 	} `help:"The [parse] section in the config contains settings specific to parsing files."`
 	Display struct {
 		UpdateTitle  bool   `help:"Updates the title bar of the shell window Please is running in as the build progresses. This isn't on by default because not everyone's shell is configured to reset it again after and we don't want to alter it forever."`
@@ -548,6 +549,7 @@ type Configuration struct {
 		Upload                   cli.URL      `help:"URL to upload test results to (in XML format)"`
 		UploadGzipped            bool         `help:"True to upload the test results gzipped."`
 		StoreTestOutputOnSuccess bool         `help:"True to store stdout and stderr in the test results for successful tests."`
+		Quarantine               []BuildLabel `help:"Tests matching any of these labels are quarantined; they are still run and their results reported, but their failures don't fail the build. Useful to contain flaky tests while they're being fixed."`
 	} `help:"A config section describing settings related to testing in general."`
 	Sandbox struct {
 		Tool               string       `help:"The location of the tool to use for sandboxing. This can assume it is being run in a new network, user, and mount namespace on linux. If not set, Please will use 'plz sandbox'."`
@@ -576,8 +578,9 @@ type Configuration struct {
 		CacheDuration           cli.Duration `help:"Length of time before we re-check locally cached build actions. Default is unlimited."`
 		BuildID                 string       `help:"ID of the build action that's being run, to attach to remote requests. If not set then one is automatically generated."`
 	} `help:"Settings related to remote execution & caching using the Google remote execution APIs. This section is still experimental and subject to change."`
-	Size  map[string]*Size `help:"Named sizes of targets; these are the definitions of what can be passed to the 'size' argument."`
-	Cover struct {
+	Size         map[string]*Size         `help:"Named sizes of targets; these are the definitions of what can be passed to the 'size' argument."`
+	TestResource map[string]*TestResource `help:"Named resources that tests can require via the test_resources argument. Tests that share a resource are limited in how many of them can run at once; other tests still run in parallel."`
+	Cover        struct {
 		FileExtension    []string `help:"Extensions of files to consider for coverage.\nDefaults to .go, .py, .java, .tsx, .ts, .js, .cc, .h, and .c"`
 		ExcludeExtension []string `help:"Extensions of files to exclude from coverage.\nTypically this is for generated code; the default is to exclude protobuf extensions like .pb.go, _pb2.py, etc."`
 		ExcludeGlob      []string `help:"Exclude glob patterns from coverage.\nTypically this is for generated code and it is useful when there is no other discrimination possible."`
@@ -672,6 +675,9 @@ type Configuration struct {
 		Accept []string `help:"Licences that are accepted in this repository.\nWhen this is empty licences are ignored. As soon as it's set any licence detected or assigned must be accepted explicitly here.\nThere's no fuzzy matching, so some package managers (especially PyPI and Maven, but shockingly not npm which rather nicely uses SPDX) will generate a lot of slightly different spellings of the same thing, which will all have to be accepted here. We'd rather that than trying to 'cleverly' match them which might result in matching the wrong thing."`
 		Reject []string `help:"Licences that are explicitly rejected in this repository.\nAn astute observer will notice that this is not very different to just not adding it to the accept section, but it does have the advantage of explicitly documenting things that the team aren't allowed to use."`
 	} `help:"Please has some limited support for declaring acceptable licences and detecting them from some libraries. You should not rely on this for complete licence compliance, but it can be a useful check to try to ensure that unacceptable licences do not slip in."`
+	Policy struct {
+		File string `help:"A file defining rules about which targets may depend on which others, for example to enforce layering between parts of the repo. Dependencies that break them fail to build; plz query policy reports all of them." example:"build/policy.plzpolicy"`
+	} `help:"Please can enforce repo-wide policies on dependencies between packages, which complement the visibility of individual targets. See the documentation on plz query policy for the format of the policy file."`
 	Alias            map[string]*Alias  `help:"Allows defining alias replacements with more detail than the [aliases] section. Otherwise follows the same process, i.e. performs replacements of command strings."`
 	Plugin           map[string]*Plugin `help:"Used to define configuration for a Please plugin."`
 	PluginDefinition struct {
@@ -736,6 +742,11 @@ type Size struct {
 	TimeoutName string       `help:"Name of the timeout, to be passed to the 'timeout' argument"`
 }
 
+// A TestResource represents a resource shared between tests (e.g. a database or a range of ports).
+type TestResource struct {
+	Count int `help:"Number of tests that can use this resource at once. Defaults to 1, i.e. tests using it run exclusively of one another."`
+}
+
 type storedBuildEnv struct {
 	Env  BuildEnv
 	Path []string
@@ -763,6 +774,17 @@ func (config *Configuration) Hash() []byte {
 	return h.Sum(nil)
 }
 
+// ParseHash returns a hash of the entire configuration. Unlike Hash this is not selective, since
+// any part of the config is visible to the BUILD language and hence can affect the result of parsing.
+func (config *Configuration) ParseHash() ([]byte, error) {
+	data, err := gcfg.RawJSON(config)
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.Sum(data)
+	return h[:], nil
+}
+
 // GetBuildEnv returns the build environment configured for this config object.
 func (config *Configuration) GetBuildEnv() BuildEnv {
 	config.buildEnvStored.Once.Do(func() {
@@ -1077,6 +1099,16 @@ func (config *Configuration) IsABuildFile(name string) bool {
 	return false
 }
 
+// IsQuarantined returns true if the given test target is quarantined, i.e. its failures shouldn't fail the build.
+func (config *Configuration) IsQuarantined(label BuildLabel) bool {
+	for _, quarantined := range config.Test.Quarantine {
+		if quarantined.Matches(label) {
+			return true
+		}
+	}
+	return false
+}
+
 // NumRemoteExecutors returns the number of actual remote executors we'll have
 func (config *Configuration) NumRemoteExecutors() int {
 	if config.Remote.URL == "" {
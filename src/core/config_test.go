@@ -441,3 +441,20 @@ func TestPluginConfig(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, []string{"fooc"}, config.Plugin["foo"].ExtraValues["fooctool"])
 }
+
+func TestQuarantine(t *testing.T) {
+	config, err := ReadConfigFiles(fs.HostFS, []string{"src/core/test_data/quarantine.plzconfig"}, nil)
+	assert.NoError(t, err)
+	assert.True(t, config.IsQuarantined(ParseBuildLabel("//src/flaky:test", "")))
+	assert.False(t, config.IsQuarantined(ParseBuildLabel("//src/flaky:other_test", "")))
+	assert.True(t, config.IsQuarantined(ParseBuildLabel("//src/integration/server:test", "")))
+	assert.False(t, config.IsQuarantined(ParseBuildLabel("//src/core:test", "")))
+}
+
+func TestTestResources(t *testing.T) {
+	c, err := ReadConfigFiles(fs.HostFS, []string{"src/core/test_data/test_resources.plzconfig"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(c.TestResource))
+	assert.Equal(t, 3, c.TestResource["postgres"].Count)
+	assert.Equal(t, 0, c.TestResource["emulator"].Count)
+}
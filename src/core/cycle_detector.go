@@ -1,10 +1,5 @@
 package core
 
-import (
-	"fmt"
-	"strings"
-)
-
 type cycleDetector struct {
 	graph   *BuildGraph
 	stopped bool
@@ -75,10 +70,9 @@ type errCycle struct {
 }
 
 func (err *errCycle) Error() string {
-	labels := make([]string, len(err.Cycle))
-	for i, t := range err.Cycle {
-		labels[i] = t.Label.String()
+	msg := "Dependency cycle found:\n" + DescribeCycle(err.Cycle) + "\nSorry, but you'll have to refactor your build files to avoid this cycle"
+	if _, ok := err.Cycle[0].DeclaredAt(); !ok {
+		msg += "\nRun plz query cycles to see where each of these dependencies was declared."
 	}
-	labels = append(labels, labels[0])
-	return fmt.Sprintf("Dependency cycle found:\n%s\nSorry, but you'll have to refactor your build files to avoid this cycle", strings.Join(labels, "\n -> "))
+	return msg
 }
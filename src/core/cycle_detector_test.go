@@ -72,3 +72,33 @@ func TestCycleDetector(t *testing.T) {
 		assert.Equal(t, []*BuildTarget{g, e, f}, err.Cycle)
 	})
 }
+
+func TestCycleErrorProvenance(t *testing.T) {
+	a := NewBuildTarget(ParseBuildLabel("//src:a", ""))
+	b := NewBuildTarget(ParseBuildLabel("//src:_b#lib", ""))
+	err := &errCycle{Cycle: []*BuildTarget{a, b}}
+	assert.Equal(t, `Dependency cycle found:
+//src:a
+ -> //src:_b#lib
+ -> //src:a
+Sorry, but you'll have to refactor your build files to avoid this cycle
+Run plz query cycles to see where each of these dependencies was declared.`, err.Error())
+
+	a.CallStack = []CallSite{{Function: "build_rule", Filename: "src/BUILD", Line: 1}}
+	b.CallStack = []CallSite{
+		{Function: "build_rule", Filename: "build_defs/lib.build_defs", Line: 2},
+		{Function: "_compile", Filename: "build_defs/lib.build_defs", Line: 8},
+		{Function: "my_library", Filename: "src/BUILD", Line: 5},
+	}
+	a.AddDependency(b.Label)
+	a.SetDependencySite(b.Label, CallSite{Function: "build_rule", Filename: "src/BUILD", Line: 3})
+	assert.Equal(t, `Dependency cycle found:
+//src:a
+ -> //src:_b#lib
+      dependency declared in build_rule() at src/BUILD:3
+      //src:a was declared by build_rule() at src/BUILD:1
+ -> //src:a
+      //src:_b#lib was declared by my_library() at src/BUILD:5
+        via _compile() at build_defs/lib.build_defs:8
+Sorry, but you'll have to refactor your build files to avoid this cycle`, err.Error())
+}
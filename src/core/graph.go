@@ -5,7 +5,9 @@
 package core
 
 import (
+	"slices"
 	"sort"
+	"sync"
 
 	"github.com/thought-machine/please/src/cmap"
 )
@@ -19,6 +21,9 @@ type BuildGraph struct {
 	packages *cmap.Map[packageKey, *Package]
 	// Registered subrepos, as a map of their name to their root.
 	subrepos *cmap.Map[string, *Subrepo]
+	// Subincludes made by subincluded files themselves, keyed by the label of the file doing the subincluding.
+	nestedSubincludes map[BuildLabel][]BuildLabel
+	nestedMutex       sync.Mutex
 }
 
 // AddTarget adds a new target to the graph.
@@ -150,10 +155,28 @@ func NewGraph() *BuildGraph {
 		targets:  cmap.New[BuildLabel, *BuildTarget](cmap.DefaultShardCount, hashBuildLabel),
 		packages: cmap.New[packageKey, *Package](cmap.DefaultShardCount, hashPackageKey),
 		subrepos: cmap.New[string, *Subrepo](cmap.SmallShardCount, cmap.XXHash),
+
+		nestedSubincludes: map[BuildLabel][]BuildLabel{},
 	}
 	return g
 }
 
+// AddNestedSubinclude records that a subincluded target has itself subincluded another one.
+func (graph *BuildGraph) AddNestedSubinclude(parent, child BuildLabel) {
+	graph.nestedMutex.Lock()
+	defer graph.nestedMutex.Unlock()
+	if !slices.Contains(graph.nestedSubincludes[parent], child) {
+		graph.nestedSubincludes[parent] = append(graph.nestedSubincludes[parent], child)
+	}
+}
+
+// NestedSubincludes returns the targets that the given subincluded target has itself subincluded.
+func (graph *BuildGraph) NestedSubincludes(label BuildLabel) []BuildLabel {
+	graph.nestedMutex.Lock()
+	defer graph.nestedMutex.Unlock()
+	return slices.Clone(graph.nestedSubincludes[label])
+}
+
 // DependentTargets returns the labels that 'from' should actually depend on when it declared a dependency on 'to'.
 // This is normally just 'to' but could be otherwise given require/provide shenanigans.
 func (graph *BuildGraph) DependentTargets(from, to BuildLabel) []BuildLabel {
@@ -0,0 +1,197 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/please-build/gcfg"
+)
+
+// A Policy defines repo-wide rules about which targets may depend on which others.
+// These complement visibility, which is declared per-target and can only allow things; a policy can
+// express larger-scale architectural rules like "nothing under //services may depend on //tools/internal".
+//
+// Policies are defined in a file in the same format as .plzconfig, for example:
+//
+//	[deny "services-internal-tools"]
+//	from = //services/...
+//	to = //tools/internal/...
+//	message = Services should use the public tools in //tools/public instead.
+//
+//	[exception "legacy-migrator"]
+//	rule = services-internal-tools
+//	from = //services/legacy:migrator
+//	to = //tools/internal:codegen
+//	justification = Needed until the legacy migration is finished.
+type Policy struct {
+	Deny      map[string]*PolicyRule
+	Exception map[string]*PolicyException
+	// Names of the rules & exceptions in sorted order, so we check them deterministically.
+	rules, exceptions []string
+}
+
+// A PolicyRule denies direct dependencies from any target matching From onto any matching To.
+type PolicyRule struct {
+	From    []string
+	To      []string
+	Message string
+	// If Report is true, violations are only reported and don't fail the build. This is useful for rolling
+	// out new rules gradually.
+	Report bool
+}
+
+// A PolicyException allows some dependencies that would otherwise be denied by a rule.
+// Each must give a justification of why it's needed.
+type PolicyException struct {
+	Rule          string
+	From          []string
+	To            []string
+	Justification string
+}
+
+// A PolicyViolation is a single dependency that matches a rule.
+type PolicyViolation struct {
+	From, To BuildLabel
+	Rule     string
+	Message  string
+	Report   bool
+	// The exception that allows this dependency, and its justification, if there is one.
+	Exception, Justification string
+}
+
+// Allowed returns true if this dependency is permitted anyway, either because of an exception or because the
+// rule is only reporting violations.
+func (v *PolicyViolation) Allowed() bool {
+	return v.Exception != "" || v.Report
+}
+
+// Error returns a description of this violation suitable for an error message.
+func (v *PolicyViolation) Error() string {
+	msg := fmt.Sprintf("%s can't depend on %s, it's denied by policy rule %s", v.From, v.To, v.Rule)
+	if v.Message != "" {
+		return msg + ": " + v.Message
+	}
+	return msg
+}
+
+// LoadPolicy loads a policy from the given file.
+func LoadPolicy(filename string) (*Policy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	policy := &Policy{}
+	if err := gcfg.ReadInto(policy, f); err != nil {
+		return nil, fmt.Errorf("Failed to read policy file %s: %w", filename, err)
+	}
+	return policy, policy.validate()
+}
+
+// validate checks that the policy is well-formed.
+func (policy *Policy) validate() error {
+	checkGlobs := func(name, field string, globs []string) error {
+		if len(globs) == 0 {
+			return fmt.Errorf("Policy %s has no %s patterns", name, field)
+		}
+		for _, glob := range globs {
+			if _, err := path.Match(glob, ""); err != nil || !strings.HasPrefix(glob, "//") {
+				return fmt.Errorf("Invalid pattern %s in policy %s", glob, name)
+			}
+		}
+		return nil
+	}
+	for name, rule := range policy.Deny {
+		if err := checkGlobs(name, "from", rule.From); err != nil {
+			return err
+		} else if err := checkGlobs(name, "to", rule.To); err != nil {
+			return err
+		}
+		policy.rules = append(policy.rules, name)
+	}
+	sort.Strings(policy.rules)
+	for name, exception := range policy.Exception {
+		if _, present := policy.Deny[exception.Rule]; !present {
+			return fmt.Errorf("Policy exception %s refers to unknown rule %s", name, exception.Rule)
+		} else if strings.TrimSpace(exception.Justification) == "" {
+			return fmt.Errorf("Policy exception %s must have a justification", name)
+		} else if err := checkGlobs(name, "from", exception.From); err != nil {
+			return err
+		} else if err := checkGlobs(name, "to", exception.To); err != nil {
+			return err
+		}
+		policy.exceptions = append(policy.exceptions, name)
+	}
+	sort.Strings(policy.exceptions)
+	return nil
+}
+
+// Check returns any violations of this policy by a dependency from one target onto another.
+// Violations that are allowed by an exception are still returned, with the exception set.
+func (policy *Policy) Check(from, to BuildLabel) []*PolicyViolation {
+	var ret []*PolicyViolation
+	for _, name := range policy.rules {
+		rule := policy.Deny[name]
+		if !matchesAnyPolicyGlob(rule.From, from) || !matchesAnyPolicyGlob(rule.To, to) {
+			continue
+		}
+		v := &PolicyViolation{From: from, To: to, Rule: name, Message: rule.Message, Report: rule.Report}
+		for _, exName := range policy.exceptions {
+			if exception := policy.Exception[exName]; exception.Rule == name && matchesAnyPolicyGlob(exception.From, from) && matchesAnyPolicyGlob(exception.To, to) {
+				v.Exception = exName
+				v.Justification = exception.Justification
+				break
+			}
+		}
+		ret = append(ret, v)
+	}
+	return ret
+}
+
+func matchesAnyPolicyGlob(globs []string, label BuildLabel) bool {
+	for _, glob := range globs {
+		if matchPolicyGlob(glob, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPolicyGlob returns true if the given glob matches the label. Globs are build labels which can contain
+// shell-style wildcards (e.g. //services/*/api:all); like build labels, /... matches any subpackage and a
+// pattern without a target name matches everything in the package. Hidden targets match as their parents do.
+func matchPolicyGlob(glob string, label BuildLabel) bool {
+	label = label.Parent()
+	pkg := "//" + label.PackageName
+	if label.Subrepo != "" {
+		pkg = "///" + label.Subrepo + pkg
+	}
+	pkgGlob, nameGlob, hasName := strings.Cut(glob, ":")
+	if !hasName || nameGlob == "all" {
+		nameGlob = "*"
+	}
+	if matched, _ := path.Match(nameGlob, label.Name); !matched {
+		return false
+	}
+	base, recursive := strings.CutSuffix(pkgGlob, "/...")
+	if !recursive {
+		matched, _ := path.Match(pkgGlob, pkg)
+		return matched
+	} else if base == "/" {
+		return label.Subrepo == ""
+	}
+	// Try the package and each of its parents in turn.
+	for p := pkg; ; {
+		if matched, _ := path.Match(base, p); matched {
+			return true
+		}
+		idx := strings.LastIndexByte(p, '/')
+		if idx <= 1 || p[idx-1] == '/' {
+			return false
+		}
+		p = p[:idx]
+	}
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy("src/core/test_data/policy.plzpolicy")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(policy.Deny))
+	assert.Equal(t, []string{"//services/..."}, policy.Deny["services-internal-tools"].From)
+	assert.Equal(t, "Use the public tools in //tools/public instead.", policy.Deny["services-internal-tools"].Message)
+	assert.True(t, policy.Deny["layering"].Report)
+	assert.Equal(t, "services-internal-tools", policy.Exception["legacy-migrator"].Rule)
+}
+
+func TestPolicyCheck(t *testing.T) {
+	policy, err := LoadPolicy("src/core/test_data/policy.plzpolicy")
+	require.NoError(t, err)
+
+	assert.Equal(t, 0, len(policy.Check(ParseBuildLabel("//services/foo:foo", ""), ParseBuildLabel("//tools/public:codegen", ""))))
+	assert.Equal(t, 0, len(policy.Check(ParseBuildLabel("//tools/internal:foo", ""), ParseBuildLabel("//services/foo:foo", ""))))
+
+	vs := policy.Check(ParseBuildLabel("//services/foo:_foo#lib", ""), ParseBuildLabel("//tools/internal/codegen:codegen", ""))
+	require.Equal(t, 1, len(vs))
+	assert.False(t, vs[0].Allowed())
+	assert.Equal(t, "//services/foo:_foo#lib can't depend on //tools/internal/codegen:codegen, it's denied by policy rule services-internal-tools: Use the public tools in //tools/public instead.", vs[0].Error())
+
+	vs = policy.Check(ParseBuildLabel("//services/legacy:migrator", ""), ParseBuildLabel("//tools/internal:codegen", ""))
+	require.Equal(t, 1, len(vs))
+	assert.True(t, vs[0].Allowed())
+	assert.Equal(t, "legacy-migrator", vs[0].Exception)
+	assert.Equal(t, "Needed until the legacy migration is finished.", vs[0].Justification)
+
+	vs = policy.Check(ParseBuildLabel("//lib/strings:strings", ""), ParseBuildLabel("//app:main", ""))
+	require.Equal(t, 1, len(vs))
+	assert.True(t, vs[0].Allowed())
+	assert.True(t, vs[0].Report)
+}
+
+func TestPolicyValidation(t *testing.T) {
+	validate := func(policy *Policy) error {
+		if policy.Deny == nil {
+			policy.Deny = map[string]*PolicyRule{"rule": {From: []string{"//a/..."}, To: []string{"//b/..."}}}
+		}
+		return policy.validate()
+	}
+	assert.NoError(t, validate(&Policy{}))
+	assert.Error(t, validate(&Policy{Deny: map[string]*PolicyRule{"rule": {From: []string{"//a/..."}}}}))
+	assert.Error(t, validate(&Policy{Deny: map[string]*PolicyRule{"rule": {From: []string{"a/..."}, To: []string{"//b/..."}}}}))
+	assert.Error(t, validate(&Policy{Deny: map[string]*PolicyRule{"rule": {From: []string{"//a/[..."}, To: []string{"//b/..."}}}}))
+	assert.NoError(t, validate(&Policy{Exception: map[string]*PolicyException{
+		"ex": {Rule: "rule", From: []string{"//a:x"}, To: []string{"//b:y"}, Justification: "because"},
+	}}))
+	assert.Error(t, validate(&Policy{Exception: map[string]*PolicyException{
+		"ex": {Rule: "rule", From: []string{"//a:x"}, To: []string{"//b:y"}},
+	}}))
+	assert.Error(t, validate(&Policy{Exception: map[string]*PolicyException{
+		"ex": {Rule: "wibble", From: []string{"//a:x"}, To: []string{"//b:y"}, Justification: "because"},
+	}}))
+}
+
+func TestMatchPolicyGlob(t *testing.T) {
+	for _, tc := range []struct {
+		glob, label string
+		matches     bool
+	}{
+		{"//...", "//src/core:core", true},
+		{"//...", "///subrepo//src/core:core", false},
+		{"//src/...", "//src/core:core", true},
+		{"//src/...", "//src:src", true},
+		{"//src/...", "//srcs/core:core", false},
+		{"//src/core", "//src/core:core", true},
+		{"//src/core", "//src/core/test:test", false},
+		{"//src/core:all", "//src/core:core", true},
+		{"//src/core:core", "//src/core:_core#lib", true},
+		{"//src/core:core", "//src/core:core_test", false},
+		{"//src/core:*_test", "//src/core:core_test", true},
+		{"//services/*/api", "//services/payments/api:api", true},
+		{"//services/*/api", "//services/payments/internal:api", false},
+		{"//services/*/api/...", "//services/payments/api/v1:api", true},
+		{"//services/...:api", "//services/payments/api:api", true},
+		{"//services/...:api", "//services/payments/api:client", false},
+		{"///subrepo//src/...", "///subrepo//src/core:core", true},
+	} {
+		t.Run(tc.glob+"/"+tc.label, func(t *testing.T) {
+			assert.Equal(t, tc.matches, matchPolicyGlob(tc.glob, ParseBuildLabel(tc.label, "")))
+		})
+	}
+}
+
+func TestCheckDependencyPolicy(t *testing.T) {
+	policy, err := LoadPolicy("src/core/test_data/policy.plzpolicy")
+	require.NoError(t, err)
+	codegen := makeTarget1("//tools/internal:codegen", "PUBLIC")
+	migrator := makeTarget1("//services/legacy:migrator", "", codegen)
+	server := makeTarget1("//services/legacy:server", "", codegen)
+	app := makeTarget1("//app:main", "PUBLIC")
+	lib := makeTarget1("//lib:lib", "", app)
+
+	state := NewDefaultBuildState()
+	state.Policy = policy
+	for _, target := range []*BuildTarget{codegen, migrator, server, app, lib} {
+		state.Graph.AddTarget(target)
+	}
+	assert.NoError(t, migrator.CheckDependencyVisibility(state))
+	assert.Error(t, server.CheckDependencyVisibility(state))
+	assert.NoError(t, lib.CheckDependencyVisibility(state)) // Only reported
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A CallSite is a single call to a function in the build language.
+type CallSite struct {
+	// The function that was called.
+	Function string `json:"function"`
+	// The file & line that it was called from.
+	Filename string `json:"filename"`
+	Line     int    `json:"line"`
+}
+
+// String returns a human-readable description of this call, e.g. "go_library() at src/core/BUILD:3".
+func (call CallSite) String() string {
+	return fmt.Sprintf("%s() at %s:%d", call.Function, call.Filename, call.Line)
+}
+
+// DeclaredAt returns the outermost call in the build language that led to this target being created, which is
+// normally the macro call in its BUILD file. It returns false if the target's call stack wasn't recorded.
+func (target *BuildTarget) DeclaredAt() (CallSite, bool) {
+	if len(target.CallStack) == 0 {
+		return CallSite{}, false
+	}
+	return target.CallStack[len(target.CallStack)-1], true
+}
+
+// Provenance returns a description of where this target was declared, followed by the rest of the chain of
+// calls that created it, outermost first. The innermost call is omitted since it's always build_rule.
+// It returns nil if the target's call stack wasn't recorded.
+func (target *BuildTarget) Provenance() []string {
+	call, ok := target.DeclaredAt()
+	if !ok {
+		return nil
+	}
+	lines := []string{"declared by " + call.String()}
+	for i := len(target.CallStack) - 2; i > 0; i-- {
+		lines = append(lines, "via "+target.CallStack[i].String())
+	}
+	return lines
+}
+
+// SetDependencySite records where the given dependency of this target was declared in the build language.
+// Only the first place it's declared is kept.
+func (target *BuildTarget) SetDependencySite(dep BuildLabel, site CallSite) {
+	if info := target.dependencyInfo(dep); info != nil && info.site == nil {
+		info.site = &site
+	}
+}
+
+// DependencySite returns where the given dependency of this target was declared, which is either the entry in
+// its deps (or the call that passed them) or the call to add_dep that added it. The dependency can also be
+// a target that a declared one resolved to (e.g. via provides). It returns false if that wasn't recorded.
+func (target *BuildTarget) DependencySite(dep BuildLabel) (CallSite, bool) {
+	for _, info := range target.dependencies {
+		if info.site != nil && (*info.declared == dep || slices.ContainsFunc(info.deps, func(t *BuildTarget) bool { return t.Label == dep })) {
+			return *info.site, true
+		}
+	}
+	return CallSite{}, false
+}
+
+// DescribeCycle returns a description of a cycle of targets, where each depends on the next and the
+// last depends on the first. If their provenance was recorded, it shows where each dependency was declared,
+// followed by where the depending target was declared and the chain of macro calls that generated it.
+func DescribeCycle(cycle []*BuildTarget) string {
+	var sb strings.Builder
+	sb.WriteString(cycle[0].Label.String())
+	for i := range cycle {
+		from := cycle[i]
+		to := cycle[(i+1)%len(cycle)]
+		sb.WriteString("\n -> ")
+		sb.WriteString(to.Label.String())
+		if site, ok := from.DependencySite(to.Label); ok {
+			sb.WriteString("\n      dependency declared in ")
+			sb.WriteString(site.String())
+		}
+		for j, line := range from.Provenance() {
+			if j == 0 {
+				sb.WriteString("\n      ")
+				sb.WriteString(from.Label.String())
+				sb.WriteString(" was ")
+			} else {
+				sb.WriteString("\n        ")
+			}
+			sb.WriteString(line)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvenance(t *testing.T) {
+	target := NewBuildTarget(ParseBuildLabel("//src/core:_lib#srcs", ""))
+	_, ok := target.DeclaredAt()
+	assert.False(t, ok)
+	assert.Nil(t, target.Provenance())
+
+	target.CallStack = []CallSite{
+		{Function: "build_rule", Filename: "build_defs/lib.build_defs", Line: 2},
+		{Function: "_srcs", Filename: "build_defs/lib.build_defs", Line: 8},
+		{Function: "my_library", Filename: "src/core/BUILD", Line: 5},
+	}
+	call, ok := target.DeclaredAt()
+	assert.True(t, ok)
+	assert.Equal(t, "my_library() at src/core/BUILD:5", call.String())
+	assert.Equal(t, []string{
+		"declared by my_library() at src/core/BUILD:5",
+		"via _srcs() at build_defs/lib.build_defs:8",
+	}, target.Provenance())
+}
+
+func TestDependencySite(t *testing.T) {
+	target := NewBuildTarget(ParseBuildLabel("//src/core:core", ""))
+	dep := ParseBuildLabel("//src/fs:fs", "")
+	target.AddDependency(dep)
+	_, ok := target.DependencySite(dep)
+	assert.False(t, ok)
+
+	site := CallSite{Function: "go_library", Filename: "src/core/BUILD", Line: 7}
+	target.SetDependencySite(dep, site)
+	target.SetDependencySite(dep, CallSite{Function: "add_dep", Filename: "src/core/BUILD", Line: 12})
+	s, ok := target.DependencySite(dep)
+	assert.True(t, ok)
+	assert.Equal(t, site, s, "only the first site should be kept")
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/thought-machine/please/src/cli"
+	pb "github.com/thought-machine/please/src/core/snapshot"
+)
+
+// graphSnapshotVersion is incremented whenever the format of a snapshot changes incompatibly.
+const graphSnapshotVersion = 2
+
+// WriteGraphSnapshot writes a snapshot of all the packages & targets in the given graph.
+// It's written as a gzipped Snapshot message (defined in src/core/snapshot/snapshot.proto), which is much
+// smaller & faster to load than the JSON from `plz query graph` and contains enough to reconstruct a
+// BuildGraph that queries can run against without reparsing.
+// Any pre- or post-build functions on targets are not recorded.
+func WriteGraphSnapshot(w io.Writer, graph *BuildGraph) error {
+	snapshot := &pb.Snapshot{
+		Version:       graphSnapshotVersion,
+		PleaseVersion: PleaseVersion,
+	}
+	for _, subrepo := range graph.subrepos.Values() {
+		s := &pb.Subrepo{
+			Name:                  subrepo.Name,
+			Root:                  subrepo.Root,
+			PackageRoot:           subrepo.PackageRoot,
+			Os:                    subrepo.Arch.OS,
+			Arch:                  subrepo.Arch.Arch,
+			IsCrossCompile:        subrepo.IsCrossCompile,
+			AdditionalConfigFiles: subrepo.AdditionalConfigFiles,
+		}
+		if subrepo.Target != nil {
+			s.Target = labelToProto(subrepo.Target.Label)
+		}
+		snapshot.Subrepos = append(snapshot.Subrepos, s)
+	}
+	sort.Slice(snapshot.Subrepos, func(i, j int) bool { return snapshot.Subrepos[i].Name < snapshot.Subrepos[j].Name })
+	for _, pkg := range graph.packages.Values() {
+		p := &pb.Package{
+			Name:        pkg.Name,
+			Subrepo:     pkg.SubrepoName,
+			Filename:    pkg.Filename,
+			Subincludes: labelsToProto(pkg.Subincludes),
+		}
+		for _, target := range pkg.AllTargets() {
+			t := targetRecordToProto(NewTargetRecord(target))
+			t.RuleHash = target.RuleHash
+			t.CallStack = callStackToProto(target.CallStack)
+			p.Targets = append(p.Targets, t)
+		}
+		snapshot.Packages = append(snapshot.Packages, p)
+	}
+	sort.Slice(snapshot.Packages, func(i, j int) bool {
+		if snapshot.Packages[i].Subrepo != snapshot.Packages[j].Subrepo {
+			return snapshot.Packages[i].Subrepo < snapshot.Packages[j].Subrepo
+		}
+		return snapshot.Packages[i].Name < snapshot.Packages[j].Name
+	})
+	b, err := proto.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// LoadGraphSnapshot populates this state's graph from a snapshot previously written by WriteGraphSnapshot,
+// and marks the given labels as the original targets, as though they had been parsed.
+// The graph should be empty beforehand.
+func (state *BuildState) LoadGraphSnapshot(r io.Reader, labels []BuildLabel) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("Failed to read graph snapshot: %w", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		return fmt.Errorf("Failed to read graph snapshot: %w", err)
+	}
+	snapshot := &pb.Snapshot{}
+	if err := proto.Unmarshal(b, snapshot); err != nil {
+		return fmt.Errorf("Failed to decode graph snapshot: %w", err)
+	} else if snapshot.Version != graphSnapshotVersion {
+		return fmt.Errorf("Graph snapshot has version %d, but this version of Please (%s) requires version %d", snapshot.Version, PleaseVersion, graphSnapshotVersion)
+	}
+	for _, s := range snapshot.Subrepos {
+		state.Graph.AddSubrepo(&Subrepo{
+			Name:                  s.Name,
+			Root:                  s.Root,
+			PackageRoot:           s.PackageRoot,
+			State:                 state,
+			Arch:                  cli.NewArch(s.Os, s.Arch),
+			IsCrossCompile:        s.IsCrossCompile,
+			AdditionalConfigFiles: s.AdditionalConfigFiles,
+		})
+	}
+	for _, p := range snapshot.Packages {
+		pkg := NewPackageSubrepo(p.Name, p.Subrepo)
+		pkg.Filename = p.Filename
+		pkg.Subincludes = labelsFromProto(p.Subincludes)
+		if p.Subrepo != "" {
+			if pkg.Subrepo = state.Graph.Subrepo(p.Subrepo); pkg.Subrepo == nil {
+				return fmt.Errorf("Package %s is in unknown subrepo %s", p.Name, p.Subrepo)
+			}
+		}
+		for _, t := range p.Targets {
+			record := targetRecordFromProto(t)
+			target, err := record.Target(pkg.Subrepo)
+			if err != nil {
+				return fmt.Errorf("Failed to load %s from graph snapshot: %w", record.Label, err)
+			}
+			target.RuleHash = t.RuleHash
+			target.CallStack = callStackFromProto(t.CallStack)
+			state.AddTarget(pkg, target)
+		}
+		state.Graph.AddPackage(pkg)
+	}
+	for _, s := range snapshot.Subrepos {
+		if s.Target != nil {
+			state.Graph.Subrepo(s.Name).Target = state.Graph.Target(labelFromProto(s.Target))
+		}
+	}
+	// Now everything is in the graph we can resolve dependencies. We can't use resolveDependencies since
+	// it would wait forever for targets outside the snapshot, so we leave those unresolved.
+	for _, target := range state.Graph.AllTargets() {
+		target.resolveSnapshotDependencies(state.Graph)
+	}
+	for _, label := range labels {
+		if !label.IsAllSubpackages() {
+			state.progress.originalTargets.Add(label)
+			continue
+		}
+		// We'd normally expand these by walking the filesystem; here we use the packages in the snapshot.
+		for _, p := range snapshot.Packages {
+			if pkgLabel := (BuildLabel{PackageName: p.Name, Name: "all", Subrepo: p.Subrepo}); p.Subrepo == label.Subrepo && label.Includes(pkgLabel) {
+				state.progress.originalTargets.Add(pkgLabel)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSnapshotDependencies resolves the dependencies of a target loaded from a snapshot against
+// the targets that are already in the graph.
+func (target *BuildTarget) resolveSnapshotDependencies(graph *BuildGraph) {
+	target.mutex.Lock()
+	defer target.mutex.Unlock()
+	for i := range target.dependencies {
+		dep := &target.dependencies[i]
+		depTarget := graph.Target(*dep.declared)
+		if depTarget == nil {
+			continue
+		}
+		dep.declared = &depTarget.Label
+		providesLabels, ok := depTarget.provideFor(target)
+		if !ok {
+			dep.deps = []*BuildTarget{depTarget}
+			continue
+		}
+		for _, l := range providesLabels {
+			if t := graph.Target(l); t != nil {
+				dep.deps = append(dep.deps, t)
+			}
+		}
+	}
+}
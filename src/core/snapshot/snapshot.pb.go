@@ -0,0 +1,2377 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        (unknown)
+// source: src/core/snapshot/snapshot.proto
+
+package snapshot
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// A Snapshot is the serialised form of a parsed build graph.
+type Snapshot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Incremented whenever the format changes incompatibly.
+	Version int32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// The version of Please that wrote the snapshot.
+	PleaseVersion string     `protobuf:"bytes,2,opt,name=please_version,json=pleaseVersion,proto3" json:"please_version,omitempty"`
+	Subrepos      []*Subrepo `protobuf:"bytes,3,rep,name=subrepos,proto3" json:"subrepos,omitempty"`
+	Packages      []*Package `protobuf:"bytes,4,rep,name=packages,proto3" json:"packages,omitempty"`
+}
+
+func (x *Snapshot) Reset() {
+	*x = Snapshot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Snapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snapshot) ProtoMessage() {}
+
+func (x *Snapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snapshot.ProtoReflect.Descriptor instead.
+func (*Snapshot) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Snapshot) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Snapshot) GetPleaseVersion() string {
+	if x != nil {
+		return x.PleaseVersion
+	}
+	return ""
+}
+
+func (x *Snapshot) GetSubrepos() []*Subrepo {
+	if x != nil {
+		return x.Subrepos
+	}
+	return nil
+}
+
+func (x *Snapshot) GetPackages() []*Package {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+// A Label identifies a single build target.
+type Label struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Package string `protobuf:"bytes,1,opt,name=package,proto3" json:"package,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Subrepo string `protobuf:"bytes,3,opt,name=subrepo,proto3" json:"subrepo,omitempty"`
+}
+
+func (x *Label) Reset() {
+	*x = Label{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Label) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Label) ProtoMessage() {}
+
+func (x *Label) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Label.ProtoReflect.Descriptor instead.
+func (*Label) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Label) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *Label) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Label) GetSubrepo() string {
+	if x != nil {
+		return x.Subrepo
+	}
+	return ""
+}
+
+// A Subrepo is a subrepository that packages in the graph can belong to.
+type Subrepo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name        string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Root        string `protobuf:"bytes,2,opt,name=root,proto3" json:"root,omitempty"`
+	PackageRoot string `protobuf:"bytes,3,opt,name=package_root,json=packageRoot,proto3" json:"package_root,omitempty"`
+	// The target that defines the subrepo, if there is one.
+	Target                *Label   `protobuf:"bytes,4,opt,name=target,proto3" json:"target,omitempty"`
+	Os                    string   `protobuf:"bytes,5,opt,name=os,proto3" json:"os,omitempty"`
+	Arch                  string   `protobuf:"bytes,6,opt,name=arch,proto3" json:"arch,omitempty"`
+	IsCrossCompile        bool     `protobuf:"varint,7,opt,name=is_cross_compile,json=isCrossCompile,proto3" json:"is_cross_compile,omitempty"`
+	AdditionalConfigFiles []string `protobuf:"bytes,8,rep,name=additional_config_files,json=additionalConfigFiles,proto3" json:"additional_config_files,omitempty"`
+}
+
+func (x *Subrepo) Reset() {
+	*x = Subrepo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Subrepo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Subrepo) ProtoMessage() {}
+
+func (x *Subrepo) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Subrepo.ProtoReflect.Descriptor instead.
+func (*Subrepo) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Subrepo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Subrepo) GetRoot() string {
+	if x != nil {
+		return x.Root
+	}
+	return ""
+}
+
+func (x *Subrepo) GetPackageRoot() string {
+	if x != nil {
+		return x.PackageRoot
+	}
+	return ""
+}
+
+func (x *Subrepo) GetTarget() *Label {
+	if x != nil {
+		return x.Target
+	}
+	return nil
+}
+
+func (x *Subrepo) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *Subrepo) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *Subrepo) GetIsCrossCompile() bool {
+	if x != nil {
+		return x.IsCrossCompile
+	}
+	return false
+}
+
+func (x *Subrepo) GetAdditionalConfigFiles() []string {
+	if x != nil {
+		return x.AdditionalConfigFiles
+	}
+	return nil
+}
+
+// A Package is a single BUILD file and the targets it defines.
+type Package struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The subrepo the package belongs to; empty for the host repo.
+	Subrepo     string    `protobuf:"bytes,2,opt,name=subrepo,proto3" json:"subrepo,omitempty"`
+	Filename    string    `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"`
+	Subincludes []*Label  `protobuf:"bytes,4,rep,name=subincludes,proto3" json:"subincludes,omitempty"`
+	Targets     []*Target `protobuf:"bytes,5,rep,name=targets,proto3" json:"targets,omitempty"`
+}
+
+func (x *Package) Reset() {
+	*x = Package{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Package) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Package) ProtoMessage() {}
+
+func (x *Package) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Package.ProtoReflect.Descriptor instead.
+func (*Package) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Package) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Package) GetSubrepo() string {
+	if x != nil {
+		return x.Subrepo
+	}
+	return ""
+}
+
+func (x *Package) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *Package) GetSubincludes() []*Label {
+	if x != nil {
+		return x.Subincludes
+	}
+	return nil
+}
+
+func (x *Package) GetTargets() []*Target {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+// A Target is a single build target. Any pre- or post-build functions are only recorded by name since
+// they can't be serialised.
+type Target struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Label               *Label              `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Dependencies        []*Dependency       `protobuf:"bytes,2,rep,name=dependencies,proto3" json:"dependencies,omitempty"`
+	Visibility          []*Label            `protobuf:"bytes,3,rep,name=visibility,proto3" json:"visibility,omitempty"`
+	Sources             []*Input            `protobuf:"bytes,4,rep,name=sources,proto3" json:"sources,omitempty"`
+	NamedSources        map[string]*Inputs  `protobuf:"bytes,5,rep,name=named_sources,json=namedSources,proto3" json:"named_sources,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Data                []*Input            `protobuf:"bytes,6,rep,name=data,proto3" json:"data,omitempty"`
+	NamedData           map[string]*Inputs  `protobuf:"bytes,7,rep,name=named_data,json=namedData,proto3" json:"named_data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Outputs             []string            `protobuf:"bytes,8,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	NamedOutputs        map[string]*Strings `protobuf:"bytes,9,rep,name=named_outputs,json=namedOutputs,proto3" json:"named_outputs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	OptionalOutputs     []string            `protobuf:"bytes,10,rep,name=optional_outputs,json=optionalOutputs,proto3" json:"optional_outputs,omitempty"`
+	Labels              []string            `protobuf:"bytes,11,rep,name=labels,proto3" json:"labels,omitempty"`
+	Command             string              `protobuf:"bytes,12,opt,name=command,proto3" json:"command,omitempty"`
+	Commands            map[string]string   `protobuf:"bytes,13,rep,name=commands,proto3" json:"commands,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Test                *Test               `protobuf:"bytes,14,opt,name=test,proto3" json:"test,omitempty"`
+	Debug               *Debug              `protobuf:"bytes,15,opt,name=debug,proto3" json:"debug,omitempty"`
+	BuildingDescription string              `protobuf:"bytes,16,opt,name=building_description,json=buildingDescription,proto3" json:"building_description,omitempty"`
+	Hashes              []string            `protobuf:"bytes,17,rep,name=hashes,proto3" json:"hashes,omitempty"`
+	Licences            []string            `protobuf:"bytes,18,rep,name=licences,proto3" json:"licences,omitempty"`
+	Secrets             []string            `protobuf:"bytes,19,rep,name=secrets,proto3" json:"secrets,omitempty"`
+	NamedSecrets        map[string]*Strings `protobuf:"bytes,20,rep,name=named_secrets,json=namedSecrets,proto3" json:"named_secrets,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	PreBuildFunction    string              `protobuf:"bytes,21,opt,name=pre_build_function,json=preBuildFunction,proto3" json:"pre_build_function,omitempty"`
+	PostBuildFunction   string              `protobuf:"bytes,22,opt,name=post_build_function,json=postBuildFunction,proto3" json:"post_build_function,omitempty"`
+	Requires            []string            `protobuf:"bytes,23,rep,name=requires,proto3" json:"requires,omitempty"`
+	Provides            map[string]*Labels  `protobuf:"bytes,24,rep,name=provides,proto3" json:"provides,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Tools               []*Input            `protobuf:"bytes,25,rep,name=tools,proto3" json:"tools,omitempty"`
+	NamedTools          map[string]*Inputs  `protobuf:"bytes,26,rep,name=named_tools,json=namedTools,proto3" json:"named_tools,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// These are unset if the target doesn't pass any environment variables through.
+	PassEnv                     *Strings             `protobuf:"bytes,27,opt,name=pass_env,json=passEnv,proto3" json:"pass_env,omitempty"`
+	PassUnsafeEnv               *Strings             `protobuf:"bytes,28,opt,name=pass_unsafe_env,json=passUnsafeEnv,proto3" json:"pass_unsafe_env,omitempty"`
+	BuildTimeout                *durationpb.Duration `protobuf:"bytes,29,opt,name=build_timeout,json=buildTimeout,proto3" json:"build_timeout,omitempty"`
+	OutputDirectories           []string             `protobuf:"bytes,30,rep,name=output_directories,json=outputDirectories,proto3" json:"output_directories,omitempty"`
+	EntryPoints                 map[string]string    `protobuf:"bytes,31,rep,name=entry_points,json=entryPoints,proto3" json:"entry_points,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Env                         map[string]string    `protobuf:"bytes,32,rep,name=env,proto3" json:"env,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	FileContent                 string               `protobuf:"bytes,33,opt,name=file_content,json=fileContent,proto3" json:"file_content,omitempty"`
+	IsBinary                    bool                 `protobuf:"varint,34,opt,name=is_binary,json=isBinary,proto3" json:"is_binary,omitempty"`
+	IsSubrepo                   bool                 `protobuf:"varint,35,opt,name=is_subrepo,json=isSubrepo,proto3" json:"is_subrepo,omitempty"`
+	TestOnly                    bool                 `protobuf:"varint,36,opt,name=test_only,json=testOnly,proto3" json:"test_only,omitempty"`
+	Sandbox                     bool                 `protobuf:"varint,37,opt,name=sandbox,proto3" json:"sandbox,omitempty"`
+	NeedsTransitiveDependencies bool                 `protobuf:"varint,38,opt,name=needs_transitive_dependencies,json=needsTransitiveDependencies,proto3" json:"needs_transitive_dependencies,omitempty"`
+	OutputIsComplete            bool                 `protobuf:"varint,39,opt,name=output_is_complete,json=outputIsComplete,proto3" json:"output_is_complete,omitempty"`
+	Stamp                       bool                 `protobuf:"varint,40,opt,name=stamp,proto3" json:"stamp,omitempty"`
+	Local                       bool                 `protobuf:"varint,41,opt,name=local,proto3" json:"local,omitempty"`
+	ExitOnError                 bool                 `protobuf:"varint,42,opt,name=exit_on_error,json=exitOnError,proto3" json:"exit_on_error,omitempty"`
+	IsFilegroup                 bool                 `protobuf:"varint,43,opt,name=is_filegroup,json=isFilegroup,proto3" json:"is_filegroup,omitempty"`
+	IsRemoteFile                bool                 `protobuf:"varint,44,opt,name=is_remote_file,json=isRemoteFile,proto3" json:"is_remote_file,omitempty"`
+	IsTextFile                  bool                 `protobuf:"varint,45,opt,name=is_text_file,json=isTextFile,proto3" json:"is_text_file,omitempty"`
+	ShowProgress                bool                 `protobuf:"varint,46,opt,name=show_progress,json=showProgress,proto3" json:"show_progress,omitempty"`
+	// The target's rule hash, if it had been calculated when the snapshot was taken.
+	RuleHash []byte `protobuf:"bytes,47,opt,name=rule_hash,json=ruleHash,proto3" json:"rule_hash,omitempty"`
+	// The call stack in the build language that created the target, innermost first, if it was recorded.
+	CallStack []*CallSite `protobuf:"bytes,48,rep,name=call_stack,json=callStack,proto3" json:"call_stack,omitempty"`
+}
+
+func (x *Target) Reset() {
+	*x = Target{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Target) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Target) ProtoMessage() {}
+
+func (x *Target) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Target.ProtoReflect.Descriptor instead.
+func (*Target) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Target) GetLabel() *Label {
+	if x != nil {
+		return x.Label
+	}
+	return nil
+}
+
+func (x *Target) GetDependencies() []*Dependency {
+	if x != nil {
+		return x.Dependencies
+	}
+	return nil
+}
+
+func (x *Target) GetVisibility() []*Label {
+	if x != nil {
+		return x.Visibility
+	}
+	return nil
+}
+
+func (x *Target) GetSources() []*Input {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *Target) GetNamedSources() map[string]*Inputs {
+	if x != nil {
+		return x.NamedSources
+	}
+	return nil
+}
+
+func (x *Target) GetData() []*Input {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Target) GetNamedData() map[string]*Inputs {
+	if x != nil {
+		return x.NamedData
+	}
+	return nil
+}
+
+func (x *Target) GetOutputs() []string {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Target) GetNamedOutputs() map[string]*Strings {
+	if x != nil {
+		return x.NamedOutputs
+	}
+	return nil
+}
+
+func (x *Target) GetOptionalOutputs() []string {
+	if x != nil {
+		return x.OptionalOutputs
+	}
+	return nil
+}
+
+func (x *Target) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Target) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Target) GetCommands() map[string]string {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+func (x *Target) GetTest() *Test {
+	if x != nil {
+		return x.Test
+	}
+	return nil
+}
+
+func (x *Target) GetDebug() *Debug {
+	if x != nil {
+		return x.Debug
+	}
+	return nil
+}
+
+func (x *Target) GetBuildingDescription() string {
+	if x != nil {
+		return x.BuildingDescription
+	}
+	return ""
+}
+
+func (x *Target) GetHashes() []string {
+	if x != nil {
+		return x.Hashes
+	}
+	return nil
+}
+
+func (x *Target) GetLicences() []string {
+	if x != nil {
+		return x.Licences
+	}
+	return nil
+}
+
+func (x *Target) GetSecrets() []string {
+	if x != nil {
+		return x.Secrets
+	}
+	return nil
+}
+
+func (x *Target) GetNamedSecrets() map[string]*Strings {
+	if x != nil {
+		return x.NamedSecrets
+	}
+	return nil
+}
+
+func (x *Target) GetPreBuildFunction() string {
+	if x != nil {
+		return x.PreBuildFunction
+	}
+	return ""
+}
+
+func (x *Target) GetPostBuildFunction() string {
+	if x != nil {
+		return x.PostBuildFunction
+	}
+	return ""
+}
+
+func (x *Target) GetRequires() []string {
+	if x != nil {
+		return x.Requires
+	}
+	return nil
+}
+
+func (x *Target) GetProvides() map[string]*Labels {
+	if x != nil {
+		return x.Provides
+	}
+	return nil
+}
+
+func (x *Target) GetTools() []*Input {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+func (x *Target) GetNamedTools() map[string]*Inputs {
+	if x != nil {
+		return x.NamedTools
+	}
+	return nil
+}
+
+func (x *Target) GetPassEnv() *Strings {
+	if x != nil {
+		return x.PassEnv
+	}
+	return nil
+}
+
+func (x *Target) GetPassUnsafeEnv() *Strings {
+	if x != nil {
+		return x.PassUnsafeEnv
+	}
+	return nil
+}
+
+func (x *Target) GetBuildTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.BuildTimeout
+	}
+	return nil
+}
+
+func (x *Target) GetOutputDirectories() []string {
+	if x != nil {
+		return x.OutputDirectories
+	}
+	return nil
+}
+
+func (x *Target) GetEntryPoints() map[string]string {
+	if x != nil {
+		return x.EntryPoints
+	}
+	return nil
+}
+
+func (x *Target) GetEnv() map[string]string {
+	if x != nil {
+		return x.Env
+	}
+	return nil
+}
+
+func (x *Target) GetFileContent() string {
+	if x != nil {
+		return x.FileContent
+	}
+	return ""
+}
+
+func (x *Target) GetIsBinary() bool {
+	if x != nil {
+		return x.IsBinary
+	}
+	return false
+}
+
+func (x *Target) GetIsSubrepo() bool {
+	if x != nil {
+		return x.IsSubrepo
+	}
+	return false
+}
+
+func (x *Target) GetTestOnly() bool {
+	if x != nil {
+		return x.TestOnly
+	}
+	return false
+}
+
+func (x *Target) GetSandbox() bool {
+	if x != nil {
+		return x.Sandbox
+	}
+	return false
+}
+
+func (x *Target) GetNeedsTransitiveDependencies() bool {
+	if x != nil {
+		return x.NeedsTransitiveDependencies
+	}
+	return false
+}
+
+func (x *Target) GetOutputIsComplete() bool {
+	if x != nil {
+		return x.OutputIsComplete
+	}
+	return false
+}
+
+func (x *Target) GetStamp() bool {
+	if x != nil {
+		return x.Stamp
+	}
+	return false
+}
+
+func (x *Target) GetLocal() bool {
+	if x != nil {
+		return x.Local
+	}
+	return false
+}
+
+func (x *Target) GetExitOnError() bool {
+	if x != nil {
+		return x.ExitOnError
+	}
+	return false
+}
+
+func (x *Target) GetIsFilegroup() bool {
+	if x != nil {
+		return x.IsFilegroup
+	}
+	return false
+}
+
+func (x *Target) GetIsRemoteFile() bool {
+	if x != nil {
+		return x.IsRemoteFile
+	}
+	return false
+}
+
+func (x *Target) GetIsTextFile() bool {
+	if x != nil {
+		return x.IsTextFile
+	}
+	return false
+}
+
+func (x *Target) GetShowProgress() bool {
+	if x != nil {
+		return x.ShowProgress
+	}
+	return false
+}
+
+func (x *Target) GetRuleHash() []byte {
+	if x != nil {
+		return x.RuleHash
+	}
+	return nil
+}
+
+func (x *Target) GetCallStack() []*CallSite {
+	if x != nil {
+		return x.CallStack
+	}
+	return nil
+}
+
+// A Dependency is a single declared dependency of a target.
+type Dependency struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Label    *Label `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Exported bool   `protobuf:"varint,2,opt,name=exported,proto3" json:"exported,omitempty"`
+	Internal bool   `protobuf:"varint,3,opt,name=internal,proto3" json:"internal,omitempty"`
+	Source   bool   `protobuf:"varint,4,opt,name=source,proto3" json:"source,omitempty"`
+	Data     bool   `protobuf:"varint,5,opt,name=data,proto3" json:"data,omitempty"`
+}
+
+func (x *Dependency) Reset() {
+	*x = Dependency{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Dependency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Dependency) ProtoMessage() {}
+
+func (x *Dependency) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Dependency.ProtoReflect.Descriptor instead.
+func (*Dependency) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Dependency) GetLabel() *Label {
+	if x != nil {
+		return x.Label
+	}
+	return nil
+}
+
+func (x *Dependency) GetExported() bool {
+	if x != nil {
+		return x.Exported
+	}
+	return false
+}
+
+func (x *Dependency) GetInternal() bool {
+	if x != nil {
+		return x.Internal
+	}
+	return false
+}
+
+func (x *Dependency) GetSource() bool {
+	if x != nil {
+		return x.Source
+	}
+	return false
+}
+
+func (x *Dependency) GetData() bool {
+	if x != nil {
+		return x.Data
+	}
+	return false
+}
+
+// The test-specific fields of a target.
+type Test struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Command       string               `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Commands      map[string]string    `protobuf:"bytes,2,rep,name=commands,proto3" json:"commands,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Tools         []*Input             `protobuf:"bytes,3,rep,name=tools,proto3" json:"tools,omitempty"`
+	NamedTools    map[string]*Inputs   `protobuf:"bytes,4,rep,name=named_tools,json=namedTools,proto3" json:"named_tools,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Timeout       *durationpb.Duration `protobuf:"bytes,5,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	Outputs       []string             `protobuf:"bytes,6,rep,name=outputs,proto3" json:"outputs,omitempty"`
+	Flakiness     uint32               `protobuf:"varint,7,opt,name=flakiness,proto3" json:"flakiness,omitempty"`
+	Shards        uint32               `protobuf:"varint,8,opt,name=shards,proto3" json:"shards,omitempty"`
+	ResultsFormat string               `protobuf:"bytes,9,opt,name=results_format,json=resultsFormat,proto3" json:"results_format,omitempty"`
+	Resources     []string             `protobuf:"bytes,10,rep,name=resources,proto3" json:"resources,omitempty"`
+	Sandbox       bool                 `protobuf:"varint,11,opt,name=sandbox,proto3" json:"sandbox,omitempty"`
+	NoOutput      bool                 `protobuf:"varint,12,opt,name=no_output,json=noOutput,proto3" json:"no_output,omitempty"`
+	NoCoverage    bool                 `protobuf:"varint,13,opt,name=no_coverage,json=noCoverage,proto3" json:"no_coverage,omitempty"`
+}
+
+func (x *Test) Reset() {
+	*x = Test{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Test) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Test) ProtoMessage() {}
+
+func (x *Test) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Test.ProtoReflect.Descriptor instead.
+func (*Test) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Test) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Test) GetCommands() map[string]string {
+	if x != nil {
+		return x.Commands
+	}
+	return nil
+}
+
+func (x *Test) GetTools() []*Input {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+func (x *Test) GetNamedTools() map[string]*Inputs {
+	if x != nil {
+		return x.NamedTools
+	}
+	return nil
+}
+
+func (x *Test) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+func (x *Test) GetOutputs() []string {
+	if x != nil {
+		return x.Outputs
+	}
+	return nil
+}
+
+func (x *Test) GetFlakiness() uint32 {
+	if x != nil {
+		return x.Flakiness
+	}
+	return 0
+}
+
+func (x *Test) GetShards() uint32 {
+	if x != nil {
+		return x.Shards
+	}
+	return 0
+}
+
+func (x *Test) GetResultsFormat() string {
+	if x != nil {
+		return x.ResultsFormat
+	}
+	return ""
+}
+
+func (x *Test) GetResources() []string {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+func (x *Test) GetSandbox() bool {
+	if x != nil {
+		return x.Sandbox
+	}
+	return false
+}
+
+func (x *Test) GetNoOutput() bool {
+	if x != nil {
+		return x.NoOutput
+	}
+	return false
+}
+
+func (x *Test) GetNoCoverage() bool {
+	if x != nil {
+		return x.NoCoverage
+	}
+	return false
+}
+
+// The fields of a target that are only used when debugging it.
+type Debug struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Command    string             `protobuf:"bytes,1,opt,name=command,proto3" json:"command,omitempty"`
+	Data       []*Input           `protobuf:"bytes,2,rep,name=data,proto3" json:"data,omitempty"`
+	NamedData  map[string]*Inputs `protobuf:"bytes,3,rep,name=named_data,json=namedData,proto3" json:"named_data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Tools      []*Input           `protobuf:"bytes,4,rep,name=tools,proto3" json:"tools,omitempty"`
+	NamedTools map[string]*Inputs `protobuf:"bytes,5,rep,name=named_tools,json=namedTools,proto3" json:"named_tools,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (x *Debug) Reset() {
+	*x = Debug{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Debug) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Debug) ProtoMessage() {}
+
+func (x *Debug) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Debug.ProtoReflect.Descriptor instead.
+func (*Debug) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Debug) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Debug) GetData() []*Input {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Debug) GetNamedData() map[string]*Inputs {
+	if x != nil {
+		return x.NamedData
+	}
+	return nil
+}
+
+func (x *Debug) GetTools() []*Input {
+	if x != nil {
+		return x.Tools
+	}
+	return nil
+}
+
+func (x *Debug) GetNamedTools() map[string]*Inputs {
+	if x != nil {
+		return x.NamedTools
+	}
+	return nil
+}
+
+// An Input is a single input to a target, e.g. a source file or a tool.
+type Input struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Input:
+	//	*Input_Label
+	//	*Input_AnnotatedOutput
+	//	*Input_File
+	//	*Input_SubrepoFile
+	//	*Input_SystemFile
+	//	*Input_SystemPath
+	//	*Input_Url
+	Input isInput_Input `protobuf_oneof:"input"`
+}
+
+func (x *Input) Reset() {
+	*x = Input{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Input) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Input) ProtoMessage() {}
+
+func (x *Input) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Input.ProtoReflect.Descriptor instead.
+func (*Input) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{8}
+}
+
+func (m *Input) GetInput() isInput_Input {
+	if m != nil {
+		return m.Input
+	}
+	return nil
+}
+
+func (x *Input) GetLabel() *Label {
+	if x, ok := x.GetInput().(*Input_Label); ok {
+		return x.Label
+	}
+	return nil
+}
+
+func (x *Input) GetAnnotatedOutput() *AnnotatedOutputInput {
+	if x, ok := x.GetInput().(*Input_AnnotatedOutput); ok {
+		return x.AnnotatedOutput
+	}
+	return nil
+}
+
+func (x *Input) GetFile() *FileInput {
+	if x, ok := x.GetInput().(*Input_File); ok {
+		return x.File
+	}
+	return nil
+}
+
+func (x *Input) GetSubrepoFile() *SubrepoFileInput {
+	if x, ok := x.GetInput().(*Input_SubrepoFile); ok {
+		return x.SubrepoFile
+	}
+	return nil
+}
+
+func (x *Input) GetSystemFile() string {
+	if x, ok := x.GetInput().(*Input_SystemFile); ok {
+		return x.SystemFile
+	}
+	return ""
+}
+
+func (x *Input) GetSystemPath() *SystemPathInput {
+	if x, ok := x.GetInput().(*Input_SystemPath); ok {
+		return x.SystemPath
+	}
+	return nil
+}
+
+func (x *Input) GetUrl() string {
+	if x, ok := x.GetInput().(*Input_Url); ok {
+		return x.Url
+	}
+	return ""
+}
+
+type isInput_Input interface {
+	isInput_Input()
+}
+
+type Input_Label struct {
+	// Another build target.
+	Label *Label `protobuf:"bytes,1,opt,name=label,proto3,oneof"`
+}
+
+type Input_AnnotatedOutput struct {
+	// A named output of another build target.
+	AnnotatedOutput *AnnotatedOutputInput `protobuf:"bytes,2,opt,name=annotated_output,json=annotatedOutput,proto3,oneof"`
+}
+
+type Input_File struct {
+	// A source file in a package.
+	File *FileInput `protobuf:"bytes,3,opt,name=file,proto3,oneof"`
+}
+
+type Input_SubrepoFile struct {
+	// A source file in a package in a subrepo.
+	SubrepoFile *SubrepoFileInput `protobuf:"bytes,4,opt,name=subrepo_file,json=subrepoFile,proto3,oneof"`
+}
+
+type Input_SystemFile struct {
+	// An absolute path to a file on the system.
+	SystemFile string `protobuf:"bytes,5,opt,name=system_file,json=systemFile,proto3,oneof"`
+}
+
+type Input_SystemPath struct {
+	// A binary to be found on the system path.
+	SystemPath *SystemPathInput `protobuf:"bytes,6,opt,name=system_path,json=systemPath,proto3,oneof"`
+}
+
+type Input_Url struct {
+	// A remote URL.
+	Url string `protobuf:"bytes,7,opt,name=url,proto3,oneof"`
+}
+
+func (*Input_Label) isInput_Input() {}
+
+func (*Input_AnnotatedOutput) isInput_Input() {}
+
+func (*Input_File) isInput_Input() {}
+
+func (*Input_SubrepoFile) isInput_Input() {}
+
+func (*Input_SystemFile) isInput_Input() {}
+
+func (*Input_SystemPath) isInput_Input() {}
+
+func (*Input_Url) isInput_Input() {}
+
+type AnnotatedOutputInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Label      *Label `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	Annotation string `protobuf:"bytes,2,opt,name=annotation,proto3" json:"annotation,omitempty"`
+}
+
+func (x *AnnotatedOutputInput) Reset() {
+	*x = AnnotatedOutputInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AnnotatedOutputInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnnotatedOutputInput) ProtoMessage() {}
+
+func (x *AnnotatedOutputInput) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnnotatedOutputInput.ProtoReflect.Descriptor instead.
+func (*AnnotatedOutputInput) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AnnotatedOutputInput) GetLabel() *Label {
+	if x != nil {
+		return x.Label
+	}
+	return nil
+}
+
+func (x *AnnotatedOutputInput) GetAnnotation() string {
+	if x != nil {
+		return x.Annotation
+	}
+	return ""
+}
+
+type FileInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	File    string `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	Package string `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+}
+
+func (x *FileInput) Reset() {
+	*x = FileInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FileInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInput) ProtoMessage() {}
+
+func (x *FileInput) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInput.ProtoReflect.Descriptor instead.
+func (*FileInput) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *FileInput) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *FileInput) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+type SubrepoFileInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	File        string `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	Package     string `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+	FullPackage string `protobuf:"bytes,3,opt,name=full_package,json=fullPackage,proto3" json:"full_package,omitempty"`
+}
+
+func (x *SubrepoFileInput) Reset() {
+	*x = SubrepoFileInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubrepoFileInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubrepoFileInput) ProtoMessage() {}
+
+func (x *SubrepoFileInput) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubrepoFileInput.ProtoReflect.Descriptor instead.
+func (*SubrepoFileInput) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SubrepoFileInput) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *SubrepoFileInput) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *SubrepoFileInput) GetFullPackage() string {
+	if x != nil {
+		return x.FullPackage
+	}
+	return ""
+}
+
+type SystemPathInput struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path []string `protobuf:"bytes,2,rep,name=path,proto3" json:"path,omitempty"`
+}
+
+func (x *SystemPathInput) Reset() {
+	*x = SystemPathInput{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SystemPathInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SystemPathInput) ProtoMessage() {}
+
+func (x *SystemPathInput) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SystemPathInput.ProtoReflect.Descriptor instead.
+func (*SystemPathInput) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SystemPathInput) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *SystemPathInput) GetPath() []string {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+// A CallSite is a single call to a function in the build language.
+type CallSite struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Function string `protobuf:"bytes,1,opt,name=function,proto3" json:"function,omitempty"`
+	Filename string `protobuf:"bytes,2,opt,name=filename,proto3" json:"filename,omitempty"`
+	Line     int32  `protobuf:"varint,3,opt,name=line,proto3" json:"line,omitempty"`
+}
+
+func (x *CallSite) Reset() {
+	*x = CallSite{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CallSite) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CallSite) ProtoMessage() {}
+
+func (x *CallSite) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CallSite.ProtoReflect.Descriptor instead.
+func (*CallSite) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *CallSite) GetFunction() string {
+	if x != nil {
+		return x.Function
+	}
+	return ""
+}
+
+func (x *CallSite) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *CallSite) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+// These wrap lists so they can be used as map values.
+type Inputs struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Inputs []*Input `protobuf:"bytes,1,rep,name=inputs,proto3" json:"inputs,omitempty"`
+}
+
+func (x *Inputs) Reset() {
+	*x = Inputs{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Inputs) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Inputs) ProtoMessage() {}
+
+func (x *Inputs) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Inputs.ProtoReflect.Descriptor instead.
+func (*Inputs) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Inputs) GetInputs() []*Input {
+	if x != nil {
+		return x.Inputs
+	}
+	return nil
+}
+
+type Strings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Values []string `protobuf:"bytes,1,rep,name=values,proto3" json:"values,omitempty"`
+}
+
+func (x *Strings) Reset() {
+	*x = Strings{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Strings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Strings) ProtoMessage() {}
+
+func (x *Strings) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Strings.ProtoReflect.Descriptor instead.
+func (*Strings) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Strings) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type Labels struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Labels []*Label `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty"`
+}
+
+func (x *Labels) Reset() {
+	*x = Labels{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_src_core_snapshot_snapshot_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Labels) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Labels) ProtoMessage() {}
+
+func (x *Labels) ProtoReflect() protoreflect.Message {
+	mi := &file_src_core_snapshot_snapshot_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Labels.ProtoReflect.Descriptor instead.
+func (*Labels) Descriptor() ([]byte, []int) {
+	return file_src_core_snapshot_snapshot_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *Labels) GetLabels() []*Label {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+var File_src_core_snapshot_snapshot_proto protoreflect.FileDescriptor
+
+var file_src_core_snapshot_snapshot_proto_rawDesc = []byte{
+	0x0a, 0x20, 0x73, 0x72, 0x63, 0x2f, 0x63, 0x6f, 0x72, 0x65, 0x2f, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x2f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x08, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x1a, 0x1e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xa9, 0x01, 0x0a,
+	0x08, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x25, 0x0a, 0x0e, 0x70, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x5f, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x70, 0x6c, 0x65,
+	0x61, 0x73, 0x65, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x2d, 0x0a, 0x08, 0x73, 0x75,
+	0x62, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x53, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x52,
+	0x08, 0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x73, 0x12, 0x2d, 0x0a, 0x08, 0x70, 0x61, 0x63,
+	0x6b, 0x61, 0x67, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6e,
+	0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x08,
+	0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x73, 0x22, 0x4f, 0x0a, 0x05, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x22, 0x83, 0x02, 0x0a, 0x07, 0x53, 0x75,
+	0x62, 0x72, 0x65, 0x70, 0x6f, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x6f,
+	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x72, 0x6f, 0x6f, 0x74, 0x12, 0x21, 0x0a,
+	0x0c, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x5f, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x52, 0x6f, 0x6f, 0x74,
+	0x12, 0x27, 0x0a, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x52, 0x06, 0x74, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x6f, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x6f, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x63,
+	0x68, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x63, 0x68, 0x12, 0x28, 0x0a,
+	0x10, 0x69, 0x73, 0x5f, 0x63, 0x72, 0x6f, 0x73, 0x73, 0x5f, 0x63, 0x6f, 0x6d, 0x70, 0x69, 0x6c,
+	0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0e, 0x69, 0x73, 0x43, 0x72, 0x6f, 0x73, 0x73,
+	0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x12, 0x36, 0x0a, 0x17, 0x61, 0x64, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x5f, 0x66, 0x69, 0x6c,
+	0x65, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x15, 0x61, 0x64, 0x64, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x61, 0x6c, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x22,
+	0xb2, 0x01, 0x0a, 0x07, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66, 0x69, 0x6c,
+	0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x31, 0x0a, 0x0b, 0x73, 0x75, 0x62, 0x69, 0x6e, 0x63, 0x6c,
+	0x75, 0x64, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x0b, 0x73, 0x75, 0x62,
+	0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x73, 0x12, 0x2a, 0x0a, 0x07, 0x74, 0x61, 0x72, 0x67,
+	0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x52, 0x07, 0x74, 0x61, 0x72,
+	0x67, 0x65, 0x74, 0x73, 0x22, 0xc7, 0x15, 0x0a, 0x06, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x12,
+	0x25, 0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f,
+	0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52,
+	0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x38, 0x0a, 0x0c, 0x64, 0x65, 0x70, 0x65, 0x6e, 0x64,
+	0x65, 0x6e, 0x63, 0x69, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x44, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e,
+	0x63, 0x79, 0x52, 0x0c, 0x64, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73,
+	0x12, 0x2f, 0x0a, 0x0a, 0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x0a, 0x76, 0x69, 0x73, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74,
+	0x79, 0x12, 0x29, 0x0a, 0x07, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x52, 0x07, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x47, 0x0a, 0x0d,
+	0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f, 0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x05, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54,
+	0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x53, 0x6f, 0x75, 0x72, 0x63,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x53, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x3e, 0x0a, 0x0a, 0x6e, 0x61,
+	0x6d, 0x65, 0x64, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f,
+	0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74,
+	0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x44, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x09, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x44, 0x61, 0x74, 0x61, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x08, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x73, 0x12, 0x47, 0x0a, 0x0d, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x6e,
+	0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x4e, 0x61,
+	0x6d, 0x65, 0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x0c, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x29, 0x0a,
+	0x10, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74,
+	0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61,
+	0x6c, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x6c, 0x61, 0x62, 0x65,
+	0x6c, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x73,
+	0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x0c, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x3a, 0x0a, 0x08, 0x63, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x0d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x43,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x63, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x65, 0x73, 0x74, 0x18, 0x0e,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x54, 0x65, 0x73, 0x74, 0x52, 0x04, 0x74, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x05, 0x64, 0x65,
+	0x62, 0x75, 0x67, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x52, 0x05, 0x64, 0x65, 0x62, 0x75,
+	0x67, 0x12, 0x31, 0x0a, 0x14, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x64, 0x65,
+	0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x10, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x13, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x69, 0x6e, 0x67, 0x44, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x11,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x1a, 0x0a, 0x08,
+	0x6c, 0x69, 0x63, 0x65, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x12, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08,
+	0x6c, 0x69, 0x63, 0x65, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x73, 0x18, 0x13, 0x20, 0x03, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x73, 0x12, 0x47, 0x0a, 0x0d, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f, 0x73, 0x65, 0x63, 0x72,
+	0x65, 0x74, 0x73, 0x18, 0x14, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x4e, 0x61, 0x6d, 0x65,
+	0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x6e,
+	0x61, 0x6d, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65, 0x74, 0x73, 0x12, 0x2c, 0x0a, 0x12, 0x70,
+	0x72, 0x65, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x18, 0x15, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x70, 0x72, 0x65, 0x42, 0x75, 0x69, 0x6c,
+	0x64, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2e, 0x0a, 0x13, 0x70, 0x6f, 0x73,
+	0x74, 0x5f, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x16, 0x20, 0x01, 0x28, 0x09, 0x52, 0x11, 0x70, 0x6f, 0x73, 0x74, 0x42, 0x75, 0x69, 0x6c,
+	0x64, 0x46, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x71,
+	0x75, 0x69, 0x72, 0x65, 0x73, 0x18, 0x17, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x72, 0x65, 0x71,
+	0x75, 0x69, 0x72, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x76, 0x69, 0x64, 0x65,
+	0x73, 0x18, 0x18, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68,
+	0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x64,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x76, 0x69, 0x64, 0x65,
+	0x73, 0x12, 0x25, 0x0a, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x19, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e, 0x70, 0x75,
+	0x74, 0x52, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x41, 0x0a, 0x0b, 0x6e, 0x61, 0x6d, 0x65,
+	0x64, 0x5f, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x1a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e,
+	0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e,
+	0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52,
+	0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x2c, 0x0a, 0x08, 0x70,
+	0x61, 0x73, 0x73, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e,
+	0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73,
+	0x52, 0x07, 0x70, 0x61, 0x73, 0x73, 0x45, 0x6e, 0x76, 0x12, 0x39, 0x0a, 0x0f, 0x70, 0x61, 0x73,
+	0x73, 0x5f, 0x75, 0x6e, 0x73, 0x61, 0x66, 0x65, 0x5f, 0x65, 0x6e, 0x76, 0x18, 0x1c, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x53, 0x74,
+	0x72, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x0d, 0x70, 0x61, 0x73, 0x73, 0x55, 0x6e, 0x73, 0x61, 0x66,
+	0x65, 0x45, 0x6e, 0x76, 0x12, 0x3e, 0x0a, 0x0d, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x1d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f,
+	0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75,
+	0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0c, 0x62, 0x75, 0x69, 0x6c, 0x64, 0x54, 0x69, 0x6d,
+	0x65, 0x6f, 0x75, 0x74, 0x12, 0x2d, 0x0a, 0x12, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x64,
+	0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x69, 0x65, 0x73, 0x18, 0x1e, 0x20, 0x03, 0x28, 0x09,
+	0x52, 0x11, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x44, 0x69, 0x72, 0x65, 0x63, 0x74, 0x6f, 0x72,
+	0x69, 0x65, 0x73, 0x12, 0x44, 0x0a, 0x0c, 0x65, 0x6e, 0x74, 0x72, 0x79, 0x5f, 0x70, 0x6f, 0x69,
+	0x6e, 0x74, 0x73, 0x18, 0x1f, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0b, 0x65, 0x6e,
+	0x74, 0x72, 0x79, 0x50, 0x6f, 0x69, 0x6e, 0x74, 0x73, 0x12, 0x2b, 0x0a, 0x03, 0x65, 0x6e, 0x76,
+	0x18, 0x20, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f,
+	0x74, 0x2e, 0x54, 0x61, 0x72, 0x67, 0x65, 0x74, 0x2e, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x03, 0x65, 0x6e, 0x76, 0x12, 0x21, 0x0a, 0x0c, 0x66, 0x69, 0x6c, 0x65, 0x5f, 0x63,
+	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x21, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x69,
+	0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x69, 0x73, 0x5f,
+	0x62, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x22, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x69, 0x73,
+	0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x73, 0x5f, 0x73, 0x75, 0x62,
+	0x72, 0x65, 0x70, 0x6f, 0x18, 0x23, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x69, 0x73, 0x53, 0x75,
+	0x62, 0x72, 0x65, 0x70, 0x6f, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x65, 0x73, 0x74, 0x5f, 0x6f, 0x6e,
+	0x6c, 0x79, 0x18, 0x24, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x74, 0x65, 0x73, 0x74, 0x4f, 0x6e,
+	0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62, 0x6f, 0x78, 0x18, 0x25, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62, 0x6f, 0x78, 0x12, 0x42, 0x0a, 0x1d,
+	0x6e, 0x65, 0x65, 0x64, 0x73, 0x5f, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x69, 0x74, 0x69, 0x76, 0x65,
+	0x5f, 0x64, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73, 0x18, 0x26, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x1b, 0x6e, 0x65, 0x65, 0x64, 0x73, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x69,
+	0x74, 0x69, 0x76, 0x65, 0x44, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x69, 0x65, 0x73,
+	0x12, 0x2c, 0x0a, 0x12, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x5f, 0x69, 0x73, 0x5f, 0x63, 0x6f,
+	0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x18, 0x27, 0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x6f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x49, 0x73, 0x43, 0x6f, 0x6d, 0x70, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x28, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x18, 0x29, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x05, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x12, 0x22, 0x0a, 0x0d, 0x65, 0x78,
+	0x69, 0x74, 0x5f, 0x6f, 0x6e, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0b, 0x65, 0x78, 0x69, 0x74, 0x4f, 0x6e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x21,
+	0x0a, 0x0c, 0x69, 0x73, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x2b,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x69, 0x73, 0x46, 0x69, 0x6c, 0x65, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x12, 0x24, 0x0a, 0x0e, 0x69, 0x73, 0x5f, 0x72, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x5f, 0x66,
+	0x69, 0x6c, 0x65, 0x18, 0x2c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c, 0x69, 0x73, 0x52, 0x65, 0x6d,
+	0x6f, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x20, 0x0a, 0x0c, 0x69, 0x73, 0x5f, 0x74, 0x65,
+	0x78, 0x74, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x2d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69,
+	0x73, 0x54, 0x65, 0x78, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x73, 0x68, 0x6f,
+	0x77, 0x5f, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x18, 0x2e, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0c, 0x73, 0x68, 0x6f, 0x77, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x1b,
+	0x0a, 0x09, 0x72, 0x75, 0x6c, 0x65, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x2f, 0x20, 0x01, 0x28,
+	0x0c, 0x52, 0x08, 0x72, 0x75, 0x6c, 0x65, 0x48, 0x61, 0x73, 0x68, 0x12, 0x31, 0x0a, 0x0a, 0x63,
+	0x61, 0x6c, 0x6c, 0x5f, 0x73, 0x74, 0x61, 0x63, 0x6b, 0x18, 0x30, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x12, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x43, 0x61, 0x6c, 0x6c, 0x53,
+	0x69, 0x74, 0x65, 0x52, 0x09, 0x63, 0x61, 0x6c, 0x6c, 0x53, 0x74, 0x61, 0x63, 0x6b, 0x1a, 0x51,
+	0x0a, 0x11, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x53, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x1a, 0x4e, 0x0a, 0x0e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x44, 0x61, 0x74, 0x61, 0x45, 0x6e,
+	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38,
+	0x01, 0x1a, 0x52, 0x0a, 0x11, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x27, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68,
+	0x6f, 0x74, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b, 0x0a, 0x0d, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
+	0x38, 0x01, 0x1a, 0x52, 0x0a, 0x11, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x53, 0x65, 0x63, 0x72, 0x65,
+	0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x27, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x2e, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x4d, 0x0a, 0x0d, 0x50, 0x72, 0x6f, 0x76, 0x69, 0x64,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x4f, 0x0a, 0x0f, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f,
+	0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70,
+	0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3e, 0x0a, 0x10, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x50,
+	0x6f, 0x69, 0x6e, 0x74, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
+	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x36, 0x0a, 0x08, 0x45, 0x6e, 0x76, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x97,
+	0x01, 0x0a, 0x0a, 0x44, 0x65, 0x70, 0x65, 0x6e, 0x64, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x25, 0x0a,
+	0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x05, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x65, 0x78, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64,
+	0x12, 0x1a, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x12, 0x16, 0x0a, 0x06,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x73, 0x6f,
+	0x75, 0x72, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0xf2, 0x04, 0x0a, 0x04, 0x54, 0x65, 0x73,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12, 0x38, 0x0a, 0x08, 0x63,
+	0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1c, 0x2e,
+	0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6f,
+	0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x63, 0x6f, 0x6d,
+	0x6d, 0x61, 0x6e, 0x64, 0x73, 0x12, 0x25, 0x0a, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x3f, 0x0a, 0x0b,
+	0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x54, 0x65, 0x73,
+	0x74, 0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x33, 0x0a,
+	0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x74, 0x69, 0x6d, 0x65, 0x6f,
+	0x75, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x18, 0x06, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x66, 0x6c, 0x61, 0x6b, 0x69, 0x6e, 0x65, 0x73, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x09, 0x66, 0x6c, 0x61, 0x6b, 0x69, 0x6e, 0x65, 0x73, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x68,
+	0x61, 0x72, 0x64, 0x73, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x06, 0x73, 0x68, 0x61, 0x72,
+	0x64, 0x73, 0x12, 0x25, 0x0a, 0x0e, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x5f, 0x66, 0x6f,
+	0x72, 0x6d, 0x61, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x72, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x73, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x73,
+	0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65,
+	0x73, 0x6f, 0x75, 0x72, 0x63, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62,
+	0x6f, 0x78, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x61, 0x6e, 0x64, 0x62, 0x6f,
+	0x78, 0x12, 0x1b, 0x0a, 0x09, 0x6e, 0x6f, 0x5f, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x6e, 0x6f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x1f,
+	0x0a, 0x0b, 0x6e, 0x6f, 0x5f, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0a, 0x6e, 0x6f, 0x43, 0x6f, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x1a,
+	0x3b, 0x0a, 0x0d, 0x43, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x4f, 0x0a, 0x0f,
+	0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
+	0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e, 0x70, 0x75,
+	0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0x8f, 0x03,
+	0x0a, 0x05, 0x44, 0x65, 0x62, 0x75, 0x67, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61,
+	0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e,
+	0x64, 0x12, 0x23, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74,
+	0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x12, 0x3d, 0x0a, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f,
+	0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73, 0x6e, 0x61,
+	0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x44, 0x65, 0x62, 0x75, 0x67, 0x2e, 0x4e, 0x61, 0x6d, 0x65,
+	0x64, 0x44, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x09, 0x6e, 0x61, 0x6d, 0x65,
+	0x64, 0x44, 0x61, 0x74, 0x61, 0x12, 0x25, 0x0a, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x05, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x12, 0x40, 0x0a, 0x0b,
+	0x6e, 0x61, 0x6d, 0x65, 0x64, 0x5f, 0x74, 0x6f, 0x6f, 0x6c, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x44, 0x65, 0x62,
+	0x75, 0x67, 0x2e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74,
+	0x72, 0x79, 0x52, 0x0a, 0x6e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x1a, 0x4e,
+	0x0a, 0x0e, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x44, 0x61, 0x74, 0x61, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e, 0x70,
+	0x75, 0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x4f,
+	0x0a, 0x0f, 0x4e, 0x61, 0x6d, 0x65, 0x64, 0x54, 0x6f, 0x6f, 0x6c, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x73, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22,
+	0xe7, 0x02, 0x0a, 0x05, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x27, 0x0a, 0x05, 0x6c, 0x61, 0x62,
+	0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73,
+	0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x61, 0x62,
+	0x65, 0x6c, 0x12, 0x4b, 0x0a, 0x10, 0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x65, 0x64, 0x5f,
+	0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x73,
+	0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x41, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x65,
+	0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x48, 0x00, 0x52, 0x0f,
+	0x61, 0x6e, 0x6e, 0x6f, 0x74, 0x61, 0x74, 0x65, 0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12,
+	0x29, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x70,
+	0x75, 0x74, 0x48, 0x00, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x3f, 0x0a, 0x0c, 0x73, 0x75,
+	0x62, 0x72, 0x65, 0x70, 0x6f, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1a, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x53, 0x75, 0x62, 0x72,
+	0x65, 0x70, 0x6f, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x48, 0x00, 0x52, 0x0b,
+	0x73, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x21, 0x0a, 0x0b, 0x73,
+	0x79, 0x73, 0x74, 0x65, 0x6d, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x48, 0x00, 0x52, 0x0a, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x3c,
+	0x0a, 0x0b, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x5f, 0x70, 0x61, 0x74, 0x68, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x53,
+	0x79, 0x73, 0x74, 0x65, 0x6d, 0x50, 0x61, 0x74, 0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x48, 0x00,
+	0x52, 0x0a, 0x73, 0x79, 0x73, 0x74, 0x65, 0x6d, 0x50, 0x61, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x03,
+	0x75, 0x72, 0x6c, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x48, 0x00, 0x52, 0x03, 0x75, 0x72, 0x6c,
+	0x42, 0x07, 0x0a, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x22, 0x5d, 0x0a, 0x14, 0x41, 0x6e, 0x6e,
+	0x6f, 0x74, 0x61, 0x74, 0x65, 0x64, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x49, 0x6e, 0x70, 0x75,
+	0x74, 0x12, 0x25, 0x0a, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65,
+	0x6c, 0x52, 0x05, 0x6c, 0x61, 0x62, 0x65, 0x6c, 0x12, 0x1e, 0x0a, 0x0a, 0x61, 0x6e, 0x6e, 0x6f,
+	0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x61, 0x6e,
+	0x6e, 0x6f, 0x74, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x39, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x63,
+	0x6b, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x63, 0x6b,
+	0x61, 0x67, 0x65, 0x22, 0x63, 0x0a, 0x10, 0x53, 0x75, 0x62, 0x72, 0x65, 0x70, 0x6f, 0x46, 0x69,
+	0x6c, 0x65, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x70,
+	0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x66, 0x75, 0x6c, 0x6c, 0x5f, 0x70, 0x61,
+	0x63, 0x6b, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x66, 0x75, 0x6c,
+	0x6c, 0x50, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x22, 0x39, 0x0a, 0x0f, 0x53, 0x79, 0x73, 0x74,
+	0x65, 0x6d, 0x50, 0x61, 0x74, 0x68, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x22, 0x56, 0x0a, 0x08, 0x43, 0x61, 0x6c, 0x6c, 0x53, 0x69, 0x74, 0x65, 0x12,
+	0x1a, 0x0a, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x66, 0x75, 0x6e, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x66,
+	0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x66,
+	0x69, 0x6c, 0x65, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x22, 0x31, 0x0a, 0x06, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74,
+	0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x52, 0x06, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x73, 0x22, 0x21,
+	0x0a, 0x07, 0x53, 0x74, 0x72, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x61, 0x6c,
+	0x75, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x73, 0x22, 0x31, 0x0a, 0x06, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x73, 0x12, 0x27, 0x0a, 0x06, 0x6c,
+	0x61, 0x62, 0x65, 0x6c, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e,
+	0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x2e, 0x4c, 0x61, 0x62, 0x65, 0x6c, 0x52, 0x06, 0x6c, 0x61,
+	0x62, 0x65, 0x6c, 0x73, 0x42, 0x35, 0x5a, 0x33, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63,
+	0x6f, 0x6d, 0x2f, 0x74, 0x68, 0x6f, 0x75, 0x67, 0x68, 0x74, 0x2d, 0x6d, 0x61, 0x63, 0x68, 0x69,
+	0x6e, 0x65, 0x2f, 0x70, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x2f, 0x73, 0x72, 0x63, 0x2f, 0x63, 0x6f,
+	0x72, 0x65, 0x2f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
+}
+
+var (
+	file_src_core_snapshot_snapshot_proto_rawDescOnce sync.Once
+	file_src_core_snapshot_snapshot_proto_rawDescData = file_src_core_snapshot_snapshot_proto_rawDesc
+)
+
+func file_src_core_snapshot_snapshot_proto_rawDescGZIP() []byte {
+	file_src_core_snapshot_snapshot_proto_rawDescOnce.Do(func() {
+		file_src_core_snapshot_snapshot_proto_rawDescData = protoimpl.X.CompressGZIP(file_src_core_snapshot_snapshot_proto_rawDescData)
+	})
+	return file_src_core_snapshot_snapshot_proto_rawDescData
+}
+
+var file_src_core_snapshot_snapshot_proto_msgTypes = make([]protoimpl.MessageInfo, 30)
+var file_src_core_snapshot_snapshot_proto_goTypes = []interface{}{
+	(*Snapshot)(nil),             // 0: snapshot.Snapshot
+	(*Label)(nil),                // 1: snapshot.Label
+	(*Subrepo)(nil),              // 2: snapshot.Subrepo
+	(*Package)(nil),              // 3: snapshot.Package
+	(*Target)(nil),               // 4: snapshot.Target
+	(*Dependency)(nil),           // 5: snapshot.Dependency
+	(*Test)(nil),                 // 6: snapshot.Test
+	(*Debug)(nil),                // 7: snapshot.Debug
+	(*Input)(nil),                // 8: snapshot.Input
+	(*AnnotatedOutputInput)(nil), // 9: snapshot.AnnotatedOutputInput
+	(*FileInput)(nil),            // 10: snapshot.FileInput
+	(*SubrepoFileInput)(nil),     // 11: snapshot.SubrepoFileInput
+	(*SystemPathInput)(nil),      // 12: snapshot.SystemPathInput
+	(*CallSite)(nil),             // 13: snapshot.CallSite
+	(*Inputs)(nil),               // 14: snapshot.Inputs
+	(*Strings)(nil),              // 15: snapshot.Strings
+	(*Labels)(nil),               // 16: snapshot.Labels
+	nil,                          // 17: snapshot.Target.NamedSourcesEntry
+	nil,                          // 18: snapshot.Target.NamedDataEntry
+	nil,                          // 19: snapshot.Target.NamedOutputsEntry
+	nil,                          // 20: snapshot.Target.CommandsEntry
+	nil,                          // 21: snapshot.Target.NamedSecretsEntry
+	nil,                          // 22: snapshot.Target.ProvidesEntry
+	nil,                          // 23: snapshot.Target.NamedToolsEntry
+	nil,                          // 24: snapshot.Target.EntryPointsEntry
+	nil,                          // 25: snapshot.Target.EnvEntry
+	nil,                          // 26: snapshot.Test.CommandsEntry
+	nil,                          // 27: snapshot.Test.NamedToolsEntry
+	nil,                          // 28: snapshot.Debug.NamedDataEntry
+	nil,                          // 29: snapshot.Debug.NamedToolsEntry
+	(*durationpb.Duration)(nil),  // 30: google.protobuf.Duration
+}
+var file_src_core_snapshot_snapshot_proto_depIdxs = []int32{
+	2,  // 0: snapshot.Snapshot.subrepos:type_name -> snapshot.Subrepo
+	3,  // 1: snapshot.Snapshot.packages:type_name -> snapshot.Package
+	1,  // 2: snapshot.Subrepo.target:type_name -> snapshot.Label
+	1,  // 3: snapshot.Package.subincludes:type_name -> snapshot.Label
+	4,  // 4: snapshot.Package.targets:type_name -> snapshot.Target
+	1,  // 5: snapshot.Target.label:type_name -> snapshot.Label
+	5,  // 6: snapshot.Target.dependencies:type_name -> snapshot.Dependency
+	1,  // 7: snapshot.Target.visibility:type_name -> snapshot.Label
+	8,  // 8: snapshot.Target.sources:type_name -> snapshot.Input
+	17, // 9: snapshot.Target.named_sources:type_name -> snapshot.Target.NamedSourcesEntry
+	8,  // 10: snapshot.Target.data:type_name -> snapshot.Input
+	18, // 11: snapshot.Target.named_data:type_name -> snapshot.Target.NamedDataEntry
+	19, // 12: snapshot.Target.named_outputs:type_name -> snapshot.Target.NamedOutputsEntry
+	20, // 13: snapshot.Target.commands:type_name -> snapshot.Target.CommandsEntry
+	6,  // 14: snapshot.Target.test:type_name -> snapshot.Test
+	7,  // 15: snapshot.Target.debug:type_name -> snapshot.Debug
+	21, // 16: snapshot.Target.named_secrets:type_name -> snapshot.Target.NamedSecretsEntry
+	22, // 17: snapshot.Target.provides:type_name -> snapshot.Target.ProvidesEntry
+	8,  // 18: snapshot.Target.tools:type_name -> snapshot.Input
+	23, // 19: snapshot.Target.named_tools:type_name -> snapshot.Target.NamedToolsEntry
+	15, // 20: snapshot.Target.pass_env:type_name -> snapshot.Strings
+	15, // 21: snapshot.Target.pass_unsafe_env:type_name -> snapshot.Strings
+	30, // 22: snapshot.Target.build_timeout:type_name -> google.protobuf.Duration
+	24, // 23: snapshot.Target.entry_points:type_name -> snapshot.Target.EntryPointsEntry
+	25, // 24: snapshot.Target.env:type_name -> snapshot.Target.EnvEntry
+	13, // 25: snapshot.Target.call_stack:type_name -> snapshot.CallSite
+	1,  // 26: snapshot.Dependency.label:type_name -> snapshot.Label
+	26, // 27: snapshot.Test.commands:type_name -> snapshot.Test.CommandsEntry
+	8,  // 28: snapshot.Test.tools:type_name -> snapshot.Input
+	27, // 29: snapshot.Test.named_tools:type_name -> snapshot.Test.NamedToolsEntry
+	30, // 30: snapshot.Test.timeout:type_name -> google.protobuf.Duration
+	8,  // 31: snapshot.Debug.data:type_name -> snapshot.Input
+	28, // 32: snapshot.Debug.named_data:type_name -> snapshot.Debug.NamedDataEntry
+	8,  // 33: snapshot.Debug.tools:type_name -> snapshot.Input
+	29, // 34: snapshot.Debug.named_tools:type_name -> snapshot.Debug.NamedToolsEntry
+	1,  // 35: snapshot.Input.label:type_name -> snapshot.Label
+	9,  // 36: snapshot.Input.annotated_output:type_name -> snapshot.AnnotatedOutputInput
+	10, // 37: snapshot.Input.file:type_name -> snapshot.FileInput
+	11, // 38: snapshot.Input.subrepo_file:type_name -> snapshot.SubrepoFileInput
+	12, // 39: snapshot.Input.system_path:type_name -> snapshot.SystemPathInput
+	1,  // 40: snapshot.AnnotatedOutputInput.label:type_name -> snapshot.Label
+	8,  // 41: snapshot.Inputs.inputs:type_name -> snapshot.Input
+	1,  // 42: snapshot.Labels.labels:type_name -> snapshot.Label
+	14, // 43: snapshot.Target.NamedSourcesEntry.value:type_name -> snapshot.Inputs
+	14, // 44: snapshot.Target.NamedDataEntry.value:type_name -> snapshot.Inputs
+	15, // 45: snapshot.Target.NamedOutputsEntry.value:type_name -> snapshot.Strings
+	15, // 46: snapshot.Target.NamedSecretsEntry.value:type_name -> snapshot.Strings
+	16, // 47: snapshot.Target.ProvidesEntry.value:type_name -> snapshot.Labels
+	14, // 48: snapshot.Target.NamedToolsEntry.value:type_name -> snapshot.Inputs
+	14, // 49: snapshot.Test.NamedToolsEntry.value:type_name -> snapshot.Inputs
+	14, // 50: snapshot.Debug.NamedDataEntry.value:type_name -> snapshot.Inputs
+	14, // 51: snapshot.Debug.NamedToolsEntry.value:type_name -> snapshot.Inputs
+	52, // [52:52] is the sub-list for method output_type
+	52, // [52:52] is the sub-list for method input_type
+	52, // [52:52] is the sub-list for extension type_name
+	52, // [52:52] is the sub-list for extension extendee
+	0,  // [0:52] is the sub-list for field type_name
+}
+
+func init() { file_src_core_snapshot_snapshot_proto_init() }
+func file_src_core_snapshot_snapshot_proto_init() {
+	if File_src_core_snapshot_snapshot_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_src_core_snapshot_snapshot_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Snapshot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Label); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Subrepo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Package); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Target); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Dependency); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Test); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Debug); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Input); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AnnotatedOutputInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FileInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubrepoFileInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SystemPathInput); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CallSite); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Inputs); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Strings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_src_core_snapshot_snapshot_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Labels); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_src_core_snapshot_snapshot_proto_msgTypes[8].OneofWrappers = []interface{}{
+		(*Input_Label)(nil),
+		(*Input_AnnotatedOutput)(nil),
+		(*Input_File)(nil),
+		(*Input_SubrepoFile)(nil),
+		(*Input_SystemFile)(nil),
+		(*Input_SystemPath)(nil),
+		(*Input_Url)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_src_core_snapshot_snapshot_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   30,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_src_core_snapshot_snapshot_proto_goTypes,
+		DependencyIndexes: file_src_core_snapshot_snapshot_proto_depIdxs,
+		MessageInfos:      file_src_core_snapshot_snapshot_proto_msgTypes,
+	}.Build()
+	File_src_core_snapshot_snapshot_proto = out.File
+	file_src_core_snapshot_snapshot_proto_rawDesc = nil
+	file_src_core_snapshot_snapshot_proto_goTypes = nil
+	file_src_core_snapshot_snapshot_proto_depIdxs = nil
+}
@@ -0,0 +1,377 @@
+// Conversions between target records and their protobuf form in graph snapshots.
+
+package core
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/thought-machine/please/src/core/snapshot"
+)
+
+func targetRecordToProto(r *TargetRecord) *pb.Target {
+	t := &pb.Target{
+		Label:                       labelToProto(r.Label),
+		Dependencies:                make([]*pb.Dependency, len(r.Dependencies)),
+		Visibility:                  labelsToProto(r.Visibility),
+		Sources:                     inputsToProto(r.Sources),
+		NamedSources:                namedInputsToProto(r.NamedSources),
+		Data:                        inputsToProto(r.Data),
+		NamedData:                   namedInputsToProto(r.NamedData),
+		Outputs:                     r.Outputs,
+		NamedOutputs:                namedStringsToProto(r.NamedOutputs),
+		OptionalOutputs:             r.OptionalOutputs,
+		Labels:                      r.Labels,
+		Command:                     r.Command,
+		Commands:                    r.Commands,
+		BuildingDescription:         r.BuildingDescription,
+		Hashes:                      r.Hashes,
+		Licences:                    r.Licences,
+		Secrets:                     r.Secrets,
+		NamedSecrets:                namedStringsToProto(r.NamedSecrets),
+		PreBuildFunction:            r.PreBuildFunction,
+		PostBuildFunction:           r.PostBuildFunction,
+		Requires:                    r.Requires,
+		Tools:                       inputsToProto(r.Tools),
+		NamedTools:                  namedInputsToProto(r.NamedTools),
+		PassEnv:                     optionalStringsToProto(r.PassEnv),
+		PassUnsafeEnv:               optionalStringsToProto(r.PassUnsafeEnv),
+		BuildTimeout:                durationToProto(r.BuildTimeout),
+		EntryPoints:                 r.EntryPoints,
+		Env:                         r.Env,
+		FileContent:                 r.FileContent,
+		IsBinary:                    r.IsBinary,
+		IsSubrepo:                   r.IsSubrepo,
+		TestOnly:                    r.TestOnly,
+		Sandbox:                     r.Sandbox,
+		NeedsTransitiveDependencies: r.NeedsTransitiveDependencies,
+		OutputIsComplete:            r.OutputIsComplete,
+		Stamp:                       r.Stamp,
+		Local:                       r.Local,
+		ExitOnError:                 r.ExitOnError,
+		IsFilegroup:                 r.IsFilegroup,
+		IsRemoteFile:                r.IsRemoteFile,
+		IsTextFile:                  r.IsTextFile,
+		ShowProgress:                r.ShowProgress,
+	}
+	for i, dep := range r.Dependencies {
+		t.Dependencies[i] = &pb.Dependency{
+			Label:    labelToProto(dep.Label),
+			Exported: dep.Exported,
+			Internal: dep.Internal,
+			Source:   dep.Source,
+			Data:     dep.Data,
+		}
+	}
+	if r.Provides != nil {
+		t.Provides = make(map[string]*pb.Labels, len(r.Provides))
+		for name, labels := range r.Provides {
+			t.Provides[name] = &pb.Labels{Labels: labelsToProto(labels)}
+		}
+	}
+	for _, dir := range r.OutputDirectories {
+		t.OutputDirectories = append(t.OutputDirectories, string(dir))
+	}
+	if test := r.Test; test != nil {
+		t.Test = &pb.Test{
+			Command:       test.Command,
+			Commands:      test.Commands,
+			Tools:         inputsToProto(test.Tools),
+			NamedTools:    namedInputsToProto(test.NamedTools),
+			Timeout:       durationToProto(test.Timeout),
+			Outputs:       test.Outputs,
+			Flakiness:     uint32(test.Flakiness),
+			Shards:        uint32(test.Shards),
+			ResultsFormat: test.ResultsFormat,
+			Resources:     test.Resources,
+			Sandbox:       test.Sandbox,
+			NoOutput:      test.NoOutput,
+			NoCoverage:    test.NoCoverage,
+		}
+	}
+	if debug := r.Debug; debug != nil {
+		t.Debug = &pb.Debug{
+			Command:    debug.Command,
+			Data:       inputsToProto(debug.Data),
+			NamedData:  namedInputsToProto(debug.NamedData),
+			Tools:      inputsToProto(debug.Tools),
+			NamedTools: namedInputsToProto(debug.NamedTools),
+		}
+	}
+	return t
+}
+
+func targetRecordFromProto(t *pb.Target) *TargetRecord {
+	r := &TargetRecord{
+		Label:                       labelFromProto(t.Label),
+		Dependencies:                make([]DependencyRecord, len(t.Dependencies)),
+		Visibility:                  labelsFromProto(t.Visibility),
+		Sources:                     inputsFromProto(t.Sources),
+		NamedSources:                namedInputsFromProto(t.NamedSources),
+		Data:                        inputsFromProto(t.Data),
+		NamedData:                   namedInputsFromProto(t.NamedData),
+		Outputs:                     t.Outputs,
+		NamedOutputs:                namedStringsFromProto(t.NamedOutputs),
+		OptionalOutputs:             t.OptionalOutputs,
+		Labels:                      t.Labels,
+		Command:                     t.Command,
+		Commands:                    t.Commands,
+		BuildingDescription:         t.BuildingDescription,
+		Hashes:                      t.Hashes,
+		Licences:                    t.Licences,
+		Secrets:                     t.Secrets,
+		NamedSecrets:                namedStringsFromProto(t.NamedSecrets),
+		PreBuildFunction:            t.PreBuildFunction,
+		PostBuildFunction:           t.PostBuildFunction,
+		Requires:                    t.Requires,
+		Tools:                       inputsFromProto(t.Tools),
+		NamedTools:                  namedInputsFromProto(t.NamedTools),
+		PassEnv:                     optionalStringsFromProto(t.PassEnv),
+		PassUnsafeEnv:               optionalStringsFromProto(t.PassUnsafeEnv),
+		BuildTimeout:                t.BuildTimeout.AsDuration(),
+		EntryPoints:                 t.EntryPoints,
+		Env:                         t.Env,
+		FileContent:                 t.FileContent,
+		IsBinary:                    t.IsBinary,
+		IsSubrepo:                   t.IsSubrepo,
+		TestOnly:                    t.TestOnly,
+		Sandbox:                     t.Sandbox,
+		NeedsTransitiveDependencies: t.NeedsTransitiveDependencies,
+		OutputIsComplete:            t.OutputIsComplete,
+		Stamp:                       t.Stamp,
+		Local:                       t.Local,
+		ExitOnError:                 t.ExitOnError,
+		IsFilegroup:                 t.IsFilegroup,
+		IsRemoteFile:                t.IsRemoteFile,
+		IsTextFile:                  t.IsTextFile,
+		ShowProgress:                t.ShowProgress,
+	}
+	for i, dep := range t.Dependencies {
+		r.Dependencies[i] = DependencyRecord{
+			Label:    labelFromProto(dep.Label),
+			Exported: dep.Exported,
+			Internal: dep.Internal,
+			Source:   dep.Source,
+			Data:     dep.Data,
+		}
+	}
+	if t.Provides != nil {
+		r.Provides = make(map[string][]BuildLabel, len(t.Provides))
+		for name, labels := range t.Provides {
+			r.Provides[name] = labelsFromProto(labels.Labels)
+		}
+	}
+	for _, dir := range t.OutputDirectories {
+		r.OutputDirectories = append(r.OutputDirectories, OutputDirectory(dir))
+	}
+	if test := t.Test; test != nil {
+		r.Test = &TestRecord{
+			Command:       test.Command,
+			Commands:      test.Commands,
+			Tools:         inputsFromProto(test.Tools),
+			NamedTools:    namedInputsFromProto(test.NamedTools),
+			Timeout:       test.Timeout.AsDuration(),
+			Outputs:       test.Outputs,
+			Flakiness:     uint8(test.Flakiness),
+			Shards:        uint16(test.Shards),
+			ResultsFormat: test.ResultsFormat,
+			Resources:     test.Resources,
+			Sandbox:       test.Sandbox,
+			NoOutput:      test.NoOutput,
+			NoCoverage:    test.NoCoverage,
+		}
+	}
+	if debug := t.Debug; debug != nil {
+		r.Debug = &DebugRecord{
+			Command:    debug.Command,
+			Data:       inputsFromProto(debug.Data),
+			NamedData:  namedInputsFromProto(debug.NamedData),
+			Tools:      inputsFromProto(debug.Tools),
+			NamedTools: namedInputsFromProto(debug.NamedTools),
+		}
+	}
+	return r
+}
+
+func labelToProto(label BuildLabel) *pb.Label {
+	return &pb.Label{Package: label.PackageName, Name: label.Name, Subrepo: label.Subrepo}
+}
+
+func labelFromProto(label *pb.Label) BuildLabel {
+	return BuildLabel{PackageName: label.GetPackage(), Name: label.GetName(), Subrepo: label.GetSubrepo()}
+}
+
+func labelsToProto(labels []BuildLabel) []*pb.Label {
+	if labels == nil {
+		return nil
+	}
+	ret := make([]*pb.Label, len(labels))
+	for i, label := range labels {
+		ret[i] = labelToProto(label)
+	}
+	return ret
+}
+
+func labelsFromProto(labels []*pb.Label) []BuildLabel {
+	if labels == nil {
+		return nil
+	}
+	ret := make([]BuildLabel, len(labels))
+	for i, label := range labels {
+		ret[i] = labelFromProto(label)
+	}
+	return ret
+}
+
+func inputToProto(r InputRecord) *pb.Input {
+	switch r.Type {
+	case buildLabelInput:
+		return &pb.Input{Input: &pb.Input_Label{Label: labelToProto(r.Label)}}
+	case annotatedOutputLabelInput:
+		return &pb.Input{Input: &pb.Input_AnnotatedOutput{AnnotatedOutput: &pb.AnnotatedOutputInput{Label: labelToProto(r.Label), Annotation: r.Annotation}}}
+	case fileLabelInput:
+		return &pb.Input{Input: &pb.Input_File{File: &pb.FileInput{File: r.File, Package: r.Package}}}
+	case subrepoFileLabelInput:
+		return &pb.Input{Input: &pb.Input_SubrepoFile{SubrepoFile: &pb.SubrepoFileInput{File: r.File, Package: r.Package, FullPackage: r.FullPackage}}}
+	case systemFileLabelInput:
+		return &pb.Input{Input: &pb.Input_SystemFile{SystemFile: r.File}}
+	case systemPathLabelInput:
+		return &pb.Input{Input: &pb.Input_SystemPath{SystemPath: &pb.SystemPathInput{Name: r.Name, Path: r.Path}}}
+	case urlLabelInput:
+		return &pb.Input{Input: &pb.Input_Url{Url: r.File}}
+	}
+	return &pb.Input{}
+}
+
+// inputFromProto converts an input from a snapshot back into a record. Unknown kinds of input become
+// records with an invalid type, which fail when the target is recreated from them.
+func inputFromProto(input *pb.Input) InputRecord {
+	switch in := input.GetInput().(type) {
+	case *pb.Input_Label:
+		return InputRecord{Type: buildLabelInput, Label: labelFromProto(in.Label)}
+	case *pb.Input_AnnotatedOutput:
+		return InputRecord{Type: annotatedOutputLabelInput, Label: labelFromProto(in.AnnotatedOutput.GetLabel()), Annotation: in.AnnotatedOutput.GetAnnotation()}
+	case *pb.Input_File:
+		return InputRecord{Type: fileLabelInput, File: in.File.GetFile(), Package: in.File.GetPackage()}
+	case *pb.Input_SubrepoFile:
+		return InputRecord{Type: subrepoFileLabelInput, File: in.SubrepoFile.GetFile(), Package: in.SubrepoFile.GetPackage(), FullPackage: in.SubrepoFile.GetFullPackage()}
+	case *pb.Input_SystemFile:
+		return InputRecord{Type: systemFileLabelInput, File: in.SystemFile}
+	case *pb.Input_SystemPath:
+		return InputRecord{Type: systemPathLabelInput, Name: in.SystemPath.GetName(), Path: in.SystemPath.GetPath()}
+	case *pb.Input_Url:
+		return InputRecord{Type: urlLabelInput, File: in.Url}
+	}
+	return InputRecord{Type: ^inputType(0)}
+}
+
+func inputsToProto(records []InputRecord) []*pb.Input {
+	if records == nil {
+		return nil
+	}
+	ret := make([]*pb.Input, len(records))
+	for i, r := range records {
+		ret[i] = inputToProto(r)
+	}
+	return ret
+}
+
+func inputsFromProto(inputs []*pb.Input) []InputRecord {
+	if inputs == nil {
+		return nil
+	}
+	ret := make([]InputRecord, len(inputs))
+	for i, input := range inputs {
+		ret[i] = inputFromProto(input)
+	}
+	return ret
+}
+
+func namedInputsToProto(records map[string][]InputRecord) map[string]*pb.Inputs {
+	if records == nil {
+		return nil
+	}
+	ret := make(map[string]*pb.Inputs, len(records))
+	for name, r := range records {
+		ret[name] = &pb.Inputs{Inputs: inputsToProto(r)}
+	}
+	return ret
+}
+
+func namedInputsFromProto(inputs map[string]*pb.Inputs) map[string][]InputRecord {
+	if inputs == nil {
+		return nil
+	}
+	ret := make(map[string][]InputRecord, len(inputs))
+	for name, in := range inputs {
+		ret[name] = inputsFromProto(in.GetInputs())
+	}
+	return ret
+}
+
+func namedStringsToProto(strs map[string][]string) map[string]*pb.Strings {
+	if strs == nil {
+		return nil
+	}
+	ret := make(map[string]*pb.Strings, len(strs))
+	for name, s := range strs {
+		ret[name] = &pb.Strings{Values: s}
+	}
+	return ret
+}
+
+func namedStringsFromProto(strs map[string]*pb.Strings) map[string][]string {
+	if strs == nil {
+		return nil
+	}
+	ret := make(map[string][]string, len(strs))
+	for name, s := range strs {
+		ret[name] = s.GetValues()
+	}
+	return ret
+}
+
+func optionalStringsToProto(strs *[]string) *pb.Strings {
+	if strs == nil {
+		return nil
+	}
+	return &pb.Strings{Values: *strs}
+}
+
+func optionalStringsFromProto(strs *pb.Strings) *[]string {
+	if strs == nil {
+		return nil
+	}
+	values := strs.Values
+	return &values
+}
+
+func durationToProto(d time.Duration) *durationpb.Duration {
+	if d == 0 {
+		return nil
+	}
+	return durationpb.New(d)
+}
+
+func callStackToProto(stack []CallSite) []*pb.CallSite {
+	if stack == nil {
+		return nil
+	}
+	ret := make([]*pb.CallSite, len(stack))
+	for i, call := range stack {
+		ret[i] = &pb.CallSite{Function: call.Function, Filename: call.Filename, Line: int32(call.Line)}
+	}
+	return ret
+}
+
+func callStackFromProto(stack []*pb.CallSite) []CallSite {
+	if stack == nil {
+		return nil
+	}
+	ret := make([]CallSite, len(stack))
+	for i, call := range stack {
+		ret[i] = CallSite{Function: call.GetFunction(), Filename: call.GetFilename(), Line: int(call.GetLine())}
+	}
+	return ret
+}
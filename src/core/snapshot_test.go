@@ -0,0 +1,67 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphSnapshotRoundTrip(t *testing.T) {
+	state := NewDefaultBuildState()
+	pkg := NewPackage("src/core")
+	pkg.Filename = "src/core/BUILD"
+	pkg.Subincludes = []BuildLabel{ParseBuildLabel("//build_defs:go", "")}
+	lib := NewBuildTarget(ParseBuildLabel("//src/core:lib", ""))
+	lib.AddOutput("lib.a")
+	lib.RuleHash = []byte{1, 2, 3}
+	lib.CallStack = []CallSite{{Function: "build_rule", Filename: "src/core/BUILD", Line: 4}}
+	bin := NewBuildTarget(ParseBuildLabel("//src/core:bin", ""))
+	bin.AddDependency(lib.Label)
+	bin.AddDependency(ParseBuildLabel("//src/missing:missing", ""))
+	bin.IsBinary = true
+	state.AddTarget(pkg, lib)
+	state.AddTarget(pkg, bin)
+	state.Graph.AddPackage(pkg)
+
+	subrepo := &Subrepo{Name: "third_party", Root: "plz-out/gen/third_party"}
+	state.Graph.AddSubrepo(subrepo)
+	subpkg := NewPackageSubrepo("pkg", "third_party")
+	subpkg.Subrepo = subrepo
+	dep := NewBuildTarget(BuildLabel{PackageName: "pkg", Name: "dep", Subrepo: "third_party"})
+	dep.Subrepo = subrepo
+	state.AddTarget(subpkg, dep)
+	state.Graph.AddPackage(subpkg)
+	lib.AddDependency(dep.Label)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteGraphSnapshot(&buf, state.Graph))
+
+	state2 := NewDefaultBuildState()
+	require.NoError(t, state2.LoadGraphSnapshot(&buf, []BuildLabel{ParseBuildLabel("//src/...", "")}))
+	pkg2 := state2.Graph.PackageOrDie(pkg.Label())
+	assert.Equal(t, pkg.Filename, pkg2.Filename)
+	assert.Equal(t, pkg.Subincludes, pkg2.Subincludes)
+	assert.Equal(t, 2, pkg2.NumTargets())
+	assert.Equal(t, pkg2.TargetOrDie("lib"), pkg2.Outputs["lib.a"])
+
+	lib2 := state2.Graph.TargetOrDie(lib.Label)
+	bin2 := state2.Graph.TargetOrDie(bin.Label)
+	dep2 := state2.Graph.TargetOrDie(dep.Label)
+	assert.Equal(t, []byte{1, 2, 3}, lib2.RuleHash)
+	assert.Equal(t, lib.CallStack, lib2.CallStack)
+	assert.True(t, bin2.IsBinary)
+	assert.Equal(t, bin.DeclaredDependencies(), bin2.DeclaredDependencies())
+	// The dependency that wasn't in the graph is left unresolved.
+	assert.Equal(t, []*BuildTarget{lib2}, bin2.Dependencies())
+	assert.Equal(t, []*BuildTarget{dep2}, lib2.Dependencies())
+	assert.Equal(t, state2.Graph.SubrepoOrDie("third_party"), dep2.Subrepo)
+	assert.Equal(t, "plz-out/gen/third_party", dep2.Subrepo.Root)
+	assert.Equal(t, BuildLabels{bin.Label, lib.Label}, state2.ExpandOriginalLabels())
+}
+
+func TestGraphSnapshotInvalid(t *testing.T) {
+	state := NewDefaultBuildState()
+	assert.Error(t, state.LoadGraphSnapshot(bytes.NewReader([]byte("not a snapshot")), nil))
+}
@@ -10,6 +10,7 @@ import (
 	"io"
 	iofs "io/fs"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -166,6 +167,9 @@ type BuildState struct {
 	TargetHasher TargetHasher
 	// Arguments to tests.
 	TestArgs []string
+	// Test cases to run for individual targets, which take precedence over TestArgs.
+	// These are populated by --failed to rerun only the cases that failed last time.
+	TestSelections map[BuildLabel][]string
 	// Labels of targets that we will include / exclude
 	Include, Exclude []string
 	// Actual targets to exclude from discovery
@@ -178,6 +182,10 @@ type BuildState struct {
 	Arch cli.Arch
 	// Aggregated coverage for this run
 	Coverage TestCoverage
+	// History of previous test runs, used to schedule the slowest tests first.
+	TestHistory *TestHistory
+	// Repo-wide policy on dependencies between targets, if one is configured.
+	Policy *Policy
 	// True if we want to keep going on build failures and not exit early on the first error encountered
 	KeepGoing bool
 	// True if we require rule hashes to be correctly verified (usually the case).
@@ -234,6 +242,17 @@ type BuildState struct {
 	// EnableBreakpoints enablese the breakpoint() build-in, and drops Please into an interactive debugger when
 	// they're encountered.
 	EnableBreakpoints bool
+	// Breakpoints are locations (as file:line) in BUILD files or build_defs at which the debugger will stop.
+	Breakpoints []string
+	// DebugAdapterAddress is the address to serve the Debug Adapter Protocol on. If it's empty the debugger
+	// will use the console instead.
+	DebugAdapterAddress string
+	// RecordProvenance makes the parser record the call stack that created each target, so we can
+	// explain where they came from.
+	RecordProvenance bool
+	// LogQueuedTargets makes us log a result when each target is queued to be built or tested. Normally we
+	// don't since nothing is interested in them except the event stream.
+	LogQueuedTargets bool
 
 	// initOnce is used to control loading the subrepo .plzconfig
 	initOnce *sync.Once
@@ -303,6 +322,8 @@ type stateProgress struct {
 	internalResults chan *BuildResult
 	// The cycle checker itself.
 	cycleDetector cycleDetector
+	// Semaphores for each of the test resources in the config, created as tests need them.
+	testResources map[string]chan struct{}
 }
 
 // SystemStats stores information about the system.
@@ -352,6 +373,14 @@ func (state *BuildState) addPendingParse(label, dependent BuildLabel, mode Parse
 // addPendingBuild adds a task for a pending build of a target.
 func (state *BuildState) addPendingBuild(target *BuildTarget) {
 	atomic.AddInt64(&state.progress.numPending, 1)
+	if state.LogQueuedTargets {
+		state.logResult(&BuildResult{
+			Label:       target.Label,
+			target:      target,
+			Status:      TargetQueued,
+			Description: "Queued for building",
+		})
+	}
 	go func() {
 		defer func() {
 			recover() // Prevent death on 'send on closed channel'
@@ -362,15 +391,30 @@ func (state *BuildState) addPendingBuild(target *BuildTarget) {
 
 // AddPendingTest adds a task for a pending test of a target.
 func (state *BuildState) AddPendingTest(target *BuildTarget) {
+	state.addPendingTest(target, state.numTestTasks(target))
+}
+
+// numTestTasks returns the number of test tasks we'll run for a target.
+// That's one for each run of each shard, unless we're testing sequentially in which case
+// each shard does all of its runs in one task.
+func (state *BuildState) numTestTasks(target *BuildTarget) int {
 	if state.TestSequentially {
-		state.addPendingTest(target, 1)
-	} else {
-		state.addPendingTest(target, int(state.NumTestRuns))
+		return target.NumTestShards()
 	}
+	return int(state.NumTestRuns) * target.NumTestShards()
 }
 
 func (state *BuildState) addPendingTest(target *BuildTarget, numRuns int) {
 	atomic.AddInt64(&state.progress.numPending, int64(numRuns))
+	for run := 1; run <= numRuns && state.LogQueuedTargets; run++ {
+		state.logResult(&BuildResult{
+			Label:       target.Label,
+			target:      target,
+			Run:         run,
+			Status:      TargetQueued,
+			Description: "Queued for testing",
+		})
+	}
 	go func() {
 		defer func() {
 			recover() // Prevent death on 'send on closed channel'
@@ -381,6 +425,57 @@ func (state *BuildState) addPendingTest(target *BuildTarget, numRuns int) {
 	}()
 }
 
+// AcquireTestResources blocks until all the resources that the given test uses are available.
+// If it has to wait, the given limiter (if there is one) is released for the duration so the test
+// doesn't hold up a worker that other tasks could be using.
+// It returns a function that releases them again, which must be called once the test has finished.
+func (state *BuildState) AcquireTestResources(target *BuildTarget, limiter cmap.Limiter) func() {
+	names := slices.Clone(target.Test.Resources)
+	slices.Sort(names) // Always acquire in the same order so two tests can't deadlock on one another.
+	names = slices.Compact(names)
+	sems := make([]chan struct{}, len(names))
+	released := false
+	for i, name := range names {
+		sems[i] = state.testResource(name)
+		select {
+		case sems[i] <- struct{}{}:
+		default:
+			if limiter != nil && !released {
+				limiter.Release()
+				released = true
+			}
+			sems[i] <- struct{}{}
+		}
+	}
+	if released {
+		limiter.Acquire()
+	}
+	return func() {
+		for _, sem := range sems {
+			<-sem
+		}
+	}
+}
+
+// testResource returns the semaphore for a single test resource.
+func (state *BuildState) testResource(name string) chan struct{} {
+	state.progress.mutex.Lock()
+	defer state.progress.mutex.Unlock()
+	if sem, present := state.progress.testResources[name]; present {
+		return sem
+	}
+	count := 1
+	if resource := state.Config.TestResource[name]; resource != nil && resource.Count > 1 {
+		count = resource.Count
+	}
+	if state.progress.testResources == nil {
+		state.progress.testResources = map[string]chan struct{}{}
+	}
+	sem := make(chan struct{}, count)
+	state.progress.testResources[name] = sem
+	return sem
+}
+
 // TaskQueues returns a set of channels to listen on for tasks of various types.
 func (state *BuildState) TaskQueues() (parses <-chan ParseTask, actions <-chan Task) {
 	return state.pendingParses, state.pendingActions
@@ -462,6 +557,15 @@ func (state *BuildState) SetIncludeAndExclude(include, exclude []string) {
 	}
 }
 
+// TestSelection returns the test cases that were selected to run for the given target.
+// If it's empty, all of them should be run.
+func (state *BuildState) TestSelection(label BuildLabel) []string {
+	if selection, present := state.TestSelections[label]; present {
+		return selection
+	}
+	return state.TestArgs
+}
+
 // ShouldInclude returns true if the given target is included by the include/exclude flags.
 func (state *BuildState) ShouldInclude(target *BuildTarget) bool {
 	for _, e := range state.ExcludeTargets {
@@ -558,9 +662,12 @@ func (state *BuildState) ArchSubrepoInitialised(subrepoLabel BuildLabel) {
 
 // LogTestRunning logs a target while its tests are running.
 func (state *BuildState) LogTestRunning(target *BuildTarget, run int, status BuildResultStatus, message string) {
-	// Annotate the message with the run number if appropriate.
+	// Annotate the message with the run number & shard if appropriate.
+	if shards := target.NumTestShards(); shards > 1 {
+		message = strings.TrimSuffix(message, "...") + fmt.Sprintf(" (shard %d of %d)...", target.TestShard(run)+1, shards)
+	}
 	if state.NumTestRuns > 1 {
-		message = strings.TrimSuffix(message, "...") + fmt.Sprintf(" (run %d of %d)...", run, state.NumTestRuns)
+		message = strings.TrimSuffix(message, "...") + fmt.Sprintf(" (run %d of %d)...", (run-1)/target.NumTestShards()+1, state.NumTestRuns)
 	}
 	state.logResult(&BuildResult{
 		Label:       target.Label,
@@ -581,6 +688,7 @@ func (state *BuildState) LogTestResult(target *BuildTarget, run int, status Buil
 		Err:         err,
 		Description: fmt.Sprintf(format, args...),
 		Tests:       *results,
+		Coverage:    coverage,
 	})
 	state.progress.mutex.Lock()
 	defer state.progress.mutex.Unlock()
@@ -1129,12 +1237,8 @@ func (state *BuildState) queueResolvedTarget(target *BuildTarget, forceBuild boo
 
 	queueAsync := func(shouldBuild bool) {
 		if target.IsTest() && state.NeedTests {
-			if state.TestSequentially {
-				state.addActiveTargets(2) // One for build & one for test
-			} else {
-				// Tests count however many times we're going to run them if parallel.
-				state.addActiveTargets(int(1 + state.NumTestRuns))
-			}
+			// One for the build, plus however many times we're going to run the tests.
+			state.addActiveTargets(1 + state.numTestTasks(target))
 		} else {
 			state.addActiveTargets(1)
 		}
@@ -1423,6 +1527,7 @@ func NewBuildState(config *Configuration) *BuildState {
 		NeedBuild:       true,
 		XattrsSupported: config.Build.Xattrs,
 		Coverage:        TestCoverage{Files: map[string][]LineCoverage{}},
+		TestHistory:     NewTestHistory(),
 		TargetArch:      config.Build.Arch,
 		Arch:            cli.HostArch(),
 		stats:           &lockedStats{},
@@ -1475,6 +1580,8 @@ type BuildResult struct {
 	Description string
 	// Test results
 	Tests TestSuite
+	// Coverage from this test run. Only populated for test results when we're collecting coverage.
+	Coverage *TestCoverage
 }
 
 // A BuildResultStatus represents the status of a target when we log a build result.
@@ -1494,6 +1601,7 @@ const (
 	TargetTestStopped
 	TargetTested
 	TargetTestFailed
+	TargetQueued
 )
 
 // Category returns the broad area that this event represents in the tasks we perform for a target.
@@ -1510,6 +1618,12 @@ func (s BuildResultStatus) Category() string {
 	}
 }
 
+// IsQueued returns true if this status indicates that a target is waiting to be built or tested.
+// These don't represent any work being done on the target yet.
+func (s BuildResultStatus) IsQueued() bool {
+	return s == TargetQueued
+}
+
 // IsParse returns true if this status is a parse event
 func (s BuildResultStatus) IsParse() bool {
 	return s == PackageParsing || s == PackageParsed || s == ParseFailed
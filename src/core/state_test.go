@@ -2,7 +2,9 @@ package core
 
 import (
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -167,3 +169,82 @@ func TestCopyPlugin(t *testing.T) {
 
 	assert.NotEqual(t, plugin.ExtraValues["foo"], newPlugin.ExtraValues["foo"])
 }
+
+func TestTestSelection(t *testing.T) {
+	state := NewDefaultBuildState()
+	label1 := ParseBuildLabel("//src/core:test1", "")
+	label2 := ParseBuildLabel("//src/core:test2", "")
+	assert.Nil(t, state.TestSelection(label1))
+	state.TestArgs = []string{"TestOne"}
+	state.TestSelections = map[BuildLabel][]string{label2: {"TestTwo", "TestThree"}}
+	assert.Equal(t, []string{"TestOne"}, state.TestSelection(label1))
+	assert.Equal(t, []string{"TestTwo", "TestThree"}, state.TestSelection(label2))
+}
+
+func TestAcquireTestResources(t *testing.T) {
+	state := NewDefaultBuildState()
+	state.Config.TestResource = map[string]*TestResource{
+		"postgres": {Count: 2},
+		"gpu":      {},
+	}
+	newTest := func(name string, resources ...string) *BuildTarget {
+		target := NewBuildTarget(ParseBuildLabel("//src/core:"+name, ""))
+		target.Test = &TestFields{Resources: resources}
+		return target
+	}
+	release1 := state.AcquireTestResources(newTest("test1", "postgres", "gpu"), nil)
+	release2 := state.AcquireTestResources(newTest("test2", "postgres"), nil)
+	// Neither resource is free now, so these have to wait.
+	acquired := make(chan string, 2)
+	go func() {
+		defer state.AcquireTestResources(newTest("test3", "postgres"), nil)()
+		acquired <- "test3"
+	}()
+	go func() {
+		defer state.AcquireTestResources(newTest("test4", "gpu", "gpu"), nil)()
+		acquired <- "test4"
+	}()
+	// A test with no resources never waits.
+	state.AcquireTestResources(newTest("test5"), nil)()
+	select {
+	case name := <-acquired:
+		t.Fatalf("%s acquired resources that should have been held", name)
+	case <-time.After(50 * time.Millisecond):
+	}
+	release2()
+	assert.Equal(t, "test3", <-acquired)
+	release1()
+	assert.Equal(t, "test4", <-acquired)
+}
+
+// A countingLimiter records how many slots are currently held from it.
+type countingLimiter struct {
+	held atomic.Int32
+}
+
+func (l *countingLimiter) Acquire() { l.held.Add(1) }
+func (l *countingLimiter) Release() { l.held.Add(-1) }
+
+func TestAcquireTestResourcesReleasesLimiter(t *testing.T) {
+	state := NewDefaultBuildState()
+	newTest := func(name string) *BuildTarget {
+		target := NewBuildTarget(ParseBuildLabel("//src/core:"+name, ""))
+		target.Test = &TestFields{Resources: []string{"postgres"}}
+		return target
+	}
+	limiter := &countingLimiter{}
+	limiter.Acquire()
+	// Nothing else holds the resource, so the limiter isn't touched.
+	release := state.AcquireTestResources(newTest("test1"), limiter)
+	assert.EqualValues(t, 1, limiter.held.Load())
+
+	acquired := make(chan struct{})
+	go func() {
+		defer state.AcquireTestResources(newTest("test2"), limiter)()
+		close(acquired)
+	}()
+	assert.Eventually(t, func() bool { return limiter.held.Load() == 0 }, time.Second, time.Millisecond, "limiter should be released while waiting")
+	release()
+	<-acquired
+	assert.EqualValues(t, 1, limiter.held.Load())
+}
@@ -0,0 +1,412 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// A TargetRecord is a flattened, serialisable form of a BuildTarget as it stands after parsing.
+// It is suitable for encoding with encoding/gob, and can be turned back into a target later
+// without having to re-run the interpreter.
+// Only the parse-time state of the target is captured; anything determined while building
+// (state, results, hashes etc) is not.
+type TargetRecord struct {
+	Label                       BuildLabel
+	Dependencies                []DependencyRecord
+	Visibility                  []BuildLabel
+	Sources                     []InputRecord
+	NamedSources                map[string][]InputRecord
+	Data                        []InputRecord
+	NamedData                   map[string][]InputRecord
+	Outputs                     []string
+	NamedOutputs                map[string][]string
+	OptionalOutputs             []string
+	Labels                      []string
+	Command                     string
+	Commands                    map[string]string
+	Test                        *TestRecord
+	Debug                       *DebugRecord
+	BuildingDescription         string
+	Hashes                      []string
+	Licences                    []string
+	Secrets                     []string
+	NamedSecrets                map[string][]string
+	PreBuildFunction            string
+	PostBuildFunction           string
+	Requires                    []string
+	Provides                    map[string][]BuildLabel
+	Tools                       []InputRecord
+	NamedTools                  map[string][]InputRecord
+	PassEnv                     *[]string
+	PassUnsafeEnv               *[]string
+	BuildTimeout                time.Duration
+	OutputDirectories           []OutputDirectory
+	EntryPoints                 map[string]string
+	Env                         map[string]string
+	FileContent                 string
+	IsBinary                    bool
+	IsSubrepo                   bool
+	TestOnly                    bool
+	Sandbox                     bool
+	NeedsTransitiveDependencies bool
+	OutputIsComplete            bool
+	Stamp                       bool
+	Local                       bool
+	ExitOnError                 bool
+	IsFilegroup                 bool
+	IsRemoteFile                bool
+	IsTextFile                  bool
+	ShowProgress                bool
+}
+
+// A DependencyRecord is the serialisable form of a single declared dependency of a target.
+type DependencyRecord struct {
+	Label    BuildLabel
+	Exported bool
+	Internal bool
+	Source   bool
+	Data     bool
+}
+
+// A TestRecord is the serialisable form of a target's TestFields.
+type TestRecord struct {
+	Command       string
+	Commands      map[string]string
+	Tools         []InputRecord
+	NamedTools    map[string][]InputRecord
+	Timeout       time.Duration
+	Outputs       []string
+	Flakiness     uint8
+	Shards        uint16
+	ResultsFormat string
+	Resources     []string
+	Sandbox       bool
+	NoOutput      bool
+	NoCoverage    bool
+}
+
+// A DebugRecord is the serialisable form of a target's DebugFields.
+type DebugRecord struct {
+	Command    string
+	Data       []InputRecord
+	NamedData  map[string][]InputRecord
+	Tools      []InputRecord
+	NamedTools map[string][]InputRecord
+}
+
+// An inputType identifies which implementation of BuildInput an InputRecord represents.
+type inputType uint8
+
+const (
+	buildLabelInput inputType = iota
+	annotatedOutputLabelInput
+	fileLabelInput
+	subrepoFileLabelInput
+	systemFileLabelInput
+	systemPathLabelInput
+	urlLabelInput
+)
+
+// An InputRecord is the serialisable form of a BuildInput.
+type InputRecord struct {
+	Type        inputType
+	Label       BuildLabel
+	Annotation  string
+	File        string
+	Package     string
+	FullPackage string
+	Name        string
+	Path        []string
+}
+
+// NewTargetRecord returns a record of the given target.
+func NewTargetRecord(target *BuildTarget) *TargetRecord {
+	target.mutex.RLock()
+	defer target.mutex.RUnlock()
+	r := &TargetRecord{
+		Label:                       target.Label,
+		Dependencies:                make([]DependencyRecord, len(target.dependencies)),
+		Visibility:                  target.Visibility,
+		Sources:                     newInputRecords(target.Sources),
+		NamedSources:                newNamedInputRecords(target.NamedSources),
+		Data:                        newInputRecords(target.Data),
+		NamedData:                   newNamedInputRecords(target.NamedData),
+		Outputs:                     target.outputs,
+		NamedOutputs:                target.namedOutputs,
+		OptionalOutputs:             target.OptionalOutputs,
+		Labels:                      target.Labels,
+		Command:                     target.Command,
+		Commands:                    target.Commands,
+		BuildingDescription:         target.BuildingDescription,
+		Hashes:                      target.Hashes,
+		Licences:                    target.Licences,
+		Secrets:                     target.Secrets,
+		NamedSecrets:                target.NamedSecrets,
+		Requires:                    target.Requires,
+		Provides:                    target.Provides,
+		Tools:                       newInputRecords(target.Tools),
+		NamedTools:                  newNamedInputRecords(target.namedTools),
+		PassEnv:                     target.PassEnv,
+		PassUnsafeEnv:               target.PassUnsafeEnv,
+		BuildTimeout:                target.BuildTimeout,
+		OutputDirectories:           target.OutputDirectories,
+		EntryPoints:                 target.EntryPoints,
+		Env:                         target.Env,
+		FileContent:                 target.FileContent,
+		IsBinary:                    target.IsBinary,
+		IsSubrepo:                   target.IsSubrepo,
+		TestOnly:                    target.TestOnly,
+		Sandbox:                     target.Sandbox,
+		NeedsTransitiveDependencies: target.NeedsTransitiveDependencies,
+		OutputIsComplete:            target.OutputIsComplete,
+		Stamp:                       target.Stamp,
+		Local:                       target.Local,
+		ExitOnError:                 target.ExitOnError,
+		IsFilegroup:                 target.IsFilegroup,
+		IsRemoteFile:                target.IsRemoteFile,
+		IsTextFile:                  target.IsTextFile,
+		ShowProgress:                target.showProgress.Load(),
+	}
+	for i, dep := range target.dependencies {
+		r.Dependencies[i] = DependencyRecord{
+			Label:    *dep.declared,
+			Exported: dep.exported,
+			Internal: dep.internal,
+			Source:   dep.source,
+			Data:     dep.data,
+		}
+	}
+	if target.PreBuildFunction != nil {
+		r.PreBuildFunction = target.PreBuildFunction.String()
+	}
+	if target.PostBuildFunction != nil {
+		r.PostBuildFunction = target.PostBuildFunction.String()
+	}
+	if t := target.Test; t != nil {
+		r.Test = &TestRecord{
+			Command:       t.Command,
+			Commands:      t.Commands,
+			Tools:         newInputRecords(t.tools),
+			NamedTools:    newNamedInputRecords(t.namedTools),
+			Timeout:       t.Timeout,
+			Outputs:       t.Outputs,
+			Flakiness:     t.Flakiness,
+			Shards:        t.Shards,
+			ResultsFormat: t.ResultsFormat,
+			Resources:     t.Resources,
+			Sandbox:       t.Sandbox,
+			NoOutput:      t.NoOutput,
+			NoCoverage:    t.NoCoverage,
+		}
+	}
+	if d := target.Debug; d != nil {
+		r.Debug = &DebugRecord{
+			Command:    d.Command,
+			Data:       newInputRecords(d.data),
+			NamedData:  newNamedInputRecords(d.namedData),
+			Tools:      newInputRecords(d.tools),
+			NamedTools: newNamedInputRecords(d.namedTools),
+		}
+	}
+	return r
+}
+
+// HasBuildFunctions returns true if the recorded target had a pre- or post-build function.
+// These cannot be restored from a record since they are closures in the BUILD language.
+func (r *TargetRecord) HasBuildFunctions() bool {
+	return r.PreBuildFunction != "" || r.PostBuildFunction != ""
+}
+
+// Target recreates a BuildTarget from this record. The target is not added to any package or graph.
+// Any pre- or post-build functions are not restored.
+func (r *TargetRecord) Target(subrepo *Subrepo) (*BuildTarget, error) {
+	target := NewBuildTarget(r.Label)
+	target.Subrepo = subrepo
+	target.dependencies = make([]depInfo, len(r.Dependencies))
+	for i, dep := range r.Dependencies {
+		label := dep.Label
+		target.dependencies[i] = depInfo{
+			declared: &label,
+			exported: dep.Exported,
+			internal: dep.Internal,
+			source:   dep.Source,
+			data:     dep.Data,
+		}
+	}
+	var err error
+	if target.Sources, err = inputsFromRecords(r.Sources); err != nil {
+		return nil, err
+	} else if target.NamedSources, err = namedInputsFromRecords(r.NamedSources); err != nil {
+		return nil, err
+	} else if target.Data, err = inputsFromRecords(r.Data); err != nil {
+		return nil, err
+	} else if target.NamedData, err = namedInputsFromRecords(r.NamedData); err != nil {
+		return nil, err
+	} else if target.Tools, err = inputsFromRecords(r.Tools); err != nil {
+		return nil, err
+	} else if target.namedTools, err = namedInputsFromRecords(r.NamedTools); err != nil {
+		return nil, err
+	}
+	target.Visibility = r.Visibility
+	target.outputs = r.Outputs
+	target.namedOutputs = r.NamedOutputs
+	target.OptionalOutputs = r.OptionalOutputs
+	target.Labels = r.Labels
+	target.Command = r.Command
+	target.Commands = r.Commands
+	target.BuildingDescription = r.BuildingDescription
+	target.Hashes = r.Hashes
+	target.Licences = r.Licences
+	target.Secrets = r.Secrets
+	target.NamedSecrets = r.NamedSecrets
+	target.Requires = r.Requires
+	target.Provides = r.Provides
+	target.PassEnv = r.PassEnv
+	target.PassUnsafeEnv = r.PassUnsafeEnv
+	target.BuildTimeout = r.BuildTimeout
+	target.OutputDirectories = r.OutputDirectories
+	target.EntryPoints = r.EntryPoints
+	target.Env = r.Env
+	target.FileContent = r.FileContent
+	target.IsBinary = r.IsBinary
+	target.IsSubrepo = r.IsSubrepo
+	target.TestOnly = r.TestOnly
+	target.Sandbox = r.Sandbox
+	target.NeedsTransitiveDependencies = r.NeedsTransitiveDependencies
+	target.OutputIsComplete = r.OutputIsComplete
+	target.Stamp = r.Stamp
+	target.Local = r.Local
+	target.ExitOnError = r.ExitOnError
+	target.IsFilegroup = r.IsFilegroup
+	target.IsRemoteFile = r.IsRemoteFile
+	target.IsTextFile = r.IsTextFile
+	target.showProgress.Store(r.ShowProgress)
+	if t := r.Test; t != nil {
+		target.Test = &TestFields{
+			Command:       t.Command,
+			Commands:      t.Commands,
+			Timeout:       t.Timeout,
+			Outputs:       t.Outputs,
+			Flakiness:     t.Flakiness,
+			Shards:        t.Shards,
+			ResultsFormat: t.ResultsFormat,
+			Resources:     t.Resources,
+			Sandbox:       t.Sandbox,
+			NoOutput:      t.NoOutput,
+			NoCoverage:    t.NoCoverage,
+		}
+		if target.Test.tools, err = inputsFromRecords(t.Tools); err != nil {
+			return nil, err
+		} else if target.Test.namedTools, err = namedInputsFromRecords(t.NamedTools); err != nil {
+			return nil, err
+		}
+	}
+	if d := r.Debug; d != nil {
+		target.Debug = &DebugFields{Command: d.Command}
+		if target.Debug.data, err = inputsFromRecords(d.Data); err != nil {
+			return nil, err
+		} else if target.Debug.namedData, err = namedInputsFromRecords(d.NamedData); err != nil {
+			return nil, err
+		} else if target.Debug.tools, err = inputsFromRecords(d.Tools); err != nil {
+			return nil, err
+		} else if target.Debug.namedTools, err = namedInputsFromRecords(d.NamedTools); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// NewInputRecord returns a record of the given build input.
+func NewInputRecord(input BuildInput) InputRecord {
+	switch in := input.(type) {
+	case BuildLabel:
+		return InputRecord{Type: buildLabelInput, Label: in}
+	case AnnotatedOutputLabel:
+		return InputRecord{Type: annotatedOutputLabelInput, Label: in.BuildLabel, Annotation: in.Annotation}
+	case FileLabel:
+		return InputRecord{Type: fileLabelInput, File: in.File, Package: in.Package}
+	case SubrepoFileLabel:
+		return InputRecord{Type: subrepoFileLabelInput, File: in.File, Package: in.Package, FullPackage: in.FullPackage}
+	case SystemFileLabel:
+		return InputRecord{Type: systemFileLabelInput, File: in.Path}
+	case SystemPathLabel:
+		return InputRecord{Type: systemPathLabelInput, Name: in.Name, Path: in.Path}
+	case URLLabel:
+		return InputRecord{Type: urlLabelInput, File: string(in)}
+	}
+	// This can't happen; nothing outside this package can implement BuildInput.
+	panic(fmt.Sprintf("unknown build input type %T", input))
+}
+
+// Input recreates the BuildInput that this record represents.
+func (r InputRecord) Input() (BuildInput, error) {
+	switch r.Type {
+	case buildLabelInput:
+		return r.Label, nil
+	case annotatedOutputLabelInput:
+		return AnnotatedOutputLabel{BuildLabel: r.Label, Annotation: r.Annotation}, nil
+	case fileLabelInput:
+		return FileLabel{File: r.File, Package: r.Package}, nil
+	case subrepoFileLabelInput:
+		return SubrepoFileLabel{File: r.File, Package: r.Package, FullPackage: r.FullPackage}, nil
+	case systemFileLabelInput:
+		return SystemFileLabel{Path: r.File}, nil
+	case systemPathLabelInput:
+		return SystemPathLabel{Name: r.Name, Path: r.Path}, nil
+	case urlLabelInput:
+		return URLLabel(r.File), nil
+	}
+	return nil, fmt.Errorf("unknown build input type %d", r.Type)
+}
+
+func newInputRecords(inputs []BuildInput) []InputRecord {
+	if inputs == nil {
+		return nil
+	}
+	ret := make([]InputRecord, len(inputs))
+	for i, input := range inputs {
+		ret[i] = NewInputRecord(input)
+	}
+	return ret
+}
+
+func newNamedInputRecords(inputs map[string][]BuildInput) map[string][]InputRecord {
+	if inputs == nil {
+		return nil
+	}
+	ret := make(map[string][]InputRecord, len(inputs))
+	for name, in := range inputs {
+		ret[name] = newInputRecords(in)
+	}
+	return ret
+}
+
+func inputsFromRecords(records []InputRecord) ([]BuildInput, error) {
+	if records == nil {
+		return nil, nil
+	}
+	ret := make([]BuildInput, len(records))
+	for i, r := range records {
+		input, err := r.Input()
+		if err != nil {
+			return nil, err
+		}
+		ret[i] = input
+	}
+	return ret, nil
+}
+
+func namedInputsFromRecords(records map[string][]InputRecord) (map[string][]BuildInput, error) {
+	if records == nil {
+		return nil, nil
+	}
+	ret := make(map[string][]BuildInput, len(records))
+	for name, r := range records {
+		inputs, err := inputsFromRecords(r)
+		if err != nil {
+			return nil, err
+		}
+		ret[name] = inputs
+	}
+	return ret, nil
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetRecordRoundTrip(t *testing.T) {
+	target := makeTarget1("//src/core:target", "PUBLIC")
+	target.AddSource(FileLabel{File: "file.go", Package: "src/core"})
+	target.AddSource(ParseBuildLabel("//src/fs:fs", ""))
+	target.AddNamedSource("srcs", SubrepoFileLabel{File: "a.go", Package: "pkg", FullPackage: "plz-out/subrepos/x/pkg"})
+	target.AddTool(SystemPathLabel{Name: "go", Path: []string{"/usr/bin"}})
+	target.AddNamedTool("jarcat", AnnotatedOutputLabel{BuildLabel: ParseBuildLabel("//tools:jarcat", ""), Annotation: "bin"})
+	target.AddDatum(SystemFileLabel{Path: "/etc/hosts"})
+	target.AddSource(URLLabel("https://example.com/file.txt"))
+	target.AddMaybeExportedDependency(ParseBuildLabel("//src/cli:cli", ""), true, false, false)
+	target.AddOutput("target.a")
+	target.AddNamedOutput("hdrs", "target.h")
+	target.AddLabel("go")
+	target.AddCommand("opt", "go build")
+	target.AddEntryPoint("main", "target.a")
+	target.Env = map[string]string{"FOO": "bar"}
+	target.BuildTimeout = 5 * time.Minute
+	target.IsBinary = true
+	target.ShowProgress()
+	target.Test = &TestFields{Command: "run", Timeout: time.Minute, Flakiness: 3}
+	target.AddTestTool(ParseBuildLabel("//tools:test", ""))
+
+	var buf bytes.Buffer
+	require.NoError(t, gob.NewEncoder(&buf).Encode(NewTargetRecord(target)))
+	r := &TargetRecord{}
+	require.NoError(t, gob.NewDecoder(&buf).Decode(r))
+	assert.False(t, r.HasBuildFunctions())
+
+	target2, err := r.Target(nil)
+	require.NoError(t, err)
+	assert.Equal(t, target.Label, target2.Label)
+	assert.Equal(t, target.Visibility, target2.Visibility)
+	assert.Equal(t, target.Sources, target2.Sources)
+	assert.Equal(t, target.NamedSources, target2.NamedSources)
+	assert.Equal(t, target.Tools, target2.Tools)
+	assert.Equal(t, target.AllNamedTools(), target2.AllNamedTools())
+	assert.Equal(t, target.Data, target2.Data)
+	assert.Equal(t, target.DeclaredDependencies(), target2.DeclaredDependencies())
+	assert.Equal(t, target.ExportedDependencies(), target2.ExportedDependencies())
+	assert.Equal(t, target.DeclaredOutputs(), target2.DeclaredOutputs())
+	assert.Equal(t, target.DeclaredNamedOutputs(), target2.DeclaredNamedOutputs())
+	assert.Equal(t, target.Labels, target2.Labels)
+	assert.Equal(t, target.Commands, target2.Commands)
+	assert.Equal(t, target.EntryPoints, target2.EntryPoints)
+	assert.Equal(t, target.Env, target2.Env)
+	assert.Equal(t, target.BuildTimeout, target2.BuildTimeout)
+	assert.True(t, target2.IsBinary)
+	assert.True(t, target2.ShouldShowProgress())
+	assert.Equal(t, target.Test.Command, target2.Test.Command)
+	assert.Equal(t, target.Test.Timeout, target2.Test.Timeout)
+	assert.Equal(t, target.Test.Flakiness, target2.Test.Flakiness)
+	assert.Equal(t, target.AllTestTools(), target2.AllTestTools())
+	assert.True(t, target2.IsSourceOnlyDep(ParseBuildLabel("//src/fs:fs", "")))
+}
+
+func TestTargetRecordBuildFunctions(t *testing.T) {
+	target := makeTarget1("//src/core:target", "")
+	target.PreBuildFunction = preBuildFunction{}
+	r := NewTargetRecord(target)
+	assert.True(t, r.HasBuildFunctions())
+	assert.Equal(t, "pre_build", r.PreBuildFunction)
+}
+
+type preBuildFunction struct{}
+
+func (f preBuildFunction) Call(target *BuildTarget) error { return nil }
+func (f preBuildFunction) String() string                 { return "pre_build" }
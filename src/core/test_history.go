@@ -0,0 +1,298 @@
+package core
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thought-machine/please/src/fs"
+)
+
+// TestHistoryFile is the file that we store the history of previous test runs in.
+const TestHistoryFile = "plz-out/.test_history"
+
+// testHistoryWindow is the number of previous runs that we average durations over.
+// Beyond that older runs decay exponentially so we track tests that get faster or slower.
+const testHistoryWindow = 10
+
+// A TestHistory records the durations and outcomes of previous test runs.
+// It's used to schedule tests (we prefer to start the slowest ones first) and for reporting.
+type TestHistory struct {
+	Targets map[BuildLabel]*TargetTestHistory
+	mutex   sync.RWMutex
+}
+
+// A TargetTestHistory is the history for a single test target.
+type TargetTestHistory struct {
+	Runs     int
+	Failures int
+	// Flakes is the number of runs that passed, but only after some test cases were retried.
+	Flakes int
+	// Duration is the average duration of a single run of the target (across all its shards).
+	Duration time.Duration
+	LastRun  time.Time
+	Cases    map[string]*TestCaseHistory
+}
+
+// A TestCaseHistory is the history for a single test case within a target.
+type TestCaseHistory struct {
+	Runs     int
+	Failures int
+	Flakes   int
+	Duration time.Duration
+}
+
+// A TestHistoryEntry is a single target's history, as returned by TestHistory.Slowest.
+type TestHistoryEntry struct {
+	Label BuildLabel
+	*TargetTestHistory
+}
+
+// NewTestHistory returns a new, empty, TestHistory.
+func NewTestHistory() *TestHistory {
+	return &TestHistory{Targets: map[BuildLabel]*TargetTestHistory{}}
+}
+
+// LoadTestHistory loads the test history from the given file.
+// If it can't be read, an empty history is returned.
+func LoadTestHistory(filename string) *TestHistory {
+	h := NewTestHistory()
+	f, err := os.Open(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Failed to read test history: %s", err)
+		}
+		return h
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(h); err != nil {
+		log.Warning("Failed to decode test history: %s", err)
+		return NewTestHistory()
+	}
+	return h
+}
+
+// Save writes the test history to the given file.
+func (h *TestHistory) Save(filename string) error {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(h); err != nil {
+		return err
+	}
+	return fs.WriteFile(&buf, filename, 0644)
+}
+
+// Record adds the results of a test run of the given target to the history.
+// numRuns is the number of runs that the results are collapsed over; the recorded duration is per run.
+func (h *TestHistory) Record(label BuildLabel, results TestSuite, numRuns int, timestamp time.Time) {
+	if results.Cached || (results.Duration == 0 && len(results.TestCases) == 0) {
+		return // Nothing was actually run, so there's nothing to learn here.
+	}
+	if numRuns < 1 {
+		numRuns = 1
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	target, present := h.Targets[label]
+	if !present {
+		target = &TargetTestHistory{Cases: map[string]*TestCaseHistory{}}
+		h.Targets[label] = target
+	}
+	target.Runs++
+	if results.TimedOut || !results.TestCases.AllSucceeded() {
+		target.Failures++
+	} else if results.FlakyPasses() > 0 {
+		target.Flakes++
+	}
+	target.Duration = averageDuration(target.Duration, results.Duration/time.Duration(numRuns), target.Runs)
+	target.LastRun = timestamp
+	target.recordCases(results)
+}
+
+// RecordCases adds the results of a run of a subset of the given target's test cases to the history.
+// Only the individual test cases are recorded since the run isn't representative of the whole target.
+func (h *TestHistory) RecordCases(label BuildLabel, results TestSuite) {
+	if results.Cached {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	// We don't start a history for a target from a partial run; it'd throw off its expected duration.
+	if target, present := h.Targets[label]; present {
+		target.recordCases(results)
+	}
+}
+
+// recordCases adds the results of each test case in the given suite to the history.
+func (target *TargetTestHistory) recordCases(results TestSuite) {
+	// Each test case here is one run of it (retries of flaky tests are multiple executions of the same case).
+	for _, tc := range results.TestCases {
+		success := tc.Success()
+		if success == nil && tc.Skip() != nil {
+			continue
+		}
+		name := testCaseName(tc)
+		c, present := target.Cases[name]
+		if !present {
+			c = &TestCaseHistory{}
+			target.Cases[name] = c
+		}
+		c.Runs++
+		if success == nil {
+			c.Failures++
+		} else if tc.FlakyPass() {
+			c.Flakes++
+		}
+		if d := tc.Duration(); d != nil {
+			c.Duration = averageDuration(c.Duration, *d, c.Runs)
+		}
+	}
+}
+
+// averageDuration updates a running average with a new duration, given the number of runs (including this one).
+func averageDuration(avg, d time.Duration, runs int) time.Duration {
+	if runs > testHistoryWindow {
+		runs = testHistoryWindow
+	}
+	return avg + (d-avg)/time.Duration(runs)
+}
+
+// testCaseName returns the name we record a test case under.
+func testCaseName(tc TestCase) string {
+	if tc.ClassName == "" {
+		return tc.Name
+	}
+	return tc.ClassName + "." + tc.Name
+}
+
+// Get returns the history for a single target, or nil if there isn't any.
+func (h *TestHistory) Get(label BuildLabel) *TargetTestHistory {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return h.Targets[label]
+}
+
+// HasCase returns true if the target has run a test case with the given name, which may be qualified with its
+// class name.
+func (target *TargetTestHistory) HasCase(name string) bool {
+	if _, present := target.Cases[name]; present {
+		return true
+	}
+	for caseName := range target.Cases {
+		if strings.HasSuffix(caseName, "."+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpectedDuration returns how long we expect a single run of the given target to take.
+// If we haven't seen it before, we guess that it'll take the average time of the tests we have seen.
+func (h *TestHistory) ExpectedDuration(label BuildLabel) time.Duration {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if target, present := h.Targets[label]; present {
+		return target.Duration
+	} else if len(h.Targets) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, target := range h.Targets {
+		total += target.Duration
+	}
+	return total / time.Duration(len(h.Targets))
+}
+
+// Slowest returns the history of all targets, sorted with the slowest first.
+// The results are filtered to those matching any of the given labels, if any are given.
+func (h *TestHistory) Slowest(labels []BuildLabel) []TestHistoryEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	entries := make([]TestHistoryEntry, 0, len(h.Targets))
+	for label, target := range h.Targets {
+		if len(labels) == 0 || BuildLabels(labels).Includes(label) {
+			entries = append(entries, TestHistoryEntry{Label: label, TargetTestHistory: target})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Duration != entries[j].Duration {
+			return entries[i].Duration > entries[j].Duration
+		}
+		return entries[i].Label.Less(entries[j].Label)
+	})
+	return entries
+}
+
+// FlakeRate returns the proportion of runs of this target that were flaky (i.e. only passed on retry).
+func (target *TargetTestHistory) FlakeRate() float64 {
+	return flakeRate(target.Flakes, target.Runs)
+}
+
+// FlakeRate returns the proportion of runs of this test case that were flaky (i.e. only passed on retry).
+func (c *TestCaseHistory) FlakeRate() float64 {
+	return flakeRate(c.Flakes, c.Runs)
+}
+
+func flakeRate(flakes, runs int) float64 {
+	if runs == 0 {
+		return 0
+	}
+	return float64(flakes) / float64(runs)
+}
+
+// Flakiest returns the history of all targets that have flaked at least once, sorted with the flakiest first.
+// The results are filtered to those matching any of the given labels, if any are given.
+func (h *TestHistory) Flakiest(labels []BuildLabel) []TestHistoryEntry {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	entries := []TestHistoryEntry{}
+	for label, target := range h.Targets {
+		if target.Flakes > 0 && (len(labels) == 0 || BuildLabels(labels).Includes(label)) {
+			entries = append(entries, TestHistoryEntry{Label: label, TargetTestHistory: target})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if ri, rj := entries[i].FlakeRate(), entries[j].FlakeRate(); ri != rj {
+			return ri > rj
+		}
+		return entries[i].Label.Less(entries[j].Label)
+	})
+	return entries
+}
+
+// FlakiestCases returns the names of this target's test cases that have flaked at least once, sorted with the flakiest first.
+func (target *TargetTestHistory) FlakiestCases() []string {
+	names := []string{}
+	for name, c := range target.Cases {
+		if c.Flakes > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if ri, rj := target.Cases[names[i]].FlakeRate(), target.Cases[names[j]].FlakeRate(); ri != rj {
+			return ri > rj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// SlowestCases returns the names of this target's test cases, sorted with the slowest first.
+func (target *TargetTestHistory) SlowestCases() []string {
+	names := make([]string, 0, len(target.Cases))
+	for name := range target.Cases {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if di, dj := target.Cases[names[i]].Duration, target.Cases[names[j]].Duration; di != dj {
+			return di > dj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestHistoryRecord(t *testing.T) {
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	now := time.Now()
+	h.Record(label, historySuite(4*time.Second, false), 1, now)
+	h.Record(label, historySuite(2*time.Second, true), 1, now)
+
+	target := h.Get(label)
+	require.NotNil(t, target)
+	assert.Equal(t, 2, target.Runs)
+	assert.Equal(t, 1, target.Failures)
+	assert.Equal(t, 3*time.Second, target.Duration)
+	assert.Equal(t, 2, target.Cases["test.TestOne"].Runs)
+	assert.Equal(t, 1, target.Cases["test.TestOne"].Failures)
+	assert.Equal(t, 1500*time.Millisecond, target.Cases["test.TestOne"].Duration)
+}
+
+func TestTestHistoryRecordPerRun(t *testing.T) {
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	h.Record(label, historySuite(6*time.Second, false), 3, time.Now())
+	assert.Equal(t, 2*time.Second, h.Get(label).Duration)
+}
+
+func TestTestHistoryIgnoresCached(t *testing.T) {
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	suite := historySuite(time.Second, false)
+	suite.Cached = true
+	h.Record(label, suite, 1, time.Now())
+	assert.Nil(t, h.Get(label))
+}
+
+func TestTestHistoryDecays(t *testing.T) {
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	for i := 0; i < 50; i++ {
+		h.Record(label, historySuite(time.Minute, false), 1, time.Now())
+	}
+	for i := 0; i < 50; i++ {
+		h.Record(label, historySuite(time.Second, false), 1, time.Now())
+	}
+	// Older runs should have decayed away to (almost) nothing.
+	assert.InDelta(t, float64(time.Second), float64(h.Get(label).Duration), float64(time.Second))
+}
+
+func TestTestHistoryExpectedDuration(t *testing.T) {
+	h := NewTestHistory()
+	assert.Equal(t, time.Duration(0), h.ExpectedDuration(ParseBuildLabel("//src/core:test", "")))
+	h.Record(ParseBuildLabel("//src/core:fast", ""), historySuite(time.Second, false), 1, time.Now())
+	h.Record(ParseBuildLabel("//src/core:slow", ""), historySuite(5*time.Second, false), 1, time.Now())
+	assert.Equal(t, 5*time.Second, h.ExpectedDuration(ParseBuildLabel("//src/core:slow", "")))
+	// Unknown tests are assumed to take the average time.
+	assert.Equal(t, 3*time.Second, h.ExpectedDuration(ParseBuildLabel("//src/core:test", "")))
+}
+
+func TestTestHistorySlowest(t *testing.T) {
+	h := NewTestHistory()
+	h.Record(ParseBuildLabel("//src/core:fast", ""), historySuite(time.Second, false), 1, time.Now())
+	h.Record(ParseBuildLabel("//src/core:slow", ""), historySuite(5*time.Second, false), 1, time.Now())
+	h.Record(ParseBuildLabel("//src/fs:medium", ""), historySuite(3*time.Second, false), 1, time.Now())
+
+	entries := h.Slowest(nil)
+	require.Equal(t, 3, len(entries))
+	assert.Equal(t, "//src/core:slow", entries[0].Label.String())
+	assert.Equal(t, "//src/fs:medium", entries[1].Label.String())
+	assert.Equal(t, "//src/core:fast", entries[2].Label.String())
+
+	entries = h.Slowest([]BuildLabel{ParseBuildLabel("//src/core:all", "")})
+	require.Equal(t, 2, len(entries))
+	assert.Equal(t, "//src/core:slow", entries[0].Label.String())
+	assert.Equal(t, "//src/core:fast", entries[1].Label.String())
+}
+
+func TestTestHistoryFlakes(t *testing.T) {
+	h := NewTestHistory()
+	flaky := ParseBuildLabel("//src/core:flaky", "")
+	stable := ParseBuildLabel("//src/core:stable", "")
+	h.Record(flaky, flakySuite(), 1, time.Now())
+	h.Record(flaky, historySuite(time.Second, false), 1, time.Now())
+	h.Record(flaky, historySuite(time.Second, true), 1, time.Now())
+	h.Record(flaky, flakySuite(), 1, time.Now())
+	h.Record(stable, historySuite(time.Second, false), 1, time.Now())
+
+	target := h.Get(flaky)
+	assert.Equal(t, 4, target.Runs)
+	assert.Equal(t, 2, target.Flakes)
+	assert.Equal(t, 1, target.Failures)
+	assert.Equal(t, 0.5, target.FlakeRate())
+	assert.Equal(t, 2, target.Cases["test.TestOne"].Flakes)
+	assert.Equal(t, 0.5, target.Cases["test.TestOne"].FlakeRate())
+	assert.Equal(t, []string{"test.TestOne"}, target.FlakiestCases())
+
+	entries := h.Flakiest(nil)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, flaky, entries[0].Label)
+}
+
+func TestTestHistorySaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test_history")
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	h.Record(label, historySuite(time.Second, false), 1, time.Now())
+	require.NoError(t, h.Save(filename))
+
+	loaded := LoadTestHistory(filename)
+	require.NotNil(t, loaded.Get(label))
+	assert.Equal(t, time.Second, loaded.Get(label).Duration)
+	assert.Equal(t, 1, loaded.Get(label).Cases["test.TestOne"].Runs)
+}
+
+func TestLoadTestHistoryMissing(t *testing.T) {
+	h := LoadTestHistory(filepath.Join(t.TempDir(), "test_history"))
+	assert.Equal(t, 0, len(h.Targets))
+}
+
+func TestTestHistoryRecordCases(t *testing.T) {
+	h := NewTestHistory()
+	label := ParseBuildLabel("//src/core:test", "")
+	h.RecordCases(label, historySuite(time.Second, true))
+	assert.Nil(t, h.Get(label), "A partial run shouldn't start a target's history")
+
+	h.Record(label, historySuite(4*time.Second, false), 1, time.Now())
+	h.RecordCases(label, historySuite(time.Second, true))
+	target := h.Get(label)
+	assert.Equal(t, 1, target.Runs)
+	assert.Equal(t, 0, target.Failures)
+	assert.Equal(t, 4*time.Second, target.Duration)
+	assert.Equal(t, 2, target.Cases["test.TestOne"].Runs)
+	assert.Equal(t, 1, target.Cases["test.TestOne"].Failures)
+	assert.True(t, target.HasCase("test.TestOne"))
+	assert.True(t, target.HasCase("TestOne"))
+	assert.False(t, target.HasCase("TestTwo"))
+	assert.False(t, target.HasCase("One"))
+}
+
+func historySuite(duration time.Duration, failed bool) TestSuite {
+	caseDuration := duration / 2
+	execution := TestExecution{Duration: &caseDuration}
+	if failed {
+		execution.Failure = &TestResultFailure{Message: "failed"}
+	}
+	return TestSuite{
+		Duration: duration,
+		TestCases: TestCases{{
+			ClassName:  "test",
+			Name:       "TestOne",
+			Executions: []TestExecution{execution},
+		}},
+	}
+}
+
+func flakySuite() TestSuite {
+	suite := historySuite(time.Second, true)
+	suite.TestCases[0].Executions = append(suite.TestCases[0].Executions, TestExecution{})
+	return suite
+}
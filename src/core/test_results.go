@@ -16,14 +16,15 @@ type TestSuites struct {
 
 // TestSuite describes all the test results for a target.
 type TestSuite struct {
-	Package    string            // The package name of the test suite (usually the first part of the target label).
-	Name       string            // The name of the test suite (usually the last part of the target label).
-	Duration   time.Duration     // The length of time it took to run this target (may be different from the sum of times of test cases).
-	Cached     bool              // True if the test results were retrieved from cache.
-	TimedOut   bool              // True if the test failed because we timed it out.
-	TestCases  TestCases         // The test cases that ran during execution of this target.
-	Properties map[string]string // The system properties at the time of the test.
-	Timestamp  string            // ISO8601 formatted datetime when the test ran.
+	Package     string            // The package name of the test suite (usually the first part of the target label).
+	Name        string            // The name of the test suite (usually the last part of the target label).
+	Duration    time.Duration     // The length of time it took to run this target (may be different from the sum of times of test cases).
+	Cached      bool              // True if the test results were retrieved from cache.
+	TimedOut    bool              // True if the test failed because we timed it out.
+	Quarantined bool              // True if the target is quarantined, in which case its failures don't fail the build.
+	TestCases   TestCases         // The test cases that ran during execution of this target.
+	Properties  map[string]string // The system properties at the time of the test.
+	Timestamp   string            // ISO8601 formatted datetime when the test ran.
 }
 
 // JavaStyleName pretends we are using a language that has package names and classnames etc.
@@ -36,6 +37,27 @@ func (testSuite *TestSuite) Collapse(incoming TestSuite) {
 	testSuite.TestCases = append(testSuite.TestCases, incoming.TestCases...)
 	testSuite.Duration += incoming.Duration
 	testSuite.TimedOut = testSuite.TimedOut || incoming.TimedOut
+	testSuite.Quarantined = testSuite.Quarantined || incoming.Quarantined
+	if testSuite.Properties == nil {
+		testSuite.Properties = make(map[string]string)
+	}
+	testSuite.Properties = addAll(testSuite.Properties, incoming.Properties)
+}
+
+// Merge updates the current test suite with the results of a run of a subset of its test cases.
+// Any test cases that ran replace the existing results for them; the others are left as they were.
+func (testSuite *TestSuite) Merge(incoming TestSuite) {
+	for _, testCase := range incoming.TestCases {
+		if idx := findMatchingTestCase(&testCase, &testSuite.TestCases); idx >= 0 {
+			testSuite.TestCases[idx] = testCase
+		} else {
+			testSuite.TestCases = append(testSuite.TestCases, testCase)
+		}
+	}
+	testSuite.Cached = incoming.Cached
+	testSuite.TimedOut = incoming.TimedOut
+	testSuite.Quarantined = incoming.Quarantined
+	testSuite.Timestamp = incoming.Timestamp
 	if testSuite.Properties == nil {
 		testSuite.Properties = make(map[string]string)
 	}
@@ -54,12 +76,12 @@ func (testSuite *TestSuite) Tests() int {
 	return len(testSuite.TestCases)
 }
 
-// FlakyPasses returns the number of TestCases which succeeded after some number of executions.
+// FlakyPasses returns the number of TestCases which succeeded after failing on some previous executions.
 func (testSuite TestSuite) FlakyPasses() int {
 	flakyPasses := 0
 
 	for _, result := range testSuite.TestCases {
-		if result.Success() != nil && len(result.Executions) > 1 {
+		if result.FlakyPass() {
 			flakyPasses++
 		}
 	}
@@ -164,6 +186,11 @@ func (testCase *TestCase) Success() *TestExecution {
 	return nil
 }
 
+// FlakyPass returns true if the test case eventually succeeded, but failed or errored on some previous executions.
+func (testCase *TestCase) FlakyPass() bool {
+	return testCase.Success() != nil && (len(testCase.Failures()) > 0 || len(testCase.Errors()) > 0)
+}
+
 // Skip returns the either the skipped execution of a test case, or nil if it was never skipped.
 func (testCase *TestCase) Skip() *TestExecution {
 	for _, execution := range testCase.Executions {
@@ -220,6 +247,16 @@ func (testCases TestCases) AllSucceeded() bool {
 	return true
 }
 
+// Includes returns true if there's a test case with the given name, which may be qualified with its class name.
+func (testCases TestCases) Includes(name string) bool {
+	for _, testCase := range testCases {
+		if testCase.Name == name || testCaseName(testCase) == name {
+			return true
+		}
+	}
+	return false
+}
+
 // TestExecution represents one execution of a test method. The absence of a Failure, Error or Skip implies the test
 // executed successfully.
 type TestExecution struct {
@@ -262,6 +299,8 @@ var lineCoverageOutput = [...]rune{'N', 'X', 'U', 'C'} // Corresponds to orderin
 type TestCoverage struct {
 	Tests map[BuildLabel]map[string][]LineCoverage
 	Files map[string][]LineCoverage
+	// TestCases breaks the coverage of each test down by test case, for tests whose coverage output records that.
+	TestCases map[BuildLabel]map[string]map[string][]LineCoverage
 }
 
 // Aggregate aggregates results from another coverage object into this one.
@@ -273,9 +312,40 @@ func (coverage *TestCoverage) Aggregate(cov *TestCoverage) {
 		coverage.Files = map[string][]LineCoverage{}
 	}
 
-	// Assume that tests are independent (will currently always be the case).
+	// Tests are independent, but may be reported in parts (e.g. one for each shard of a sharded test).
 	for label, c := range cov.Tests {
-		coverage.Tests[label] = c
+		if existing, present := coverage.Tests[label]; present {
+			merged := make(map[string][]LineCoverage, len(existing))
+			for filename, lines := range existing {
+				merged[filename] = lines
+			}
+			for filename, lines := range c {
+				merged[filename] = MergeCoverageLines(merged[filename], lines)
+			}
+			coverage.Tests[label] = merged
+		} else {
+			coverage.Tests[label] = c
+		}
+	}
+	for label, cases := range cov.TestCases {
+		if coverage.TestCases == nil {
+			coverage.TestCases = map[BuildLabel]map[string]map[string][]LineCoverage{}
+		}
+		existing, present := coverage.TestCases[label]
+		if !present {
+			existing = map[string]map[string][]LineCoverage{}
+			coverage.TestCases[label] = existing
+		}
+		for name, c := range cases {
+			merged := existing[name]
+			if merged == nil {
+				merged = map[string][]LineCoverage{}
+				existing[name] = merged
+			}
+			for filename, lines := range c {
+				merged[filename] = MergeCoverageLines(merged[filename], lines)
+			}
+		}
 	}
 	// Files are more complex since multiple tests can cover the same file.
 	// We take the best result for each line from each test.
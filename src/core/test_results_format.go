@@ -0,0 +1,85 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// A TestResultsFormat describes one format of test results that we know how to parse.
+type TestResultsFormat struct {
+	// Name is the name of the format, as given to the test_results_format attribute.
+	Name string
+	// Detect returns true if the given data looks like it's in this format.
+	// If it's nil the format can't be reliably detected, so it's only used if it's requested explicitly
+	// or as a fallback when nothing else matches.
+	Detect func(data []byte) bool
+	// Parse parses the given data into a test suite.
+	Parse func(data []byte) (TestSuite, error)
+}
+
+var testResultsFormats struct {
+	mutex   sync.RWMutex
+	formats []TestResultsFormat
+}
+
+// RegisterTestResultsFormat registers a new format that test results can be parsed from.
+// When a target doesn't specify a format, we use the first registered one whose Detect function
+// matches its results, so more specific formats should be registered earlier.
+// It panics if a format of the same name is already registered.
+func RegisterTestResultsFormat(format TestResultsFormat) {
+	testResultsFormats.mutex.Lock()
+	defer testResultsFormats.mutex.Unlock()
+	for _, f := range testResultsFormats.formats {
+		if f.Name == format.Name {
+			panic("test results format " + format.Name + " is already registered")
+		}
+	}
+	testResultsFormats.formats = append(testResultsFormats.formats, format)
+}
+
+// FindTestResultsFormat returns the format to parse the given test results with.
+// If a name is given that format is used, otherwise we try to detect it from the data, falling back
+// to the first format that can't be detected if none of the others match.
+func FindTestResultsFormat(name string, data []byte) (TestResultsFormat, error) {
+	testResultsFormats.mutex.RLock()
+	defer testResultsFormats.mutex.RUnlock()
+	if name != "" {
+		return findTestResultsFormat(name)
+	}
+	for _, format := range testResultsFormats.formats {
+		if format.Detect != nil && format.Detect(data) {
+			return format, nil
+		}
+	}
+	for _, format := range testResultsFormats.formats {
+		if format.Detect == nil {
+			return format, nil
+		}
+	}
+	return TestResultsFormat{}, fmt.Errorf("Couldn't detect the format of the test results")
+}
+
+// ValidateTestResultsFormat returns an error if the given name isn't a registered test results format.
+// An empty name is valid and means the format will be detected automatically.
+func ValidateTestResultsFormat(name string) error {
+	if name == "" {
+		return nil
+	}
+	testResultsFormats.mutex.RLock()
+	defer testResultsFormats.mutex.RUnlock()
+	_, err := findTestResultsFormat(name)
+	return err
+}
+
+// findTestResultsFormat returns the registered format with the given name. The caller must hold the lock.
+func findTestResultsFormat(name string) (TestResultsFormat, error) {
+	names := make([]string, len(testResultsFormats.formats))
+	for i, format := range testResultsFormats.formats {
+		if format.Name == name {
+			return format, nil
+		}
+		names[i] = format.Name
+	}
+	return TestResultsFormat{}, fmt.Errorf("Unknown test results format %s; must be one of %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestResultsFormats(t *testing.T) {
+	RegisterTestResultsFormat(TestResultsFormat{Name: "fallback"})
+	RegisterTestResultsFormat(TestResultsFormat{Name: "xml", Detect: func(data []byte) bool { return bytes.HasPrefix(data, []byte("<")) }})
+	assert.Panics(t, func() { RegisterTestResultsFormat(TestResultsFormat{Name: "xml"}) })
+
+	format, err := FindTestResultsFormat("", []byte("<testsuites />"))
+	assert.NoError(t, err)
+	assert.Equal(t, "xml", format.Name)
+	// Formats that can't be detected are only used if nothing else matches.
+	format, err = FindTestResultsFormat("", []byte("PASS"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback", format.Name)
+	format, err = FindTestResultsFormat("fallback", []byte("<testsuites />"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fallback", format.Name)
+	_, err = FindTestResultsFormat("json", nil)
+	assert.EqualError(t, err, "Unknown test results format json; must be one of fallback, xml")
+
+	assert.NoError(t, ValidateTestResultsFormat(""))
+	assert.NoError(t, ValidateTestResultsFormat("xml"))
+	assert.Error(t, ValidateTestResultsFormat("json"))
+}
@@ -123,6 +123,86 @@ func TestAdd(t *testing.T) {
 	assert.Equal(t, &duration20, success.Duration)
 }
 
+func TestFlakyPasses(t *testing.T) {
+	suite := TestSuite{
+		TestCases: []TestCase{
+			{
+				Name: "flaky",
+				Executions: []TestExecution{
+					{Failure: &TestResultFailure{Message: "failed"}},
+					{},
+				},
+			},
+			{
+				// Multiple successful executions (e.g. from --num_runs) aren't flaky.
+				Name:       "passed",
+				Executions: []TestExecution{{}, {}},
+			},
+			{
+				Name:       "failed",
+				Executions: []TestExecution{{Failure: &TestResultFailure{Message: "failed"}}},
+			},
+		},
+	}
+	assert.True(t, suite.TestCases[0].FlakyPass())
+	assert.False(t, suite.TestCases[1].FlakyPass())
+	assert.False(t, suite.TestCases[2].FlakyPass())
+	assert.Equal(t, 1, suite.FlakyPasses())
+}
+
+func TestMerge(t *testing.T) {
+	failure := TestExecution{Failure: &TestResultFailure{Message: "failed"}}
+	suite := TestSuite{
+		Name:     "Test",
+		Duration: 10 * time.Second,
+		TestCases: TestCases{
+			{Name: "TestOne", Executions: []TestExecution{{}}},
+			{Name: "TestTwo", Executions: []TestExecution{failure}},
+		},
+	}
+	suite.Merge(TestSuite{
+		Name:     "Test",
+		Duration: time.Second,
+		Cached:   true,
+		TestCases: TestCases{
+			{Name: "TestTwo", Executions: []TestExecution{{}}},
+			{Name: "TestThree", Executions: []TestExecution{{}}},
+		},
+	})
+	assert.Equal(t, 3, suite.Tests())
+	assert.Equal(t, 3, suite.Passes())
+	assert.Equal(t, "TestTwo", suite.TestCases[1].Name)
+	assert.Equal(t, 1, len(suite.TestCases[1].Executions))
+	assert.Equal(t, 10*time.Second, suite.Duration)
+	assert.True(t, suite.Cached)
+}
+
+func TestTestCasesIncludes(t *testing.T) {
+	cases := TestCases{{ClassName: "CoreTest", Name: "TestOne"}, {Name: "TestTwo"}}
+	assert.True(t, cases.Includes("TestOne"))
+	assert.True(t, cases.Includes("CoreTest.TestOne"))
+	assert.True(t, cases.Includes("TestTwo"))
+	assert.False(t, cases.Includes("TestThree"))
+}
+
+func TestAggregateTestCoverage(t *testing.T) {
+	label := ParseBuildLabel("//src/core:test", "")
+	coverage := NewTestCoverage()
+	// Two shards of the same test each cover some of the lines.
+	coverage.Aggregate(&TestCoverage{
+		Tests: map[BuildLabel]map[string][]LineCoverage{label: {"a.go": {Covered, Uncovered}}},
+		Files: map[string][]LineCoverage{"a.go": {Covered, Uncovered}},
+	})
+	coverage.Aggregate(&TestCoverage{
+		Tests:     map[BuildLabel]map[string][]LineCoverage{label: {"a.go": {Uncovered, Covered}, "b.go": {Covered}}},
+		Files:     map[string][]LineCoverage{"a.go": {Uncovered, Covered}, "b.go": {Covered}},
+		TestCases: map[BuildLabel]map[string]map[string][]LineCoverage{label: {"TestB": {"b.go": {Covered}}}},
+	})
+	assert.Equal(t, map[string][]LineCoverage{"a.go": {Covered, Covered}, "b.go": {Covered}}, coverage.Tests[label])
+	assert.Equal(t, map[string][]LineCoverage{"a.go": {Covered, Covered}, "b.go": {Covered}}, coverage.Files)
+	assert.Equal(t, map[string]map[string][]LineCoverage{"TestB": {"b.go": {Covered}}}, coverage.TestCases[label])
+}
+
 func TestOrderedFiles(t *testing.T) {
 	cov := NewTestCoverage()
 	cov.Files["common/python/async_unblock.py"] = nil
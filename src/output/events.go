@@ -0,0 +1,219 @@
+// For writing out a stream of build & test events as newline-delimited JSON, which other tools
+// (e.g. IDEs or dashboards) can follow while the build is running.
+
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/test"
+)
+
+// An eventWriter is responsible for writing the event stream.
+type eventWriter struct {
+	w       io.WriteCloser
+	b       *bufio.Writer
+	e       *json.Encoder
+	started map[eventKey]struct{}
+	// The test cases we've already emitted, mapped to how many executions they had at the time.
+	// Results accumulate over multiple runs / shards of a target so we use this to only send the new ones.
+	testCases map[testCaseKey]int
+}
+
+type eventKey struct {
+	Label core.BuildLabel
+	Run   int
+}
+
+type testCaseKey struct {
+	Label           core.BuildLabel
+	ClassName, Name string
+}
+
+// newEventWriter returns a new eventWriter writing to the given destination, which is either
+// a filename or, if prefixed with unix://, the path to a Unix socket to connect to.
+// If it can't be opened it will silently discard all events given to it.
+func newEventWriter(destination string) *eventWriter {
+	ew := &eventWriter{
+		started:   map[eventKey]struct{}{},
+		testCases: map[testCaseKey]int{},
+	}
+	var err error
+	if path, ok := strings.CutPrefix(destination, "unix://"); ok {
+		ew.w, err = net.Dial("unix", path)
+	} else {
+		ew.w, err = os.Create(destination)
+	}
+	if err != nil {
+		log.Errorf("Couldn't open event stream: %s", err)
+		return ew
+	}
+	ew.b = bufio.NewWriter(ew.w)
+	ew.e = json.NewEncoder(ew.b)
+	return ew
+}
+
+// Close closes this writer and any associated files or connections.
+func (ew *eventWriter) Close() error {
+	if ew.w == nil {
+		return nil
+	} else if err := ew.b.Flush(); err != nil {
+		ew.w.Close()
+		return err
+	}
+	return ew.w.Close()
+}
+
+// AddEvent adds the events for a single build result to this writer.
+func (ew *eventWriter) AddEvent(result *core.BuildResult) {
+	if ew.w == nil {
+		return
+	}
+	key := eventKey{Label: result.Label, Run: result.Run}
+	switch result.Status {
+	case core.TargetQueued:
+		ev := ew.newEvent(result, "target_queued")
+		if result.Run > 0 {
+			ev.Phase = "test"
+		} else {
+			ev.Phase = "build"
+		}
+		ew.write(ev)
+	case core.TargetBuilding:
+		if _, present := ew.started[key]; !present {
+			ew.started[key] = struct{}{}
+			ew.write(ew.newEvent(result, "build_started"))
+		}
+	case core.TargetBuilt, core.TargetCached, core.TargetBuildFailed, core.TargetBuildStopped:
+		delete(ew.started, key)
+		ev := ew.newEvent(result, "build_finished")
+		ev.Status = map[core.BuildResultStatus]string{
+			core.TargetBuilt:        "built",
+			core.TargetCached:       "cached",
+			core.TargetBuildFailed:  "failed",
+			core.TargetBuildStopped: "stopped",
+		}[result.Status]
+		ew.write(ev)
+	case core.TargetTesting:
+		if _, present := ew.started[key]; !present {
+			ew.started[key] = struct{}{}
+			ew.write(ew.newEvent(result, "test_started"))
+		}
+	case core.TargetTested, core.TargetTestFailed, core.TargetTestStopped:
+		delete(ew.started, key)
+		ew.addTestCases(result)
+		ev := ew.newEvent(result, "test_finished")
+		ev.Status = map[core.BuildResultStatus]string{
+			core.TargetTested:      "passed",
+			core.TargetTestFailed:  "failed",
+			core.TargetTestStopped: "stopped",
+		}[result.Status]
+		ev.Cached = result.Tests.Cached
+		ew.write(ev)
+		if result.Coverage != nil && len(result.Coverage.Files) > 0 {
+			ev := ew.newEvent(result, "coverage_available")
+			ev.Coverage = map[string]eventCoverage{}
+			for file, lines := range result.Coverage.Files {
+				covered, total := test.CountCoverage(lines)
+				ev.Coverage[file] = eventCoverage{Covered: covered, Total: total}
+			}
+			ew.write(ev)
+		}
+	case core.ParseFailed:
+		ew.write(ew.newEvent(result, "parse_failed"))
+	}
+}
+
+// addTestCases writes an event for each new test case result.
+func (ew *eventWriter) addTestCases(result *core.BuildResult) {
+	for _, testCase := range result.Tests.TestCases {
+		key := testCaseKey{Label: result.Label, ClassName: testCase.ClassName, Name: testCase.Name}
+		if ew.testCases[key] == len(testCase.Executions) {
+			continue
+		}
+		ew.testCases[key] = len(testCase.Executions)
+		ev := ew.newEvent(result, "test_case")
+		ev.TestCase = &eventTestCase{
+			ClassName: testCase.ClassName,
+			Name:      testCase.Name,
+			Status:    test.TestCaseStatus(testCase),
+			Runs:      len(testCase.Executions),
+		}
+		if d := testCase.Duration(); d != nil {
+			ev.TestCase.Duration = d.Seconds()
+		}
+		if failures := testCase.Failures(); len(failures) > 0 && ev.TestCase.Status == "fail" {
+			ev.TestCase.Message = failures[len(failures)-1].Failure.Message
+		} else if errors := testCase.Errors(); len(errors) > 0 && ev.TestCase.Status == "error" {
+			ev.TestCase.Message = errors[len(errors)-1].Error.Message
+		}
+		ew.write(ev)
+	}
+}
+
+func (ew *eventWriter) newEvent(result *core.BuildResult, eventType string) *event {
+	ev := &event{
+		Time:        result.Time,
+		Type:        eventType,
+		Label:       result.Label.String(),
+		Run:         result.Run,
+		Description: result.Description,
+	}
+	if result.Err != nil {
+		ev.Error = result.Err.Error()
+	}
+	return ev
+}
+
+// write writes a single event. Each is flushed straight away so consumers can follow along.
+// If it fails (e.g. because the consumer has gone away) we give up on the stream and discard any further events.
+func (ew *eventWriter) write(ev *event) {
+	if ew.w == nil {
+		return
+	}
+	err := ew.e.Encode(ev)
+	if err == nil {
+		err = ew.b.Flush()
+	}
+	if err != nil {
+		log.Warning("Failed to write event, no more events will be streamed: %s", err)
+		ew.w.Close()
+		ew.w = nil
+	}
+}
+
+// An event is a single entry in the event stream.
+type event struct {
+	Time        time.Time                `json:"time"`
+	Type        string                   `json:"type"`
+	Label       string                   `json:"label"`
+	Run         int                      `json:"run,omitempty"`
+	Phase       string                   `json:"phase,omitempty"`
+	Status      string                   `json:"status,omitempty"`
+	Cached      bool                     `json:"cached,omitempty"`
+	Description string                   `json:"description,omitempty"`
+	Error       string                   `json:"error,omitempty"`
+	TestCase    *eventTestCase           `json:"test_case,omitempty"`
+	Coverage    map[string]eventCoverage `json:"coverage,omitempty"`
+}
+
+type eventTestCase struct {
+	ClassName string  `json:"class_name,omitempty"`
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Runs      int     `json:"runs"`
+	Duration  float64 `json:"duration,omitempty"` // in seconds
+	Message   string  `json:"message,omitempty"`
+}
+
+type eventCoverage struct {
+	Covered int `json:"covered"`
+	Total   int `json:"total"`
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestEventStream(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "events.json")
+	ew := newEventWriter(filename)
+	label := core.ParseBuildLabel("//src/output:output_test", "")
+	for _, result := range testResults(label) {
+		ew.AddEvent(result)
+	}
+	require.NoError(t, ew.Close())
+
+	f, err := os.Open(filename)
+	require.NoError(t, err)
+	defer f.Close()
+	assertEvents(t, f)
+}
+
+func TestEventStreamSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+	ew := newEventWriter("unix://" + path)
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	for _, result := range testResults(core.ParseBuildLabel("//src/output:output_test", "")) {
+		ew.AddEvent(result)
+	}
+	require.NoError(t, ew.Close())
+	assertEvents(t, conn)
+}
+
+func TestEventStreamConsumerGoesAway(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.sock")
+	l, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer l.Close()
+	ew := newEventWriter("unix://" + path)
+	conn, err := l.Accept()
+	require.NoError(t, err)
+	conn.Close()
+	// The first few writes might succeed before we notice the other end has gone, but after that we should stop.
+	label := core.ParseBuildLabel("//src/output:output_test", "")
+	for i := 0; i < 100 && ew.w != nil; i++ {
+		ew.AddEvent(&core.BuildResult{Label: label, Status: core.ParseFailed})
+	}
+	assert.Nil(t, ew.w)
+	assert.NoError(t, ew.Close())
+}
+
+func testResults(label core.BuildLabel) []*core.BuildResult {
+	suite := core.TestSuite{
+		TestCases: core.TestCases{
+			{Name: "TestPass", Executions: []core.TestExecution{{}}},
+			{Name: "TestFail", Executions: []core.TestExecution{{Failure: &core.TestResultFailure{Message: "oh no"}}}},
+		},
+	}
+	coverage := core.NewTestCoverage()
+	coverage.Files["src/output/events.go"] = []core.LineCoverage{core.NotExecutable, core.Covered, core.Uncovered}
+	return []*core.BuildResult{
+		{Label: label, Status: core.TargetQueued},
+		{Label: label, Status: core.TargetBuilding, Description: "Preparing..."},
+		{Label: label, Status: core.TargetBuilding, Description: "Building..."},
+		{Label: label, Status: core.TargetBuilt},
+		{Label: label, Run: 1, Status: core.TargetQueued},
+		{Label: label, Run: 1, Status: core.TargetTesting},
+		{Label: label, Run: 1, Status: core.TargetTestFailed, Tests: suite, Coverage: coverage, Err: fmt.Errorf("oh no")},
+	}
+}
+
+func assertEvents(t *testing.T, r io.Reader) {
+	var events []event
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		ev := event{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	require.Equal(t, 9, len(events))
+	types := make([]string, len(events))
+	for i, ev := range events {
+		types[i] = ev.Type
+		assert.Equal(t, "//src/output:output_test", ev.Label)
+	}
+	assert.Equal(t, []string{
+		"target_queued",
+		"build_started",
+		"build_finished",
+		"target_queued",
+		"test_started",
+		"test_case",
+		"test_case",
+		"test_finished",
+		"coverage_available",
+	}, types)
+	assert.Equal(t, "build", events[0].Phase)
+	assert.Equal(t, "built", events[2].Status)
+	assert.Equal(t, "test", events[3].Phase)
+	assert.Equal(t, 1, events[4].Run)
+	assert.Equal(t, "pass", events[5].TestCase.Status)
+	assert.Equal(t, "TestFail", events[6].TestCase.Name)
+	assert.Equal(t, "fail", events[6].TestCase.Status)
+	assert.Equal(t, "oh no", events[6].TestCase.Message)
+	assert.Equal(t, "failed", events[7].Status)
+	assert.Equal(t, "oh no", events[7].Error)
+	assert.Equal(t, eventCoverage{Covered: 1, Total: 2}, events[8].Coverage["src/output/events.go"])
+}
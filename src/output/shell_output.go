@@ -27,7 +27,7 @@ const testDurationGranularity = time.Millisecond
 
 // MonitorState monitors the build while it's running and prints output until the results
 // channel of state has completed.
-func MonitorState(state *core.BuildState, plainOutput, detailedTests, streamTestResults, shell, shellRun bool, traceFile string) {
+func MonitorState(state *core.BuildState, plainOutput, detailedTests, streamTestResults, shell, shellRun bool, traceFile, eventStream string) {
 	initPrintf(state.Config)
 
 	if len(state.Config.Please.Motd) != 0 {
@@ -40,6 +40,11 @@ func MonitorState(state *core.BuildState, plainOutput, detailedTests, streamTest
 		tw = newTraceWriter(traceFile)
 		defer tw.Close()
 	}
+	var ew *eventWriter
+	if eventStream != "" {
+		ew = newEventWriter(eventStream)
+		defer ew.Close()
+	}
 
 	displayer := setupDisplayer(state, plainOutput)
 	t := time.NewTicker(displayer.Frequency())
@@ -54,9 +59,12 @@ loop:
 			if !ok || (state.DebugFailingTests && result.Status == core.TargetTesting) {
 				break loop
 			}
-			if threadID := bt.ProcessResult(result); tw != nil && !result.Status.IsParse() {
+			if threadID := bt.ProcessResult(result); tw != nil && !result.Status.IsParse() && !result.Status.IsQueued() {
 				tw.AddTrace(threadID, result, result.Status.IsActive())
 			}
+			if ew != nil {
+				ew.AddEvent(result)
+			}
 			if streamTestResults && (result.Status == core.TargetTested || result.Status == core.TargetTestFailed) {
 				os.Stdout.Write(test.SerialiseResultsToXML(state.Graph.TargetOrDie(result.Label), false, state.Config.Test.StoreTestOutputOnSuccess))
 				os.Stdout.Write([]byte{'\n'})
@@ -281,7 +289,7 @@ func formatTestCase(result core.TestCase, name string, detailed bool) string {
 		return formatTestName(result, name) + " (No results)"
 	}
 	var outcome core.TestExecution
-	if len(result.Executions) > 1 && result.Success() != nil {
+	if result.FlakyPass() {
 		return fmt.Sprintf("%s ${BOLD_MAGENTA}%s${RESET}", formatTestName(result, name), "FLAKY PASS")
 	}
 
@@ -362,6 +370,9 @@ func testResultMessage(results *core.TestSuite, showDuration bool) string {
 	if results.Cached {
 		msg += " ${GREEN}[cached]${RESET}"
 	}
+	if results.Quarantined {
+		msg += " ${BOLD_MAGENTA}[quarantined]${RESET}"
+	}
 	return msg
 }
 
@@ -430,8 +441,8 @@ func printTempDirs(state *core.BuildState, duration time.Duration, shell, shellR
 			dir = filepath.Join(core.RepoRoot, target.TestDir(1))
 			env = core.TestEnvironment(state, target, dir, 1)
 			shouldSandbox = target.Test.Sandbox
-			if len(state.TestArgs) > 0 {
-				env["TESTS"] = strings.Join(state.TestArgs, " ")
+			if selection := state.TestSelection(target.Label); len(selection) > 0 {
+				env["TESTS"] = strings.Join(selection, " ")
 			}
 		}
 		cmd, _ = core.ReplaceSequences(state, target, cmd)
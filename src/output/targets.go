@@ -1,6 +1,7 @@
 package output
 
 import (
+	"strings"
 	"time"
 
 	"github.com/thought-machine/please/src/core"
@@ -67,7 +68,7 @@ func (bt *buildingTargets) Targets() []buildingTarget {
 // It returns a 'thread id' for it (which is relevant for trace output)
 func (bt *buildingTargets) ProcessResult(result *core.BuildResult) int {
 	defer bt.handleOutput(result)
-	if result.Status.IsParse() { // Parse tasks don't take a slot here
+	if result.Status.IsParse() || result.Status.IsQueued() { // Parse tasks & queued targets don't take a slot here
 		return 0
 	}
 	idx := bt.index(result.Label, result.Run)
@@ -84,14 +85,14 @@ func (bt *buildingTargets) handleOutput(result *core.BuildResult) {
 		// Don't stop here after test failure, aggregate them for later.
 		if result.Status != core.TargetTestFailed {
 			// Reset colour so the entire compiler error output doesn't appear red.
-			log.Errorf("%s failed:\x1b[0m\n%s", label, shortError(result.Err))
+			log.Errorf("%s failed:\x1b[0m\n%s", label, bt.withProvenance(label, shortError(result.Err)))
 			// TODO(rgodden): make sure we close off any pending targets when their package fails to parse e.g. because
 			// 	a subrepo failed to build.
 			if !bt.state.KeepGoing || result.Status == core.ParseFailed {
 				bt.state.Stop()
 			}
 		} else if msg := shortError(result.Err); msg != "" {
-			log.Errorf("%s failed: %s", result.Label, msg)
+			log.Errorf("%s failed: %s", result.Label, bt.withProvenance(label, msg))
 		} else {
 			log.Errorf("%s failed", label)
 		}
@@ -111,6 +112,16 @@ func (bt *buildingTargets) handleOutput(result *core.BuildResult) {
 	}
 }
 
+// withProvenance appends where a target was declared to an error message about it, if that was recorded.
+func (bt *buildingTargets) withProvenance(label core.BuildLabel, msg string) string {
+	if t := bt.state.Graph.Target(label); t != nil {
+		if lines := t.Provenance(); len(lines) > 0 {
+			return strings.TrimRight(msg, "\n") + "\n" + label.String() + " was " + strings.Join(lines, "\n    ")
+		}
+	}
+	return msg
+}
+
 // index returns the index to use for a result
 func (bt *buildingTargets) index(label core.BuildLabel, run int) int {
 	if idx, present := bt.currentTargets[buildingTargetKey{Label: label, Run: run}]; present {
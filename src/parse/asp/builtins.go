@@ -1,10 +1,11 @@
 package asp
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	iofs "io/fs"
 	"path/filepath"
 	"reflect"
 	"slices"
@@ -15,7 +16,6 @@ import (
 	"unicode"
 
 	"github.com/Masterminds/semver/v3"
-	"github.com/manifoldco/promptui"
 
 	"github.com/thought-machine/please/src/cli"
 	"github.com/thought-machine/please/src/core"
@@ -53,6 +53,8 @@ func registerBuiltins(s *scope) {
 	setNativeCode(s, "ord", ord)
 	setNativeCode(s, "len", lenFunc)
 	setNativeCode(s, "glob", glob)
+	setNativeCode(s, "read_file", readFile)
+	setNativeCode(s, "read_json", readJSON)
 	setNativeCode(s, "bool", boolType)
 	setNativeCode(s, "int", intType)
 	setNativeCode(s, "str", strType)
@@ -167,6 +169,9 @@ func setNativeCode(s *scope, name string, code nativeFunc, flags ...bool) *pyFun
 // setLogCode specialises setNativeCode for handling the log functions (of which there are a few)
 func setLogCode(s *scope, name string, f func(format string, args ...interface{})) {
 	setNativeCode(s, name, func(s *scope, args []pyObject) pyObject {
+		if name != "debug" && name != "info" {
+			s.markUncacheable("it logs messages at " + name + " level")
+		}
 		if str, ok := args[0].(pyString); ok {
 			l := make([]interface{}, len(args))
 			for i, arg := range args {
@@ -202,6 +207,9 @@ func buildRule(s *scope, args []pyObject) pyObject {
 
 	target := createTarget(s, args)
 	s.Assert(s.pkg.Target(target.Label.Name) == nil, "Duplicate build target in %s: %s", s.pkg.Name, target.Label.Name)
+	if r := s.interpreter.calls; r != nil {
+		target.CallStack = r.Stack(s, "build_rule")
+	}
 	populateTarget(s, target, args)
 	s.state.AddTarget(s.pkg, target)
 	if s.Callback {
@@ -284,6 +292,7 @@ func tagName(name, tag string) string {
 // bazelLoad implements the load() builtin, which is only available for Bazel compatibility.
 func bazelLoad(s *scope, args []pyObject) pyObject {
 	s.Assert(s.state.Config.Bazel.Compatibility, "load() is only available in Bazel compatibility mode. See `plz help bazel` for more information.")
+	s.markUncacheable("it calls load()")
 	// The argument always looks like a build label, but it is not really one (i.e. there is no BUILD file that defines it).
 	// We do not support their legacy syntax here (i.e. "/tools/build_rules/build_test" etc).
 	l := s.parseLabelInContextPkg(string(args[0].(pyString)))
@@ -406,10 +415,12 @@ func subincludeTarget(s *scope, l core.BuildLabel) *core.BuildTarget {
 
 	t := s.WaitForSubincludedTarget(l, pkgLabel)
 
-	// TODO(jpoole): when pkg is nil, that means this subinclude was made by another subinclude. We're currently loosing
-	// this information here. We probably need a way to transitively record the subincludes.
+	// When pkg is nil, that means this subinclude was made by another subinclude. We record that on the graph
+	// since the package doesn't know about it.
 	if s.pkg != nil {
 		s.pkg.RegisterSubinclude(l)
+	} else if s.subincludeLabel != nil {
+		s.state.Graph.AddNestedSubinclude(*s.subincludeLabel, l)
 	}
 	return t
 }
@@ -612,12 +623,12 @@ func strFormat(s *scope, args []pyObject) pyObject {
 			} else {
 				buf.WriteString(self[start : end+1])
 			}
-		} else if key := self[start+1 : end]; key == "" {
+		} else if key, conversion, spec := parseFormatField(self[start+1 : end]); key == "" {
 			s.Assert(arg < len(args), "format string specifies at least %d positional arguments, but only %d were supplied", arg, len(args)-1)
-			buf.WriteString(args[arg].String())
+			buf.WriteString(formatField(s, args[arg], conversion, spec))
 			arg++
 		} else if val, present := s.locals[key]; present {
-			buf.WriteString(val.String())
+			buf.WriteString(formatField(s, val, conversion, spec))
 		} else {
 			// We may want to error here in some future revision
 			buf.WriteString(self[start : end+1])
@@ -627,6 +638,25 @@ func strFormat(s *scope, args []pyObject) pyObject {
 	return pyString(buf.String())
 }
 
+// parseFormatField splits a replacement field in a format string into its name, conversion and format spec.
+func parseFormatField(field string) (string, byte, string) {
+	field, spec, _ := strings.Cut(field, ":")
+	if field, conversion, found := strings.Cut(field, "!"); found && len(conversion) == 1 {
+		return field, conversion[0], spec
+	}
+	return field, 0, spec
+}
+
+// formatField formats a single replacement field for strFormat.
+func formatField(s *scope, obj pyObject, conversion byte, spec string) string {
+	if conversion == 0 && spec == "" {
+		return obj.String()
+	}
+	str, err := formatValue(obj, conversion, spec)
+	s.Assert(err == nil, "%s", err)
+	return str
+}
+
 func strCount(s *scope, args []pyObject) pyObject {
 	self := string(args[0].(pyString))
 	needle := string(args[1].(pyString))
@@ -673,6 +703,7 @@ func glob(s *scope, args []pyObject) pyObject {
 	}
 
 	glob := s.globber.Glob(s.pkg.Name, include, exclude, hidden, includeSymlinks)
+	s.recordGlob(include, exclude, hidden, includeSymlinks, glob)
 	if !allowEmpty && len(glob) == 0 {
 		// Strip build file name from exclude list for error message
 		exclude = exclude[:len(exclude)-len(s.state.Config.Parse.BuildFileName)]
@@ -682,6 +713,103 @@ func glob(s *scope, args []pyObject) pyObject {
 	return fromStringList(glob)
 }
 
+// readFile implements read_file(), which returns the contents of a source file in the current package.
+func readFile(s *scope, args []pyObject) pyObject {
+	return pyString(readSourceFile(s, "read_file", string(args[0].(pyString))))
+}
+
+// readJSON implements read_json(), which decodes a JSON source file in the current package.
+func readJSON(s *scope, args []pyObject) pyObject {
+	filename := string(args[0].(pyString))
+	d := json.NewDecoder(bytes.NewReader(readSourceFile(s, "read_json", filename)))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		s.Error("read_json(): failed to decode %s: %s", filename, err)
+	}
+	obj, err := jsonToPyObject(v)
+	s.Assert(err == nil, "read_json(): failed to decode %s: %s", filename, err)
+	return obj
+}
+
+// jsonToPyObject converts a decoded JSON value into the equivalent pyObject.
+func jsonToPyObject(v any) (pyObject, error) {
+	switch v := v.(type) {
+	case nil:
+		return None, nil
+	case bool:
+		return newPyBool(v), nil
+	case string:
+		return pyString(v), nil
+	case json.Number:
+		i, err := v.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("%s is not an integer", v)
+		}
+		return pyInt(i), nil
+	case []any:
+		l := make(pyList, len(v))
+		for i, x := range v {
+			obj, err := jsonToPyObject(x)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = obj
+		}
+		return l, nil
+	case map[string]any:
+		d := make(pyDict, len(v))
+		for k, x := range v {
+			obj, err := jsonToPyObject(x)
+			if err != nil {
+				return nil, err
+			}
+			d[k] = obj
+		}
+		return d, nil
+	}
+	return nil, fmt.Errorf("unknown JSON value %v", v)
+}
+
+// readSourceFile reads a source file in the current package for read_file() or read_json().
+// Anything outside the package or that's generated by a build rule is refused. Note that we can only
+// tell a file is generated here if its rule has already been registered, i.e. it's defined earlier
+// in the same BUILD file; anything later is indistinguishable from a source file at this point.
+// The file is recorded so the parse cache notices if it changes.
+func readSourceFile(s *scope, builtin, filename string) []byte {
+	s.NAssert(s.pkg == nil, "%s() can only be called while parsing a package", builtin)
+	s.NAssert(filepath.IsAbs(filename), "%s(): %s must be relative to the package", builtin, filename)
+	rel := filepath.Clean(filename)
+	s.NAssert(rel == ".." || strings.HasPrefix(rel, "../"), "%s(): %s is outside the package", builtin, filename)
+	s.NAssert(s.pkg.Outputs[rel] != nil, "%s(): %s is generated by a build rule; only source files can be read", builtin, filename)
+	path := filepath.Join(s.pkg.Name, rel)
+	root := core.RepoRoot
+	var fsys iofs.FS = fs.HostFS
+	local := true
+	if subrepo := s.pkg.Subrepo; subrepo != nil && subrepo.Root != "" {
+		root = subrepo.Root
+		fsys = subrepo.FS()
+		local = !subrepo.IsRemoteSubrepo()
+	}
+	if local {
+		// Resolve any symlinks to make sure they don't lead us out of the repo.
+		root, err := filepath.Abs(root)
+		s.Assert(err == nil, "%s(): %s", builtin, err)
+		root, err = filepath.EvalSymlinks(root)
+		s.Assert(err == nil, "%s(): %s", builtin, err)
+		resolved, err := filepath.EvalSymlinks(filepath.Join(root, path))
+		s.Assert(err == nil, "%s(): %s", builtin, err)
+		resolved, err = filepath.Rel(root, resolved)
+		s.Assert(err == nil, "%s(): %s", builtin, err)
+		s.NAssert(resolved == ".." || strings.HasPrefix(resolved, "../"), "%s(): %s is outside the repo", builtin, filename)
+		s.NAssert(resolved == core.OutDir || strings.HasPrefix(resolved, core.OutDir+"/"), "%s(): %s is generated by a build rule; only source files can be read", builtin, filename)
+	}
+	contents, err := iofs.ReadFile(fsys, path)
+	s.Assert(err == nil, "%s(): %s", builtin, err)
+	s.recordFile(path, contents)
+	return contents
+}
+
 func pyStrOrListAsList(s *scope, arg pyObject, name string) []string {
 	if str, ok := arg.(pyString); ok {
 		return []string{str.String()}
@@ -1068,6 +1196,7 @@ func getLabels(s *scope, args []pyObject) pyObject {
 	transitive := args[3].IsTruthy()
 	if core.LooksLikeABuildLabel(name) {
 		label := core.ParseBuildLabel(name, s.pkg.Name)
+		s.markUncacheable("it calls get_labels() on " + label.String())
 		return getLabelsInternal(s.state.Graph.TargetOrDie(label), prefix, core.Built, all, transitive)
 	}
 	target := getTargetPost(s, name)
@@ -1081,6 +1210,7 @@ func addLabel(s *scope, args []pyObject) pyObject {
 	var target *core.BuildTarget
 	if core.LooksLikeABuildLabel(name) {
 		label := core.ParseBuildLabel(name, s.pkg.Name)
+		s.markUncacheable("it calls add_label() on " + label.String())
 		target = s.state.Graph.TargetOrDie(label)
 	} else {
 		target = getTargetPost(s, name)
@@ -1149,6 +1279,9 @@ func addDep(s *scope, args []pyObject) pyObject {
 	dep := s.parseLabelInPackage(string(args[1].(pyString)), s.pkg)
 	exported := args[2].IsTruthy()
 	target.AddMaybeExportedDependency(dep, exported, false, false)
+	if r := s.interpreter.calls; r != nil {
+		target.SetDependencySite(dep, r.Stack(s, "add_dep")[0])
+	}
 	// Queue this dependency if it'll be needed.
 	if target.State() > core.Inactive {
 		err := s.state.QueueTarget(dep, target.Label, false, core.ParseModeNormal)
@@ -1234,6 +1367,7 @@ func getOuts(s *scope, args []pyObject) pyObject {
 	var target *core.BuildTarget
 	if name := args[0].String(); core.LooksLikeABuildLabel(name) {
 		label := core.ParseBuildLabel(name, s.pkg.Name)
+		s.markUncacheable("it looks up the target " + label.String())
 		target = s.state.Graph.TargetOrDie(label)
 	} else {
 		target = getTargetPost(s, name)
@@ -1252,6 +1386,7 @@ func getNamedOuts(s *scope, args []pyObject) pyObject {
 	var target *core.BuildTarget
 	if name := args[0].String(); core.LooksLikeABuildLabel(name) {
 		label := core.ParseBuildLabel(name, s.pkg.Name)
+		s.markUncacheable("it looks up the target " + label.String())
 		target = s.state.Graph.TargetOrDie(label)
 	} else {
 		target = getTargetPost(s, name)
@@ -1289,6 +1424,7 @@ func getEntryPoints(s *scope, args []pyObject) pyObject {
 	var target *core.BuildTarget
 	if name := args[0].String(); core.LooksLikeABuildLabel(name) {
 		label := core.ParseBuildLabel(name, s.pkg.Name)
+		s.markUncacheable("it looks up the target " + label.String())
 		target = s.state.Graph.TargetOrDie(label)
 	} else {
 		target = getTargetPost(s, name)
@@ -1403,6 +1539,7 @@ func subrepo(s *scope, args []pyObject) pyObject {
 	)
 
 	s.NAssert(s.pkg == nil, "Cannot create new subrepos in this scope")
+	s.markUncacheable("it defines a subrepo")
 	name := string(args[NameArgIdx].(pyString))
 	dep := string(args[DepArgIdx].(pyString))
 
@@ -1474,56 +1611,14 @@ func subrepo(s *scope, args []pyObject) pyObject {
 	return pyString("///" + sr.Name)
 }
 
-// breakpoint implements an interactive debugger for the breakpoint() builtin
+// breakpoint implements the breakpoint() builtin, which stops in the debugger.
 func breakpoint(s *scope, args []pyObject) pyObject {
-	if !s.state.EnableBreakpoints {
+	s.markUncacheable("it calls breakpoint()")
+	if s.interpreter.debugger == nil {
 		log.Warningf("Skipping breakpoint. Use --debug to enable breakpoints.")
 		return None
 	}
-	// Take this mutex to ensure only one debugger runs at a time
-	s.interpreter.breakpointMutex.Lock()
-	defer s.interpreter.breakpointMutex.Unlock()
-	fmt.Printf("breakpoint() encountered in %s, entering interactive debugger...\n", s.pkg.Filename)
-	// This is a small hack to get the return value back from an ident statement, which
-	// is normally not available since we don't have implicit returns.
-	interpretStatements := func(stmts []*Statement) (ret pyObject, err error) {
-		if len(stmts) == 1 && stmts[0].Ident != nil {
-			defer func() {
-				if r := recover(); r != nil {
-					err = fmt.Errorf("%s", r)
-				}
-			}()
-			return s.interpretIdentStatement(stmts[0].Ident), nil
-		}
-		return s.interpreter.interpretStatements(s, stmts)
-	}
-	for {
-		prompt := promptui.Prompt{
-			Label: "plz",
-			Validate: func(input string) error {
-				_, err := s.interpreter.parser.ParseData([]byte(input), "<stdin>")
-				return err
-			},
-		}
-		if input, err := prompt.Run(); err != nil {
-			if err == io.EOF || err.Error() == "^D" {
-				break
-			} else if err.Error() != "^C" {
-				log.Error("%s", err)
-			}
-		} else if input == "exit" {
-			break
-		} else if stmts, err := s.interpreter.parser.ParseData([]byte(input), "<stdin>"); err != nil {
-			log.Error("Syntax error: %s", err)
-		} else if ret, err := interpretStatements(stmts); err != nil {
-			log.Error("%s", err)
-		} else if ret != nil && ret != None {
-			fmt.Printf("%s\n", ret)
-		} else {
-			fmt.Printf("\n")
-		}
-	}
-	fmt.Printf("Debugger exited, continuing...\n")
+	s.interpreter.debugger.Breakpoint(s)
 	return None
 }
 
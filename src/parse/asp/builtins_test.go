@@ -112,3 +112,35 @@ func TestObjLen(t *testing.T) {
 	assert.EqualValues(t, 1, objLen(d))
 	assert.EqualValues(t, 1, objLen(d.Freeze()))
 }
+
+func TestReadFile(t *testing.T) {
+	s := &scope{
+		pkg:   core.NewPackage("src/parse/asp/test_data/read_file"),
+		state: core.NewDefaultBuildState(),
+	}
+	assert.EqualValues(t, "1.2.3\n", readFile(s, []pyObject{pyString("version.txt")}))
+	assert.EqualValues(t, pyDict{
+		"name":    pyString("example"),
+		"deps":    pyList{pyString("a"), pyString("b")},
+		"pinned":  True,
+		"retries": pyInt(3),
+		"extra":   None,
+	}, readJSON(s, []pyObject{pyString("manifest.json")}))
+}
+
+func TestReadFileRefusals(t *testing.T) {
+	s := &scope{
+		pkg:   core.NewPackage("src/parse/asp/test_data/read_file"),
+		state: core.NewDefaultBuildState(),
+	}
+	s.pkg.MustRegisterOutput(s.state, "generated.txt", core.NewBuildTarget(core.NewBuildLabel(s.pkg.Name, "gen")))
+	for _, filename := range []string{
+		"/etc/hostname",
+		"../cache/a.txt",
+		"generated.txt",
+		"does_not_exist.txt",
+	} {
+		assert.Panics(t, func() { readFile(s, []pyObject{pyString(filename)}) }, filename)
+	}
+	assert.Panics(t, func() { readJSON(s, []pyObject{pyString("version.txt")}) })
+}
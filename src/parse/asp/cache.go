@@ -0,0 +1,358 @@
+package asp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/thought-machine/please/src/cli"
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/fs"
+)
+
+// parseCacheDir is the directory that we store cached parse results in.
+const parseCacheDir = "plz-out/parse_cache"
+
+// parseCacheVersion is bumped whenever the format of cached packages changes incompatibly.
+const parseCacheVersion = 3
+
+// A parseCache persists the results of interpreting BUILD files between invocations, so that
+// unchanged packages can be reloaded without running the interpreter again.
+type parseCache struct {
+	dir        string
+	state      *core.BuildState
+	hashOnce   sync.Once
+	configHash []byte
+}
+
+// A parseRecord tracks things that happen while interpreting a single package which determine whether
+// we can cache it, and what we need to check again before the cached version can be reused.
+type parseRecord struct {
+	mutex       sync.Mutex
+	uncacheable string
+	globs       []globRecord
+	files       []fileRecord
+}
+
+// A globRecord is a single call to glob() and the files that it returned.
+type globRecord struct {
+	Include, Exclude        []string
+	Hidden, IncludeSymlinks bool
+	Files                   []string
+}
+
+// A fileRecord is a single file read by read_file() or read_json(), and the hash of its contents.
+type fileRecord struct {
+	Path string
+	Hash []byte
+}
+
+// A subincludeRecord is a single subincluded target and the hash of its outputs.
+type subincludeRecord struct {
+	Label core.BuildLabel
+	Hash  []byte
+	// Subincludes are any further targets that this one subincluded.
+	Subincludes []core.BuildLabel
+}
+
+// A cachedPackage is the representation of a package that we store on disk.
+type cachedPackage struct {
+	// Key covers the BUILD file, the config and the version of Please.
+	Key []byte
+	// Subincludes are the direct subincludes of the package.
+	Subincludes []core.BuildLabel
+	// Inputs are all the subincludes that went into interpreting the package, including transitive and preloaded ones.
+	Inputs  []subincludeRecord
+	Globs   []globRecord
+	Files   []fileRecord
+	Targets []*core.TargetRecord
+	// Outputs maps the package's registered output files to the names of the targets that own them.
+	Outputs map[string]string
+}
+
+// newParseCache returns a new parse cache, or nil if caching isn't enabled.
+// It's always disabled when debugging, since we'd never stop in packages loaded from the cache, and
+// when recording provenance since we don't interpret them so can't tell what created their targets.
+func newParseCache(state *core.BuildState) *parseCache {
+	if !state.Config.Parse.Cache || state.EnableBreakpoints || state.RecordProvenance {
+		return nil
+	}
+	return &parseCache{
+		dir:   parseCacheDir,
+		state: state,
+	}
+}
+
+// ShouldCache returns true if the given package is eligible to be cached.
+func (c *parseCache) ShouldCache(pkg *core.Package, mode core.ParseMode) bool {
+	return c != nil && pkg.Subrepo == nil && !mode.IsPreload()
+}
+
+// Key returns the key for the given BUILD file.
+func (c *parseCache) Key(fsys iofs.FS, filename string) ([]byte, error) {
+	c.hashOnce.Do(func() {
+		hash, err := c.state.Config.ParseHash()
+		if err != nil {
+			log.Warning("Failed to hash config, parse results will not be cached: %s", err)
+			return
+		}
+		c.configHash = hash
+	})
+	if c.configHash == nil {
+		return nil, fmt.Errorf("no config hash available")
+	}
+	var contents []byte
+	var err error
+	if fsys == nil {
+		contents, err = os.ReadFile(filename)
+	} else {
+		contents, err = iofs.ReadFile(fsys, filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.New()
+	fmt.Fprintf(h, "%d %s %s %s\n", parseCacheVersion, core.PleaseVersion, cli.HostArch(), filename)
+	h.Write(c.configHash)
+	h.Write(contents)
+	return h.Sum(nil), nil
+}
+
+// Load attempts to load the given package from the cache. It returns true if it succeeds, in which case
+// the package's targets have been added to the graph.
+func (c *parseCache) Load(i *interpreter, pkg *core.Package, key []byte, mode core.ParseMode) bool {
+	entry, err := c.read(pkg)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Debug("Failed to read cached parse of %s: %s", pkg.Label(), err)
+		}
+		return false
+	} else if !bytes.Equal(entry.Key, key) {
+		log.Debug("Cached parse of %s is out of date", pkg.Label())
+		return false
+	}
+	s := i.scope.NewPackagedScope(pkg, mode, 1)
+	for _, input := range entry.Inputs {
+		hash, err := c.hashSubinclude(s, input.Label, true)
+		if err != nil {
+			log.Debug("Not using cached parse of %s: %s", pkg.Label(), err)
+			return false
+		} else if !bytes.Equal(hash, input.Hash) {
+			log.Debug("Not using cached parse of %s: subinclude %s has changed", pkg.Label(), input.Label)
+			return false
+		}
+	}
+	globber := fs.NewGlobber(fs.HostFS, c.state.Config.Parse.BuildFileName)
+	for _, g := range entry.Globs {
+		if !slices.Equal(g.Files, globber.Glob(pkg.Name, g.Include, g.Exclude, g.Hidden, g.IncludeSymlinks)) {
+			log.Debug("Not using cached parse of %s: result of glob(%s) has changed", pkg.Label(), g.Include)
+			return false
+		}
+	}
+	for _, f := range entry.Files {
+		if contents, err := os.ReadFile(f.Path); err != nil {
+			log.Debug("Not using cached parse of %s: %s", pkg.Label(), err)
+			return false
+		} else if hash := sha1.Sum(contents); !bytes.Equal(hash[:], f.Hash) {
+			log.Debug("Not using cached parse of %s: %s has changed", pkg.Label(), f.Path)
+			return false
+		}
+	}
+	targets := make([]*core.BuildTarget, len(entry.Targets))
+	for idx, r := range entry.Targets {
+		t, err := r.Target(pkg.Subrepo)
+		if err != nil {
+			log.Debug("Not using cached parse of %s: %s", pkg.Label(), err)
+			return false
+		}
+		targets[idx] = t
+	}
+	for _, t := range targets {
+		c.state.AddTarget(pkg, t)
+	}
+	for _, input := range entry.Inputs {
+		for _, nested := range input.Subincludes {
+			c.state.Graph.AddNestedSubinclude(input.Label, nested)
+		}
+	}
+	for out, name := range entry.Outputs {
+		if t := pkg.Target(name); t != nil {
+			pkg.Outputs[out] = t
+		}
+	}
+	pkg.Subincludes = entry.Subincludes
+	log.Debug("Loaded %s from parse cache", pkg.Label())
+	return true
+}
+
+// Store stores the given package in the cache, if it's possible to do so.
+func (c *parseCache) Store(i *interpreter, pkg *core.Package, key []byte, record *parseRecord) {
+	if err := c.store(i, pkg, key, record); err != nil {
+		log.Debug("Not caching parse of %s: %s", pkg.Label(), err)
+		// Make sure we don't leave an outdated entry lying around.
+		os.Remove(c.filename(pkg))
+	}
+}
+
+func (c *parseCache) store(i *interpreter, pkg *core.Package, key []byte, record *parseRecord) error {
+	if record.uncacheable != "" {
+		return fmt.Errorf("%s", record.uncacheable)
+	}
+	entry := &cachedPackage{
+		Key:         key,
+		Subincludes: pkg.Subincludes,
+		Globs:       record.globs,
+		Files:       record.files,
+		Outputs:     make(map[string]string, len(pkg.Outputs)),
+	}
+	for _, t := range pkg.AllTargets() {
+		r := core.NewTargetRecord(t)
+		if r.HasBuildFunctions() {
+			return fmt.Errorf("%s has a pre- or post-build function", t.Label)
+		}
+		entry.Targets = append(entry.Targets, r)
+	}
+	for out, t := range pkg.Outputs {
+		entry.Outputs[out] = t.Label.Name
+	}
+	s := i.scope.NewPackagedScope(pkg, core.ParseModeNormal, 1)
+	seen := map[core.BuildLabel]bool{}
+	queue := append(slices.Clone(pkg.Subincludes), c.state.GetPreloadedSubincludes()...)
+	for len(queue) > 0 {
+		label := queue[0]
+		queue = queue[1:]
+		if seen[label] {
+			continue
+		}
+		seen[label] = true
+		if label.PackageName == pkg.Name && label.Subrepo == pkg.SubrepoName {
+			return fmt.Errorf("it subincludes %s which is defined in the same package", label)
+		}
+		hash, err := c.hashSubinclude(s, label, false)
+		if err != nil {
+			return err
+		}
+		nested := c.state.Graph.NestedSubincludes(label)
+		entry.Inputs = append(entry.Inputs, subincludeRecord{Label: label, Hash: hash, Subincludes: nested})
+		queue = append(queue, nested...)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(entry); err != nil {
+		return err
+	}
+	return fs.WriteFile(&buf, c.filename(pkg), 0)
+}
+
+// read reads the cached entry for a package.
+func (c *parseCache) read(pkg *core.Package) (*cachedPackage, error) {
+	f, err := os.Open(c.filename(pkg))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	entry := &cachedPackage{}
+	if err := gob.NewDecoder(f).Decode(entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// filename returns the file that we cache a package's parse results in.
+func (c *parseCache) filename(pkg *core.Package) string {
+	h := sha1.Sum([]byte(pkg.Name))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:]))
+}
+
+// hashSubinclude returns a hash of the outputs of a subincluded target.
+// If wait is true it will wait for the target to be built first, otherwise it must already have been.
+func (c *parseCache) hashSubinclude(s *scope, label core.BuildLabel, wait bool) (hash []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = handleErrors(r)
+		}
+	}()
+	var t *core.BuildTarget
+	if wait {
+		t = s.WaitForSubincludedTarget(label, s.pkg.Label())
+	} else {
+		t = c.state.Graph.Target(label)
+	}
+	if t == nil {
+		return nil, fmt.Errorf("subinclude %s is not available", label)
+	}
+	h := sha1.New()
+	for _, out := range t.FullOutputs() {
+		hash, err := c.state.PathHasher.Hash(out, false, true, false)
+		if err != nil {
+			return nil, err
+		}
+		h.Write(hash)
+	}
+	return h.Sum(nil), nil
+}
+
+// startRecording begins recording the interpretation of a package.
+func (i *interpreter) startRecording(pkg *core.Package) *parseRecord {
+	record := &parseRecord{}
+	i.records.Store(pkg, record)
+	return record
+}
+
+// stopRecording finishes recording the interpretation of a package.
+func (i *interpreter) stopRecording(pkg *core.Package) {
+	i.records.Delete(pkg)
+}
+
+// record returns the record for the package this scope is interpreting, or nil if there isn't one.
+func (s *scope) record() *parseRecord {
+	if s.pkg == nil || s.interpreter == nil {
+		return nil
+	}
+	if r, present := s.interpreter.records.Load(s.pkg); present {
+		return r.(*parseRecord)
+	}
+	return nil
+}
+
+// markUncacheable marks the package this scope is interpreting as not being eligible for caching.
+func (s *scope) markUncacheable(reason string) {
+	if r := s.record(); r != nil {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		if r.uncacheable == "" {
+			r.uncacheable = reason
+		}
+	}
+}
+
+// recordGlob records the result of a glob() call in the package this scope is interpreting.
+func (s *scope) recordGlob(include, exclude []string, hidden, includeSymlinks bool, files []string) {
+	if r := s.record(); r != nil {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.globs = append(r.globs, globRecord{
+			Include:         include,
+			Exclude:         exclude,
+			Hidden:          hidden,
+			IncludeSymlinks: includeSymlinks,
+			Files:           files,
+		})
+	}
+}
+
+// recordFile records a file read by the package this scope is interpreting.
+func (s *scope) recordFile(path string, contents []byte) {
+	if r := s.record(); r != nil {
+		hash := sha1.Sum(contents)
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.files = append(r.files, fileRecord{Path: path, Hash: hash[:]})
+	}
+}
@@ -0,0 +1,111 @@
+package asp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/rules"
+	"github.com/thought-machine/please/src/core"
+)
+
+func newCachingParser(dir string, loadBuiltins bool) *Parser {
+	state := core.NewDefaultBuildState()
+	state.Config.Parse.Cache = true
+	parser := NewParser(state)
+	parser.cache.dir = dir
+	if loadBuiltins {
+		src, err := rules.ReadAsset("builtins.build_defs")
+		if err != nil {
+			panic(err)
+		}
+		parser.MustLoadBuiltins("builtins.build_defs", src)
+	}
+	return parser
+}
+
+func parseCachedPackage(parser *Parser, name string) (*core.Package, error) {
+	pkg := core.NewPackage(name)
+	pkg.Filename = name + "/BUILD_FILE"
+	return pkg, parser.ParseFile(pkg, nil, nil, core.ParseModeNormal, nil, pkg.Filename)
+}
+
+func TestParseCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	pkg, err := parseCachedPackage(newCachingParser(dir, true), "src/parse/asp/test_data/cache")
+	require.NoError(t, err)
+	require.Equal(t, 2, pkg.NumTargets())
+
+	// The second parser doesn't have any builtins loaded, so it can only succeed if it's loaded from the cache.
+	parser := newCachingParser(dir, false)
+	pkg2, err := parseCachedPackage(parser, "src/parse/asp/test_data/cache")
+	require.NoError(t, err)
+	require.Equal(t, 2, pkg2.NumTargets())
+
+	lib := pkg2.Target("lib")
+	require.NotNil(t, lib)
+	assert.Equal(t, []string{"lib.a"}, lib.DeclaredOutputs())
+	assert.Equal(t, []string{"cached"}, lib.Labels)
+	assert.Equal(t, []core.BuildInput{core.FileLabel{File: "a.txt", Package: "src/parse/asp/test_data/cache"}}, lib.Sources)
+	assert.True(t, pkg2.HasOutput("lib.a"))
+	assert.NotNil(t, parser.interpreter.scope.state.Graph.Target(lib.Label))
+
+	test := pkg2.Target("test")
+	require.NotNil(t, test)
+	assert.True(t, test.IsTest())
+	assert.Equal(t, []core.BuildLabel{lib.Label}, test.DeclaredDependencies())
+}
+
+func TestParseCacheKeyChanges(t *testing.T) {
+	dir := t.TempDir()
+	_, err := parseCachedPackage(newCachingParser(dir, true), "src/parse/asp/test_data/cache")
+	require.NoError(t, err)
+
+	parser := newCachingParser(dir, false)
+	parser.interpreter.scope.state.Config.Build.Config = "dbg"
+	pkg := core.NewPackage("src/parse/asp/test_data/cache")
+	key, err := parser.cache.Key(nil, "src/parse/asp/test_data/cache/BUILD_FILE")
+	require.NoError(t, err)
+	assert.False(t, parser.cache.Load(parser.interpreter, pkg, key, core.ParseModeNormal))
+	assert.Equal(t, 0, pkg.NumTargets())
+}
+
+func TestParseCacheUncacheable(t *testing.T) {
+	dir := t.TempDir()
+	parser := newCachingParser(dir, true)
+	pkg, err := parseCachedPackage(parser, "src/parse/asp/test_data/cache/uncacheable")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pkg.NumTargets())
+	_, err = os.Stat(parser.cache.filename(pkg))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseCacheReadFile(t *testing.T) {
+	// Work on a copy of the package so we can change the file it reads without touching the checked-in one.
+	const pkgName = "read_file"
+	tmp := t.TempDir()
+	require.NoError(t, os.CopyFS(filepath.Join(tmp, pkgName), os.DirFS("src/parse/asp/test_data/read_file")))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(tmp))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	dir := t.TempDir()
+	pkg, err := parseCachedPackage(newCachingParser(dir, true), pkgName)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, pkg.Target("version").Labels)
+
+	parser := newCachingParser(dir, false)
+	pkg = core.NewPackage(pkgName)
+	key, err := parser.cache.Key(nil, pkgName+"/BUILD_FILE")
+	require.NoError(t, err)
+	assert.True(t, parser.cache.Load(parser.interpreter, pkg, key, core.ParseModeNormal))
+
+	// Changing the file that was read should invalidate the cached parse.
+	require.NoError(t, os.WriteFile(filepath.Join(pkgName, "version.txt"), []byte("1.2.4\n"), 0644))
+	pkg = core.NewPackage(pkgName)
+	assert.False(t, parser.cache.Load(parser.interpreter, pkg, key, core.ParseModeNormal))
+}
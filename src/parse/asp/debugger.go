@@ -0,0 +1,346 @@
+package asp
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// A stepMode describes how the debugger should resume interpretation after it has stopped.
+type stepMode int
+
+const (
+	// stepContinue runs until the next breakpoint.
+	stepContinue stepMode = iota
+	// stepOver stops at the next statement in the current function, or in its caller once it returns.
+	stepOver
+	// stepInto stops at the very next statement, including those in any functions that are called.
+	stepInto
+	// stepOut stops at the next statement after the current function returns.
+	stepOut
+)
+
+// Reasons that the debugger can stop for.
+const (
+	reasonBreakpoint = "breakpoint"
+	reasonStep       = "step"
+)
+
+// A frame is a single entry in the call stack of the build language.
+type frame struct {
+	name   string
+	parent *frame
+	// thread identifies the stack that this frame belongs to; each package being parsed has its own.
+	thread int
+	depth  int
+	// These are updated as each statement is interpreted.
+	scope *scope
+	pos   Position
+}
+
+// A debugFrontend is the part of the debugger that interacts with the user.
+type debugFrontend interface {
+	// Stop is called when interpretation stops at the given frame.
+	// It blocks until the user chooses to resume, and returns how to do so.
+	Stop(d *debugger, f *frame, reason, description string) stepMode
+}
+
+// A debugger implements breakpoints and stepping through the build language.
+type debugger struct {
+	interpreter *interpreter
+	frontend    debugFrontend
+
+	mutex sync.RWMutex
+	// Breakpoints, keyed by the path they were given as and then by line.
+	breakpoints map[string]map[int]bool
+	// Breakpoints keyed by the name of files we've interpreted. Rebuilt lazily when they change.
+	resolved map[string]map[int]bool
+	// Current stepping state.
+	step       stepMode
+	stepThread int
+	stepDepth  int
+	// The thread that we're currently evaluating user input in, if any.
+	evalThread int
+	threads    int
+	files      map[string]*File
+}
+
+// newDebugger creates a new debugger for the given interpreter, or returns nil if debugging isn't enabled.
+func newDebugger(state *core.BuildState, i *interpreter) *debugger {
+	if !state.EnableBreakpoints {
+		return nil
+	}
+	d := &debugger{
+		interpreter: i,
+		breakpoints: map[string]map[int]bool{},
+		resolved:    map[string]map[int]bool{},
+		files:       map[string]*File{},
+		frontend:    consoleFrontend{},
+	}
+	for _, bp := range state.Breakpoints {
+		filename, line, err := parseBreakpoint(bp)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		d.AddBreakpoint(filename, line)
+	}
+	if state.DebugAdapterAddress != "" {
+		server, err := newDAPServer(d, state.DebugAdapterAddress)
+		if err != nil {
+			log.Fatalf("Failed to start debug adapter: %s", err)
+		}
+		d.frontend = server
+		log.Warning("Waiting for a debugger to attach on %s...", server.Addr())
+		server.WaitUntilConfigured()
+	}
+	return d
+}
+
+// parseBreakpoint parses a breakpoint given as file:line.
+func parseBreakpoint(bp string) (string, int, error) {
+	idx := strings.LastIndexByte(bp, ':')
+	if idx == -1 {
+		return "", 0, fmt.Errorf("invalid breakpoint %s, must be in the form file:line", bp)
+	}
+	line, err := strconv.Atoi(bp[idx+1:])
+	if err != nil || line <= 0 {
+		return "", 0, fmt.Errorf("invalid line number in breakpoint %s", bp)
+	}
+	return bp[:idx], line, nil
+}
+
+// AddBreakpoint adds a breakpoint at the given file & line.
+func (d *debugger) AddBreakpoint(filename string, line int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	filename = filepath.Clean(filename)
+	if d.breakpoints[filename] == nil {
+		d.breakpoints[filename] = map[int]bool{}
+	}
+	d.breakpoints[filename][line] = true
+	clear(d.resolved)
+}
+
+// RemoveBreakpoint removes a breakpoint at the given file & line. It returns true if there was one.
+func (d *debugger) RemoveBreakpoint(filename string, line int) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	filename = filepath.Clean(filename)
+	if !d.breakpoints[filename][line] {
+		return false
+	}
+	delete(d.breakpoints[filename], line)
+	clear(d.resolved)
+	return true
+}
+
+// SetBreakpoints replaces all the breakpoints in the given file.
+func (d *debugger) SetBreakpoints(filename string, lines []int) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	m := make(map[int]bool, len(lines))
+	for _, line := range lines {
+		m[line] = true
+	}
+	d.breakpoints[filepath.Clean(filename)] = m
+	clear(d.resolved)
+}
+
+// ClearBreakpoints removes all breakpoints.
+func (d *debugger) ClearBreakpoints() {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	clear(d.breakpoints)
+	clear(d.resolved)
+}
+
+// Breakpoints returns a list of all the current breakpoints, as file:line.
+func (d *debugger) Breakpoints() []string {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	var ret []string
+	for filename, lines := range d.breakpoints {
+		for line := range lines {
+			ret = append(ret, fmt.Sprintf("%s:%d", filename, line))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// linesFor returns the set of lines that have breakpoints in the given file.
+// Breakpoints can be given as a suffix of the filename, so "go.build_defs:12" matches a subinclude
+// in plz-out/gen wherever it happens to be.
+func (d *debugger) linesFor(filename string) map[int]bool {
+	d.mutex.RLock()
+	lines, present := d.resolved[filename]
+	d.mutex.RUnlock()
+	if present {
+		return lines
+	}
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	lines = map[int]bool{}
+	for bp, bpLines := range d.breakpoints {
+		if filename == bp || strings.HasSuffix(filename, "/"+bp) {
+			for line := range bpLines {
+				lines[line] = true
+			}
+		}
+	}
+	d.resolved[filename] = lines
+	return lines
+}
+
+// newFrame creates a new call frame. If parent is nil, the frame begins a new thread.
+func (d *debugger) newFrame(name string, parent *frame) *frame {
+	if parent == nil {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		d.threads++
+		return &frame{name: name, thread: d.threads, depth: 1}
+	}
+	return &frame{name: name, parent: parent, thread: parent.thread, depth: parent.depth + 1}
+}
+
+// frameFor returns the frame for the given scope, creating one if it doesn't have one yet.
+func (d *debugger) frameFor(s *scope) *frame {
+	if s.frame == nil {
+		s.frame = d.newFrame("<module>", nil)
+		s.frame.scope = s
+	}
+	return s.frame
+}
+
+// Statement is called before each statement is interpreted and stops if the debugger needs to.
+func (d *debugger) Statement(s *scope, stmt *Statement) {
+	f := d.frameFor(s)
+	if d.evaluating(f) {
+		return
+	}
+	f.scope = s
+	f.pos = stmt.Pos
+	if d.stepping(f) {
+		d.stop(f, reasonStep, "")
+	} else if lines := d.linesFor(s.filename); len(lines) > 0 && lines[d.Position(f).Line] {
+		d.stop(f, reasonBreakpoint, "")
+	}
+}
+
+// Breakpoint is called when the breakpoint() builtin is called.
+func (d *debugger) Breakpoint(s *scope) {
+	if f := d.frameFor(s); !d.evaluating(f) {
+		d.stop(f, reasonBreakpoint, "breakpoint() called")
+	}
+}
+
+// evaluating returns true if the given frame is in a thread that's evaluating input from the user.
+// We never stop in that case, since that thread is already stopped.
+func (d *debugger) evaluating(f *frame) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return d.evalThread == f.thread
+}
+
+// stepping returns true if we should stop at this frame because the user is stepping through code.
+func (d *debugger) stepping(f *frame) bool {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	if d.stepThread != f.thread {
+		return false
+	}
+	switch d.step {
+	case stepInto:
+		return true
+	case stepOver:
+		return f.depth <= d.stepDepth
+	case stepOut:
+		return f.depth < d.stepDepth
+	}
+	return false
+}
+
+// stop stops interpretation at the given frame and hands over to the frontend.
+func (d *debugger) stop(f *frame, reason, description string) {
+	// Only one thread can be stopped in the debugger at once.
+	d.interpreter.breakpointMutex.Lock()
+	defer d.interpreter.breakpointMutex.Unlock()
+	mode := d.frontend.Stop(d, f, reason, description)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.step = mode
+	d.stepThread = f.thread
+	d.stepDepth = f.depth
+}
+
+// Position returns the current position of the given frame.
+func (d *debugger) Position(f *frame) FilePosition {
+	return d.file(f.scope.filename).Pos(f.pos)
+}
+
+// file returns a File for the given filename.
+func (d *debugger) file(filename string) *File {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	f, present := d.files[filename]
+	if !present {
+		if contents, present := d.interpreter.parser.builtins[filename]; present {
+			f = NewFile(filename, contents)
+		} else {
+			f = newFile(filename)
+		}
+		d.files[filename] = f
+	}
+	return f
+}
+
+// Stack returns the call stack from the given frame, innermost first.
+func (d *debugger) Stack(f *frame) []*frame {
+	var stack []*frame
+	for ; f != nil; f = f.parent {
+		stack = append(stack, f)
+	}
+	return stack
+}
+
+// Scopes returns the chain of scopes visible from the given frame, innermost first.
+// The interpreter's root scope containing all the builtins is omitted.
+func (d *debugger) Scopes(f *frame) []*scope {
+	var scopes []*scope
+	for s := f.scope; s != nil && s != f.scope.interpreter.scope; s = s.parent {
+		scopes = append(scopes, s)
+	}
+	return scopes
+}
+
+// Evaluate interprets the given input in the scope of a frame and returns the result, if there is one.
+func (d *debugger) Evaluate(f *frame, input string) (pyObject, error) {
+	stmts, err := d.parse(input)
+	if err != nil {
+		return nil, err
+	}
+	d.mutex.Lock()
+	d.evalThread = f.thread
+	d.mutex.Unlock()
+	defer func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		d.evalThread = 0
+	}()
+	return f.scope.interpreter.interpretStatements(f.scope, stmts)
+}
+
+// parse parses some input from the user.
+func (d *debugger) parse(input string) ([]*Statement, error) {
+	// This is a small hack to get the value of an expression back, which is normally not
+	// available since we don't have implicit returns.
+	if stmts, err := d.interpreter.parser.ParseData([]byte("return "+input), "<stdin>"); err == nil {
+		return stmts, nil
+	}
+	return d.interpreter.parser.ParseData([]byte(input), "<stdin>")
+}
@@ -0,0 +1,151 @@
+package asp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/manifoldco/promptui"
+)
+
+// maxValueLength is the longest that we'll print a value when listing variables.
+const maxValueLength = 100
+
+// A consoleFrontend implements an interactive debugger on the terminal.
+type consoleFrontend struct{}
+
+// consoleCommands are the commands the console understands, in addition to interpreting code.
+var consoleCommands = map[string]string{
+	"continue":    "resume until the next breakpoint",
+	"exit":        "synonym for continue",
+	"next":        "step to the next statement, stepping over function calls",
+	"step":        "step to the next statement, stepping into function calls",
+	"finish":      "step out of the current function",
+	"where":       "print the call stack",
+	"scopes":      "print the variables in each enclosing scope",
+	"break":       "add a breakpoint at file:line",
+	"clear":       "remove the breakpoint at file:line",
+	"breakpoints": "list all breakpoints",
+	"help":        "print this message",
+}
+
+func (c consoleFrontend) Stop(d *debugger, f *frame, reason, description string) stepMode {
+	pos := d.Position(f)
+	if description == "" {
+		description = reason
+	}
+	fmt.Printf("Stopped at %s (%s), entering interactive debugger...\n", pos, description)
+	c.printLine(d, pos)
+	for {
+		prompt := promptui.Prompt{
+			Label: "plz",
+			Validate: func(input string) error {
+				if _, present := consoleCommands[strings.Fields(input + " ")[0]]; present {
+					return nil
+				}
+				_, err := d.parse(input)
+				return err
+			},
+		}
+		input, err := prompt.Run()
+		if err != nil {
+			if err == io.EOF || err.Error() == "^D" {
+				break
+			} else if err.Error() != "^C" {
+				log.Error("%s", err)
+			}
+			continue
+		}
+		fields := strings.Fields(input)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "continue", "exit":
+			fmt.Printf("Debugger exited, continuing...\n")
+			return stepContinue
+		case "next":
+			return stepOver
+		case "step":
+			return stepInto
+		case "finish":
+			return stepOut
+		case "where":
+			for _, fr := range d.Stack(f) {
+				fmt.Printf("  %s at %s\n", fr.name, d.Position(fr))
+			}
+		case "scopes":
+			c.printScopes(d, f)
+		case "break", "clear":
+			if len(fields) != 2 {
+				log.Error("Usage: %s file:line", fields[0])
+			} else if filename, line, err := parseBreakpoint(fields[1]); err != nil {
+				log.Error("%s", err)
+			} else if fields[0] == "break" {
+				d.AddBreakpoint(filename, line)
+			} else if !d.RemoveBreakpoint(filename, line) {
+				log.Error("No breakpoint at %s", fields[1])
+			}
+		case "breakpoints":
+			for _, bp := range d.Breakpoints() {
+				fmt.Printf("  %s\n", bp)
+			}
+		case "help":
+			c.printHelp()
+		default:
+			if ret, err := d.Evaluate(f, input); err != nil {
+				log.Error("%s", err)
+			} else if ret != nil && ret != None {
+				fmt.Printf("%s\n", ret)
+			} else {
+				fmt.Printf("\n")
+			}
+		}
+	}
+	fmt.Printf("Debugger exited, continuing...\n")
+	return stepContinue
+}
+
+// printLine prints the source line at the given position, if we can find it.
+func (c consoleFrontend) printLine(d *debugger, pos FilePosition) {
+	if line := d.file(pos.Filename).Line(pos.Line); line != "" {
+		fmt.Printf("%5d  %s\n", pos.Line, line)
+	}
+}
+
+// printScopes prints all the variables that are visible from a frame.
+func (c consoleFrontend) printScopes(d *debugger, f *frame) {
+	for i, s := range d.Scopes(f) {
+		fmt.Printf("Scope %d (%s):\n", i, s.filename)
+		keys := make([]string, 0, len(s.locals))
+		for k := range s.locals {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %s = %s\n", k, truncateValue(s.locals[k]))
+		}
+	}
+}
+
+func (c consoleFrontend) printHelp() {
+	fmt.Printf("Enter any expression or statement to evaluate it in the current scope, or one of these commands:\n")
+	cmds := make([]string, 0, len(consoleCommands))
+	for cmd := range consoleCommands {
+		cmds = append(cmds, cmd)
+	}
+	sort.Strings(cmds)
+	for _, cmd := range cmds {
+		fmt.Printf("  %-12s %s\n", cmd, consoleCommands[cmd])
+	}
+}
+
+// truncateValue returns a string representation of a value, truncated if it's very long.
+func truncateValue(obj pyObject) string {
+	s := obj.String()
+	if len(s) > maxValueLength {
+		return s[:maxValueLength-3] + "..."
+	}
+	return s
+}
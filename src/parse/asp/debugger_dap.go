@@ -0,0 +1,494 @@
+package asp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// A dapServer implements the Debug Adapter Protocol (https://microsoft.github.io/debug-adapter-protocol/)
+// so that editors can attach to the debugger.
+type dapServer struct {
+	d        *debugger
+	listener net.Listener
+	ready    chan struct{}
+	once     sync.Once
+
+	mutex   sync.Mutex
+	conn    io.ReadWriteCloser
+	seq     int
+	stopped *dapStop
+}
+
+// A dapStop represents a thread that is stopped in the debugger.
+type dapStop struct {
+	frames  []*frame
+	resume  chan stepMode
+	handles []pyObject
+}
+
+// A dapRequest is an incoming request from the client.
+type dapRequest struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// A dapResponse is the response we send to a request.
+type dapResponse struct {
+	Seq        int    `json:"seq"`
+	Type       string `json:"type"`
+	RequestSeq int    `json:"request_seq"`
+	Success    bool   `json:"success"`
+	Command    string `json:"command"`
+	Message    string `json:"message,omitempty"`
+	Body       any    `json:"body,omitempty"`
+}
+
+// A dapEvent is an event that we send to the client.
+type dapEvent struct {
+	Seq   int    `json:"seq"`
+	Type  string `json:"type"`
+	Event string `json:"event"`
+	Body  any    `json:"body,omitempty"`
+}
+
+type dapSource struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+type dapStackFrame struct {
+	ID     int       `json:"id"`
+	Name   string    `json:"name"`
+	Source dapSource `json:"source"`
+	Line   int       `json:"line"`
+	Column int       `json:"column"`
+}
+
+type dapScope struct {
+	Name               string `json:"name"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type dapVariable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type dapBreakpoint struct {
+	Verified bool `json:"verified"`
+	Line     int  `json:"line"`
+}
+
+type dapThread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// newDAPServer starts a new server listening on the given address.
+func newDAPServer(d *debugger, address string) (*dapServer, error) {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	s := &dapServer{
+		d:        d,
+		listener: l,
+		ready:    make(chan struct{}),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address that the server is listening on.
+func (s *dapServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// WaitUntilConfigured blocks until a client has attached and finished setting breakpoints.
+func (s *dapServer) WaitUntilConfigured() {
+	<-s.ready
+}
+
+// accept accepts connections from clients, one at a time.
+func (s *dapServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			log.Error("Failed to accept debug adapter connection: %s", err)
+			return
+		}
+		s.Serve(conn)
+	}
+}
+
+// Serve serves a single client connection until it disconnects.
+func (s *dapServer) Serve(conn io.ReadWriteCloser) {
+	s.mutex.Lock()
+	s.conn = conn
+	s.mutex.Unlock()
+	defer s.disconnect()
+	r := bufio.NewReader(conn)
+	for {
+		req, err := readDAPMessage(r)
+		if err != nil {
+			if err != io.EOF {
+				log.Error("Failed to read debug adapter message: %s", err)
+			}
+			return
+		} else if req.Type != "request" {
+			continue
+		}
+		body, err := s.dispatch(req)
+		resp := &dapResponse{
+			Type:       "response",
+			RequestSeq: req.Seq,
+			Success:    err == nil,
+			Command:    req.Command,
+			Body:       body,
+		}
+		if err != nil {
+			resp.Message = err.Error()
+		}
+		s.send(resp)
+		switch req.Command {
+		case "initialize":
+			s.send(&dapEvent{Type: "event", Event: "initialized"})
+		case "disconnect":
+			return
+		}
+	}
+}
+
+// disconnect handles the client disconnecting; we remove all breakpoints and resume.
+func (s *dapServer) disconnect() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.d.ClearBreakpoints()
+	if s.stopped != nil {
+		s.stopped.resume <- stepContinue
+		s.stopped = nil
+	}
+	s.once.Do(func() { close(s.ready) })
+}
+
+// dispatch handles a single request and returns the body of the response.
+func (s *dapServer) dispatch(req *dapRequest) (any, error) {
+	switch req.Command {
+	case "initialize":
+		return map[string]bool{
+			"supportsConfigurationDoneRequest": true,
+			"supportsEvaluateForHovers":        true,
+		}, nil
+	case "launch", "attach", "disconnect", "setExceptionBreakpoints":
+		return nil, nil
+	case "configurationDone":
+		s.once.Do(func() { close(s.ready) })
+		return nil, nil
+	case "setBreakpoints":
+		return s.setBreakpoints(req)
+	case "threads":
+		return s.threads(), nil
+	case "stackTrace":
+		return s.stackTrace()
+	case "scopes":
+		return s.scopes(req)
+	case "variables":
+		return s.variables(req)
+	case "evaluate":
+		return s.evaluate(req)
+	case "continue":
+		return map[string]bool{"allThreadsContinued": false}, s.resume(stepContinue)
+	case "next":
+		return nil, s.resume(stepOver)
+	case "stepIn":
+		return nil, s.resume(stepInto)
+	case "stepOut":
+		return nil, s.resume(stepOut)
+	}
+	return nil, fmt.Errorf("unsupported request %s", req.Command)
+}
+
+func (s *dapServer) setBreakpoints(req *dapRequest) (any, error) {
+	var args struct {
+		Source      dapSource `json:"source"`
+		Breakpoints []struct {
+			Line int `json:"line"`
+		} `json:"breakpoints"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	path := args.Source.Path
+	if rel, err := filepath.Rel(core.RepoRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	lines := make([]int, len(args.Breakpoints))
+	bps := make([]dapBreakpoint, len(args.Breakpoints))
+	for i, bp := range args.Breakpoints {
+		lines[i] = bp.Line
+		bps[i] = dapBreakpoint{Verified: true, Line: bp.Line}
+	}
+	s.d.SetBreakpoints(path, lines)
+	return map[string][]dapBreakpoint{"breakpoints": bps}, nil
+}
+
+func (s *dapServer) threads() any {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// We only report the stopped thread; there are potentially many more but they are
+	// short-lived and can't be inspected while they're running.
+	thread := dapThread{ID: 1, Name: "parse"}
+	if s.stopped != nil {
+		f := s.stopped.frames[len(s.stopped.frames)-1]
+		thread = dapThread{ID: f.thread, Name: f.scope.filename}
+	}
+	return map[string][]dapThread{"threads": {thread}}
+}
+
+func (s *dapServer) stackTrace() (any, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped == nil {
+		return nil, fmt.Errorf("not stopped")
+	}
+	frames := make([]dapStackFrame, len(s.stopped.frames))
+	for i, f := range s.stopped.frames {
+		pos := s.d.Position(f)
+		frames[i] = dapStackFrame{
+			ID:     i + 1,
+			Name:   f.name,
+			Source: dapSource{Name: filepath.Base(pos.Filename), Path: filepath.Join(core.RepoRoot, pos.Filename)},
+			Line:   pos.Line,
+			Column: pos.Column,
+		}
+	}
+	return map[string]any{"stackFrames": frames, "totalFrames": len(frames)}, nil
+}
+
+func (s *dapServer) scopes(req *dapRequest) (any, error) {
+	var args struct {
+		FrameID int `json:"frameId"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	var scopes []dapScope
+	for i, sc := range s.d.Scopes(f) {
+		name := "Locals"
+		if i > 0 {
+			name = fmt.Sprintf("Enclosing (%s)", sc.filename)
+		}
+		scopes = append(scopes, dapScope{Name: name, VariablesReference: s.handle(sc.locals)})
+	}
+	return map[string][]dapScope{"scopes": scopes}, nil
+}
+
+func (s *dapServer) variables(req *dapRequest) (any, error) {
+	var args struct {
+		VariablesReference int `json:"variablesReference"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped == nil || args.VariablesReference <= 0 || args.VariablesReference > len(s.stopped.handles) {
+		return nil, fmt.Errorf("unknown variables reference %d", args.VariablesReference)
+	}
+	vars := []dapVariable{}
+	switch obj := s.stopped.handles[args.VariablesReference-1].(type) {
+	case pyDict:
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			vars = append(vars, s.variable(k, obj[k]))
+		}
+	case pyList:
+		for i, v := range obj {
+			vars = append(vars, s.variable(strconv.Itoa(i), v))
+		}
+	}
+	return map[string][]dapVariable{"variables": vars}, nil
+}
+
+func (s *dapServer) evaluate(req *dapRequest) (any, error) {
+	var args struct {
+		Expression string `json:"expression"`
+		FrameID    int    `json:"frameId"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		return nil, err
+	}
+	s.mutex.Lock()
+	f, err := s.frame(args.FrameID)
+	s.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ret, err := s.d.Evaluate(f, args.Expression)
+	if err != nil {
+		return nil, err
+	} else if ret == nil {
+		ret = None
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	v := s.variable("", ret)
+	return map[string]any{"result": v.Value, "type": v.Type, "variablesReference": v.VariablesReference}, nil
+}
+
+// resume resumes the stopped thread.
+func (s *dapServer) resume(mode stepMode) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.stopped == nil {
+		return fmt.Errorf("not stopped")
+	}
+	s.stopped.resume <- mode
+	s.stopped = nil
+	return nil
+}
+
+// frame returns the frame with the given id in the current stop. The mutex must be held.
+// If the id is zero it returns the innermost frame.
+func (s *dapServer) frame(id int) (*frame, error) {
+	if s.stopped == nil {
+		return nil, fmt.Errorf("not stopped")
+	} else if id == 0 {
+		return s.stopped.frames[0], nil
+	} else if id < 0 || id > len(s.stopped.frames) {
+		return nil, fmt.Errorf("unknown frame %d", id)
+	}
+	return s.stopped.frames[id-1], nil
+}
+
+// variable returns a dapVariable for a value, registering a handle for it if it has children.
+// The mutex must be held.
+func (s *dapServer) variable(name string, obj pyObject) dapVariable {
+	v := dapVariable{Name: name, Value: truncateValue(obj), Type: obj.Type()}
+	switch o := obj.(type) {
+	case pyDict:
+		if len(o) > 0 {
+			v.VariablesReference = s.handle(o)
+		}
+	case pyFrozenDict:
+		if len(o.pyDict) > 0 {
+			v.VariablesReference = s.handle(o.pyDict)
+		}
+	case pyList:
+		if len(o) > 0 {
+			v.VariablesReference = s.handle(o)
+		}
+	case pyFrozenList:
+		if len(o.pyList) > 0 {
+			v.VariablesReference = s.handle(o.pyList)
+		}
+	}
+	return v
+}
+
+// handle registers a handle for an object so the client can request its children. The mutex must be held.
+func (s *dapServer) handle(obj pyObject) int {
+	s.stopped.handles = append(s.stopped.handles, obj)
+	return len(s.stopped.handles)
+}
+
+func (s *dapServer) Stop(d *debugger, f *frame, reason, description string) stepMode {
+	s.mutex.Lock()
+	if s.conn == nil {
+		s.mutex.Unlock()
+		return stepContinue // Nobody is attached to stop for.
+	}
+	stop := &dapStop{
+		frames: d.Stack(f),
+		resume: make(chan stepMode, 1),
+	}
+	s.stopped = stop
+	s.mutex.Unlock()
+	s.send(&dapEvent{
+		Type:  "event",
+		Event: "stopped",
+		Body: map[string]any{
+			"reason":      reason,
+			"description": description,
+			"threadId":    f.thread,
+		},
+	})
+	return <-stop.resume
+}
+
+// send sends a message to the client.
+func (s *dapServer) send(msg any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.conn == nil {
+		return
+	}
+	s.seq++
+	switch m := msg.(type) {
+	case *dapResponse:
+		m.Seq = s.seq
+	case *dapEvent:
+		m.Seq = s.seq
+	}
+	if err := writeDAPMessage(s.conn, msg); err != nil {
+		log.Error("Failed to write debug adapter message: %s", err)
+	}
+}
+
+// readDAPMessage reads a single message from the given reader.
+func readDAPMessage(r *bufio.Reader) (*dapRequest, error) {
+	headers, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %w", err)
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	req := &dapRequest{}
+	return req, json.Unmarshal(buf, req)
+}
+
+// writeDAPMessage writes a single message to the given writer.
+func writeDAPMessage(w io.Writer, msg any) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
@@ -0,0 +1,220 @@
+package asp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+const debugFile = "src/parse/asp/test_data/debugger/debug.build"
+
+// A fakeFrontend records where the debugger stops and resumes with a predetermined sequence of steps.
+type fakeFrontend struct {
+	steps  []stepMode
+	stops  []string
+	stacks [][]string
+	evals  []string
+	eval   string
+}
+
+func (f *fakeFrontend) Stop(d *debugger, fr *frame, reason, description string) stepMode {
+	pos := d.Position(fr)
+	f.stops = append(f.stops, fmt.Sprintf("%d %s", pos.Line, reason))
+	var stack []string
+	for _, fr := range d.Stack(fr) {
+		stack = append(stack, fr.name)
+	}
+	f.stacks = append(f.stacks, stack)
+	if f.eval != "" {
+		ret, err := d.Evaluate(fr, f.eval)
+		if err != nil {
+			f.evals = append(f.evals, err.Error())
+		} else {
+			f.evals = append(f.evals, ret.String())
+		}
+	}
+	step := f.steps[0]
+	f.steps = f.steps[1:]
+	return step
+}
+
+func newDebugParser(breakpoints ...string) *Parser {
+	state := core.NewDefaultBuildState()
+	state.EnableBreakpoints = true
+	state.Breakpoints = breakpoints
+	p := NewParser(state)
+	p.EnableDebugger(state)
+	return p
+}
+
+func parseDebugFile(t *testing.T, parser *Parser) *scope {
+	t.Helper()
+	statements, err := parser.parse(nil, debugFile)
+	require.NoError(t, err)
+	pkg := core.NewPackage("test/package")
+	pkg.Filename = debugFile
+	s, err := parser.interpreter.interpretAll(pkg, nil, nil, 0, statements)
+	require.NoError(t, err)
+	return s
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	parser := newDebugParser("debugger/debug.build:6")
+	frontend := &fakeFrontend{steps: []stepMode{stepInto, stepOver, stepOut, stepContinue}}
+	parser.interpreter.debugger.frontend = frontend
+	s := parseDebugFile(t, parser)
+	assert.Equal(t, []string{"6 breakpoint", "2 step", "3 step", "7 step"}, frontend.stops)
+	assert.Equal(t, [][]string{
+		{"<module>"},
+		{"add", "<module>"},
+		{"add", "<module>"},
+		{"<module>"},
+	}, frontend.stacks)
+	assert.EqualValues(t, 4, s.Lookup("z"))
+}
+
+func TestDebuggerStepOverFunction(t *testing.T) {
+	parser := newDebugParser("debugger/debug.build:5")
+	frontend := &fakeFrontend{steps: []stepMode{stepOver, stepOver, stepContinue}}
+	parser.interpreter.debugger.frontend = frontend
+	parseDebugFile(t, parser)
+	assert.Equal(t, []string{"5 breakpoint", "6 step", "7 step"}, frontend.stops)
+}
+
+func TestDebuggerEvaluate(t *testing.T) {
+	parser := newDebugParser("debugger/debug.build:3")
+	frontend := &fakeFrontend{steps: []stepMode{stepContinue}, eval: "c * 10"}
+	parser.interpreter.debugger.frontend = frontend
+	parseDebugFile(t, parser)
+	assert.Equal(t, []string{"3 breakpoint"}, frontend.stops)
+	assert.Equal(t, []string{"30"}, frontend.evals)
+}
+
+func TestDebuggerNoBreakpoints(t *testing.T) {
+	parser := newDebugParser()
+	frontend := &fakeFrontend{}
+	parser.interpreter.debugger.frontend = frontend
+	s := parseDebugFile(t, parser)
+	assert.Empty(t, frontend.stops)
+	assert.EqualValues(t, 4, s.Lookup("z"))
+}
+
+func TestDebuggerOnlyForMainParser(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	state.EnableBreakpoints = true
+	// Parsers that haven't enabled it (e.g. ones only used to read BUILD files) don't get a debugger.
+	assert.Nil(t, NewParser(state).interpreter.debugger)
+	// Interpreters created for subrepos share the main parser's one.
+	parser := NewParser(state)
+	parser.EnableDebugger(state)
+	assert.NotNil(t, parser.interpreter.debugger)
+	assert.Equal(t, parser.interpreter.debugger, newInterpreter(state, parser).debugger)
+}
+
+func TestParseBreakpoint(t *testing.T) {
+	filename, line, err := parseBreakpoint("src/BUILD:12")
+	assert.NoError(t, err)
+	assert.Equal(t, "src/BUILD", filename)
+	assert.Equal(t, 12, line)
+	_, _, err = parseBreakpoint("src/BUILD")
+	assert.Error(t, err)
+	_, _, err = parseBreakpoint("src/BUILD:wibble")
+	assert.Error(t, err)
+}
+
+// A dapClient is a minimal client for testing the debug adapter.
+type dapClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+func (c *dapClient) Request(command string, args any) {
+	c.seq++
+	b, err := json.Marshal(args)
+	require.NoError(c.t, err)
+	require.NoError(c.t, writeDAPMessage(c.conn, &dapRequest{Seq: c.seq, Type: "request", Command: command, Arguments: b}))
+}
+
+// Read reads the next message from the server.
+func (c *dapClient) Read() map[string]any {
+	var length int
+	_, err := fmt.Fscanf(c.r, "Content-Length: %d\r\n\r\n", &length)
+	require.NoError(c.t, err)
+	buf := make([]byte, length)
+	_, err = io.ReadFull(c.r, buf)
+	require.NoError(c.t, err)
+	msg := map[string]any{}
+	require.NoError(c.t, json.Unmarshal(buf, &msg))
+	return msg
+}
+
+func TestDebugAdapter(t *testing.T) {
+	parser := newDebugParser()
+	server := &dapServer{d: parser.interpreter.debugger, ready: make(chan struct{})}
+	parser.interpreter.debugger.frontend = server
+	serverConn, clientConn := net.Pipe()
+	go server.Serve(serverConn)
+	c := &dapClient{t: t, conn: clientConn, r: bufio.NewReader(clientConn)}
+	read := c.Read
+
+	c.Request("initialize", map[string]string{"adapterID": "plz"})
+	assert.Equal(t, true, read()["success"])
+	assert.Equal(t, "initialized", read()["event"])
+	c.Request("setBreakpoints", map[string]any{
+		"source":      map[string]string{"path": debugFile},
+		"breakpoints": []map[string]int{{"line": 3}},
+	})
+	assert.Equal(t, true, read()["success"])
+	c.Request("configurationDone", nil)
+	assert.Equal(t, true, read()["success"])
+	server.WaitUntilConfigured()
+
+	done := make(chan *scope)
+	go func() {
+		done <- parseDebugFile(t, parser)
+	}()
+	stopped := read()
+	assert.Equal(t, "stopped", stopped["event"])
+	assert.Equal(t, "breakpoint", stopped["body"].(map[string]any)["reason"])
+
+	c.Request("stackTrace", map[string]int{"threadId": 1})
+	frames := read()["body"].(map[string]any)["stackFrames"].([]any)
+	require.Equal(t, 2, len(frames))
+	assert.Equal(t, "add", frames[0].(map[string]any)["name"])
+	assert.EqualValues(t, 3, frames[0].(map[string]any)["line"])
+	assert.Equal(t, "<module>", frames[1].(map[string]any)["name"])
+	assert.EqualValues(t, 6, frames[1].(map[string]any)["line"])
+
+	c.Request("scopes", map[string]int{"frameId": 1})
+	scopes := read()["body"].(map[string]any)["scopes"].([]any)
+	require.True(t, len(scopes) > 0)
+	ref := scopes[0].(map[string]any)["variablesReference"]
+	c.Request("variables", map[string]any{"variablesReference": ref})
+	vars := read()["body"].(map[string]any)["variables"].([]any)
+	values := map[string]any{}
+	for _, v := range vars {
+		values[v.(map[string]any)["name"].(string)] = v.(map[string]any)["value"]
+	}
+	assert.Equal(t, map[string]any{"a": "1", "b": "2", "c": "3", "CONFIG": values["CONFIG"]}, values)
+
+	c.Request("evaluate", map[string]any{"expression": "a + c", "frameId": 1})
+	assert.Equal(t, "4", read()["body"].(map[string]any)["result"])
+
+	c.Request("continue", map[string]int{"threadId": 1})
+	assert.Equal(t, true, read()["success"])
+	s := <-done
+	assert.EqualValues(t, 4, s.Lookup("z"))
+
+	c.Request("disconnect", nil)
+	assert.Equal(t, true, read()["success"])
+}
@@ -54,6 +54,7 @@ func init() {
 //
 // NOTE: Commands that rely on the current working directory must not be cached.
 func doExec(s *scope, cmdIn pyObject, cacheOutput bool, storeNegative bool) (pyObj pyObject, success bool, err error) {
+	s.markUncacheable("it runs external commands")
 	var argv []string
 	if isType(cmdIn, "str") {
 		argv = strings.Fields(string(cmdIn.(pyString)))
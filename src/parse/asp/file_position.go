@@ -23,6 +23,7 @@ func (pos FilePosition) String() string {
 // A File represents a file being parsed and is useful for converting raw Positions to FilePositions.
 type File struct {
 	Name        string
+	contents    []byte
 	lineOffsets []int
 }
 
@@ -31,7 +32,7 @@ func NewFile(name string, buf []byte) *File {
 	// N.B. The line offsets are the index of the preceding newline character.
 	// This happens to be convenient when we binary search it (to avoid falling off the front
 	// of the array, etc).
-	f := &File{Name: name, lineOffsets: []int{-1}}
+	f := &File{Name: name, contents: buf, lineOffsets: []int{-1}}
 	for i, x := range buf {
 		if x == '\n' {
 			f.lineOffsets = append(f.lineOffsets, i)
@@ -59,3 +60,16 @@ func (f *File) Pos(pos Position) FilePosition {
 		Column:   i - lineOffset,
 	}
 }
+
+// Line returns the contents of the given line (1-indexed) of this file, or the empty string if we don't have it.
+func (f *File) Line(line int) string {
+	if line <= 0 || line > len(f.lineOffsets) || f.contents == nil {
+		return ""
+	}
+	start := f.lineOffsets[line-1] + 1
+	end := len(f.contents)
+	if line < len(f.lineOffsets) {
+		end = f.lineOffsets[line]
+	}
+	return string(f.contents[start:end])
+}
@@ -0,0 +1,525 @@
+package asp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// A formatSpec is a parsed version of Python's format specification mini-language, i.e.
+// the bit after the colon in "{x:>10}". See
+// https://docs.python.org/3/library/string.html#format-specification-mini-language
+type formatSpec struct {
+	Fill      rune
+	Align     byte // One of '<', '>', '^', '=' or zero if unspecified
+	Sign      byte // One of '+', '-', ' ' or zero if unspecified
+	Alternate bool // '#': adds 0x / 0o / 0b prefixes
+	Width     int
+	Grouping  byte // ',' or '_' or zero if unspecified
+	Precision int  // -1 if unspecified
+	Type      byte // The presentation type, or zero if unspecified
+	// minDigits pads integers with zeroes to at least this many digits; it's only used for
+	// %-style formatting where the precision has that meaning.
+	minDigits int
+}
+
+// parseFormatSpec parses a format specification.
+func parseFormatSpec(spec string) (formatSpec, error) {
+	f := formatSpec{Fill: ' ', Precision: -1}
+	isAlign := func(b byte) bool { return b == '<' || b == '>' || b == '^' || b == '=' }
+	// A fill character can only be given along with an alignment.
+	if r, size := utf8.DecodeRuneInString(spec); size > 0 && size < len(spec) && isAlign(spec[size]) {
+		f.Fill = r
+		f.Align = spec[size]
+		spec = spec[size+1:]
+	} else if len(spec) > 0 && isAlign(spec[0]) {
+		f.Align = spec[0]
+		spec = spec[1:]
+	}
+	if len(spec) > 0 && (spec[0] == '+' || spec[0] == '-' || spec[0] == ' ') {
+		f.Sign = spec[0]
+		spec = spec[1:]
+	}
+	if len(spec) > 0 && spec[0] == '#' {
+		f.Alternate = true
+		spec = spec[1:]
+	}
+	if len(spec) > 0 && spec[0] == '0' {
+		if f.Align == 0 {
+			f.Fill = '0'
+			f.Align = '='
+		}
+		spec = spec[1:]
+	}
+	if f.Width, spec = parseFormatNumber(spec); f.Width == -1 {
+		f.Width = 0
+	}
+	if len(spec) > 0 && (spec[0] == ',' || spec[0] == '_') {
+		f.Grouping = spec[0]
+		spec = spec[1:]
+	}
+	if len(spec) > 0 && spec[0] == '.' {
+		if f.Precision, spec = parseFormatNumber(spec[1:]); f.Precision == -1 {
+			return f, fmt.Errorf("Format specifier missing precision")
+		}
+	}
+	if len(spec) > 0 {
+		f.Type = spec[0]
+		spec = spec[1:]
+		if !strings.ContainsRune("sbcdoxXneEfFgG%", rune(f.Type)) {
+			return f, fmt.Errorf("Unknown format code '%c'", f.Type)
+		}
+	}
+	if spec != "" {
+		return f, fmt.Errorf("Invalid format specifier")
+	}
+	if f.Precision != -1 && strings.ContainsRune("bcdoxXn", rune(f.Type)) {
+		return f, fmt.Errorf("Precision not allowed in integer format specifier")
+	}
+	return f, nil
+}
+
+// parseFormatNumber parses a leading decimal number from the given string, returning -1 if there isn't one.
+func parseFormatNumber(s string) (int, string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	if i == 0 {
+		return -1, s
+	}
+	n, _ := strconv.Atoi(s[:i])
+	return n, s[i:]
+}
+
+// formatValue formats a single value according to the given conversion (i.e. 'r' or 's', or zero for none)
+// and format specification.
+func formatValue(obj pyObject, conversion byte, spec string) (string, error) {
+	f, err := parseFormatSpec(spec)
+	if err != nil {
+		return "", err
+	}
+	switch conversion {
+	case 0:
+	case 's':
+		obj = pyString(obj.String())
+	case 'r':
+		obj = pyString(repr(obj))
+	default:
+		return "", fmt.Errorf("Unknown conversion specifier %c", conversion)
+	}
+	return f.Format(obj)
+}
+
+// Format formats a value according to this spec.
+func (f formatSpec) Format(obj pyObject) (string, error) {
+	switch o := obj.(type) {
+	case pyInt:
+		return f.formatInt(int64(o))
+	case pyBool:
+		// Like Python, bools only format as strings if there's no spec at all.
+		if f == (formatSpec{Fill: ' ', Precision: -1}) {
+			return o.String(), nil
+		} else if o {
+			return f.formatInt(1)
+		}
+		return f.formatInt(0)
+	case pyString:
+		if f.Type == 'c' && o.Len() == 1 {
+			return f.pad(string(o), false), nil
+		} else if f.Type != 0 && f.Type != 's' {
+			return "", fmt.Errorf("Unknown format code '%c' for object of type str", f.Type)
+		}
+		return f.formatString(string(o))
+	}
+	if f.Type != 0 && f.Type != 's' {
+		return "", fmt.Errorf("Unknown format code '%c' for object of type %s", f.Type, obj.Type())
+	}
+	return f.formatString(obj.String())
+}
+
+func (f formatSpec) formatString(s string) (string, error) {
+	if f.Sign != 0 {
+		return "", fmt.Errorf("Sign not allowed in string format specifier")
+	} else if f.Align == '=' {
+		return "", fmt.Errorf("'=' alignment not allowed in string format specifier")
+	} else if f.Grouping != 0 {
+		return "", fmt.Errorf("Cannot specify '%c' with 's'", f.Grouping)
+	}
+	if f.Precision >= 0 && f.Precision < utf8.RuneCountInString(s) {
+		s = string([]rune(s)[:f.Precision])
+	}
+	return f.pad(s, false), nil
+}
+
+func (f formatSpec) formatInt(i int64) (string, error) {
+	if strings.IndexByte("eEfFgG%", f.Type) != -1 {
+		return f.formatFloat(float64(i))
+	} else if f.Type == 'c' {
+		if i < 0 || i > utf8.MaxRune {
+			return "", fmt.Errorf("%%c arg not in range(0x110000)")
+		}
+		return f.pad(string(rune(i)), false), nil
+	}
+	neg := i < 0
+	if neg {
+		i = -i
+	}
+	var digits, prefix string
+	switch f.Type {
+	case 'b':
+		digits, prefix = strconv.FormatInt(i, 2), "0b"
+	case 'o':
+		digits, prefix = strconv.FormatInt(i, 8), "0o"
+	case 'x':
+		digits, prefix = strconv.FormatInt(i, 16), "0x"
+	case 'X':
+		digits, prefix = strings.ToUpper(strconv.FormatInt(i, 16)), "0X"
+	case 0, 'd', 'n':
+		digits = strconv.FormatInt(i, 10)
+	default:
+		return "", fmt.Errorf("Unknown format code '%c' for object of type int", f.Type)
+	}
+	if len(digits) < f.minDigits {
+		digits = strings.Repeat("0", f.minDigits-len(digits)) + digits
+	}
+	if f.Grouping == ',' && f.groupSize() != 3 {
+		return "", fmt.Errorf("Cannot specify ',' with '%c'", f.Type)
+	} else if f.Grouping != 0 {
+		digits = groupDigits(digits, f.groupSize(), f.Grouping)
+	}
+	if !f.Alternate {
+		prefix = ""
+	}
+	return f.pad(f.sign(neg)+prefix+digits, true), nil
+}
+
+func (f formatSpec) formatFloat(x float64) (string, error) {
+	neg := x < 0
+	if neg {
+		x = -x
+	}
+	precision := f.Precision
+	if precision == -1 {
+		precision = 6
+	}
+	var s string
+	switch f.Type {
+	case 'e', 'f':
+		s = strconv.FormatFloat(x, f.Type, precision, 64)
+	case 'E', 'F':
+		s = strings.ToUpper(strconv.FormatFloat(x, f.Type+'a'-'A', precision, 64))
+	case 'g', 'G':
+		if precision == 0 {
+			precision = 1
+		}
+		s = strconv.FormatFloat(x, 'g', precision, 64)
+		if f.Type == 'G' {
+			s = strings.ToUpper(s)
+		}
+	case '%':
+		s = strconv.FormatFloat(x*100, 'f', precision, 64)
+	}
+	if f.Alternate && !strings.ContainsAny(s, ".eE") {
+		s += "."
+	}
+	if f.Grouping != 0 {
+		intPart, rest, found := strings.Cut(s, ".")
+		s = groupDigits(intPart, 3, f.Grouping)
+		if found {
+			s += "." + rest
+		}
+	}
+	if f.Type == '%' {
+		s += "%"
+	}
+	return f.pad(f.sign(neg)+s, true), nil
+}
+
+// groupSize returns the number of digits between each grouping separator.
+func (f formatSpec) groupSize() int {
+	if f.Type == 'b' || f.Type == 'o' || f.Type == 'x' || f.Type == 'X' {
+		return 4
+	}
+	return 3
+}
+
+// sign returns the sign to apply to a number.
+func (f formatSpec) sign(neg bool) string {
+	if neg {
+		return "-"
+	} else if f.Sign == '+' {
+		return "+"
+	} else if f.Sign == ' ' {
+		return " "
+	}
+	return ""
+}
+
+// pad pads the given string out to the specified width.
+func (f formatSpec) pad(s string, numeric bool) string {
+	n := f.Width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	fill := func(n int) string { return strings.Repeat(string(f.Fill), n) }
+	align := f.Align
+	if align == 0 {
+		if align = '<'; numeric {
+			align = '>'
+		}
+	}
+	switch align {
+	case '>':
+		return fill(n) + s
+	case '^':
+		return fill(n/2) + s + fill(n-n/2)
+	case '=':
+		// Padding goes after the sign and any prefix.
+		i := 0
+		if i < len(s) && (s[i] == '-' || s[i] == '+' || s[i] == ' ') {
+			i++
+		}
+		if i+1 < len(s) && s[i] == '0' && strings.IndexByte("bBoOxX", s[i+1]) != -1 {
+			i += 2
+		}
+		padded := s[:i] + fill(n) + s[i:]
+		if f.Grouping != 0 && f.Fill == '0' {
+			// Python carries the separators on through any zero padding.
+			digits := strings.ReplaceAll(padded[i:], string(f.Grouping), "")
+			grouped := groupDigits(digits, f.groupSize(), f.Grouping)
+			for len(grouped) > f.Width-i && len(grouped) > 0 && (grouped[0] == '0' || grouped[0] == f.Grouping) {
+				grouped = grouped[1:]
+			}
+			if len(grouped) > 0 && grouped[0] == f.Grouping {
+				grouped = "0" + grouped
+			}
+			padded = s[:i] + grouped
+		}
+		return padded
+	}
+	return s + fill(n)
+}
+
+// groupDigits inserts a separator between every n digits of the given string.
+func groupDigits(digits string, every int, sep byte) string {
+	var b strings.Builder
+	for i, c := range digits {
+		if i > 0 && (len(digits)-i)%every == 0 {
+			b.WriteByte(sep)
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// repr returns a representation of an object similar to Python's repr().
+func repr(obj pyObject) string {
+	switch o := obj.(type) {
+	case pyString:
+		return quoteString(string(o))
+	case pyList:
+		parts := make([]string, len(o))
+		for i, x := range o {
+			parts[i] = repr(x)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case pyDict:
+		parts := make([]string, 0, len(o))
+		for _, k := range o.Keys() {
+			parts = append(parts, quoteString(k)+": "+repr(o[k]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return obj.String()
+}
+
+// quoteString quotes a string the way Python does, preferring single quotes.
+func quoteString(s string) string {
+	quote := byte('\'')
+	if strings.ContainsRune(s, '\'') && !strings.ContainsRune(s, '"') {
+		quote = '"'
+	}
+	var b strings.Builder
+	b.WriteByte(quote)
+	for _, r := range s {
+		switch {
+		case r == rune(quote) || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < ' ' || r == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte(quote)
+	return b.String()
+}
+
+// percentFormat implements printf-style formatting using the % operator, e.g. "%-5s %03d" % ["a", 1].
+// The operand may be a single value, a list of values or a dict for named "%(key)s" specifiers.
+func percentFormat(format string, operand pyObject) (string, error) {
+	args := []pyObject{operand}
+	if l, ok := operand.(pyList); ok {
+		args = l
+	}
+	dict, _ := operand.(pyDict)
+	usedDict := false
+	var b strings.Builder
+	b.Grow(len(format))
+	arg := 0
+	for {
+		idx := strings.IndexByte(format, '%')
+		if idx == -1 {
+			b.WriteString(format)
+			break
+		}
+		b.WriteString(format[:idx])
+		format = format[idx+1:]
+		if format == "" {
+			return "", fmt.Errorf("incomplete format")
+		}
+		var value pyObject
+		if format[0] == '(' {
+			end := strings.IndexByte(format, ')')
+			if end == -1 {
+				return "", fmt.Errorf("incomplete format key")
+			} else if dict == nil {
+				return "", fmt.Errorf("format requires a mapping")
+			}
+			key := format[1:end]
+			v, present := dict[key]
+			if !present {
+				return "", fmt.Errorf("unknown key %s in format", key)
+			}
+			value = v
+			usedDict = true
+			format = format[end+1:]
+		}
+		f := formatSpec{Fill: ' ', Precision: -1}
+	flags:
+		for ; format != ""; format = format[1:] {
+			switch format[0] {
+			case '-':
+				f.Align = '<'
+			case '+':
+				f.Sign = '+'
+			case ' ':
+				if f.Sign == 0 {
+					f.Sign = ' '
+				}
+			case '#':
+				f.Alternate = true
+			case '0':
+				if f.Align == 0 {
+					f.Fill = '0'
+					f.Align = '='
+				}
+			default:
+				break flags
+			}
+		}
+		if f.Align == '<' {
+			f.Fill = ' '
+		} else if f.Align == 0 {
+			f.Align = '>' // Unlike format specs, everything is right-aligned by default.
+		}
+		if f.Width, format = parseFormatNumber(format); f.Width == -1 {
+			f.Width = 0
+		}
+		if format != "" && format[0] == '.' {
+			if f.Precision, format = parseFormatNumber(format[1:]); f.Precision == -1 {
+				f.Precision = 0
+			}
+		}
+		if format == "" {
+			return "", fmt.Errorf("incomplete format")
+		}
+		f.Type = format[0]
+		format = format[1:]
+		if f.Type == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if value == nil {
+			if arg >= len(args) {
+				return "", fmt.Errorf("not enough arguments for format string")
+			}
+			value = args[arg]
+			arg++
+		}
+		s, err := f.percent(value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+	if arg < len(args) && !usedDict {
+		return "", fmt.Errorf("not all arguments converted during string formatting")
+	}
+	return b.String(), nil
+}
+
+// percent formats a single value for a %-style conversion.
+func (f formatSpec) percent(value pyObject) (string, error) {
+	isInt := func() (int64, error) {
+		switch v := value.(type) {
+		case pyInt:
+			return int64(v), nil
+		case pyBool:
+			if v {
+				return 1, nil
+			}
+			return 0, nil
+		}
+		return 0, fmt.Errorf("%%%c format: a number is required, not %s", f.Type, value.Type())
+	}
+	if strings.IndexByte("srac", f.Type) != -1 && f.Align == '=' {
+		f.Align = '>' // Zero padding doesn't apply to strings.
+		f.Fill = ' '
+	}
+	switch f.Type {
+	case 's':
+		return f.formatString(value.String())
+	case 'r', 'a':
+		return f.formatString(repr(value))
+	case 'c':
+		if s, ok := value.(pyString); ok {
+			if s.Len() != 1 {
+				return "", fmt.Errorf("%%c requires int or char")
+			}
+			return f.pad(string(s), false), nil
+		}
+		i, err := isInt()
+		if err != nil {
+			return "", err
+		}
+		return f.formatInt(i)
+	case 'd', 'i', 'u':
+		f.Type = 'd'
+		fallthrough
+	case 'o', 'x', 'X':
+		i, err := isInt()
+		if err != nil {
+			return "", err
+		}
+		f.minDigits = f.Precision
+		f.Precision = -1
+		return f.formatInt(i)
+	case 'e', 'E', 'f', 'F', 'g', 'G':
+		i, err := isInt()
+		if err != nil {
+			return "", err
+		}
+		return f.formatFloat(float64(i))
+	}
+	return "", fmt.Errorf("unsupported format character '%c'", f.Type)
+}
@@ -0,0 +1,129 @@
+package asp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatValue(t *testing.T) {
+	for _, tc := range []struct {
+		obj      pyObject
+		spec     string
+		expected string
+	}{
+		{pyString("hello"), "", "hello"},
+		{pyString("hello"), ">10", "     hello"},
+		{pyString("hello"), "<10", "hello     "},
+		{pyString("hello"), "^11", "   hello   "},
+		{pyString("hello"), "*^9", "**hello**"},
+		{pyString("hello"), ".3", "hel"},
+		{pyString("hello"), "-<8.2", "he------"},
+		{pyInt(42), "", "42"},
+		{pyInt(42), "08x", "0000002a"},
+		{pyInt(255), "#x", "0xff"},
+		{pyInt(255), "#010X", "0X000000FF"},
+		{pyInt(5), "b", "101"},
+		{pyInt(5), "#b", "0b101"},
+		{pyInt(8), "o", "10"},
+		{pyInt(1234567), ",", "1,234,567"},
+		{pyInt(1234567), "_", "1_234_567"},
+		{pyInt(255), "_b", "1111_1111"},
+		{pyInt(-42), "05d", "-0042"},
+		{pyInt(42), "+d", "+42"},
+		{pyInt(42), " d", " 42"},
+		{pyInt(-42), "=+8", "-     42"},
+		{pyInt(42), ">6", "    42"},
+		{pyInt(42), "<6", "42    "},
+		{pyInt(1234), "010,", "00,001,234"},
+		{pyInt(1234), "08,", "0,001,234"},
+		{pyInt(3), ".2f", "3.00"},
+		{pyInt(1234567), ".3e", "1.235e+06"},
+		{pyInt(1234567), "g", "1.23457e+06"},
+		{pyInt(1), "%", "100.000000%"},
+		{pyInt(65), "c", "A"},
+		{pyInt(1234567), ",.2f", "1,234,567.00"},
+		{True, "", "True"},
+		{True, "d", "1"},
+		{False, ">6", "     0"},
+	} {
+		t.Run(tc.spec, func(t *testing.T) {
+			s, err := formatValue(tc.obj, 0, tc.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, s)
+		})
+	}
+}
+
+func TestFormatValueConversion(t *testing.T) {
+	s, err := formatValue(pyString("it's"), 'r', ">8")
+	assert.NoError(t, err)
+	assert.Equal(t, `  "it's"`, s)
+	s, err = formatValue(pyList{pyString("a"), pyInt(1)}, 'r', "")
+	assert.NoError(t, err)
+	assert.Equal(t, "['a', 1]", s)
+	s, err = formatValue(pyInt(42), 's', ">4")
+	assert.NoError(t, err)
+	assert.Equal(t, "  42", s)
+}
+
+func TestFormatValueErrors(t *testing.T) {
+	for _, tc := range []struct {
+		obj  pyObject
+		spec string
+	}{
+		{pyString("x"), "d"},
+		{pyString("x"), "+"},
+		{pyInt(1), "s"},
+		{pyInt(1), ".2d"},
+		{pyInt(1), ",x"},
+		{pyInt(1), "10q"},
+		{pyInt(1), "."},
+	} {
+		_, err := formatValue(tc.obj, 0, tc.spec)
+		assert.Error(t, err, tc.spec)
+	}
+}
+
+func TestPercentFormat(t *testing.T) {
+	for _, tc := range []struct {
+		format   string
+		operand  pyObject
+		expected string
+	}{
+		{"%s", pyString("x"), "x"},
+		{"%d", pyInt(4), "4"},
+		{"%5s|%-5s|", pyList{pyString("a"), pyString("b")}, "    a|b    |"},
+		{"%03d", pyInt(7), "007"},
+		{"%x %X %o", pyList{pyInt(255), pyInt(255), pyInt(8)}, "ff FF 10"},
+		{"%#x", pyInt(255), "0xff"},
+		{"%+d", pyInt(3), "+3"},
+		{"%.3d", pyInt(5), "005"},
+		{"%r", pyString("it"), "'it'"},
+		{"%.2f", pyInt(3), "3.00"},
+		{"100%%", pyList{}, "100%"},
+		{"%(a)s-%(b)03d", pyDict{"a": pyString("x"), "b": pyInt(7)}, "x-007"},
+		{"%c%c", pyList{pyString("h"), pyInt(105)}, "hi"},
+		{"%s", True, "True"},
+		{"%e", pyInt(12345), "1.234500e+04"},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			s, err := percentFormat(tc.format, tc.operand)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, s)
+		})
+	}
+}
+
+func TestPercentFormatErrors(t *testing.T) {
+	_, err := percentFormat("%s %s", pyString("x"))
+	assert.Error(t, err)
+	_, err = percentFormat("%s", pyList{pyString("x"), pyString("y")})
+	assert.Error(t, err)
+	_, err = percentFormat("%d", pyString("x"))
+	assert.Error(t, err)
+	_, err = percentFormat("%(a)s", pyString("x"))
+	assert.Error(t, err)
+	_, err = percentFormat("100%", pyList{})
+	assert.Error(t, err)
+}
@@ -149,8 +149,9 @@ type ValueExpression struct {
 }
 
 // An FString represents a minimal version of a Python literal format string.
-// Note that we only support a very small subset of what Python allows there; essentially only
-// variable substitution, which gives a much simpler AST structure here.
+// Note that we only support a small subset of what Python allows there; essentially only
+// variable substitution (with optional conversions and format specs), which gives a much
+// simpler AST structure here.
 type FString struct {
 	Vars   []FStringVar
 	Suffix string // Following string bit
@@ -158,8 +159,10 @@ type FString struct {
 
 // An FStringVar represents a single variable in an FString.
 type FStringVar struct {
-	Prefix string   // Preceding string bit
-	Var    []string // Variable name to interpolate, plus any accessors
+	Prefix     string   // Preceding string bit
+	Var        []string // Variable name to interpolate, plus any accessors
+	Conversion byte     // Conversion to apply (i.e. 's' or 'r' for !s or !r), if any
+	Spec       string   // Format spec (the part after the colon), if any
 }
 
 // An IdentStatement implements a statement that begins with an identifier (i.e. anything that
@@ -717,7 +717,18 @@ func (p *parser) parseFString() *FString {
 		tok.Pos += Position(idx + 1)
 		idx = strings.IndexByte(s, '}')
 		p.assert(idx != -1, tok, "Unterminated brace in fstring")
-		v.Var = strings.Split(s[:idx], ".")
+		name, spec, hasSpec := strings.Cut(s[:idx], ":")
+		if hasSpec {
+			_, err := parseFormatSpec(spec)
+			p.assert(err == nil, tok, "Invalid format spec in fstring: %s", err)
+			v.Spec = spec
+		}
+		name, conversion, hasConversion := strings.Cut(name, "!")
+		if hasConversion {
+			p.assert(conversion == "s" || conversion == "r", tok, "Invalid conversion in fstring: !%s", conversion)
+			v.Conversion = conversion[0]
+		}
+		v.Var = strings.Split(name, ".")
 		f.Vars = append(f.Vars, v)
 		s = s[idx+1:]
 		tok.Pos += Position(idx + 1)
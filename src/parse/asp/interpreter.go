@@ -27,6 +27,13 @@ type interpreter struct {
 
 	breakpointMutex sync.Mutex
 	limiter         semaphore
+	// The debugger, which is nil unless debugging is enabled.
+	debugger *debugger
+	// Records the call stacks that create targets. Nil unless we're recording provenance.
+	calls *callRecorder
+
+	// Records of packages currently being interpreted, used for the parse cache.
+	records sync.Map
 
 	stringMethods, dictMethods, configMethods map[string]*pyFunc
 }
@@ -48,12 +55,14 @@ func newInterpreter(state *core.BuildState, p *Parser) *interpreter {
 	if p.interpreter != nil {
 		i.subincludes = p.interpreter.subincludes
 		i.asts = p.interpreter.asts
+		i.debugger = p.interpreter.debugger
 	} else {
 		i.subincludes = cmap.NewErrMap[string, pyDict](cmap.SmallShardCount, cmap.XXHash, i.limiter)
 		i.asts = cmap.NewErrMap[string, []*Statement](cmap.SmallShardCount, cmap.XXHash, i.limiter)
 	}
 	s.interpreter = i
 	s.LoadSingletons(state)
+	i.calls = newCallRecorder(state, p)
 	return i
 }
 
@@ -157,6 +166,9 @@ func (i *interpreter) preloadSubinclude(s *scope, label core.BuildLabel) (err er
 func (i *interpreter) interpretAll(pkg *core.Package, forLabel, dependent *core.BuildLabel, mode core.ParseMode, statements []*Statement) (*scope, error) {
 	s := i.scope.NewPackagedScope(pkg, mode, 1)
 	s.config = i.getConfig(s.state).Copy()
+	if i.debugger != nil {
+		i.debugger.frameFor(s)
+	}
 
 	// Config needs a little separate tweaking.
 	// Annoyingly we'd like to not have to do this at all, but it's very hard to handle
@@ -222,6 +234,10 @@ func (i *interpreter) Subinclude(pkgScope *scope, path string, label core.BuildL
 		s.config = i.scope.config.Copy()
 		s.Set("CONFIG", s.config)
 		s.subincludeLabel = &label
+		if i.debugger != nil {
+			s.frame = i.debugger.newFrame("<module>", i.debugger.frameFor(pkgScope))
+			s.frame.scope = s
+		}
 
 		if !mode.IsPreload() {
 			if err := i.preloadSubincludes(s); err != nil {
@@ -292,6 +308,12 @@ type scope struct {
 	locals          pyDict
 	config          *pyConfig
 	globber         *fs.Globber
+	// The call frame this scope is part of. Only used when debugging.
+	frame *frame
+	// The call that this scope is part of, and the extent of the statement currently being
+	// interpreted in it. Only used when recording provenance.
+	call     *callSite
+	pos, end Position
 	// True if this scope is for a pre- or post-build callback.
 	Callback bool
 	mode     core.ParseMode
@@ -389,7 +411,11 @@ func (s *scope) subincludePackage() *core.Package {
 
 // NewScope creates a new child scope of this one.
 func (s *scope) NewScope(filename string, mode core.ParseMode) *scope {
-	return s.newScope(s.pkg, mode, filename, 0)
+	s2 := s.newScope(s.pkg, mode, filename, 0)
+	s2.frame = s.frame
+	s2.call = s.call
+	s2.pos, s2.end = s.pos, s.end
+	return s2
 }
 
 // NewPackagedScope creates a new child scope of this one pointing to the given package.
@@ -509,6 +535,12 @@ func (s *scope) interpretStatements(statements []*Statement) pyObject {
 		}
 	}()
 	for _, stmt = range statements {
+		if s.interpreter.debugger != nil {
+			s.interpreter.debugger.Statement(s, stmt)
+		}
+		if s.interpreter.calls != nil {
+			s.pos, s.end = stmt.Pos, stmt.EndPos
+		}
 		if stmt.FuncDef != nil {
 			s.Set(stmt.FuncDef.Name, newPyFunc(s, stmt.FuncDef))
 		} else if stmt.If != nil {
@@ -809,8 +841,7 @@ func (s *scope) interpretFString(f *FString) pyObject {
 		for _, key := range v.Var[1:] {
 			obj = s.property(obj, key)
 		}
-
-		return obj.String()
+		return formatField(s, obj, v.Conversion, v.Spec)
 	}
 	var b strings.Builder
 	size := len(f.Suffix)
@@ -120,6 +120,39 @@ func TestInterpreterBuiltins(t *testing.T) {
 	assert.NotNil(t, s.pkg.Target("lib"))
 }
 
+func TestInterpreterTestShards(t *testing.T) {
+	s, err := parseFile("src/parse/asp/test_data/interpreter/test_shards.build")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, s.pkg.Target("sharded_test").Test.Shards)
+	assert.Equal(t, 4, s.pkg.Target("sharded_test").NumTestShards())
+	assert.EqualValues(t, 0, s.pkg.Target("unsharded_test").Test.Shards)
+	assert.Equal(t, 1, s.pkg.Target("unsharded_test").NumTestShards())
+}
+
+func init() {
+	// The real formats are registered by the test package, which we don't depend on here.
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "tap"})
+}
+
+func TestInterpreterTestResultsFormat(t *testing.T) {
+	s, err := parseFile("src/parse/asp/test_data/interpreter/test_results_format.build")
+	require.NoError(t, err)
+	assert.Equal(t, "tap", s.pkg.Target("tap_test").Test.ResultsFormat)
+	assert.Equal(t, "", s.pkg.Target("detected_test").Test.ResultsFormat)
+}
+
+func TestInterpreterUnknownTestResultsFormat(t *testing.T) {
+	_, err := parseFile("src/parse/asp/test_data/interpreter/unknown_test_results_format.build")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unknown test results format tapp")
+}
+
+func TestInterpreterUnknownTestResource(t *testing.T) {
+	_, err := parseFile("src/parse/asp/test_data/interpreter/test_resources.build")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unknown test resource postgres")
+}
+
 func TestInterpreterConfig(t *testing.T) {
 	s, err := parseFile("src/parse/asp/test_data/interpreter/config.build")
 	require.NoError(t, err)
@@ -462,6 +495,14 @@ func TestFormat(t *testing.T) {
 	assert.EqualValues(t, `ARCH="linux_amd64"`, s.Lookup("arch2"))
 }
 
+func TestInterpreterFormatSpec(t *testing.T) {
+	s, err := parseFile("src/parse/asp/test_data/interpreter/format_spec.build")
+	require.NoError(t, err)
+	assert.EqualValues(t, "   plz|007|'plz'", s.Lookup("a"))
+	assert.EqualValues(t, "ab   |0xff|   'x'   ", s.Lookup("b"))
+	assert.EqualValues(t, "ab  |  3.0%|    z", s.Lookup("c"))
+}
+
 func TestAny(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
 		s, err := parseFile("src/parse/asp/test_data/interpreter/any.build")
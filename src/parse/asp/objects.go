@@ -292,23 +292,11 @@ func (s pyString) Operator(operator Operator, operand pyObject) pyObject {
 	case GreaterThanOrEqual:
 		return newPyBool(s >= s2)
 	case Modulo:
-		if ok {
-			// Special case: "%s" % "x"
-			return pyString(fmt.Sprintf(string(s), s2))
-		} else if i, ok := operand.(pyInt); ok {
-			// Another one: "%d" % 4
-			return pyString(fmt.Sprintf(string(s), i))
+		str, err := percentFormat(string(s), operand)
+		if err != nil {
+			panic(err)
 		}
-		l, ok := operand.(pyList)
-		if !ok {
-			panic("Argument to string interpolation must be a string or list; was " + operand.Type())
-		}
-		// Classic issue: can't use a []pyObject as a []interface{} :(
-		l2 := make([]interface{}, len(l))
-		for i, v := range l {
-			l2[i] = v
-		}
-		return pyString(fmt.Sprintf(string(s), l2...))
+		return pyString(str)
 	case In:
 		return newPyBool(strings.Contains(string(s), string(s2)))
 	case NotIn:
@@ -695,6 +683,12 @@ func (f *pyFunc) Call(s *scope, c *Call) pyObject {
 	s2.Set("CONFIG", s.config) // This needs to be copied across too :(
 	s2.Callback = s.Callback
 	s2.parsingFor = s.parsingFor
+	if d := s2.interpreter.debugger; d != nil {
+		s2.frame = d.newFrame(f.name, d.frameFor(s))
+	}
+	if r := s2.interpreter.calls; r != nil {
+		s2.call = r.Call(s, f.name)
+	}
 	// Handle implicit 'self' parameter for bound functions.
 	args := c.Arguments
 	if f.self != nil {
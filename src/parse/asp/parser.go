@@ -31,6 +31,9 @@ type Parser struct {
 
 	// Parallelism limiter to ensure we don't try to run too many parses simultaneously
 	limiter semaphore
+
+	// Persistent cache of parse results. Is nil if caching is disabled.
+	cache *parseCache
 }
 
 // NewParser creates a new parser instance. One is normally sufficient for a process lifetime.
@@ -38,9 +41,17 @@ func NewParser(state *core.BuildState) *Parser {
 	p := newParser()
 	p.interpreter = newInterpreter(state, p)
 	p.limiter = p.interpreter.limiter
+	p.cache = newParseCache(state)
 	return p
 }
 
+// EnableDebugger starts the build language debugger for this parser if the given state enables it.
+// It should only be called for the main parser; the debug adapter can only listen once per process, and
+// other parsers that are only used to read BUILD files have no need for one.
+func (p *Parser) EnableDebugger(state *core.BuildState) {
+	p.interpreter.debugger = newDebugger(state, p.interpreter)
+}
+
 // newParser creates just the parser with no interpreter.
 func newParser() *Parser {
 	return &Parser{
@@ -77,6 +88,21 @@ func (p *Parser) ParseFile(pkg *core.Package, label, dependent *core.BuildLabel,
 	p.limiter.Acquire()
 	defer p.limiter.Release()
 
+	var key []byte
+	var record *parseRecord
+	if p.cache.ShouldCache(pkg, mode) {
+		k, err := p.cache.Key(fs, filename)
+		if err != nil {
+			log.Debug("Not using parse cache for %s: %s", filename, err)
+		} else if p.cache.Load(p.interpreter, pkg, k, mode) {
+			return nil
+		} else {
+			key = k
+			record = p.interpreter.startRecording(pkg)
+			defer p.interpreter.stopRecording(pkg)
+		}
+	}
+
 	statements, err := p.parse(fs, filename)
 	if err != nil {
 		return err
@@ -85,6 +111,8 @@ func (p *Parser) ParseFile(pkg *core.Package, label, dependent *core.BuildLabel,
 	if err != nil {
 		f, _ := p.open(fs, filename)
 		p.annotate(err, f)
+	} else if record != nil {
+		p.cache.Store(p.interpreter, pkg, key, record)
 	}
 	return err
 }
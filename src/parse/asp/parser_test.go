@@ -831,3 +831,25 @@ func TestFStringIncompleteError(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "Unterminated brace in fstring")
 }
+
+func TestFStringFormatSpec(t *testing.T) {
+	stmts, err := newParser().parseAndHandleErrors(strings.NewReader("s = f'{x.y!r:>10} {z:08x}'"))
+	require.NoError(t, err)
+	fs := stmts[0].Ident.Action.Assign.Val.FString
+	require.Equal(t, 2, len(fs.Vars))
+	assert.Equal(t, []string{"x", "y"}, fs.Vars[0].Var)
+	assert.Equal(t, byte('r'), fs.Vars[0].Conversion)
+	assert.Equal(t, ">10", fs.Vars[0].Spec)
+	assert.Equal(t, []string{"z"}, fs.Vars[1].Var)
+	assert.Equal(t, byte(0), fs.Vars[1].Conversion)
+	assert.Equal(t, "08x", fs.Vars[1].Spec)
+}
+
+func TestFStringFormatSpecError(t *testing.T) {
+	_, err := newParser().parseAndHandleErrors(strings.NewReader("s = f'{x:q}'"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid format spec in fstring")
+	_, err = newParser().parseAndHandleErrors(strings.NewReader("s = f'{x!q}'"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid conversion in fstring")
+}
@@ -0,0 +1,110 @@
+package asp
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// A callSite is a single call to a function in the build language. They're chained together through
+// their parents to form the call stack.
+type callSite struct {
+	function string
+	filename string
+	pos, end Position // The extent of the statement that made the call
+	parent   *callSite
+}
+
+// A callRecorder records the call stacks that lead to targets being created.
+// It's nil unless BuildState.RecordProvenance is set, since tracking calls costs a little while parsing.
+type callRecorder struct {
+	parser *Parser
+	mutex  sync.Mutex
+	files  map[string]*File
+}
+
+// newCallRecorder creates a new callRecorder, or returns nil if we aren't recording provenance.
+func newCallRecorder(state *core.BuildState, p *Parser) *callRecorder {
+	if !state.RecordProvenance {
+		return nil
+	}
+	return &callRecorder{
+		parser: p,
+		files:  map[string]*File{},
+	}
+}
+
+// Call returns a new call site for a call to the given function from the current statement in the given scope.
+func (r *callRecorder) Call(s *scope, function string) *callSite {
+	return &callSite{
+		function: function,
+		filename: s.filename,
+		pos:      s.pos,
+		end:      s.end,
+		parent:   s.call,
+	}
+}
+
+// Stack returns the call stack for a call to the given function from the given scope, innermost first.
+func (r *callRecorder) Stack(s *scope, function string) []core.CallSite {
+	var stack []core.CallSite
+	for call := r.Call(s, function); call != nil; call = call.parent {
+		stack = append(stack, r.site(call, call.pos))
+	}
+	return stack
+}
+
+// stringLiteralRegex matches string literals in the build language. It doesn't need to handle escapes since we
+// only use it to find build labels.
+var stringLiteralRegex = regexp.MustCompile(`"[^"\n]*"|'[^'\n]*'`)
+
+// DependencySite returns where the given dependency of the target being created by a call to build_rule from the
+// given scope was declared. That's the string literal naming it in the outermost call that has one (so normally
+// the entry in deps in the BUILD file), or failing that (e.g. if it's computed by a macro) the call to build_rule.
+func (r *callRecorder) DependencySite(s *scope, dep core.BuildLabel) core.CallSite {
+	innermost := r.Call(s, "build_rule")
+	var calls []*callSite
+	for call := innermost; call != nil; call = call.parent {
+		calls = append(calls, call)
+	}
+	for i := len(calls) - 1; i >= 0; i-- {
+		call := calls[i]
+		f := r.file(call.filename)
+		if call.end <= call.pos || int(call.end) > len(f.contents) {
+			continue
+		}
+		for _, loc := range stringLiteralRegex.FindAllIndex(f.contents[call.pos:call.end], -1) {
+			str := string(f.contents[int(call.pos)+loc[0]+1 : int(call.pos)+loc[1]-1])
+			if label, err := core.TryParseBuildLabel(str, s.pkg.Name, s.pkg.SubrepoName); err == nil && label == dep {
+				return r.site(call, call.pos+Position(loc[0]))
+			}
+		}
+	}
+	return r.site(innermost, innermost.pos)
+}
+
+// site returns a core.CallSite for the given call at the given position in its file.
+func (r *callRecorder) site(call *callSite, pos Position) core.CallSite {
+	return core.CallSite{
+		Function: call.function,
+		Filename: call.filename,
+		Line:     r.file(call.filename).Pos(pos).Line,
+	}
+}
+
+// file returns a File for the given filename.
+func (r *callRecorder) file(filename string) *File {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	f, present := r.files[filename]
+	if !present {
+		if contents, present := r.parser.builtins[filename]; present {
+			f = NewFile(filename, contents)
+		} else {
+			f = newFile(filename)
+		}
+		r.files[filename] = f
+	}
+	return f
+}
@@ -0,0 +1,70 @@
+package asp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/rules"
+	"github.com/thought-machine/please/src/core"
+)
+
+const provenanceFilename = "src/parse/asp/test_data/interpreter/provenance.build"
+
+func TestRecordCallStacks(t *testing.T) {
+	s := parseWithProvenance(t)
+
+	assert.Equal(t, []core.CallSite{
+		{Function: "build_rule", Filename: provenanceFilename, Line: 2},
+		{Function: "_compile", Filename: provenanceFilename, Line: 8},
+		{Function: "my_library", Filename: provenanceFilename, Line: 15},
+	}, s.pkg.Target("_lib#lib").CallStack)
+	assert.Equal(t, []core.CallSite{
+		{Function: "build_rule", Filename: provenanceFilename, Line: 9},
+		{Function: "my_library", Filename: provenanceFilename, Line: 15},
+	}, s.pkg.Target("lib").CallStack)
+	assert.Equal(t, []core.CallSite{
+		{Function: "build_rule", Filename: provenanceFilename, Line: 19},
+	}, s.pkg.Target("direct").CallStack)
+}
+
+func TestRecordDependencySites(t *testing.T) {
+	s := parseWithProvenance(t)
+	pkg := s.pkg
+	usesLib := pkg.Target("uses_lib")
+	// These are found where they're written in the BUILD file, even though they're passed through a macro.
+	site, ok := usesLib.DependencySite(core.NewBuildLabel(pkg.Name, "direct"))
+	assert.True(t, ok)
+	assert.Equal(t, core.CallSite{Function: "with_deps", Filename: provenanceFilename, Line: 34}, site)
+	site, ok = usesLib.DependencySite(core.NewBuildLabel(pkg.Name, "lib"))
+	assert.True(t, ok)
+	assert.Equal(t, core.CallSite{Function: "with_deps", Filename: provenanceFilename, Line: 35}, site)
+	// This one is computed by the macro, so the best we can do is the call that added it.
+	site, ok = pkg.Target("lib").DependencySite(core.NewBuildLabel(pkg.Name, "_lib#lib"))
+	assert.True(t, ok)
+	assert.Equal(t, core.CallSite{Function: "build_rule", Filename: provenanceFilename, Line: 9}, site)
+}
+
+func TestNoCallStacksByDefault(t *testing.T) {
+	s, err := parseFile(provenanceFilename)
+	require.NoError(t, err)
+	assert.Nil(t, s.pkg.Target("lib").CallStack)
+}
+
+// parseWithProvenance parses the provenance test file while recording provenance.
+func parseWithProvenance(t *testing.T) *scope {
+	state := core.NewDefaultBuildState()
+	state.RecordProvenance = true
+	parser := NewParser(state)
+	src, err := rules.ReadAsset("builtins.build_defs")
+	require.NoError(t, err)
+	parser.MustLoadBuiltins("builtins.build_defs", src)
+	statements, err := parser.parse(nil, provenanceFilename)
+	require.NoError(t, err)
+	pkg := core.NewPackage("test/package")
+	pkg.Filename = provenanceFilename
+	s, err := parser.interpreter.interpretAll(pkg, nil, nil, 0, statements)
+	require.NoError(t, err)
+	return s
+}
@@ -0,0 +1,77 @@
+package asp
+
+// DefinedNames returns the names that are defined at the top level of a file, i.e. those that
+// would be visible to anything that subincludes it.
+func DefinedNames(statements []*Statement) []string {
+	var names []string
+	for _, stmt := range statements {
+		switch {
+		case stmt.FuncDef != nil:
+			names = append(names, stmt.FuncDef.Name)
+		case stmt.For != nil:
+			names = append(names, stmt.For.Names...)
+			names = append(names, DefinedNames(stmt.For.Statements)...)
+		case stmt.If != nil:
+			names = append(names, DefinedNames(stmt.If.Statements)...)
+			for _, elif := range stmt.If.Elif {
+				names = append(names, DefinedNames(elif.Statements)...)
+			}
+			names = append(names, DefinedNames(stmt.If.ElseStatements)...)
+		case stmt.Ident != nil:
+			if stmt.Ident.Unpack != nil {
+				names = append(names, stmt.Ident.Name)
+				names = append(names, stmt.Ident.Unpack.Names...)
+			} else if stmt.Ident.Action != nil && (stmt.Ident.Action.Assign != nil || stmt.Ident.Action.AugAssign != nil) {
+				names = append(names, stmt.Ident.Name)
+			}
+		}
+	}
+	return names
+}
+
+// ReferencedNames returns the set of names of variables and functions that are referenced anywhere
+// in the given statements. Names that are only ever assigned to are not included.
+func ReferencedNames(statements []*Statement) map[string]bool {
+	names := map[string]bool{}
+	WalkAST(statements, func(stmt *IdentStatement) bool {
+		if stmt.Unpack == nil && (stmt.Action == nil || stmt.Action.Assign == nil) {
+			names[stmt.Name] = true
+		}
+		return true
+	})
+	WalkAST(statements, func(expr *IdentExpr) bool {
+		names[expr.Name] = true
+		return true
+	})
+	WalkAST(statements, func(v *FStringVar) bool {
+		names[v.Var[0]] = true
+		return false
+	})
+	return names
+}
+
+// SubincludeCalls returns the labels that are passed as string literals to subinclude() calls
+// in the given statements.
+func SubincludeCalls(statements []*Statement) []string {
+	var labels []string
+	add := func(expr *Expression) {
+		if expr.Val != nil && expr.Val.String != "" && len(expr.Op) == 0 {
+			labels = append(labels, stringLiteral(expr.Val.String))
+		}
+	}
+	WalkAST(statements, func(stmt *IdentStatement) bool {
+		if stmt.Name == "subinclude" && stmt.Action != nil && stmt.Action.Call != nil {
+			for _, arg := range stmt.Action.Call.Arguments {
+				if arg.Value.Val != nil && arg.Value.Val.List != nil {
+					for _, v := range arg.Value.Val.List.Values {
+						add(v)
+					}
+				} else {
+					add(&arg.Value)
+				}
+			}
+		}
+		return false
+	})
+	return labels
+}
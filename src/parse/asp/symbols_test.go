@@ -0,0 +1,32 @@
+package asp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefinedNames(t *testing.T) {
+	stmts, err := newParser().parse(nil, "src/parse/asp/test_data/symbols.build")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"go_thing", "_private", "a", "b", "linux_only", "not_linux", "i", "count"}, DefinedNames(stmts))
+}
+
+func TestReferencedNames(t *testing.T) {
+	stmts, err := newParser().parse(nil, "src/parse/asp/test_data/symbols.build")
+	require.NoError(t, err)
+	names := ReferencedNames(stmts)
+	for _, name := range []string{"subinclude", "go_library", "go_thing", "CONFIG", "_private", "range", "genrule", "i", "len", "a", "count"} {
+		assert.True(t, names[name], "%s should be referenced", name)
+	}
+	for _, name := range []string{"b", "linux_only", "not_linux"} {
+		assert.False(t, names[name], "%s should not be referenced", name)
+	}
+}
+
+func TestSubincludeCalls(t *testing.T) {
+	stmts, err := newParser().parse(nil, "src/parse/asp/test_data/symbols.build")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"//build_defs:go", "//build_defs:python", ":local"}, SubincludeCalls(stmts))
+}
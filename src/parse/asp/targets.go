@@ -2,6 +2,7 @@ package asp
 
 import (
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
@@ -72,6 +73,9 @@ const (
 	fileContentArgIdx
 	subrepoArgIdx
 	noTestCoverageArgIdx
+	testShardsArgIdx
+	testResultsFormatArgIdx
+	testResourcesArgIdx
 )
 
 // createTarget creates a new build target as part of build_rule().
@@ -167,6 +171,22 @@ func createTarget(s *scope, args []pyObject) *core.BuildTarget {
 		target.Test.Sandbox = isTruthy(testSandboxBuildRuleArgIdx)
 		target.Test.NoOutput = isTruthy(noTestOutputBuildRuleArgIdx)
 		target.Test.NoCoverage = target.Test.NoOutput || isTruthy(noTestCoverageArgIdx)
+		if shards, ok := args[testShardsArgIdx].(pyInt); ok {
+			s.Assert(shards >= 0 && shards <= math.MaxUint16, "test_shards must be between 0 and %d", math.MaxUint16)
+			target.Test.Shards = uint16(shards)
+		}
+		if format, ok := args[testResultsFormatArgIdx].(pyString); ok {
+			err := core.ValidateTestResultsFormat(string(format))
+			s.Assert(err == nil, "%s", err)
+			target.Test.ResultsFormat = string(format)
+		}
+		if args[testResourcesArgIdx] != None {
+			target.Test.Resources = asStringList(s, mustList(args[testResourcesArgIdx]), "test_resources")
+			for _, resource := range target.Test.Resources {
+				_, present := s.state.Config.TestResource[resource]
+				s.Assert(present, "Unknown test resource %s; it must be declared in a [testresource \"%s\"] section of the config", resource, resource)
+			}
+		}
 	}
 
 	if err := validateSandbox(s.state, target); err != nil {
@@ -476,7 +496,11 @@ func addDependencies(s *scope, name string, obj pyObject, target *core.BuildTarg
 			// *sigh*... Bazel seems to allow an implicit : on the start of dependencies
 			str = ":" + str
 		}
-		target.AddMaybeExportedDependency(assertNotPseudoLabel(s, s.parseLabelInPackage(str, s.pkg)), exported, false, internal)
+		label := assertNotPseudoLabel(s, s.parseLabelInPackage(str, s.pkg))
+		target.AddMaybeExportedDependency(label, exported, false, internal)
+		if r := s.interpreter.calls; r != nil {
+			target.SetDependencySite(label, r.DependencySite(s, label))
+		}
 	})
 }
 
@@ -33,6 +33,7 @@ type aspParser struct {
 // newAspParser returns a asp.Parser object with all the builtins loaded
 func newAspParser(state *core.BuildState) *asp.Parser {
 	p := asp.NewParser(state)
+	p.EnableDebugger(state)
 	log.Debug("Loading built-in build rules...")
 	dir, _ := rules.AllAssets()
 	sort.Strings(dir)
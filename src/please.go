@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
@@ -76,19 +77,23 @@ var opts struct {
 		Colour            bool          `long:"colour" description:"Forces coloured output from logging & other shell output."`
 		NoColour          bool          `long:"nocolour" description:"Forces colourless output from logging & other shell output."`
 		TraceFile         cli.Filepath  `long:"trace_file" description:"File to write Chrome tracing output into"`
+		EventStream       string        `long:"event_stream" description:"File or Unix socket (as unix:///path/to/socket) to stream build & test events to as newline-delimited JSON"`
 		ShowAllOutput     bool          `long:"show_all_output" description:"Show all output live from all commands. Implies --plain_output."`
 		CompletionScript  bool          `long:"completion_script" description:"Prints the bash / zsh completion script to stdout"`
 	} `group:"Options controlling output & logging"`
 
 	BehaviorFlags struct {
-		NoUpdate           bool    `long:"noupdate" description:"Disable Please attempting to auto-update itself."`
-		NoHashVerification bool    `long:"nohash_verification" description:"Hash verification errors are nonfatal." env:"PLZ_NO_HASH_VERIFICATION"`
-		NoLock             bool    `long:"nolock" description:"Don't attempt to lock the repo exclusively. Use with care."`
-		KeepWorkdirs       bool    `long:"keep_workdirs" description:"Don't clean directories in plz-out/tmp after successfully building targets."`
-		HTTPProxy          cli.URL `long:"http_proxy" env:"HTTP_PROXY" description:"HTTP proxy to use for downloads"`
-		Debug              bool    `long:"debug" description:"When enabled, Please will enter into an interactive debugger when breakpoint() is called during parsing."`
-		KeepGoing          bool    `long:"keep_going" description:"Continue as much as possible after an error. While the target that failed and those that depend on it cannot be build, other prerequisites of these targets can be."`
-		AllowSudo          bool    `long:"allow_sudo" hidden:"true" description:"Allow running under sudo (normally this is a very bad idea)"`
+		NoUpdate           bool     `long:"noupdate" description:"Disable Please attempting to auto-update itself."`
+		NoHashVerification bool     `long:"nohash_verification" description:"Hash verification errors are nonfatal." env:"PLZ_NO_HASH_VERIFICATION"`
+		NoLock             bool     `long:"nolock" description:"Don't attempt to lock the repo exclusively. Use with care."`
+		KeepWorkdirs       bool     `long:"keep_workdirs" description:"Don't clean directories in plz-out/tmp after successfully building targets."`
+		HTTPProxy          cli.URL  `long:"http_proxy" env:"HTTP_PROXY" description:"HTTP proxy to use for downloads"`
+		Debug              bool     `long:"debug" description:"When enabled, Please will enter into an interactive debugger when breakpoint() is called during parsing."`
+		Breakpoints        []string `long:"breakpoint" description:"Stops in the debugger at this file:line while parsing. Can be repeated. Implies --debug."`
+		DebugAdapter       string   `long:"debug_adapter" description:"Serves the Debug Adapter Protocol on this address (e.g. localhost:4711) so an editor can attach to debug parsing. Implies --debug."`
+		Provenance         bool     `long:"provenance" description:"Records the chain of macro calls that created each target while parsing, so that errors and plz query print can show where targets came from."`
+		KeepGoing          bool     `long:"keep_going" description:"Continue as much as possible after an error. While the target that failed and those that depend on it cannot be build, other prerequisites of these targets can be."`
+		AllowSudo          bool     `long:"allow_sudo" hidden:"true" description:"Allow running under sudo (normally this is a very bad idea)"`
 	} `group:"Options that enable / disable certain behaviors"`
 
 	HelpFlags struct {
@@ -136,36 +141,43 @@ var opts struct {
 		Detailed         bool         `long:"detailed" description:"Prints more detailed output after tests."`
 		Shell            string       `long:"shell" choice:"shell" choice:"run" optional:"true" optional-value:"shell" description:"Opens a shell in the test directory with the appropriate environment variables."`
 		StreamResults    bool         `long:"stream_results" description:"Prints test results on stdout as they are run."`
+		AffectedBy       string       `long:"affected_by" description:"Only runs tests affected by the changes in this diff spec (e.g. origin/master or HEAD~1), using coverage from previous runs of plz cover where possible."`
+		HTMLReport       cli.Filepath `long:"html_report" description:"Directory to write a static HTML report of the test results to."`
 		// Slightly awkward since we can specify a single test with arguments or multiple test targets.
 		Args struct {
 			Target core.BuildLabel `positional-arg-name:"target" description:"Target to test"`
 			Args   TargetsOrArgs   `positional-arg-name:"arguments" description:"Arguments or test selectors"`
 		} `positional-args:"true"`
-		StateArgs []string `no-flag:"true"`
+		StateArgs       []string                     `no-flag:"true"`
+		StateSelections map[core.BuildLabel][]string `no-flag:"true"`
 	} `command:"test" description:"Builds and tests one or more targets"`
 
 	Cover struct {
-		active              bool          `no-flag:"true"`
-		FailingTestsOk      bool          `long:"failing_tests_ok" hidden:"true" description:"Exit with status 0 even if tests fail (nonzero only if catastrophe happens)"`
-		NoCoverageReport    bool          `long:"nocoverage_report" description:"Suppress the per-file coverage report displayed in the shell"`
-		LineCoverageReport  bool          `short:"l" long:"line_coverage_report" description:" Show a line-by-line coverage report for all affected files."`
-		NumRuns             int           `short:"n" long:"num_runs" default:"1" description:"Number of times to run each test target."`
-		Rerun               bool          `long:"rerun" description:"Rerun the test even if the hash hasn't changed."`
-		Sequentially        bool          `long:"sequentially" description:"Whether to run multiple runs of the same test sequentially"`
-		IncludeAllFiles     bool          `short:"a" long:"include_all_files" description:"Include all dependent files in coverage (default is just those from relevant packages)"`
-		IncludeFile         cli.Filepaths `long:"include_file" description:"Filenames to filter coverage display to. Supports shell pattern matching e.g. file/path/*."`
-		TestResultsFile     cli.Filepath  `long:"test_results_file" default:"plz-out/log/test_results.xml" description:"File to write combined test results to."`
-		SurefireDir         cli.Filepath  `long:"surefire_dir" default:"plz-out/surefire-reports" description:"Directory to copy XML test results to."`
-		CoverageResultsFile cli.Filepath  `long:"coverage_results_file" env:"COVERAGE_RESULTS_FILE" default:"plz-out/log/coverage.json" description:"File to write combined coverage results to."`
-		CoverageXMLReport   cli.Filepath  `long:"coverage_xml_report" env:"COVERAGE_XML_REPORT" default:"plz-out/log/coverage.xml" description:"XML File to write combined coverage results to."`
-		Incremental         bool          `short:"i" long:"incremental" description:"Calculates summary statistics for incremental coverage, i.e. stats for just the lines currently modified."`
-		ShowOutput          bool          `short:"s" long:"show_output" description:"Always show output of tests, even on success."`
-		DebugFailingTest    bool          `short:"d" long:"debug" description:"Allows starting an interactive debugger on test failure. Does not work with all test types (currently only python/pytest). Implies -c dbg unless otherwise set."`
-		Failed              bool          `short:"f" long:"failed" description:"Runs just the test cases that failed from the immediately previous run."`
-		Detailed            bool          `long:"detailed" description:"Prints more detailed output after tests."`
-		Shell               string        `long:"shell" choice:"shell" choice:"run" optional:"true" optional-value:"shell" description:"Opens a shell in the test directory with the appropriate environment variables."`
-		StreamResults       bool          `long:"stream_results" description:"Prints test results on stdout as they are run."`
-		Args                struct {
+		active                  bool          `no-flag:"true"`
+		FailingTestsOk          bool          `long:"failing_tests_ok" hidden:"true" description:"Exit with status 0 even if tests fail (nonzero only if catastrophe happens)"`
+		NoCoverageReport        bool          `long:"nocoverage_report" description:"Suppress the per-file coverage report displayed in the shell"`
+		LineCoverageReport      bool          `short:"l" long:"line_coverage_report" description:" Show a line-by-line coverage report for all affected files."`
+		NumRuns                 int           `short:"n" long:"num_runs" default:"1" description:"Number of times to run each test target."`
+		Rerun                   bool          `long:"rerun" description:"Rerun the test even if the hash hasn't changed."`
+		Sequentially            bool          `long:"sequentially" description:"Whether to run multiple runs of the same test sequentially"`
+		IncludeAllFiles         bool          `short:"a" long:"include_all_files" description:"Include all dependent files in coverage (default is just those from relevant packages)"`
+		IncludeFile             cli.Filepaths `long:"include_file" description:"Filenames to filter coverage display to. Supports shell pattern matching e.g. file/path/*."`
+		TestResultsFile         cli.Filepath  `long:"test_results_file" default:"plz-out/log/test_results.xml" description:"File to write combined test results to."`
+		SurefireDir             cli.Filepath  `long:"surefire_dir" default:"plz-out/surefire-reports" description:"Directory to copy XML test results to."`
+		CoverageResultsFile     cli.Filepath  `long:"coverage_results_file" env:"COVERAGE_RESULTS_FILE" default:"plz-out/log/coverage.json" description:"File to write combined coverage results to."`
+		CoverageXMLReport       cli.Filepath  `long:"coverage_xml_report" env:"COVERAGE_XML_REPORT" default:"plz-out/log/coverage.xml" description:"XML File to write combined coverage results to."`
+		CoverageLCOVReport      cli.Filepath  `long:"coverage_lcov_report" env:"COVERAGE_LCOV_REPORT" description:"File to write combined coverage results to in LCOV format."`
+		CoverageCoberturaReport cli.Filepath  `long:"coverage_cobertura_report" env:"COVERAGE_COBERTURA_REPORT" description:"File to write combined coverage results to in Cobertura XML format."`
+		Incremental             bool          `short:"i" long:"incremental" description:"Calculates summary statistics for incremental coverage, i.e. stats for just the lines currently modified."`
+		ShowOutput              bool          `short:"s" long:"show_output" description:"Always show output of tests, even on success."`
+		DebugFailingTest        bool          `short:"d" long:"debug" description:"Allows starting an interactive debugger on test failure. Does not work with all test types (currently only python/pytest). Implies -c dbg unless otherwise set."`
+		Failed                  bool          `short:"f" long:"failed" description:"Runs just the test cases that failed from the immediately previous run."`
+		Detailed                bool          `long:"detailed" description:"Prints more detailed output after tests."`
+		Shell                   string        `long:"shell" choice:"shell" choice:"run" optional:"true" optional-value:"shell" description:"Opens a shell in the test directory with the appropriate environment variables."`
+		StreamResults           bool          `long:"stream_results" description:"Prints test results on stdout as they are run."`
+		AffectedBy              string        `long:"affected_by" description:"Only runs tests affected by the changes in this diff spec (e.g. origin/master or HEAD~1), using coverage from previous runs of plz cover where possible."`
+		HTMLReport              cli.Filepath  `long:"html_report" description:"Directory to write a static HTML report of the test results and coverage to."`
+		Args                    struct {
 			Target core.BuildLabel `positional-arg-name:"target" description:"Target to test"`
 			Args   TargetsOrArgs   `positional-arg-name:"arguments" description:"Arguments or test selectors"`
 		} `positional-args:"true"`
@@ -347,7 +359,8 @@ var opts struct {
 	} `command:"tool" hidden:"true" description:"Invoke one of Please's sub-tools"`
 
 	Query struct {
-		Deps struct {
+		FromSnapshot cli.Filepath `long:"from_snapshot" description:"Load the build graph from a snapshot written by plz query graph --snapshot instead of parsing it"`
+		Deps         struct {
 			DOT    bool `long:"dot" description:"Output in dot format"`
 			Hidden bool `long:"hidden" short:"h" description:"Output internal / hidden dependencies too"`
 			Level  int  `long:"level" default:"-1" description:"Levels of the dependencies to retrieve."`
@@ -359,6 +372,8 @@ var opts struct {
 		ReverseDeps struct {
 			Level  int  `long:"level" default:"1" description:"Levels of the dependencies to retrieve (-1 for unlimited)."`
 			Hidden bool `long:"hidden" short:"h" description:"Output internal / hidden dependencies too"`
+			Count  bool `long:"count" description:"Print the number of transitive reverse dependencies of each target, by kind, instead of listing them"`
+			Top    int  `long:"top" description:"Implies --count, and only prints this many of the most depended-upon targets"`
 			Args   struct {
 				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to query" required:"true"`
 			} `positional-args:"true" required:"true"`
@@ -371,6 +386,21 @@ var opts struct {
 				Target2 core.BuildLabel `positional-arg-name:"target2" description:"Second build target" required:"true"`
 			} `positional-args:"true" required:"true"`
 		} `command:"somepath" description:"Queries for a dependency path between two targets in the build graph"`
+		AllPaths struct {
+			DOT    bool `long:"dot" description:"Output in dot format"`
+			Rank   bool `long:"rank" description:"Order the intermediate targets by how many paths go through them"`
+			Hidden bool `long:"hidden" description:"Show hidden targets as well"`
+			Args   struct {
+				Target1 core.BuildLabel `positional-arg-name:"target1" description:"First build target" required:"true"`
+				Target2 core.BuildLabel `positional-arg-name:"target2" description:"Second build target" required:"true"`
+			} `positional-args:"true" required:"true"`
+		} `command:"allpaths" description:"Queries for all dependency paths between two targets in the build graph"`
+		Expr struct {
+			Hidden bool `long:"hidden" description:"Show hidden targets as well"`
+			Args   struct {
+				Expression string `positional-arg-name:"expression" description:"Query expression to evaluate, e.g. 'deps(//src:main) - //third_party/...'" required:"true"`
+			} `positional-args:"true" required:"true"`
+		} `command:"expr" description:"Evaluates an expression combining several queries over the build graph"`
 		AllTargets struct {
 			Hidden bool `long:"hidden" description:"Show hidden targets as well"`
 			Args   struct {
@@ -404,7 +434,8 @@ var opts struct {
 			} `positional-args:"true" required:"true"`
 		} `command:"output" alias:"outputs" description:"Prints all outputs of a target."`
 		Graph struct {
-			Args struct {
+			Snapshot cli.Filepath `long:"snapshot" description:"Write a binary snapshot of the parsed graph to this file instead of printing JSON"`
+			Args     struct {
 				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to render graph for"`
 			} `positional-args:"true"`
 		} `command:"graph" description:"Prints a representation of the build graph."`
@@ -438,6 +469,11 @@ var opts struct {
 				Files cli.StdinStrings `positional-arg-name:"files" description:"Files to calculate changes for. Overrides flags relating to SCM operations."`
 			} `positional-args:"true"`
 		} `command:"changes" description:"Calculates the set of changed targets in regard to a set of modified files or SCM commits."`
+		GraphDiff struct {
+			Since  string `short:"s" long:"since" default:"origin/master" description:"Revision to compare against"`
+			Hidden bool   `long:"hidden" description:"Show hidden targets as well"`
+			JSON   bool   `long:"json" description:"Output the differences as JSON"`
+		} `command:"graphdiff" description:"Shows the targets, attributes and dependencies that have changed in the build graph since a revision."`
 		Filter struct {
 			Hidden bool `long:"hidden" description:"Show hidden targets as well"`
 			Args   struct {
@@ -452,6 +488,48 @@ var opts struct {
 				Options []string `positional-arg-name:"options" description:"Print specific options."`
 			} `positional-args:"true"`
 		} `command:"config" description:"Prints the configuration settings"`
+		Subincludes struct {
+			Unused bool `long:"unused" description:"Report subincludes whose symbols are never used by the BUILD file instead of printing them."`
+			Args   struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets whose packages to query" required:"true"`
+			} `positional-args:"true" required:"true"`
+		} `command:"subincludes" description:"Prints the subincludes of packages, including transitive and preloaded ones."`
+		UnusedDeps struct {
+			Fix  bool `long:"fix" description:"Remove the unused dependencies from the BUILD files instead of printing them."`
+			Args struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to check" required:"true"`
+			} `positional-args:"true" required:"true"`
+		} `command:"unused_deps" description:"Finds dependencies of Go and Python targets that none of their sources import."`
+		Cycles struct {
+			Args struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to search for cycles. Defaults to the whole graph."`
+			} `positional-args:"true"`
+		} `command:"cycles" description:"Finds all the dependency cycles in the graph and shows where each dependency in them was declared."`
+		Policy struct {
+			Args struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to audit. Defaults to the whole graph."`
+			} `positional-args:"true"`
+		} `command:"policy" description:"Audits dependencies against the repo's dependency policy."`
+		Weight struct {
+			Sort   string `long:"sort" choice:"size" choice:"time" default:"size" description:"Whether to order targets by their exclusive size or build time"`
+			Hidden bool   `long:"hidden" description:"Show hidden targets as well"`
+			JSON   bool   `long:"json" description:"Output as JSON."`
+			Args   struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to query" required:"true"`
+			} `positional-args:"true" required:"true"`
+		} `command:"weight" description:"Builds the given targets and reports the size and build time of each of their dependencies."`
+		Tests struct {
+			Slowest int  `long:"slowest" description:"Only print this many of the slowest test targets."`
+			Cases   bool `long:"cases" description:"Print the history of individual test cases as well as targets."`
+			Args    struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to filter to"`
+			} `positional-args:"true"`
+		} `command:"tests" description:"Prints the history of previous test runs, with the slowest tests first."`
+		Flaky struct {
+			Args struct {
+				Targets []core.BuildLabel `positional-arg-name:"targets" description:"Targets to filter to"`
+			} `positional-args:"true"`
+		} `command:"flaky" description:"Prints the flake rates of tests that have previously only passed on retry."`
 	} `command:"query" description:"Queries information about the build state"`
 	Generate struct {
 		Gitignore string `long:"update_gitignore" description:"The gitignore file to write the generated sources to"`
@@ -503,8 +581,17 @@ var buildFunctions = map[string]func() int{
 		return toExitCode(success, state)
 	},
 	"test": func() int {
-		targets, args := testTargets(opts.Test.Args.Target, opts.Test.Args.Args, opts.Test.Failed, opts.Test.TestResultsFile)
-		success, state := doTest(targets, args, opts.Test.SurefireDir, opts.Test.TestResultsFile)
+		targets, args, selections := testTargets(opts.Test.Args.Target, opts.Test.Args.Args, opts.Test.Failed, opts.Test.TestResultsFile)
+		if opts.Test.AffectedBy != "" {
+			if targets, selections = affectedTests(targets, args, selections, opts.Test.AffectedBy); len(targets) == 0 {
+				log.Notice("No tests are affected by changes in %s", opts.Test.AffectedBy)
+				return 0
+			}
+		}
+		success, state := doTest(targets, args, selections, opts.Test.SurefireDir, opts.Test.TestResultsFile)
+		if opts.Test.HTMLReport != "" {
+			test.WriteHTMLReportOrDie(state, string(opts.Test.HTMLReport))
+		}
 		return toExitCode(success, state)
 	},
 	"cover": func() int {
@@ -514,9 +601,16 @@ var buildFunctions = map[string]func() int{
 		} else {
 			opts.BuildFlags.Config = "cover"
 		}
-		targets, args := testTargets(opts.Cover.Args.Target, opts.Cover.Args.Args, opts.Cover.Failed, opts.Cover.TestResultsFile)
+		targets, args, selections := testTargets(opts.Cover.Args.Target, opts.Cover.Args.Args, opts.Cover.Failed, opts.Cover.TestResultsFile)
+		if opts.Cover.AffectedBy != "" {
+			if targets, selections = affectedTests(targets, args, selections, opts.Cover.AffectedBy); len(targets) == 0 {
+				log.Notice("No tests are affected by changes in %s", opts.Cover.AffectedBy)
+				return 0
+			}
+		}
 		fs.RemoveAll(string(opts.Cover.CoverageResultsFile))
-		success, state := doTest(targets, args, opts.Cover.SurefireDir, opts.Cover.TestResultsFile)
+		success, state := doTest(targets, args, selections, opts.Cover.SurefireDir, opts.Cover.TestResultsFile)
+		test.UpdateCoverageIndex(state)
 		test.AddOriginalTargetsToCoverage(state, opts.Cover.IncludeAllFiles)
 		test.RemoveFilesFromCoverage(state.Coverage, state.Config.Cover.ExcludeExtension, state.Config.Cover.ExcludeGlob)
 
@@ -534,6 +628,15 @@ var buildFunctions = map[string]func() int{
 		if opts.Cover.CoverageXMLReport != "" {
 			test.WriteXMLCoverageToFileOrDie(targets, state.Coverage, string(opts.Cover.CoverageXMLReport))
 		}
+		if opts.Cover.CoverageLCOVReport != "" {
+			test.WriteLCOVCoverageToFileOrDie(state.Coverage, string(opts.Cover.CoverageLCOVReport))
+		}
+		if opts.Cover.CoverageCoberturaReport != "" {
+			test.WriteCoberturaCoverageToFileOrDie(state.Coverage, string(opts.Cover.CoverageCoberturaReport))
+		}
+		if opts.Cover.HTMLReport != "" {
+			test.WriteHTMLReportOrDie(state, string(opts.Cover.HTMLReport))
+		}
 
 		if opts.Cover.LineCoverageReport && success {
 			output.PrintLineCoverageReport(state, opts.Cover.IncludeFile.AsStrings())
@@ -784,6 +887,10 @@ var buildFunctions = map[string]func() int{
 	"query.revdeps": func() int {
 		labels := plz.ReadStdinLabels(opts.Query.ReverseDeps.Args.Targets)
 		return runQuery(true, append(labels, core.WholeGraph...), func(state *core.BuildState) {
+			if opts.Query.ReverseDeps.Count || opts.Query.ReverseDeps.Top > 0 {
+				query.ReverseDepCounts(os.Stdout, state, state.ExpandLabels(labels), opts.Query.ReverseDeps.Top, opts.Query.ReverseDeps.Hidden)
+				return
+			}
 			query.ReverseDeps(state, state.ExpandLabels(labels), opts.Query.ReverseDeps.Level, opts.Query.ReverseDeps.Hidden)
 		})
 	},
@@ -797,12 +904,37 @@ var buildFunctions = map[string]func() int{
 			}
 		})
 	},
+	"query.allpaths": func() int {
+		a := plz.ReadStdinLabels([]core.BuildLabel{opts.Query.AllPaths.Args.Target1})
+		b := plz.ReadStdinLabels([]core.BuildLabel{opts.Query.AllPaths.Args.Target2})
+		return runQuery(true, append(a, b...), func(state *core.BuildState) {
+			if err := query.AllPaths(os.Stdout, state.Graph, a, b, opts.Query.AllPaths.Hidden, opts.Query.AllPaths.DOT, opts.Query.AllPaths.Rank); err != nil {
+				fmt.Printf("%s\n", err)
+				os.Exit(1)
+			}
+		})
+	},
+	"query.expr": func() int {
+		expr, err := query.ParseExpression(opts.Query.Expr.Args.Expression, core.InitialPackagePath)
+		if err != nil {
+			log.Fatalf("Invalid expression: %s", err)
+		}
+		return runQuery(true, expr.Labels(), func(state *core.BuildState) {
+			if err := query.Evaluate(os.Stdout, state, expr, opts.Query.Expr.Hidden); err != nil {
+				fmt.Printf("%s\n", err)
+				os.Exit(1)
+			}
+		})
+	},
 	"query.alltargets": func() int {
 		return runQuery(true, opts.Query.AllTargets.Args.Targets, func(state *core.BuildState) {
 			query.AllTargets(state.Graph, state.ExpandOriginalLabels(), opts.Query.AllTargets.Hidden)
 		})
 	},
 	"query.print": func() int {
+		if slices.Contains(opts.Query.Print.Fields, "provenance") {
+			opts.BehaviorFlags.Provenance = true // Otherwise there'd be nothing to print.
+		}
 		return runQuery(false, opts.Query.Print.Args.Targets, func(state *core.BuildState) {
 			query.Print(state, state.ExpandOriginalLabels(), opts.Query.Print.Fields, opts.Query.Print.Labels, opts.Query.Print.OmitHidden, opts.Query.Print.JSON)
 		})
@@ -863,7 +995,12 @@ var buildFunctions = map[string]func() int{
 	"query.graph": func() int {
 		targets := opts.Query.Graph.Args.Targets
 		return runQuery(true, targets, func(state *core.BuildState) {
-			if len(opts.Query.Graph.Args.Targets) == 0 {
+			if opts.Query.Graph.Snapshot != "" {
+				if err := query.WriteSnapshot(state, string(opts.Query.Graph.Snapshot)); err != nil {
+					log.Fatalf("%s", err)
+				}
+				return
+			} else if len(opts.Query.Graph.Args.Targets) == 0 {
 				targets = opts.Query.Graph.Args.Targets // It special-cases doing the full graph.
 			}
 			query.Graph(state, state.ExpandLabels(targets))
@@ -940,22 +1077,12 @@ var buildFunctions = map[string]func() int{
 		} else if opts.Query.Changes.Inexact {
 			return runInexact(scm.ChangedFiles(opts.Query.Changes.Since, true, ""))
 		}
-		original := scm.CurrentRevIdentifier(false)
 		files := scm.ChangedFiles(opts.Query.Changes.Since, true, "")
 		log.Debugf("Number of changed files: %d", len(files))
-		if err := scm.Checkout(opts.Query.Changes.Since); err != nil {
-			log.Fatalf("%s", err)
-		}
-		readConfig()
-		_, before := runBuild(core.WholeGraph, false, false, false)
-		// N.B. Ignore failure here; if we can't parse the graph before then it will suffice to
-		//      assume that anything we don't know about has changed.
-		if err := scm.Checkout(original); err != nil {
-			log.Fatalf("%s", err)
-		}
-		readConfig()
-		success, after := runBuild(core.WholeGraph, false, false, false)
-		if !success {
+		// N.B. Ignore failure to parse the graph before; it will suffice to assume that anything
+		//      we don't know about has changed.
+		before, after, _ := parseBeforeAndAfter(scm, opts.Query.Changes.Since)
+		if after == nil {
 			return 1
 		}
 		for _, target := range query.DiffGraphs(before, after, files, level, includeSubrepos) {
@@ -963,6 +1090,19 @@ var buildFunctions = map[string]func() int{
 		}
 		return 0
 	},
+	"query.graphdiff": func() int {
+		before, after, beforeSuccess := parseBeforeAndAfter(scm.MustNew(core.RepoRoot), opts.Query.GraphDiff.Since)
+		if !beforeSuccess {
+			// Unlike query changes, we can't assume everything we don't know about has changed; we'd report
+			// everything missing from the partial graph as having been added.
+			log.Errorf("Failed to parse the graph at %s, can't compare against it", opts.Query.GraphDiff.Since)
+			return 1
+		} else if after == nil {
+			return 1
+		}
+		query.GraphDiffs(os.Stdout, before, after, opts.Query.GraphDiff.Hidden, opts.Query.GraphDiff.JSON)
+		return 0
+	},
 	"query.filter": func() int {
 		return runQuery(false, opts.Query.Filter.Args.Targets, func(state *core.BuildState) {
 			query.Filter(state, state.ExpandOriginalLabels(), opts.Query.Filter.Hidden)
@@ -983,8 +1123,75 @@ var buildFunctions = map[string]func() int{
 		}
 		return 0
 	},
+	"query.policy": func() int {
+		if config.Policy.File == "" {
+			log.Fatalf("No dependency policy is configured; set File in the [Policy] section of your .plzconfig")
+		}
+		denied := 0
+		ret := runQuery(true, opts.Query.Policy.Args.Targets, func(state *core.BuildState) {
+			denied = query.Policy(os.Stdout, state.Graph, state.Policy, state.ExpandOriginalLabels(), len(opts.Query.Policy.Args.Targets) == 0)
+		})
+		if ret == 0 && denied > 0 {
+			return 1
+		}
+		return ret
+	},
+	"query.weight": func() int {
+		if success, state := runBuild(opts.Query.Weight.Args.Targets, true, false, true); success {
+			query.Weight(os.Stdout, state.Graph, state.ExpandOriginalLabels(), opts.Query.Weight.Sort, opts.Query.Weight.Hidden, opts.Query.Weight.JSON)
+			return 0
+		}
+		return 1
+	},
+	"query.tests": func() int {
+		query.Tests(os.Stdout, core.LoadTestHistory(core.TestHistoryFile), opts.Query.Tests.Args.Targets, opts.Query.Tests.Slowest, opts.Query.Tests.Cases)
+		return 0
+	},
+	"query.flaky": func() int {
+		query.Flaky(os.Stdout, config, core.LoadTestHistory(core.TestHistoryFile), opts.Query.Flaky.Args.Targets)
+		return 0
+	},
+	"query.subincludes": func() int {
+		unused := 0
+		ret := runQuery(true, opts.Query.Subincludes.Args.Targets, func(state *core.BuildState) {
+			if opts.Query.Subincludes.Unused {
+				unused = query.UnusedSubincludes(os.Stdout, state, state.ExpandOriginalLabels())
+			} else {
+				query.Subincludes(os.Stdout, state, state.ExpandOriginalLabels())
+			}
+		})
+		if ret == 0 && unused > 0 {
+			return 1
+		}
+		return ret
+	},
+	"query.unused_deps": func() int {
+		unused := 0
+		ret := runQuery(true, opts.Query.UnusedDeps.Args.Targets, func(state *core.BuildState) {
+			var err error
+			if unused, err = query.UnusedDeps(os.Stdout, state, state.ExpandOriginalLabels(), opts.Query.UnusedDeps.Fix); err != nil {
+				log.Fatalf("%s", err)
+			}
+		})
+		if ret == 0 && unused > 0 && !opts.Query.UnusedDeps.Fix {
+			return 1
+		}
+		return ret
+	},
+	"query.cycles": func() int {
+		// We need the call stacks to be able to say where each dependency was declared.
+		opts.BehaviorFlags.Provenance = true
+		cycles := 0
+		ret := runQuery(true, opts.Query.Cycles.Args.Targets, func(state *core.BuildState) {
+			cycles = query.Cycles(os.Stdout, state.Graph, state.ExpandOriginalLabels())
+		})
+		if ret == 0 && cycles > 0 {
+			return 1
+		}
+		return ret
+	},
 	"watch": func() int {
-		targets, args := testTargets(opts.Watch.Args.Target, opts.Watch.Args.Args, false, "")
+		targets, args, _ := testTargets(opts.Watch.Args.Target, opts.Watch.Args.Args, false, "")
 		// Don't ask it to test now since we don't know if any of them are tests yet.
 		success, state := runBuild(targets, true, false, false)
 		state.NeedRun = opts.Watch.Run
@@ -1085,6 +1292,9 @@ func runQuery(needFullParse bool, labels []core.BuildLabel, onSuccess func(state
 	if len(labels) == 0 {
 		labels = core.WholeGraph
 	}
+	if opts.Query.FromSnapshot != "" {
+		return runQueryFromSnapshot(labels, onSuccess)
+	}
 	if success, state := runBuild(labels, false, false, true); success {
 		onSuccess(state)
 		return 0
@@ -1092,17 +1302,131 @@ func runQuery(needFullParse bool, labels []core.BuildLabel, onSuccess func(state
 	return 1
 }
 
-func doTest(targets []core.BuildLabel, args []string, surefireDir cli.Filepath, resultsFile cli.Filepath) (bool, *core.BuildState) {
+// runQueryFromSnapshot runs a query against a graph loaded from a snapshot, without parsing anything.
+func runQueryFromSnapshot(labels []core.BuildLabel, onSuccess func(state *core.BuildState)) int {
+	f, err := os.Open(string(opts.Query.FromSnapshot))
+	if err != nil {
+		log.Fatalf("Failed to open graph snapshot: %s", err)
+	}
+	defer f.Close()
+	if plz.ReadingStdin(labels) {
+		labels = plz.ReadStdinLabels(labels)
+	}
+	state := newBuildState(labels, config, false, false)
+	if err := state.LoadGraphSnapshot(f, labels); err != nil {
+		log.Fatalf("%s", err)
+	}
+	onSuccess(state)
+	return 0
+}
+
+// parseBeforeAndAfter parses the whole graph at the given revision and then again at the current one.
+// The returned 'after' state is nil if the current graph fails to parse; beforeSuccess is false if the graph
+// at the given revision failed to parse, in which case 'before' may only be partial.
+func parseBeforeAndAfter(s scm.SCM, since string) (before, after *core.BuildState, beforeSuccess bool) {
+	original := s.CurrentRevIdentifier(false)
+	if err := s.Checkout(since); err != nil {
+		log.Fatalf("%s", err)
+	}
+	readConfig()
+	beforeSuccess, before = runBuild(core.WholeGraph, false, false, false)
+	if err := s.Checkout(original); err != nil {
+		log.Fatalf("%s", err)
+	}
+	readConfig()
+	success, after := runBuild(core.WholeGraph, false, false, false)
+	if !success {
+		return before, nil, beforeSuccess
+	}
+	return before, after, beforeSuccess
+}
+
+func doTest(targets []core.BuildLabel, args []string, selections map[core.BuildLabel][]string, surefireDir cli.Filepath, resultsFile cli.Filepath) (bool, *core.BuildState) {
+	// If we're only running some test cases, their results get merged into the previous ones so the report is still complete.
+	var previous map[core.BuildLabel]core.TestSuite
+	if len(args) > 0 || len(selections) > 0 {
+		previous = test.LoadPreviousResults(string(resultsFile))
+	}
 	fs.RemoveAll(string(surefireDir))
 	fs.RemoveAll(string(resultsFile))
 	os.MkdirAll(string(surefireDir), core.DirPermissions)
 	opts.Test.StateArgs = args
+	opts.Test.StateSelections = selections
 	success, state := runBuild(targets, true, true, false)
+	test.UpdateTestHistory(state)
+	test.MergePreviousResults(state, previous)
 	test.CopySurefireXMLFilesToDir(state, string(surefireDir))
 	test.WriteResultsToFileOrDie(state.Graph, string(resultsFile), state.Config.Test.StoreTestOutputOnSuccess)
 	return success, state
 }
 
+// affectedTests returns the tests matching any of the given labels that are affected by the changes in the given diff spec.
+// Tests that we have coverage for from previous runs are only affected if they covered any of the changed lines;
+// otherwise (or for changes to files we have no coverage for) we fall back to any tests that depend on the changed files.
+// Changes are compared with the coverage using their line numbers in the original version of each file, since that's
+// the version the coverage was recorded against.
+// If we have coverage for their individual test cases, and they've all run before, only the affected ones are selected
+// to run (unless the user has already chosen which ones to run); the given selections are returned with those added.
+func affectedTests(labels []core.BuildLabel, args []string, selections map[core.BuildLabel][]string, diffSpec string) ([]core.BuildLabel, map[core.BuildLabel][]string) {
+	changes, err := scm.MustNew(core.RepoRoot).ChangedOriginalLinesIn(diffSpec)
+	if err != nil {
+		log.Fatalf("Failed to determine changes: %s", err)
+	}
+	idx := test.LoadCoverageIndex(test.CoverageIndexFile)
+	known, unknown := idx.Split(changes)
+	knownFiles := make([]string, 0, len(known))
+	for file := range known {
+		knownFiles = append(knownFiles, file)
+	}
+	var affected []core.BuildLabel
+	if runQuery(true, core.WholeGraph, func(state *core.BuildState) {
+		changedTests := func(files []string) []core.BuildLabel {
+			ret := []core.BuildLabel{}
+			for _, label := range query.Changes(state, files, -1, false) {
+				if state.Graph.TargetOrDie(label).IsTest() && core.BuildLabels(labels).Includes(label) {
+					ret = append(ret, label)
+				}
+			}
+			return ret
+		}
+		unknownTests := changedTests(unknown)
+		affected = append(slices.Clone(unknownTests), idx.Affected(changedTests(knownFiles), known)...)
+		slices.SortFunc(affected, func(a, b core.BuildLabel) int { return a.Compare(b) })
+		affected = slices.Compact(affected)
+		if len(args) > 0 {
+			return
+		}
+		history := core.LoadTestHistory(core.TestHistoryFile)
+		for _, label := range affected {
+			if _, present := selections[label]; present || slices.Contains(unknownTests, label) {
+				continue
+			} else if cases := idx.AffectedCases(label, known); len(cases) > 0 && ranBefore(history.Get(label), cases) {
+				log.Debug("Only running test cases %s of %s", strings.Join(cases, ", "), label)
+				if selections == nil {
+					selections = map[core.BuildLabel][]string{}
+				}
+				selections[label] = cases
+			}
+		}
+	}) != 0 {
+		log.Fatalf("Failed to parse the build graph")
+	}
+	return affected, selections
+}
+
+// ranBefore returns true if all the given test cases appear in the given test history.
+func ranBefore(history *core.TargetTestHistory, cases []string) bool {
+	if history == nil {
+		return false
+	}
+	for _, name := range cases {
+		if !history.HasCase(name) {
+			return false
+		}
+	}
+	return true
+}
+
 // prettyOutputs determines from input flags whether we should show 'pretty' output (ie. interactive).
 func prettyOutput(interactiveOutput bool, plainOutput bool, verbosity cli.Verbosity) bool {
 	if interactiveOutput && plainOutput {
@@ -1113,6 +1437,27 @@ func prettyOutput(interactiveOutput bool, plainOutput bool, verbosity cli.Verbos
 
 // Please starts & runs the main build process through to its completion.
 func Please(targets []core.BuildLabel, config *core.Configuration, shouldBuild, shouldTest bool) (bool, *core.BuildState) {
+	state := newBuildState(targets, config, shouldBuild, shouldTest)
+
+	// Only one target that is _not_ named "all" or "..." is allowed with debug test.
+	if state.DebugFailingTests && (len(targets) != 1 || (len(targets) == 1 && (targets[0].IsPseudoTarget()))) {
+		log.Fatalf("-d/--debug flag can only be used with a single test target")
+	}
+
+	if opts.Run.InTempDir && opts.Run.WD != "" {
+		log.Fatal("Can't use both --in_temp_dir and --wd at the same time")
+	}
+
+	runPlease(state, targets)
+	if state.RemoteClient != nil && !opts.Run.Remote {
+		defer state.RemoteClient.Disconnect()
+	}
+	failures, _, _ := state.Failures()
+	return !failures, state
+}
+
+// newBuildState creates a new build state from the given config & the command-line flags.
+func newBuildState(targets []core.BuildLabel, config *core.Configuration, shouldBuild, shouldTest bool) *core.BuildState {
 	if opts.BuildFlags.NumThreads > 0 {
 		config.Please.NumThreads = opts.BuildFlags.NumThreads
 		config.Parse.NumThreads = opts.BuildFlags.NumThreads
@@ -1133,7 +1478,18 @@ func Please(targets []core.BuildLabel, config *core.Configuration, shouldBuild,
 		state.NumTestRuns = uint16(opts.Cover.NumRuns)
 	}
 	state.TestSequentially = opts.Test.Sequentially || opts.Cover.Sequentially // Similarly here.
+	if shouldTest {
+		state.TestHistory = core.LoadTestHistory(core.TestHistoryFile)
+	}
+	if config.Policy.File != "" {
+		policy, err := core.LoadPolicy(config.Policy.File)
+		if err != nil {
+			log.Fatalf("%s", err)
+		}
+		state.Policy = policy
+	}
 	state.TestArgs = opts.Test.StateArgs
+	state.TestSelections = opts.Test.StateSelections
 	state.NeedCoverage = opts.Cover.active
 	state.NeedBuild = shouldBuild
 	state.NeedTests = shouldTest
@@ -1149,7 +1505,11 @@ func Please(targets []core.BuildLabel, config *core.Configuration, shouldBuild,
 	state.DebugFailingTests = debugFailingTests
 	state.ShowAllOutput = opts.OutputFlags.ShowAllOutput
 	state.ParsePackageOnly = opts.ParsePackageOnly
-	state.EnableBreakpoints = opts.BehaviorFlags.Debug
+	state.RecordProvenance = opts.BehaviorFlags.Provenance
+	state.LogQueuedTargets = opts.OutputFlags.EventStream != ""
+	state.EnableBreakpoints = opts.BehaviorFlags.Debug || len(opts.BehaviorFlags.Breakpoints) > 0 || opts.BehaviorFlags.DebugAdapter != ""
+	state.Breakpoints = opts.BehaviorFlags.Breakpoints
+	state.DebugAdapterAddress = opts.BehaviorFlags.DebugAdapter
 
 	// What outputs get downloaded in remote execution.
 	if debug {
@@ -1162,22 +1522,7 @@ func Please(targets []core.BuildLabel, config *core.Configuration, shouldBuild,
 	if opts.BuildFlags.Arch.OS != "" {
 		state.TargetArch = opts.BuildFlags.Arch
 	}
-
-	// Only one target that is _not_ named "all" or "..." is allowed with debug test.
-	if state.DebugFailingTests && (len(targets) != 1 || (len(targets) == 1 && (targets[0].IsPseudoTarget()))) {
-		log.Fatalf("-d/--debug flag can only be used with a single test target")
-	}
-
-	if opts.Run.InTempDir && opts.Run.WD != "" {
-		log.Fatal("Can't use both --in_temp_dir and --wd at the same time")
-	}
-
-	runPlease(state, targets)
-	if state.RemoteClient != nil && !opts.Run.Remote {
-		defer state.RemoteClient.Disconnect()
-	}
-	failures, _, _ := state.Failures()
-	return !failures, state
+	return state
 }
 
 func runPlease(state *core.BuildState, targets []core.BuildLabel) {
@@ -1197,7 +1542,7 @@ func runPlease(state *core.BuildState, targets []core.BuildLabel) {
 	streamTests := opts.Test.StreamResults || opts.Cover.StreamResults
 	shell := opts.Build.Shell != "" || opts.Test.Shell != "" || opts.Cover.Shell != ""
 	shellRun := opts.Build.Shell == "run" || opts.Test.Shell == "run" || opts.Cover.Shell == "run"
-	pretty := prettyOutput(opts.OutputFlags.InteractiveOutput, opts.OutputFlags.PlainOutput || opts.BehaviorFlags.Debug, opts.OutputFlags.Verbosity) && state.NeedBuild && !streamTests
+	pretty := prettyOutput(opts.OutputFlags.InteractiveOutput, opts.OutputFlags.PlainOutput || state.EnableBreakpoints, opts.OutputFlags.Verbosity) && state.NeedBuild && !streamTests
 	state.Cache = cache.NewCache(state)
 
 	// Run the display
@@ -1205,7 +1550,7 @@ func runPlease(state *core.BuildState, targets []core.BuildLabel) {
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
-		output.MonitorState(state, !pretty, detailedTests, streamTests, shell, shellRun, string(opts.OutputFlags.TraceFile))
+		output.MonitorState(state, !pretty, detailedTests, streamTests, shell, shellRun, string(opts.OutputFlags.TraceFile), opts.OutputFlags.EventStream)
 		wg.Done()
 	}()
 	plz.Run(targets, opts.BuildFlags.PreTargets, state, config, state.TargetArch)
@@ -1215,15 +1560,16 @@ func runPlease(state *core.BuildState, targets []core.BuildLabel) {
 // testTargets handles test targets which can be given in two formats; a list of targets or a single
 // target with a list of trailing arguments.
 // Alternatively they can be completely omitted in which case we test everything under the working dir.
-// One can also pass a 'failed' flag which runs the failed tests from last time.
-func testTargets(target core.BuildLabel, inputs TargetsOrArgs, failed bool, resultsFile cli.Filepath) ([]core.BuildLabel, []string) {
+// One can also pass a 'failed' flag which runs the failed test cases of each target from last time.
+func testTargets(target core.BuildLabel, inputs TargetsOrArgs, failed bool, resultsFile cli.Filepath) ([]core.BuildLabel, []string, map[core.BuildLabel][]string) {
 	if failed {
-		return test.LoadPreviousFailures(string(resultsFile))
+		labels, selections := test.LoadPreviousFailures(string(resultsFile))
+		return labels, nil, selections
 	} else if target.Name == "" {
-		return core.InitialPackage(), nil
+		return core.InitialPackage(), nil, nil
 	}
 	labels, args := inputs.SeparateUnannotated()
-	return append([]core.BuildLabel{target}, labels...), args
+	return append([]core.BuildLabel{target}, labels...), args, nil
 }
 
 type TargetOrArg struct {
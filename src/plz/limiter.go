@@ -0,0 +1,103 @@
+package plz
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// A limiter allows only a certain number of concurrent tasks.
+// When tasks are waiting for a slot, the one with the highest priority is given the next one.
+// TODO(peterebden): We have about four of these now, commonise this somewhere
+type limiter struct {
+	mutex     sync.Mutex
+	available int
+	waiting   waiters
+	seq       uint64
+}
+
+func newLimiter(size int) *limiter {
+	return &limiter{available: size}
+}
+
+// Acquire blocks until a slot is available for a task with the given priority.
+func (l *limiter) Acquire(priority int64) {
+	l.mutex.Lock()
+	if l.available > 0 && len(l.waiting) == 0 {
+		l.available--
+		l.mutex.Unlock()
+		return
+	}
+	w := &waiter{priority: priority, seq: l.seq, ch: make(chan struct{})}
+	l.seq++
+	heap.Push(&l.waiting, w)
+	l.mutex.Unlock()
+	<-w.ch
+}
+
+// Release releases a slot acquired by Acquire, handing it to the highest priority waiter if there is one.
+func (l *limiter) Release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.waiting) == 0 {
+		l.available++
+		return
+	}
+	close(heap.Pop(&l.waiting).(*waiter).ch)
+}
+
+// A taskLimiter acquires slots from a limiter for a single task, at that task's priority.
+type taskLimiter struct {
+	limiter  *limiter
+	priority int64
+}
+
+func (l *taskLimiter) Acquire() {
+	l.limiter.Acquire(l.priority)
+}
+
+func (l *taskLimiter) Release() {
+	l.limiter.Release()
+}
+
+// taskPriority returns the priority that a task is scheduled with.
+// Builds always go first, since other tasks are waiting on them. Tests are scheduled with the
+// slowest first (based on their previous durations), which tends to minimise the overall time taken.
+func taskPriority(state *core.BuildState, task core.Task) int64 {
+	if task.Type != core.TestTask {
+		return math.MaxInt64
+	}
+	return int64(state.TestHistory.ExpectedDuration(task.Target.Label)) / int64(task.Target.NumTestShards())
+}
+
+type waiter struct {
+	priority int64
+	seq      uint64
+	ch       chan struct{}
+}
+
+// waiters implements heap.Interface, ordered by priority and then by arrival.
+type waiters []*waiter
+
+func (w waiters) Len() int      { return len(w) }
+func (w waiters) Swap(i, j int) { w[i], w[j] = w[j], w[i] }
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority > w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+
+func (w *waiters) Push(x any) {
+	*w = append(*w, x.(*waiter))
+}
+
+func (w *waiters) Pop() any {
+	old := *w
+	x := old[len(old)-1]
+	old[len(old)-1] = nil
+	*w = old[:len(old)-1]
+	return x
+}
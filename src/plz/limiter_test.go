@@ -0,0 +1,78 @@
+package plz
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestLimiterPriority(t *testing.T) {
+	l := newLimiter(1)
+	l.Acquire(0) // Take the only slot so everything else has to queue.
+
+	var mutex sync.Mutex
+	var order []int64
+	var wg sync.WaitGroup
+	for _, priority := range []int64{1, 5, 3} {
+		wg.Add(1)
+		go func(priority int64) {
+			defer wg.Done()
+			l.Acquire(priority)
+			mutex.Lock()
+			order = append(order, priority)
+			mutex.Unlock()
+			l.Release()
+		}(priority)
+	}
+	// Wait for them all to be queued up before we release the slot.
+	for {
+		l.mutex.Lock()
+		n := len(l.waiting)
+		l.mutex.Unlock()
+		if n == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	l.Release()
+	wg.Wait()
+	assert.Equal(t, []int64{5, 3, 1}, order)
+}
+
+func TestLimiterConcurrency(t *testing.T) {
+	l := newLimiter(2)
+	l.Acquire(0)
+	l.Acquire(0)
+	acquired := make(chan struct{})
+	go func() {
+		l.Acquire(0)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Acquired more slots than the limiter has")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.Release()
+	<-acquired
+}
+
+func TestTaskPriority(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	fast := core.NewBuildTarget(core.ParseBuildLabel("//src/plz:fast", ""))
+	fast.Test = &core.TestFields{}
+	slow := core.NewBuildTarget(core.ParseBuildLabel("//src/plz:slow", ""))
+	slow.Test = &core.TestFields{Shards: 2}
+	state.TestHistory.Record(fast.Label, core.TestSuite{Duration: time.Second}, 1, time.Now())
+	state.TestHistory.Record(slow.Label, core.TestSuite{Duration: 10 * time.Second}, 1, time.Now())
+
+	assert.Equal(t, int64(math.MaxInt64), taskPriority(state, core.Task{Target: fast, Type: core.BuildTask}))
+	assert.Equal(t, int64(time.Second), taskPriority(state, core.Task{Target: fast, Type: core.TestTask}))
+	// Each shard is expected to take a proportion of the total time.
+	assert.Equal(t, int64(5*time.Second), taskPriority(state, core.Task{Target: slow, Type: core.TestTask}))
+}
@@ -41,8 +41,8 @@ func Run(targets, preTargets []core.BuildLabel, state *core.BuildState, config *
 
 	parses, actions := state.TaskQueues()
 
-	localLimiter := make(limiter, config.Please.NumThreads)
-	remoteLimiter := make(limiter, config.NumRemoteExecutors())
+	localLimiter := newLimiter(config.Please.NumThreads)
+	remoteLimiter := newLimiter(config.NumRemoteExecutors())
 	anyRemote := config.NumRemoteExecutors() > 0
 
 	// Start up all the build workers
@@ -61,16 +61,15 @@ func Run(targets, preTargets []core.BuildLabel, state *core.BuildState, config *
 		for task := range actions {
 			go func(task core.Task) {
 				remote := anyRemote && !task.Target.Local
+				l := &taskLimiter{limiter: localLimiter, priority: taskPriority(state, task)}
 				if remote {
-					remoteLimiter.Acquire()
-					defer remoteLimiter.Release()
-				} else {
-					localLimiter.Acquire()
-					defer localLimiter.Release()
+					l.limiter = remoteLimiter
 				}
+				l.Acquire()
+				defer l.Release()
 				switch task.Type {
 				case core.TestTask:
-					test.Test(state, task.Target, remote, int(task.Run))
+					test.Test(state, task.Target, remote, int(task.Run), l)
 				case core.BuildTask:
 					build.Build(state, task.Target, remote)
 				}
@@ -256,15 +255,3 @@ func ReadAndParseStdinLabels() []core.BuildLabel {
 	}
 	return ret
 }
-
-// A limiter allows only a certain number of concurrent tasks
-// TODO(peterebden): We have about four of these now, commonise this somewhere
-type limiter chan struct{}
-
-func (l limiter) Acquire() {
-	l <- struct{}{}
-}
-
-func (l limiter) Release() {
-	<-l
-}
@@ -0,0 +1,248 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"slices"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// AllPaths prints the subgraph of every dependency path between two targets (or sets of targets).
+// If rank is true it instead prints the intermediate targets ordered by how many of the paths pass through them,
+// which is useful for finding the one dependency that would disconnect them.
+func AllPaths(out io.Writer, graph *core.BuildGraph, from, to []core.BuildLabel, hidden, formatdot, rank bool) error {
+	from = expandAllTargets(graph, from)
+	to = expandAllTargets(graph, to)
+	a := newAllPaths(graph, from, to, hidden)
+	if len(a.edges) == 0 {
+		// As with somepath, we don't know which way around they go, so try the other direction too.
+		if a = newAllPaths(graph, to, from, hidden); len(a.edges) == 0 {
+			if len(from) == 1 && len(to) == 1 {
+				return fmt.Errorf("Couldn't find any dependency path between %s and %s", from[0], to[0])
+			}
+			return fmt.Errorf("Couldn't find any dependency path between those targets")
+		}
+	}
+	if rank {
+		a.PrintRanks(out)
+	} else if formatdot {
+		a.PrintDot(out)
+	} else {
+		a.Print(out)
+	}
+	return nil
+}
+
+type allpaths struct {
+	graph *core.BuildGraph
+	// The subgraph of all paths; each target on a path is mapped to its dependencies that are also on a path.
+	edges    map[core.BuildLabel][]core.BuildLabel
+	from, to map[core.BuildLabel]struct{}
+	memo     map[core.BuildLabel]bool
+}
+
+func newAllPaths(graph *core.BuildGraph, from, to []core.BuildLabel, hidden bool) *allpaths {
+	a := &allpaths{
+		graph: graph,
+		edges: map[core.BuildLabel][]core.BuildLabel{},
+		from:  make(map[core.BuildLabel]struct{}, len(from)),
+		to:    make(map[core.BuildLabel]struct{}, len(to)),
+		memo:  map[core.BuildLabel]bool{},
+	}
+	for _, l := range to {
+		a.to[l] = struct{}{}
+	}
+	for _, l := range from {
+		a.from[l] = struct{}{}
+		a.reaches(graph.TargetOrDie(l))
+	}
+	if !hidden {
+		a.collapseHidden()
+	}
+	return a
+}
+
+// reaches returns true if the given target is on a path to one of the destination targets,
+// recording the subgraph of paths as it goes.
+func (a *allpaths) reaches(target *core.BuildTarget) bool {
+	if reaches, present := a.memo[target.Label]; present {
+		return reaches
+	} else if _, present := a.to[target.Label]; present {
+		a.memo[target.Label] = true
+		return true
+	}
+	a.memo[target.Label] = false // Guards against following any cycles.
+	reaches := false
+	for _, dep := range a.dependencies(target) {
+		if a.reaches(dep) {
+			reaches = true
+			if !slices.Contains(a.edges[target.Label], dep.Label) {
+				a.edges[target.Label] = append(a.edges[target.Label], dep.Label)
+			}
+		}
+	}
+	a.memo[target.Label] = reaches
+	return reaches
+}
+
+// dependencies returns the dependencies of a target, following the same edges as somepath does.
+func (a *allpaths) dependencies(target *core.BuildTarget) []*core.BuildTarget {
+	var ret []*core.BuildTarget
+	for _, dep := range target.DeclaredDependencies() {
+		if t := a.graph.Target(dep); t != nil {
+			for _, l := range t.ProvideFor(target) {
+				ret = append(ret, a.graph.TargetOrDie(l))
+			}
+		}
+	}
+	if target.Subrepo != nil && target.Subrepo.Target != nil {
+		ret = append(ret, target.Subrepo.Target)
+	}
+	return ret
+}
+
+// collapseHidden replaces all hidden targets in the subgraph with their parents.
+func (a *allpaths) collapseHidden() {
+	edges := make(map[core.BuildLabel][]core.BuildLabel, len(a.edges))
+	for from, deps := range a.edges {
+		parent := from.Parent()
+		for _, dep := range deps {
+			if dep = dep.Parent(); dep != parent && !slices.Contains(edges[parent], dep) {
+				edges[parent] = append(edges[parent], dep)
+			}
+		}
+	}
+	a.edges = edges
+	a.from = collapseLabels(a.from)
+	a.to = collapseLabels(a.to)
+}
+
+func collapseLabels(labels map[core.BuildLabel]struct{}) map[core.BuildLabel]struct{} {
+	ret := make(map[core.BuildLabel]struct{}, len(labels))
+	for l := range labels {
+		ret[l.Parent()] = struct{}{}
+	}
+	return ret
+}
+
+// targets returns all the targets in the subgraph, in sorted order.
+func (a *allpaths) targets() core.BuildLabels {
+	seen := map[core.BuildLabel]struct{}{}
+	ret := core.BuildLabels{}
+	add := func(l core.BuildLabel) {
+		if _, present := seen[l]; !present {
+			seen[l] = struct{}{}
+			ret = append(ret, l)
+		}
+	}
+	for from, deps := range a.edges {
+		add(from)
+		for _, dep := range deps {
+			add(dep)
+		}
+	}
+	sort.Sort(ret)
+	return ret
+}
+
+// Print prints each target in the subgraph, followed by its dependencies that are also in it.
+func (a *allpaths) Print(out io.Writer) {
+	for _, target := range a.targets() {
+		fmt.Fprintf(out, "%s\n", target)
+		deps := core.BuildLabels(slices.Clone(a.edges[target]))
+		sort.Sort(deps)
+		for _, dep := range deps {
+			fmt.Fprintf(out, "  %s\n", dep)
+		}
+	}
+}
+
+// PrintDot prints the subgraph in dot format.
+func (a *allpaths) PrintDot(out io.Writer) {
+	printDotHeader(out, "allpaths")
+	for _, target := range a.targets() {
+		deps := core.BuildLabels(slices.Clone(a.edges[target]))
+		sort.Sort(deps)
+		for _, dep := range deps {
+			printTargetDot(out, a.graph.TargetOrDie(dep), a.graph.TargetOrDie(target))
+		}
+	}
+	fmt.Fprintf(out, "}\n")
+}
+
+// PrintRanks prints the total number of paths, followed by each intermediate target in the subgraph
+// and how many paths go through it, in descending order.
+func (a *allpaths) PrintRanks(out io.Writer) {
+	total, ranks := a.ranks()
+	fmt.Fprintf(out, "%s paths in total\n", total)
+	for _, r := range ranks {
+		fmt.Fprintf(out, "%s %s\n", r.Paths, r.Label)
+	}
+}
+
+type allpathsRank struct {
+	Label core.BuildLabel
+	Paths *big.Int
+}
+
+// ranks returns the total number of paths in the subgraph and the number going through each intermediate target.
+// A target that every path goes through would disconnect the two ends if it were removed.
+func (a *allpaths) ranks() (*big.Int, []allpathsRank) {
+	// The number of paths through a target is the number of paths from the start to it multiplied by
+	// the number from it to the end. We count paths with big ints since there can be a lot of them.
+	pathsTo := map[core.BuildLabel]*big.Int{}
+	var countTo func(l core.BuildLabel) *big.Int
+	countTo = func(l core.BuildLabel) *big.Int {
+		if n, present := pathsTo[l]; present {
+			return n
+		}
+		n := big.NewInt(0)
+		pathsTo[l] = n // Guards against cycles, which can be introduced by collapsing hidden targets.
+		if _, present := a.to[l]; present {
+			n.SetInt64(1)
+		}
+		for _, dep := range a.edges[l] {
+			n.Add(n, countTo(dep))
+		}
+		return n
+	}
+	revdeps := map[core.BuildLabel][]core.BuildLabel{}
+	for from, deps := range a.edges {
+		for _, dep := range deps {
+			revdeps[dep] = append(revdeps[dep], from)
+		}
+	}
+	pathsFrom := map[core.BuildLabel]*big.Int{}
+	var countFrom func(l core.BuildLabel) *big.Int
+	countFrom = func(l core.BuildLabel) *big.Int {
+		if n, present := pathsFrom[l]; present {
+			return n
+		}
+		n := big.NewInt(0)
+		pathsFrom[l] = n
+		if _, present := a.from[l]; present {
+			n.SetInt64(1)
+		}
+		for _, rdep := range revdeps[l] {
+			n.Add(n, countFrom(rdep))
+		}
+		return n
+	}
+	total := big.NewInt(0)
+	for l := range a.from {
+		total.Add(total, countTo(l))
+	}
+	ranks := []allpathsRank{}
+	for _, l := range a.targets() {
+		_, isFrom := a.from[l]
+		_, isTo := a.to[l]
+		if !isFrom && !isTo {
+			ranks = append(ranks, allpathsRank{Label: l, Paths: new(big.Int).Mul(countFrom(l), countTo(l))})
+		}
+	}
+	sort.SliceStable(ranks, func(i, j int) bool { return ranks[i].Paths.Cmp(ranks[j].Paths) > 0 })
+	return total, ranks
+}
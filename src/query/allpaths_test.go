@@ -0,0 +1,104 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func allPathsGraph() *core.BuildGraph {
+	graph := core.NewGraph()
+	d := addGraphTarget(graph, "//src/query:d")
+	b := addGraphTarget(graph, "//src/query:b", d)
+	c := addGraphTarget(graph, "//src/query:c", d)
+	e := addGraphTarget(graph, "//src/query:e", c)
+	lib := addGraphTarget(graph, "//src/query:_a#lib", b, e)
+	addGraphTarget(graph, "//src/query:a", lib, c)
+	addGraphTarget(graph, "//src/query:f", d)
+	return graph
+}
+
+func TestAllPaths(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:a", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:d", "")}, false, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, `//src/query:a
+  //src/query:b
+  //src/query:c
+  //src/query:e
+//src/query:b
+  //src/query:d
+//src/query:c
+  //src/query:d
+//src/query:d
+//src/query:e
+  //src/query:c
+`, buf.String())
+}
+
+func TestAllPathsReversed(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:c", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:e", "")}, false, false, false)
+	require.NoError(t, err)
+	assert.Equal(t, "//src/query:c\n//src/query:e\n  //src/query:c\n", buf.String())
+}
+
+func TestAllPathsNoPath(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:a", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:f", "")}, false, false, false)
+	assert.Error(t, err)
+}
+
+func TestAllPathsRank(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:a", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:d", "")}, false, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, `3 paths in total
+2 //src/query:c
+1 //src/query:b
+1 //src/query:e
+`, buf.String())
+}
+
+func TestAllPathsRankHidden(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:a", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:d", "")}, true, false, true)
+	require.NoError(t, err)
+	assert.Equal(t, `3 paths in total
+2 //src/query:_a#lib
+2 //src/query:c
+1 //src/query:b
+1 //src/query:e
+`, buf.String())
+}
+
+func TestAllPathsDot(t *testing.T) {
+	graph := allPathsGraph()
+	var buf bytes.Buffer
+	err := AllPaths(&buf, graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:e", "")}, []core.BuildLabel{core.ParseBuildLabel("//src/query:d", "")}, false, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, `digraph allpaths {
+  fontname="Helvetica,Arial,sans-serif"
+  node [fontname="Helvetica,Arial,sans-serif"]
+  edge [fontname="Helvetica,Arial,sans-serif"]
+  rankdir="LR"
+  subgraph "//src/query:d" {
+   node [shape=ellipse] "//src/query:d";
+   "//src/query:c" -> "//src/query:d";
+  }
+  subgraph "//src/query:c" {
+   node [shape=ellipse] "//src/query:c";
+   "//src/query:e" -> "//src/query:c";
+  }
+}
+`, buf.String())
+}
@@ -0,0 +1,123 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// Cycles prints all the dependency cycles among the given targets and their transitive dependencies,
+// along with where each dependency in them was declared. It returns the number of cycles found.
+func Cycles(out io.Writer, graph *core.BuildGraph, labels []core.BuildLabel) int {
+	cycles := FindCycles(graph, labels)
+	for i, cycle := range cycles {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintf(out, "Dependency cycle %d of %d:\n%s\n", i+1, len(cycles), core.DescribeCycle(cycle))
+	}
+	return len(cycles)
+}
+
+// FindCycles finds the dependency cycles among the given targets and their transitive dependencies.
+//
+// There can be exponentially many distinct cycles in a graph, so rather than enumerating all of them this
+// finds every strongly connected component (i.e. every set of targets that all depend on one another) and
+// returns the shortest cycle through the first target in each. Each cycle starts with that target, and each
+// target depends on the next, with the last depending on the first.
+func FindCycles(graph *core.BuildGraph, labels []core.BuildLabel) [][]*core.BuildTarget {
+	f := &cycleFinder{
+		index:   map[*core.BuildTarget]int{},
+		lowlink: map[*core.BuildTarget]int{},
+		onStack: map[*core.BuildTarget]bool{},
+	}
+	for _, label := range labels {
+		if target := graph.TargetOrDie(label); !f.visited(target) {
+			f.Visit(target)
+		}
+	}
+	cycles := make([][]*core.BuildTarget, 0, len(f.components))
+	for _, component := range f.components {
+		sort.Slice(component, func(i, j int) bool { return component[i].Label.Less(component[j].Label) })
+		cycles = append(cycles, shortestCycle(component))
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0].Label.Less(cycles[j][0].Label) })
+	return cycles
+}
+
+// A cycleFinder finds strongly connected components in the build graph using Tarjan's algorithm.
+type cycleFinder struct {
+	index, lowlink map[*core.BuildTarget]int
+	onStack        map[*core.BuildTarget]bool
+	stack          []*core.BuildTarget
+	components     [][]*core.BuildTarget
+}
+
+func (f *cycleFinder) visited(target *core.BuildTarget) bool {
+	_, present := f.index[target]
+	return present
+}
+
+// Visit visits a target and all its transitive dependencies, recording any components that contain cycles.
+func (f *cycleFinder) Visit(target *core.BuildTarget) {
+	f.index[target] = len(f.index)
+	f.lowlink[target] = f.index[target]
+	f.stack = append(f.stack, target)
+	f.onStack[target] = true
+	for _, dep := range target.Dependencies() {
+		if !f.visited(dep) {
+			f.Visit(dep)
+			f.lowlink[target] = min(f.lowlink[target], f.lowlink[dep])
+		} else if f.onStack[dep] {
+			f.lowlink[target] = min(f.lowlink[target], f.index[dep])
+		}
+	}
+	if f.lowlink[target] != f.index[target] {
+		return // Not the root of a component.
+	}
+	var component []*core.BuildTarget
+	for {
+		t := f.stack[len(f.stack)-1]
+		f.stack = f.stack[:len(f.stack)-1]
+		f.onStack[t] = false
+		component = append(component, t)
+		if t == target {
+			break
+		}
+	}
+	if len(component) > 1 { // Targets can't depend on themselves directly, so these are the only cycles.
+		f.components = append(f.components, component)
+	}
+}
+
+// shortestCycle returns the shortest cycle through the first target in a strongly connected component.
+func shortestCycle(component []*core.BuildTarget) []*core.BuildTarget {
+	start := component[0]
+	members := make(map[*core.BuildTarget]bool, len(component))
+	for _, t := range component {
+		members[t] = true
+	}
+	// Breadth-first search from the start until we get back to it again.
+	previous := map[*core.BuildTarget]*core.BuildTarget{}
+	queue := []*core.BuildTarget{start}
+	for len(queue) > 0 {
+		t := queue[0]
+		queue = queue[1:]
+		for _, dep := range t.Dependencies() {
+			if dep == start {
+				cycle := []*core.BuildTarget{t}
+				for t != start {
+					t = previous[t]
+					cycle = append([]*core.BuildTarget{t}, cycle...)
+				}
+				return cycle
+			} else if _, present := previous[dep]; !present && members[dep] {
+				previous[dep] = t
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return component // Shouldn't happen since all the targets in the component are reachable from one another.
+}
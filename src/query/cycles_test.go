@@ -0,0 +1,71 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestFindCycles(t *testing.T) {
+	graph := core.NewGraph()
+	pkg := core.NewPackage("src/query")
+	graph.AddPackage(pkg)
+	a := addNewTarget(graph, pkg, "a", nil)
+	b := addNewTarget(graph, pkg, "b", nil)
+	c := addNewTarget(graph, pkg, "c", nil)
+	d := addNewTarget(graph, pkg, "d", nil)
+	e := addNewTarget(graph, pkg, "e", nil)
+	f := addNewTarget(graph, pkg, "f", nil)
+	g := addNewTarget(graph, pkg, "g", nil)
+	// a -> b -> c -> a, plus a longer way round via d which is part of the same cycle.
+	addDep(a, b)
+	addDep(b, c)
+	addDep(b, d)
+	addDep(d, c)
+	addDep(c, a)
+	// A second, separate cycle between e and f, which g depends on but isn't part of.
+	addDep(c, e)
+	addDep(e, f)
+	addDep(f, e)
+	addDep(g, f)
+	resolveAll(t, graph)
+
+	assert.Equal(t, [][]*core.BuildTarget{{a, b, c}, {e, f}}, FindCycles(graph, []core.BuildLabel{a.Label, g.Label}))
+	// Only the cycles reachable from the given targets are found.
+	assert.Equal(t, [][]*core.BuildTarget{{e, f}}, FindCycles(graph, []core.BuildLabel{g.Label}))
+}
+
+func TestCyclesOutput(t *testing.T) {
+	graph := core.NewGraph()
+	pkg := core.NewPackage("src/query")
+	graph.AddPackage(pkg)
+	a := addNewTarget(graph, pkg, "a", nil)
+	b := addNewTarget(graph, pkg, "b", nil)
+	addDep(a, b)
+	addDep(b, a)
+	resolveAll(t, graph)
+	a.CallStack = []core.CallSite{{Function: "genrule", Filename: "src/query/BUILD", Line: 1}}
+
+	var buf bytes.Buffer
+	assert.Equal(t, 1, Cycles(&buf, graph, []core.BuildLabel{a.Label}))
+	assert.Equal(t, `Dependency cycle 1 of 1:
+//src/query:a
+ -> //src/query:b
+      //src/query:a was declared by genrule() at src/query/BUILD:1
+ -> //src/query:a
+`, buf.String())
+}
+
+func addDep(target, dep *core.BuildTarget) {
+	target.AddDependency(dep.Label)
+}
+
+func resolveAll(t *testing.T, graph *core.BuildGraph) {
+	for _, target := range graph.AllTargets() {
+		require.NoError(t, target.ResolveDependencies(graph))
+	}
+}
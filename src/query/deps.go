@@ -11,11 +11,7 @@ import (
 // Deps prints all transitive dependencies of a set of targets.
 func Deps(out io.Writer, state *core.BuildState, labels []core.BuildLabel, hidden bool, targetLevel int, formatdot bool) {
 	if formatdot {
-		fmt.Fprintf(out, "digraph deps {\n")
-		fmt.Fprintf(out, "  fontname=\"Helvetica,Arial,sans-serif\"\n")
-		fmt.Fprintf(out, "  node [fontname=\"Helvetica,Arial,sans-serif\"]\n")
-		fmt.Fprintf(out, "  edge [fontname=\"Helvetica,Arial,sans-serif\"]\n")
-		fmt.Fprintf(out, "  rankdir=\"LR\"\n")
+		printDotHeader(out, "deps")
 	}
 	done := map[*core.BuildTarget]bool{}
 	for _, label := range labels {
@@ -61,6 +57,15 @@ func printTarget(out io.Writer, target *core.BuildTarget, currentLevel int) {
 	fmt.Fprintf(out, "%s%s\n", indent, target.Label)
 }
 
+// printDotHeader prints the start of a graph in dot format. It should be terminated by a closing brace.
+func printDotHeader(out io.Writer, name string) {
+	fmt.Fprintf(out, "digraph %s {\n", name)
+	fmt.Fprintf(out, "  fontname=\"Helvetica,Arial,sans-serif\"\n")
+	fmt.Fprintf(out, "  node [fontname=\"Helvetica,Arial,sans-serif\"]\n")
+	fmt.Fprintf(out, "  edge [fontname=\"Helvetica,Arial,sans-serif\"]\n")
+	fmt.Fprintf(out, "  rankdir=\"LR\"\n")
+}
+
 func printTargetDot(out io.Writer, target, parent *core.BuildTarget) {
 	fmt.Fprintf(out, "  subgraph \"%s\" {\n", target)
 	shape := "ellipse"
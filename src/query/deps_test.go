@@ -11,31 +11,21 @@ import (
 
 func TestQueryDeps(t *testing.T) {
 	state := core.NewDefaultBuildState()
-	pkg1 := core.NewPackage("tools/performance")
-	pkg2 := core.NewPackage("third_party/python")
+	graph := state.Graph
+	t1 := addGraphTarget(graph, "//third_party/python:_six#download")
+	t2 := addGraphTarget(graph, "//third_party/python:_six#wheel", t1)
+	t3 := addGraphTarget(graph, "//third_party/python:six", t2)
+	t4 := addGraphTarget(graph, "//third_party/python:_absl#download")
+	t5 := addGraphTarget(graph, "//third_party/python:_absl#wheel", t4, t3)
+	t6 := addGraphTarget(graph, "//third_party/python:absl", t5)
+	t7 := addGraphTarget(graph, "//third_party/python:_colorlog#download")
+	t8 := addGraphTarget(graph, "//third_party/python:_colorlog#wheel", t7)
+	t9 := addGraphTarget(graph, "//third_party/python:colorlog", t8)
 
-	addTarget := func(pkg *core.Package, name string, deps ...*core.BuildTarget) *core.BuildTarget {
-		t := addNewTarget(state.Graph, pkg, name, nil)
-		for _, dep := range deps {
-			t.AddDependency(dep.Label)
-		}
-		return t
-	}
-
-	t1 := addTarget(pkg2, "_six#download")
-	t2 := addTarget(pkg2, "_six#wheel", t1)
-	t3 := addTarget(pkg2, "six", t2)
-	t4 := addTarget(pkg2, "_absl#download")
-	t5 := addTarget(pkg2, "_absl#wheel", t4, t3)
-	t6 := addTarget(pkg2, "absl", t5)
-	t7 := addTarget(pkg2, "_colorlog#download")
-	t8 := addTarget(pkg2, "_colorlog#wheel", t7)
-	t9 := addTarget(pkg2, "colorlog", t8)
-
-	t10 := addTarget(pkg1, "_parse_perf_test#pex")
-	t11 := addTarget(pkg1, "_parse_perf_test#lib_zip")
-	t12 := addTarget(pkg1, "_parse_perf_test#lib", t11)
-	t13 := addTarget(pkg1, "parse_perf_test", t10, t12, t6, t9)
+	t10 := addGraphTarget(graph, "//tools/performance:_parse_perf_test#pex")
+	t11 := addGraphTarget(graph, "//tools/performance:_parse_perf_test#lib_zip")
+	t12 := addGraphTarget(graph, "//tools/performance:_parse_perf_test#lib", t11)
+	t13 := addGraphTarget(graph, "//tools/performance:parse_perf_test", t10, t12, t6, t9)
 	query := []core.BuildLabel{t13.Label}
 
 	t.Run("visible_level_1", func(t *testing.T) {
@@ -0,0 +1,550 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/parse"
+)
+
+// An Expression is a parsed graph query expression, for example
+//
+//	deps(//src/core:core) ^ rdeps(//..., //src/fs:fs) - filter("_test$", //src/...)
+//
+// Expressions are made up of build labels (which can include pseudo-targets like :all and /...),
+// calls to the functions in exprFunctions and the set operators + (or union), ^ (or intersect)
+// and - (or except). All the set operators have the same precedence and associate to the left;
+// parentheses can be used to group things differently.
+type Expression struct {
+	root   exprNode
+	labels []core.BuildLabel
+}
+
+// Labels returns all the build labels mentioned in this expression. These are what need to be
+// parsed before it can be evaluated.
+func (expr *Expression) Labels() []core.BuildLabel {
+	return expr.labels
+}
+
+// An exprFunction describes one of the functions available in an expression.
+type exprFunction struct {
+	// args describes the arguments it takes; e for an expression, w for a word (e.g. a regex)
+	// and d for an optional trailing depth.
+	args string
+	eval func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error)
+}
+
+var exprFunctions = map[string]exprFunction{
+	"deps": {args: "ed", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return e.deps(sets[0], depth), nil
+	}},
+	"rdeps": {args: "eed", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return e.rdeps(sets[0], sets[1], depth), nil
+	}},
+	"allpaths": {args: "ee", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return e.rdeps(sets[0], sets[1], -1), nil
+	}},
+	"kind": {args: "we", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return filterSet(sets[0], words[0], func(target *core.BuildTarget) []string {
+			return []string{targetKind(target)}
+		})
+	}},
+	"filter": {args: "we", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return filterSet(sets[0], words[0], func(target *core.BuildTarget) []string {
+			return []string{target.Label.String()}
+		})
+	}},
+	"attr": {args: "wwe", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return filterSet(sets[0], words[1], func(target *core.BuildTarget) []string {
+			return e.attr(target, words[0])
+		})
+	}},
+	"labels": {args: "we", eval: func(e *evaluator, sets []targetSet, words []string, depth int) (targetSet, error) {
+		return e.labels(sets[0], words[0]), nil
+	}},
+}
+
+// ParseExpression parses a query expression.
+// Any relative labels (i.e. :name) in it are taken to be relative to the given package.
+func ParseExpression(s, currentPackage string) (*Expression, error) {
+	tokens, err := tokeniseExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{tokens: tokens, pkg: currentPackage}
+	expr := &Expression{}
+	root, err := p.parseExpr(expr)
+	if err != nil {
+		return nil, err
+	} else if tok := p.peek(); tok.Type != exprEOF {
+		return nil, fmt.Errorf("Unexpected %s in expression", tok)
+	}
+	expr.root = root
+	return expr, nil
+}
+
+// Evaluate evaluates an expression against the build graph and prints the resulting targets.
+func Evaluate(out io.Writer, state *core.BuildState, expr *Expression, hidden bool) error {
+	result, err := EvaluateExpression(state, expr)
+	if err != nil {
+		return err
+	}
+	for _, label := range result {
+		if hidden || !label.IsHidden() {
+			fmt.Fprintf(out, "%s\n", label)
+		}
+	}
+	return nil
+}
+
+// EvaluateExpression evaluates an expression against the build graph and returns the labels of the resulting targets, in sorted order.
+func EvaluateExpression(state *core.BuildState, expr *Expression) (core.BuildLabels, error) {
+	e := &evaluator{state: state, graph: state.Graph}
+	set, err := e.eval(expr.root)
+	if err != nil {
+		return nil, err
+	}
+	return set.Labels(), nil
+}
+
+// A targetSet is the value that each part of an expression evaluates to.
+type targetSet map[*core.BuildTarget]struct{}
+
+// Labels returns the labels of all the targets in this set, in sorted order.
+func (s targetSet) Labels() core.BuildLabels {
+	ret := make(core.BuildLabels, 0, len(s))
+	for t := range s {
+		ret = append(ret, t.Label)
+	}
+	sort.Sort(ret)
+	return ret
+}
+
+func filterSet(set targetSet, pattern string, values func(*core.BuildTarget) []string) (targetSet, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	ret := targetSet{}
+	for t := range set {
+		for _, v := range values(t) {
+			if re.MatchString(v) {
+				ret[t] = struct{}{}
+				break
+			}
+		}
+	}
+	return ret, nil
+}
+
+// targetKind returns a description of what kind of target this is, for the kind() function.
+// We don't know what rule originally created a target so this is only a broad categorisation.
+func targetKind(target *core.BuildTarget) string {
+	if target.IsFilegroup {
+		return "filegroup"
+	} else if target.IsRemoteFile {
+		return "remote_file"
+	} else if target.IsTextFile {
+		return "text_file"
+	} else if target.IsSubrepo {
+		return "subrepo"
+	} else if target.IsTest() {
+		return "test"
+	} else if target.IsBinary {
+		return "binary"
+	}
+	return "rule"
+}
+
+type exprNode interface{}
+
+type labelNode struct {
+	Label core.BuildLabel
+}
+
+type setOpNode struct {
+	Op          string
+	Left, Right exprNode
+}
+
+type callNode struct {
+	Name  string
+	Args  []exprNode
+	Words []string
+	Depth int
+}
+
+// Set operators and their aliases.
+var exprOperators = map[string]string{
+	"+":         "+",
+	"union":     "+",
+	"^":         "^",
+	"intersect": "^",
+	"-":         "-",
+	"except":    "-",
+}
+
+type exprTokenType int
+
+const (
+	exprEOF exprTokenType = iota
+	exprWord
+	exprString
+	exprPunctuation
+)
+
+type exprToken struct {
+	Type  exprTokenType
+	Value string
+}
+
+func (tok exprToken) String() string {
+	if tok.Type == exprEOF {
+		return "end of input"
+	}
+	return strconv.Quote(tok.Value)
+}
+
+// tokeniseExpression splits an expression into tokens.
+// Words are anything separated by whitespace, parentheses or commas, so operators like - need to be
+// surrounded by spaces to distinguish them from part of a build label. Quoted strings can be used
+// for arguments that would otherwise be split up (e.g. regexes containing parentheses).
+func tokeniseExpression(s string) ([]exprToken, error) {
+	tokens := []exprToken{}
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		if unicode.IsSpace(c) {
+			i++
+		} else if c == '(' || c == ')' || c == ',' {
+			tokens = append(tokens, exprToken{Type: exprPunctuation, Value: string(c)})
+			i++
+		} else if c == '"' || c == '\'' {
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end == -1 {
+				return nil, fmt.Errorf("Unterminated string in expression: %s", s[i:])
+			}
+			tokens = append(tokens, exprToken{Type: exprString, Value: s[i+1 : i+1+end]})
+			i += end + 2
+		} else {
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && !strings.ContainsRune("(),\"'", rune(s[i])) {
+				i++
+			}
+			tokens = append(tokens, exprToken{Type: exprWord, Value: s[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []exprToken
+	pkg    string
+}
+
+func (p *exprParser) peek() exprToken {
+	if len(p.tokens) == 0 {
+		return exprToken{Type: exprEOF}
+	}
+	return p.tokens[0]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.peek()
+	if len(p.tokens) > 0 {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+func (p *exprParser) expect(punctuation string) error {
+	if tok := p.next(); tok.Type != exprPunctuation || tok.Value != punctuation {
+		return fmt.Errorf("Expected %q in expression, got %s", punctuation, tok)
+	}
+	return nil
+}
+
+// parseExpr parses a sequence of terms separated by set operators.
+func (p *exprParser) parseExpr(expr *Expression) (exprNode, error) {
+	left, err := p.parseTerm(expr)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		op, present := exprOperators[tok.Value]
+		if tok.Type != exprWord || !present {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTerm(expr)
+		if err != nil {
+			return nil, err
+		}
+		left = &setOpNode{Op: op, Left: left, Right: right}
+	}
+}
+
+// parseTerm parses a single build label, function call or parenthesised expression.
+func (p *exprParser) parseTerm(expr *Expression) (exprNode, error) {
+	tok := p.next()
+	if tok.Type == exprPunctuation && tok.Value == "(" {
+		node, err := p.parseExpr(expr)
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	} else if tok.Type != exprWord && tok.Type != exprString {
+		return nil, fmt.Errorf("Unexpected %s in expression", tok)
+	} else if next := p.peek(); next.Type == exprPunctuation && next.Value == "(" && tok.Type == exprWord {
+		return p.parseCall(expr, tok.Value)
+	}
+	label, err := core.TryParseBuildLabel(tok.Value, p.pkg, "")
+	if err != nil {
+		return nil, err
+	}
+	expr.labels = append(expr.labels, label)
+	return &labelNode{Label: label}, nil
+}
+
+// parseCall parses the arguments to a function call.
+func (p *exprParser) parseCall(expr *Expression, name string) (exprNode, error) {
+	f, present := exprFunctions[name]
+	if !present {
+		return nil, fmt.Errorf("Unknown function %s", name)
+	}
+	p.next() // the opening paren
+	call := &callNode{Name: name, Depth: -1}
+	for i, arg := range f.args {
+		if i > 0 {
+			if tok := p.peek(); arg == 'd' && tok.Type == exprPunctuation && tok.Value == ")" {
+				break
+			} else if err := p.expect(","); err != nil {
+				return nil, fmt.Errorf("Not enough arguments to %s: %s", name, err)
+			}
+		}
+		switch arg {
+		case 'e':
+			node, err := p.parseExpr(expr)
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, node)
+		case 'w', 'd':
+			tok := p.next()
+			if tok.Type != exprWord && tok.Type != exprString {
+				return nil, fmt.Errorf("Unexpected %s in arguments to %s", tok, name)
+			} else if arg == 'w' {
+				call.Words = append(call.Words, tok.Value)
+			} else if depth, err := strconv.Atoi(tok.Value); err != nil {
+				return nil, fmt.Errorf("Invalid depth %s in arguments to %s", tok, name)
+			} else {
+				call.Depth = depth
+			}
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, fmt.Errorf("Too many arguments to %s: %s", name, err)
+	}
+	return call, nil
+}
+
+// An evaluator evaluates parsed expressions against the build graph.
+type evaluator struct {
+	state *core.BuildState
+	graph *core.BuildGraph
+	order map[string]int
+}
+
+func (e *evaluator) eval(node exprNode) (targetSet, error) {
+	switch node := node.(type) {
+	case *labelNode:
+		return e.resolve(node.Label)
+	case *setOpNode:
+		left, err := e.eval(node.Left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := e.eval(node.Right)
+		if err != nil {
+			return nil, err
+		}
+		return setOp(node.Op, left, right), nil
+	case *callNode:
+		sets := make([]targetSet, len(node.Args))
+		for i, arg := range node.Args {
+			set, err := e.eval(arg)
+			if err != nil {
+				return nil, err
+			}
+			sets[i] = set
+		}
+		return exprFunctions[node.Name].eval(e, sets, node.Words, node.Depth)
+	}
+	return nil, fmt.Errorf("Unknown expression node %T", node)
+}
+
+// resolve returns the set of targets that a build label refers to.
+func (e *evaluator) resolve(label core.BuildLabel) (targetSet, error) {
+	ret := targetSet{}
+	if label.IsPseudoTarget() {
+		for _, l := range e.state.ExpandLabels([]core.BuildLabel{label}) {
+			ret[e.graph.TargetOrDie(l)] = struct{}{}
+		}
+		return ret, nil
+	}
+	t := e.graph.Target(label)
+	if t == nil {
+		return nil, fmt.Errorf("Unknown target %s", label)
+	}
+	ret[t] = struct{}{}
+	return ret, nil
+}
+
+func setOp(op string, left, right targetSet) targetSet {
+	ret := targetSet{}
+	switch op {
+	case "+":
+		for t := range left {
+			ret[t] = struct{}{}
+		}
+		for t := range right {
+			ret[t] = struct{}{}
+		}
+	case "^":
+		for t := range left {
+			if _, present := right[t]; present {
+				ret[t] = struct{}{}
+			}
+		}
+	case "-":
+		for t := range left {
+			if _, present := right[t]; !present {
+				ret[t] = struct{}{}
+			}
+		}
+	}
+	return ret
+}
+
+// dependencies returns the direct dependencies of a target, as per the deps query.
+func (e *evaluator) dependencies(target *core.BuildTarget) []*core.BuildTarget {
+	var ret []*core.BuildTarget
+	for _, l := range target.DeclaredDependencies() {
+		dep := e.graph.Target(l)
+		if dep == nil || !e.state.ShouldInclude(dep) {
+			continue
+		}
+		for _, l := range dep.ProvideFor(target) {
+			ret = append(ret, e.graph.TargetOrDie(l))
+		}
+	}
+	return ret
+}
+
+// edgeCost returns how much an edge from target to dep counts towards the depth of a query.
+// As with the deps query, hidden dependencies of a target don't count as a separate level.
+func edgeCost(target, dep *core.BuildTarget) int {
+	if dep.HasParent() && dep.Label.Parent() == target.Label.Parent() {
+		return 0
+	}
+	return 1
+}
+
+// deps returns the given targets and their transitive dependencies, up to the given depth (or all of them if it's negative).
+func (e *evaluator) deps(set targetSet, depth int) targetSet {
+	return e.walk(set, depth, func(target *core.BuildTarget, f func(from, to *core.BuildTarget)) {
+		for _, dep := range e.dependencies(target) {
+			f(target, dep)
+		}
+	})
+}
+
+// rdeps returns the given targets and their transitive reverse dependencies within the transitive closure of the universe,
+// up to the given depth (or all of them if it's negative).
+func (e *evaluator) rdeps(universe, set targetSet, depth int) targetSet {
+	universe = e.deps(universe, -1)
+	revdeps := map[*core.BuildTarget][]*core.BuildTarget{}
+	for t := range universe {
+		for _, dep := range e.dependencies(t) {
+			revdeps[dep] = append(revdeps[dep], t)
+		}
+	}
+	return e.walk(setOp("^", set, universe), depth, func(target *core.BuildTarget, f func(from, to *core.BuildTarget)) {
+		for _, rdep := range revdeps[target] {
+			f(rdep, target)
+		}
+	})
+}
+
+// walk walks the graph from the given set of targets using the given function to find their neighbours.
+func (e *evaluator) walk(set targetSet, depth int, neighbours func(*core.BuildTarget, func(from, to *core.BuildTarget))) targetSet {
+	levels := make(map[*core.BuildTarget]int, len(set))
+	queue := make([]*core.BuildTarget, 0, len(set))
+	for t := range set {
+		levels[t] = 0
+		queue = append(queue, t)
+	}
+	for len(queue) > 0 {
+		target := queue[0]
+		queue = queue[1:]
+		level := levels[target]
+		neighbours(target, func(from, to *core.BuildTarget) {
+			next := to
+			if next == target {
+				next = from
+			}
+			l := level + edgeCost(from, to)
+			if depth >= 0 && l > depth {
+				return
+			} else if existing, present := levels[next]; present && existing <= l {
+				return
+			}
+			levels[next] = l
+			queue = append(queue, next)
+		})
+	}
+	ret := make(targetSet, len(levels))
+	for t := range levels {
+		ret[t] = struct{}{}
+	}
+	return ret
+}
+
+// attr returns the values of the given attribute of a target, one per line as they'd be printed by plz query print --field.
+func (e *evaluator) attr(target *core.BuildTarget, name string) []string {
+	if e.order == nil {
+		e.order = parse.BuildRuleArgOrder(e.state)
+	}
+	var buf bytes.Buffer
+	newPrinter(&buf, target, 0, e.order).PrintFields([]string{name})
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+// labels returns the targets referred to by the given attribute of the given targets.
+func (e *evaluator) labels(set targetSet, name string) targetSet {
+	ret := targetSet{}
+	for t := range set {
+		for _, value := range e.attr(t, name) {
+			// Named attributes are printed as name: value
+			if idx := strings.Index(value, ": "); idx != -1 && !core.LooksLikeABuildLabel(value) {
+				value = value[idx+2:]
+			}
+			value, _ = core.SplitLabelAnnotation(value)
+			if !core.LooksLikeABuildLabel(value) {
+				continue
+			} else if label, err := core.TryParseBuildLabel(value, t.Label.PackageName, t.Label.Subrepo); err == nil {
+				if dep := e.graph.Target(label); dep != nil {
+					ret[dep] = struct{}{}
+				}
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,106 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestParseExpression(t *testing.T) {
+	expr, err := ParseExpression("deps(//src/core:core, 2) ^ rdeps(//..., :fs) - filter('_test$', //src/...)", "src/fs")
+	require.NoError(t, err)
+	assert.Equal(t, []core.BuildLabel{
+		core.ParseBuildLabel("//src/core:core", ""),
+		core.ParseBuildLabel("//...", ""),
+		core.ParseBuildLabel("//src/fs:fs", ""),
+		core.ParseBuildLabel("//src/...", ""),
+	}, expr.Labels())
+	assert.Equal(t, &setOpNode{
+		Op: "-",
+		Left: &setOpNode{
+			Op:    "^",
+			Left:  &callNode{Name: "deps", Args: []exprNode{&labelNode{Label: core.ParseBuildLabel("//src/core:core", "")}}, Depth: 2},
+			Right: &callNode{Name: "rdeps", Args: []exprNode{&labelNode{Label: core.ParseBuildLabel("//...", "")}, &labelNode{Label: core.ParseBuildLabel("//src/fs:fs", "")}}, Depth: -1},
+		},
+		Right: &callNode{Name: "filter", Args: []exprNode{&labelNode{Label: core.ParseBuildLabel("//src/...", "")}}, Words: []string{"_test$"}, Depth: -1},
+	}, expr.root)
+}
+
+func TestParseExpressionErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"//src/core:core +",
+		"deps(//src/core:core",
+		"deps(//src/core:core, 1, 2)",
+		"wibble(//src/core:core)",
+		"allpaths(//src/core:core)",
+		"deps(//src/core:core, x)",
+		"filter('_test$, //src/...)",
+		"(//src/core:core",
+		"//src/core:core //src/fs:fs",
+		"not_a_label",
+	} {
+		t.Run(s, func(t *testing.T) {
+			_, err := ParseExpression(s, "")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEvaluateExpression(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	graph := state.Graph
+	cli := addGraphTarget(graph, "//src/cli:cli")
+	fs := addGraphTarget(graph, "//src/fs:fs")
+	fsTest := addGraphTarget(graph, "//src/fs:fs_test", fs)
+	fsTest.Test = &core.TestFields{}
+	coreLib := addGraphTarget(graph, "//src/core:_core#lib", fs, cli)
+	core1 := addGraphTarget(graph, "//src/core:core", coreLib)
+	coreLib.AddLabel("go")
+	coreTest := addGraphTarget(graph, "//src/core:core_test", core1)
+	coreTest.Test = &core.TestFields{}
+
+	eval := func(s string) string {
+		expr, err := ParseExpression(s, "src/core")
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, Evaluate(&buf, state, expr, false))
+		return buf.String()
+	}
+
+	assert.Equal(t, "//src/cli:cli\n//src/core:core\n//src/fs:fs\n", eval("deps(:core)"))
+	// The hidden target doesn't count as a separate level.
+	assert.Equal(t, "//src/cli:cli\n//src/core:core\n//src/fs:fs\n", eval("deps(:core, 1)"))
+	assert.Equal(t, "//src/core:core\n", eval("deps(:core, 0)"))
+	assert.Equal(t, "//src/core:core\n//src/core:core_test\n//src/fs:fs\n//src/fs:fs_test\n", eval("rdeps(//..., //src/fs:fs)"))
+	assert.Equal(t, "//src/core:core\n//src/fs:fs\n//src/fs:fs_test\n", eval("rdeps(//..., //src/fs:fs, 1)"))
+	assert.Equal(t, "//src/core:core\n//src/core:core_test\n//src/fs:fs\n", eval("allpaths(:core_test, //src/fs:fs)"))
+	assert.Equal(t, "", eval("allpaths(//src/fs:fs, :core_test)"))
+	assert.Equal(t, "//src/core:core_test\n//src/fs:fs_test\n", eval("kind(test, //...)"))
+	assert.Equal(t, "//src/core:core_test\n//src/fs:fs_test\n", eval("filter('_test$', //...)"))
+	assert.Equal(t, "//src/cli:cli\n//src/core:core\n", eval("deps(:core) - //src/fs:all"))
+	assert.Equal(t, "//src/cli:cli\n//src/core:core\n", eval("deps(:core) except //src/fs:all"))
+	assert.Equal(t, "//src/fs:fs\n", eval("deps(:core) ^ //src/fs:all"))
+	assert.Equal(t, "//src/cli:cli\n//src/fs:fs\n", eval("//src/cli:cli + //src/fs:fs"))
+	assert.Equal(t, "//src/fs:fs\n", eval("//src/cli:cli + //src/fs:fs - //src/cli:cli"))
+	assert.Equal(t, "//src/cli:cli\n//src/fs:fs\n", eval("//src/cli:cli + (//src/fs:fs - //src/cli:cli)"))
+	assert.Equal(t, "//src/cli:cli\n//src/core:core\n//src/fs:fs\n", eval("labels(deps, //src/core:all)"))
+	assert.Equal(t, "//src/fs:fs\n", eval("labels(deps, //src/fs:all)"))
+	assert.Equal(t, "", eval("attr(labels, go, //...)"))
+	assert.Equal(t, "//src/core:core\n", eval("rdeps(//..., attr(labels, go, //...), 1) - filter(test, //...)"))
+
+	expr, err := ParseExpression("attr(labels, go, //...)", "")
+	require.NoError(t, err)
+	labels, err := EvaluateExpression(state, expr)
+	require.NoError(t, err)
+	assert.Equal(t, core.BuildLabels{coreLib.Label}, labels)
+
+	expr, err = ParseExpression("deps(//src/core:wibble)", "")
+	require.NoError(t, err)
+	_, err = EvaluateExpression(state, expr)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package query
 import (
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
@@ -27,6 +28,24 @@ func Graph(state *core.BuildState, targets []core.BuildLabel) {
 	log.Notice("Done")
 }
 
+// WriteSnapshot writes a snapshot of the whole parsed build graph to the given file.
+// It can be loaded again much faster than reparsing, using `plz query --from_snapshot`.
+func WriteSnapshot(state *core.BuildState, filename string) error {
+	// Calculate the rule hashes now so they're stored on the targets & included in the snapshot.
+	for _, target := range state.Graph.AllTargets() {
+		build.RuleHash(state, target, false, false)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := core.WriteGraphSnapshot(f, state.Graph); err != nil {
+		return fmt.Errorf("Failed to write graph snapshot: %w", err)
+	}
+	return f.Close()
+}
+
 // JSONGraph is an alternate representation of our build graph; will contain different information
 // to the structures in core (also those ones can't be printed as JSON directly).
 type JSONGraph struct {
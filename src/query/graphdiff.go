@@ -0,0 +1,187 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/parse"
+)
+
+// A GraphDiff describes the differences between two build graphs.
+type GraphDiff struct {
+	Added       core.BuildLabels `json:"added,omitempty"`
+	Removed     core.BuildLabels `json:"removed,omitempty"`
+	Changed     []TargetDiff     `json:"changed,omitempty"`
+	AddedDeps   []DependencyEdge `json:"added_deps,omitempty"`
+	RemovedDeps []DependencyEdge `json:"removed_deps,omitempty"`
+}
+
+// A TargetDiff describes the attributes of a single target that differ between two graphs.
+type TargetDiff struct {
+	Label  core.BuildLabel `json:"label"`
+	Fields []FieldDiff     `json:"fields"`
+}
+
+// A FieldDiff is a single attribute of a target that has changed.
+// Either value is omitted if the attribute wasn't set at all.
+type FieldDiff struct {
+	Name   string          `json:"name"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// A DependencyEdge is a direct dependency of one target on another.
+type DependencyEdge struct {
+	From core.BuildLabel `json:"from"`
+	To   core.BuildLabel `json:"to"`
+}
+
+// GraphDiffs prints the differences between two build graphs; the targets that have been added or removed,
+// the attributes that have changed on each target, and the dependencies that have been added or removed.
+func GraphDiffs(out io.Writer, before, after *core.BuildState, hidden, outputJSON bool) {
+	diff := DiffGraph(before, after, hidden)
+	if outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(diff); err != nil {
+			log.Fatalf("Failed to encode JSON: %s", err)
+		}
+		return
+	}
+	printLabels := func(title string, labels core.BuildLabels) {
+		if len(labels) > 0 {
+			fmt.Fprintf(out, "%s:\n", title)
+			for _, l := range labels {
+				fmt.Fprintf(out, "  %s\n", l)
+			}
+		}
+	}
+	printEdges := func(title string, edges []DependencyEdge) {
+		if len(edges) > 0 {
+			fmt.Fprintf(out, "%s:\n", title)
+			for _, e := range edges {
+				fmt.Fprintf(out, "  %s -> %s\n", e.From, e.To)
+			}
+		}
+	}
+	printLabels("Added targets", diff.Added)
+	printLabels("Removed targets", diff.Removed)
+	if len(diff.Changed) > 0 {
+		fmt.Fprintf(out, "Changed targets:\n")
+		for _, t := range diff.Changed {
+			fmt.Fprintf(out, "  %s\n", t.Label)
+			for _, f := range t.Fields {
+				fmt.Fprintf(out, "    %s: %s -> %s\n", f.Name, fieldDiffValue(f.Before), fieldDiffValue(f.After))
+			}
+		}
+	}
+	printEdges("Added dependencies", diff.AddedDeps)
+	printEdges("Removed dependencies", diff.RemovedDeps)
+}
+
+func fieldDiffValue(value json.RawMessage) string {
+	if value == nil {
+		return "(unset)"
+	}
+	return string(value)
+}
+
+// DiffGraph calculates the differences between two build graphs. Unless hidden is true, hidden targets
+// are omitted and dependencies to or from them are attributed to their parents instead.
+func DiffGraph(before, after *core.BuildState, hidden bool) *GraphDiff {
+	diff := &GraphDiff{}
+	beforeOrder := parse.BuildRuleArgOrder(before)
+	afterOrder := parse.BuildRuleArgOrder(after)
+	for _, t := range after.Graph.AllTargets() {
+		if !hidden && t.Label.IsHidden() {
+			continue
+		} else if b := before.Graph.Target(t.Label); b == nil {
+			diff.Added = append(diff.Added, t.Label)
+		} else if fields := diffTargetFields(targetToValueMap(beforeOrder, nil, b), targetToValueMap(afterOrder, nil, t)); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, TargetDiff{Label: t.Label, Fields: fields})
+		}
+	}
+	for _, t := range before.Graph.AllTargets() {
+		if (hidden || !t.Label.IsHidden()) && after.Graph.Target(t.Label) == nil {
+			diff.Removed = append(diff.Removed, t.Label)
+		}
+	}
+	beforeEdges := dependencyEdges(before.Graph, hidden)
+	afterEdges := dependencyEdges(after.Graph, hidden)
+	for e := range afterEdges {
+		if _, present := beforeEdges[e]; !present {
+			diff.AddedDeps = append(diff.AddedDeps, e)
+		}
+	}
+	for e := range beforeEdges {
+		if _, present := afterEdges[e]; !present {
+			diff.RemovedDeps = append(diff.RemovedDeps, e)
+		}
+	}
+	sortDependencyEdges(diff.AddedDeps)
+	sortDependencyEdges(diff.RemovedDeps)
+	return diff
+}
+
+// diffTargetFields returns the fields that differ between two targets' value maps, sorted by name.
+func diffTargetFields(before, after map[string]interface{}) []FieldDiff {
+	var ret []FieldDiff
+	marshal := func(values map[string]interface{}, name string) json.RawMessage {
+		value, present := values[name]
+		if !present || value == nil || isZero(reflect.ValueOf(value)) {
+			return nil
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			log.Fatalf("Failed to encode %s: %s", name, err)
+		}
+		return b
+	}
+	names := make(map[string]struct{}, len(after))
+	for name := range before {
+		names[name] = struct{}{}
+	}
+	for name := range after {
+		names[name] = struct{}{}
+	}
+	for name := range names {
+		if b, a := marshal(before, name), marshal(after, name); !bytes.Equal(b, a) {
+			ret = append(ret, FieldDiff{Name: name, Before: b, After: a})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// dependencyEdges returns all the declared dependency edges in a graph.
+func dependencyEdges(graph *core.BuildGraph, hidden bool) map[DependencyEdge]struct{} {
+	edges := map[DependencyEdge]struct{}{}
+	for _, t := range graph.AllTargets() {
+		for _, dep := range t.DeclaredDependencies() {
+			e := DependencyEdge{From: t.Label, To: dep}
+			if !hidden {
+				e = DependencyEdge{From: t.Label.Parent(), To: dep.Parent()}
+				if e.From == e.To {
+					continue // Internal to a single rule.
+				}
+			}
+			edges[e] = struct{}{}
+		}
+	}
+	return edges
+}
+
+func sortDependencyEdges(edges []DependencyEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From.Less(edges[j].From)
+		}
+		return edges[i].To.Less(edges[j].To)
+	})
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func makeGraphDiffStates() (*core.BuildState, *core.BuildState) {
+	before := core.NewDefaultBuildState()
+	lib := addGraphTarget(before.Graph, "//src/query:lib")
+	lib.Command = "build lib"
+	old := addGraphTarget(before.Graph, "//src/query:old")
+	addGraphTarget(before.Graph, "//src/query:bin", old, addGraphTarget(before.Graph, "//src/query:_bin#srcs"))
+
+	after := core.NewDefaultBuildState()
+	lib = addGraphTarget(after.Graph, "//src/query:lib")
+	lib.Command = "build lib faster"
+	lib.AddLabel("fast")
+	srcs := addGraphTarget(after.Graph, "//src/query:_bin#srcs", addGraphTarget(after.Graph, "//src/query:new"))
+	addGraphTarget(after.Graph, "//src/query:bin", lib, srcs)
+	return before, after
+}
+
+func TestDiffGraph(t *testing.T) {
+	before, after := makeGraphDiffStates()
+	diff := DiffGraph(before, after, false)
+	label := func(name string) core.BuildLabel {
+		return core.NewBuildLabel("src/query", name)
+	}
+	assert.Equal(t, core.BuildLabels{label("new")}, diff.Added)
+	assert.Equal(t, core.BuildLabels{label("old")}, diff.Removed)
+	assert.Equal(t, []TargetDiff{
+		{Label: label("bin"), Fields: []FieldDiff{{Name: "deps", Before: json.RawMessage(`["//src/query:_bin#srcs","//src/query:old"]`), After: json.RawMessage(`["//src/query:_bin#srcs","//src/query:lib"]`)}}},
+		{Label: label("lib"), Fields: []FieldDiff{
+			{Name: "cmd", Before: json.RawMessage(`"build lib"`), After: json.RawMessage(`"build lib faster"`)},
+			{Name: "labels", After: json.RawMessage(`["fast"]`)},
+		}},
+	}, diff.Changed)
+	// The dependency of the hidden target is attributed to its parent.
+	assert.Equal(t, []DependencyEdge{
+		{From: label("bin"), To: label("lib")},
+		{From: label("bin"), To: label("new")},
+	}, diff.AddedDeps)
+	assert.Equal(t, []DependencyEdge{{From: label("bin"), To: label("old")}}, diff.RemovedDeps)
+}
+
+func TestDiffGraphHidden(t *testing.T) {
+	before, after := makeGraphDiffStates()
+	diff := DiffGraph(before, after, true)
+	assert.Equal(t, []DependencyEdge{
+		{From: core.NewBuildLabel("src/query", "_bin#srcs"), To: core.NewBuildLabel("src/query", "new")},
+		{From: core.NewBuildLabel("src/query", "bin"), To: core.NewBuildLabel("src/query", "lib")},
+	}, diff.AddedDeps)
+}
+
+func TestGraphDiffsOutput(t *testing.T) {
+	before, after := makeGraphDiffStates()
+	var buf bytes.Buffer
+	GraphDiffs(&buf, before, after, false, false)
+	assert.Equal(t, `Added targets:
+  //src/query:new
+Removed targets:
+  //src/query:old
+Changed targets:
+  //src/query:bin
+    deps: ["//src/query:_bin#srcs","//src/query:old"] -> ["//src/query:_bin#srcs","//src/query:lib"]
+  //src/query:lib
+    cmd: "build lib" -> "build lib faster"
+    labels: (unset) -> ["fast"]
+Added dependencies:
+  //src/query:bin -> //src/query:lib
+  //src/query:bin -> //src/query:new
+Removed dependencies:
+  //src/query:bin -> //src/query:old
+`, buf.String())
+}
@@ -0,0 +1,55 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// Policy prints all the dependencies of the given targets that match a rule of the repo's dependency policy,
+// including those that are only reported or are allowed by an exception. If reportUnused is true it also prints
+// any exceptions that no longer allow anything, which only makes sense when auditing the whole graph.
+// It returns the number of violations that would fail the build.
+func Policy(out io.Writer, graph *core.BuildGraph, policy *core.Policy, labels []core.BuildLabel, reportUnused bool) int {
+	violations := []*core.PolicyViolation{}
+	for _, label := range labels {
+		target := graph.TargetOrDie(label)
+		for _, dep := range target.DeclaredDependencies() {
+			violations = append(violations, policy.Check(target.Label, dep)...)
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].From != violations[j].From {
+			return violations[i].From.Less(violations[j].From)
+		}
+		return violations[i].To.Less(violations[j].To)
+	})
+	denied := 0
+	used := map[string]bool{}
+	for _, v := range violations {
+		if v.Exception != "" {
+			used[v.Exception] = true
+			fmt.Fprintf(out, "%s -> %s: allowed by exception %s to rule %s (%s)\n", v.From, v.To, v.Exception, v.Rule, v.Justification)
+		} else if v.Report {
+			fmt.Fprintf(out, "%s -> %s: reported by rule %s\n", v.From, v.To, v.Rule)
+		} else {
+			fmt.Fprintf(out, "%s -> %s: denied by rule %s\n", v.From, v.To, v.Rule)
+			denied++
+		}
+	}
+	if reportUnused {
+		names := make([]string, 0, len(policy.Exception))
+		for name := range policy.Exception {
+			if !used[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "Exception %s is unused\n", name)
+		}
+	}
+	return denied
+}
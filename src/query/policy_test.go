@@ -0,0 +1,36 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestPolicy(t *testing.T) {
+	policy, err := core.LoadPolicy("src/query/test_data/policy.plzpolicy")
+	require.NoError(t, err)
+
+	graph := core.NewGraph()
+	codegen := addGraphTarget(graph, "//tools/internal:codegen")
+	app := addGraphTarget(graph, "//app:main")
+	addGraphTarget(graph, "//services/legacy:migrator", codegen)
+	addGraphTarget(graph, "//services/legacy:server", codegen)
+	addGraphTarget(graph, "//lib:lib", app)
+
+	var buf bytes.Buffer
+	labels := []core.BuildLabel{}
+	for _, target := range graph.AllTargets() {
+		labels = append(labels, target.Label)
+	}
+	denied := Policy(&buf, graph, policy, labels, true)
+	assert.Equal(t, 1, denied)
+	assert.Equal(t, `//lib:lib -> //app:main: reported by rule layering
+//services/legacy:migrator -> //tools/internal:codegen: allowed by exception legacy-migrator to rule services-internal-tools (Needed until the legacy migration is finished.)
+//services/legacy:server -> //tools/internal:codegen: denied by rule services-internal-tools
+Exception old-server is unused
+`, buf.String())
+}
@@ -224,6 +224,12 @@ func fields(structValue reflect.Value, fieldOrder map[string]int) orderedFields
 
 // PrintTarget prints an entire build target.
 func (p *printer) PrintTarget() {
+	for i, line := range p.target.Provenance() {
+		if i > 0 {
+			line = "  " + line
+		}
+		p.printf("# %s\n", line)
+	}
 	if p.target.IsFilegroup {
 		p.printf("filegroup(\n")
 	} else if p.target.IsRemoteFile {
@@ -233,6 +239,8 @@ func (p *printer) PrintTarget() {
 	}
 	p.surroundSyntax = true
 	p.indent += 4
+	// This isn't an argument to build_rule, so it's only printed as a comment above.
+	p.doneFields["provenance"] = true
 	fs := fields(reflect.ValueOf(p.target).Elem(), p.fieldOrder)
 
 	if p.target.IsTest() {
@@ -338,6 +346,8 @@ func (p *printer) maybePrintField(f reflect.StructField, v reflect.Value) (strin
 	}
 	if customFunc, present := p.specialFields[name]; present {
 		return p.genericPrint(reflect.ValueOf(customFunc(p.target)))
+	} else if f.Tag.Get("print") == "omitempty" && isZero(v) { // Indicates not to print the field if it's unset.
+		return "", false
 	}
 	return p.genericPrint(v)
 }
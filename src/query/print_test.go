@@ -130,6 +130,21 @@ func TestTestOutput(t *testing.T) {
 	assert.Equal(t, expected, s)
 }
 
+func TestShardedTestOutput(t *testing.T) {
+	target := core.NewBuildTarget(core.ParseBuildLabel("//src/query:test_sharded_test_output", ""))
+	target.Test = &core.TestFields{Shards: 4}
+	s := testPrint(target)
+	expected := `  build_rule(
+      name = 'test_sharded_test_output',
+      test = True,
+      flaky = 0,
+      test_shards = 4,
+  )
+
+`
+	assert.Equal(t, expected, s)
+}
+
 type postBuildFunction struct{}
 
 func (f postBuildFunction) Call(target *core.BuildTarget, output string) error { return nil }
@@ -179,6 +194,26 @@ func TestPrintNamedSourcesField(t *testing.T) {
 	assert.Equal(t, "foo: file1\n", s)
 }
 
+func TestPrintProvenance(t *testing.T) {
+	target := core.NewBuildTarget(core.ParseBuildLabel("//src/query:_lib#srcs", ""))
+	target.CallStack = []core.CallSite{
+		{Function: "build_rule", Filename: "build_defs/lib.build_defs", Line: 2},
+		{Function: "_srcs", Filename: "build_defs/lib.build_defs", Line: 8},
+		{Function: "my_library", Filename: "src/query/BUILD", Line: 5},
+	}
+	assert.Equal(t, `  # declared by my_library() at src/query/BUILD:5
+  #   via _srcs() at build_defs/lib.build_defs:8
+  build_rule(
+      name = '_lib#srcs',
+  )
+
+`, testPrint(target))
+	assert.Equal(t, `build_rule() at build_defs/lib.build_defs:2
+_srcs() at build_defs/lib.build_defs:8
+my_library() at src/query/BUILD:5
+`, testPrintFields(target, []string{"provenance"}))
+}
+
 func testPrint(target *core.BuildTarget) string {
 	var buf bytes.Buffer
 	newPrinter(&buf, target, 2, order).PrintTarget()
@@ -3,6 +3,9 @@ package query
 import (
 	"container/list"
 	"fmt"
+	"io"
+	"math/bits"
+	"slices"
 	"sort"
 
 	"github.com/thought-machine/please/src/core"
@@ -86,11 +89,7 @@ func newRevdeps(graph *core.BuildGraph, hidden, followSubincludes, includeSubrep
 	// dependencies efficiently later
 	subincludes := make(map[core.BuildLabel][]*core.Package)
 	if followSubincludes {
-		for _, pkg := range graph.PackageMap() {
-			for _, inc := range pkg.Subincludes {
-				subincludes[inc] = append(subincludes[inc], pkg)
-			}
-		}
+		subincludes = buildSubincludes(graph)
 	}
 
 	return &revdeps{
@@ -106,6 +105,17 @@ func newRevdeps(graph *core.BuildGraph, hidden, followSubincludes, includeSubrep
 	}
 }
 
+// buildSubincludes builds a map of labels to the packages that subinclude them.
+func buildSubincludes(graph *core.BuildGraph) map[core.BuildLabel][]*core.Package {
+	subincludes := make(map[core.BuildLabel][]*core.Package)
+	for _, pkg := range graph.PackageMap() {
+		for _, inc := range pkg.Subincludes {
+			subincludes[inc] = append(subincludes[inc], pkg)
+		}
+	}
+	return subincludes
+}
+
 // buildRevdeps builds the reverse dependency map from a build graph.
 func buildRevdeps(graph *core.BuildGraph, includeSubrepos bool) map[core.BuildLabel][]*core.BuildTarget {
 	targets := graph.AllTargets()
@@ -207,3 +217,227 @@ func (r *revdeps) findRevdeps(state *core.BuildState) map[*core.BuildTarget]stru
 	}
 	return ret
 }
+
+// A RevdepCount is the number of transitive reverse dependencies of a target, broken down by their kind.
+type RevdepCount struct {
+	Label     core.BuildLabel
+	Total     int
+	Tests     int
+	Binaries  int
+	Libraries int
+}
+
+// ReverseDepCounts prints the number of transitive reverse dependencies of each of the given targets,
+// most depended-upon first. If top is positive only that many targets are printed.
+func ReverseDepCounts(out io.Writer, state *core.BuildState, labels []core.BuildLabel, top int, hidden bool) {
+	counts := CountRevdeps(state.Graph, labels, hidden)
+	fmt.Fprintf(out, "%10s %10s %10s %10s  %s\n", "Dependents", "Tests", "Binaries", "Libraries", "Target")
+	printed := 0
+	for _, c := range counts {
+		if !state.ShouldInclude(state.Graph.TargetOrDie(c.Label)) {
+			continue
+		} else if top > 0 && printed == top {
+			break
+		}
+		printed++
+		fmt.Fprintf(out, "%10d %10d %10d %10d  %s\n", c.Total, c.Tests, c.Binaries, c.Libraries, c.Label)
+	}
+}
+
+// CountRevdeps counts the transitive reverse dependencies of each of the given targets, sorted by the
+// total number, highest first. Tests are counted as tests even though they are also binaries.
+// Unless hidden is true, hidden targets are counted as their parents.
+//
+// Rather than searching from each target in turn, this visits each reverse dependency once and accumulates
+// the set of its transitive dependents from those of its direct ones, which is much faster when counting
+// many targets, e.g. all those in a subtree.
+func CountRevdeps(graph *core.BuildGraph, labels []core.BuildLabel, hidden bool) []RevdepCount {
+	c := &revdepCounter{
+		graph:       graph,
+		hidden:      hidden,
+		revdeps:     buildRevdeps(graph, true),
+		subincludes: buildSubincludes(graph),
+		children:    map[*core.BuildTarget][]*core.BuildTarget{},
+		indices:     map[*core.BuildTarget]int{},
+	}
+	if !hidden {
+		for _, t := range graph.AllTargets() {
+			if n := c.node(t); n != t {
+				c.children[n] = append(c.children[n], t)
+			}
+		}
+	}
+	var roots []int
+	for _, l := range labels {
+		idx := c.add(c.node(graph.TargetOrDie(l)))
+		if !slices.Contains(roots, idx) {
+			roots = append(roots, idx)
+		}
+	}
+	// Discover all the reverse dependencies; new ones get appended to c.targets as we go.
+	for i := 0; i < len(c.targets); i++ {
+		c.dependents = append(c.dependents, c.findDependents(i))
+	}
+	// Work out how many times each set will be needed so we can free them once they aren't.
+	c.refs = make([]int, len(c.targets))
+	for _, deps := range c.dependents {
+		for _, d := range deps {
+			c.refs[d]++
+		}
+	}
+	n := len(c.targets)
+	c.sets = make([]revdepSet, n)
+	c.state = make([]int, n)
+	c.tests = newRevdepSet(n)
+	c.binaries = newRevdepSet(n)
+	for i, t := range c.targets {
+		if t.IsTest() {
+			c.tests.Set(i)
+		} else if t.IsBinary {
+			c.binaries.Set(i)
+		}
+	}
+	c.counts = make([]RevdepCount, n)
+	for _, root := range roots {
+		c.compute(root)
+	}
+	ret := make([]RevdepCount, len(roots))
+	for i, root := range roots {
+		ret[i] = c.counts[root]
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Total != ret[j].Total {
+			return ret[i].Total > ret[j].Total
+		}
+		return ret[i].Label.Less(ret[j].Label)
+	})
+	return ret
+}
+
+// A revdepCounter implements CountRevdeps.
+type revdepCounter struct {
+	graph       *core.BuildGraph
+	hidden      bool
+	revdeps     map[core.BuildLabel][]*core.BuildTarget
+	subincludes map[core.BuildLabel][]*core.Package
+	// The hidden children of each target, if we're collapsing them into their parents.
+	children map[*core.BuildTarget][]*core.BuildTarget
+	indices  map[*core.BuildTarget]int
+	targets  []*core.BuildTarget
+	// The direct reverse dependencies of each target.
+	dependents [][]int
+	// The number of targets that still need each one's set.
+	refs []int
+	// The set of transitive reverse dependencies of each target, once calculated.
+	sets []revdepSet
+	// 0 if each target hasn't been visited yet, 1 if we're currently visiting it and 2 once done.
+	state    []int
+	tests    revdepSet
+	binaries revdepSet
+	counts   []RevdepCount
+}
+
+// node returns the target that we're counting the given one as.
+func (c *revdepCounter) node(t *core.BuildTarget) *core.BuildTarget {
+	if !c.hidden && t.Label.IsHidden() {
+		if parent := t.Parent(c.graph); parent != nil {
+			return parent
+		}
+	}
+	return t
+}
+
+// add returns the index of the given target, adding it if it isn't already known.
+func (c *revdepCounter) add(t *core.BuildTarget) int {
+	if idx, present := c.indices[t]; present {
+		return idx
+	}
+	idx := len(c.targets)
+	c.indices[t] = idx
+	c.targets = append(c.targets, t)
+	return idx
+}
+
+// findDependents returns the indices of the direct reverse dependencies of the given target.
+func (c *revdepCounter) findDependents(idx int) []int {
+	target := c.targets[idx]
+	var ret []int
+	addAll := func(ts []*core.BuildTarget) {
+		for _, t := range ts {
+			if t == nil {
+				continue
+			} else if d := c.add(c.node(t)); d != idx && !slices.Contains(ret, d) {
+				ret = append(ret, d)
+			}
+		}
+	}
+	for _, t := range append([]*core.BuildTarget{target}, c.children[target]...) {
+		addAll(c.revdeps[t.Label])
+		for _, pkg := range c.subincludes[t.Label] {
+			addAll(pkg.AllTargets())
+		}
+	}
+	return ret
+}
+
+// compute calculates the set of transitive reverse dependencies of a target, and counts them.
+func (c *revdepCounter) compute(idx int) {
+	if c.state[idx] != 0 {
+		return
+	}
+	c.state[idx] = 1
+	set := newRevdepSet(len(c.targets))
+	for _, d := range c.dependents[idx] {
+		c.compute(d)
+		set.Set(d)
+		if c.state[d] == 2 { // Otherwise we've found a cycle; there's not much useful we can do about it here.
+			set.Union(c.sets[d])
+		}
+		if c.refs[d]--; c.refs[d] == 0 {
+			c.sets[d] = nil
+		}
+	}
+	c.sets[idx] = set
+	c.state[idx] = 2
+	tests := set.CountIn(c.tests)
+	binaries := set.CountIn(c.binaries)
+	total := set.CountIn(nil)
+	c.counts[idx] = RevdepCount{
+		Label:     c.targets[idx].Label,
+		Total:     total,
+		Tests:     tests,
+		Binaries:  binaries,
+		Libraries: total - tests - binaries,
+	}
+}
+
+// A revdepSet is a bitset of target indices.
+type revdepSet []uint64
+
+func newRevdepSet(n int) revdepSet {
+	return make(revdepSet, (n+63)/64)
+}
+
+// Set adds the given index to the set.
+func (s revdepSet) Set(i int) {
+	s[i/64] |= 1 << (i % 64)
+}
+
+// Union adds everything in the other set to this one.
+func (s revdepSet) Union(other revdepSet) {
+	for i, x := range other {
+		s[i] |= x
+	}
+}
+
+// CountIn returns the number of items in this set that are also in the given one, or all of them if it's nil.
+func (s revdepSet) CountIn(mask revdepSet) int {
+	n := 0
+	for i, x := range s {
+		if mask != nil {
+			x &= mask[i]
+		}
+		n += bits.OnesCount64(x)
+	}
+	return n
+}
@@ -1,6 +1,7 @@
 package query
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -75,3 +76,50 @@ func revDepsLabels(state *core.BuildState, labels []core.BuildLabel, hidden bool
 	}
 	return ret
 }
+
+func TestCountRevdeps(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	graph := state.Graph
+	pkg := core.NewPackage("package")
+	graph.AddPackage(pkg)
+	add := func(name string, deps ...*core.BuildTarget) *core.BuildTarget {
+		target := addNewTarget(graph, pkg, name, nil)
+		for _, dep := range deps {
+			target.AddDependency(dep.Label)
+		}
+		return target
+	}
+	core1 := add("core")
+	util := add("util", core1)
+	libInternal := add("_lib#srcs", util)
+	lib := add("lib", libInternal)
+	bin := add("bin", lib, core1)
+	bin.IsBinary = true
+	test := add("lib_test", lib)
+	test.IsBinary = true
+	test.Test = &core.TestFields{}
+
+	counts := CountRevdeps(graph, []core.BuildLabel{core1.Label, util.Label, lib.Label, bin.Label}, false)
+	assert.Equal(t, []RevdepCount{
+		{Label: core1.Label, Total: 4, Tests: 1, Binaries: 1, Libraries: 2},
+		{Label: util.Label, Total: 3, Tests: 1, Binaries: 1, Libraries: 1},
+		{Label: lib.Label, Total: 2, Tests: 1, Binaries: 1},
+		{Label: bin.Label},
+	}, counts)
+
+	counts = CountRevdeps(graph, []core.BuildLabel{util.Label}, true)
+	assert.Equal(t, []RevdepCount{{Label: util.Label, Total: 4, Tests: 1, Binaries: 1, Libraries: 2}}, counts)
+}
+
+func TestReverseDepCounts(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	pkg := core.NewPackage("package")
+	state.Graph.AddPackage(pkg)
+	lib := addNewTarget(state.Graph, pkg, "lib", nil)
+	bin := addNewTarget(state.Graph, pkg, "bin", nil)
+	bin.AddDependency(lib.Label)
+
+	var buf bytes.Buffer
+	ReverseDepCounts(&buf, state, []core.BuildLabel{bin.Label, lib.Label}, 1, false)
+	assert.Equal(t, "Dependents      Tests   Binaries  Libraries  Target\n         1          0          0          1  //package:lib\n", buf.String())
+}
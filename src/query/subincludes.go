@@ -0,0 +1,171 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/parse/asp"
+)
+
+// Subincludes prints the graph of subincludes for each of the packages containing the given targets.
+// This includes anything they subinclude transitively, and any preloaded subincludes.
+func Subincludes(w io.Writer, state *core.BuildState, labels []core.BuildLabel) {
+	for _, pkg := range packagesOf(state.Graph, labels) {
+		fmt.Fprintf(w, "%s\n", pkg.Label())
+		for _, label := range pkg.Subincludes {
+			printSubincludes(w, state.Graph, label, "", 1, nil)
+		}
+		pkgState := state
+		if pkg.Subrepo != nil && pkg.Subrepo.State != nil {
+			pkgState = pkg.Subrepo.State
+		}
+		for _, label := range pkgState.GetPreloadedSubincludes() {
+			printSubincludes(w, state.Graph, label, " (preloaded)", 1, nil)
+		}
+	}
+}
+
+func printSubincludes(w io.Writer, graph *core.BuildGraph, label core.BuildLabel, suffix string, indent int, path []core.BuildLabel) {
+	if slices.Contains(path, label) {
+		fmt.Fprintf(w, "%*s%s (cycle)\n", indent*2, "", label)
+		return
+	}
+	fmt.Fprintf(w, "%*s%s%s\n", indent*2, "", label, suffix)
+	path = append(path, label)
+	for _, nested := range graph.NestedSubincludes(label) {
+		printSubincludes(w, graph, nested, "", indent+1, path)
+	}
+}
+
+// UnusedSubincludes prints any subincludes in BUILD files of the packages containing the given targets
+// whose symbols are never referenced by that BUILD file. It returns the number that it found.
+// Preloaded subincludes are not considered since they aren't named in any BUILD file.
+func UnusedSubincludes(w io.Writer, state *core.BuildState, labels []core.BuildLabel) int {
+	l := newSubincludeLinter(state)
+	count := 0
+	for _, pkg := range packagesOf(state.Graph, labels) {
+		if pkg.Subrepo != nil {
+			continue // We can't do anything about BUILD files in subrepos.
+		}
+		unused, err := l.Unused(pkg)
+		if err != nil {
+			log.Warning("Failed to check subincludes of %s: %s", pkg.Filename, err)
+			continue
+		}
+		for _, label := range unused {
+			fmt.Fprintf(w, "%s: %s is subincluded but none of its symbols are used\n", pkg.Filename, label)
+		}
+		count += len(unused)
+	}
+	return count
+}
+
+// A subincludeLinter finds subincludes that aren't used.
+type subincludeLinter struct {
+	graph  *core.BuildGraph
+	parser *asp.Parser
+	// symbols memoises the names that each subinclude provides.
+	symbols map[string]map[string]bool
+}
+
+func newSubincludeLinter(state *core.BuildState) *subincludeLinter {
+	return &subincludeLinter{
+		graph:   state.Graph,
+		parser:  asp.NewParser(state),
+		symbols: map[string]map[string]bool{},
+	}
+}
+
+// Unused returns the labels of any subincludes in the given package's BUILD file that aren't used.
+func (l *subincludeLinter) Unused(pkg *core.Package) ([]core.BuildLabel, error) {
+	stmts, err := l.parser.ParseFileOnly(pkg.Filename)
+	if err != nil {
+		return nil, err
+	}
+	referenced := asp.ReferencedNames(stmts)
+	var unused []core.BuildLabel
+	for _, inc := range asp.SubincludeCalls(stmts) {
+		label, annotation := core.SplitLabelAnnotation(inc)
+		bl, err := core.TryParseBuildLabel(label, pkg.Name, pkg.SubrepoName)
+		if err != nil {
+			return nil, err
+		}
+		symbols, err := l.Symbols(bl, annotation)
+		if err != nil {
+			log.Warning("Can't determine symbols of %s: %s", inc, err)
+			continue
+		}
+		used := false
+		for symbol := range symbols {
+			if referenced[symbol] {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, bl)
+		}
+	}
+	return unused, nil
+}
+
+// Symbols returns the set of names that subincluding the given target provides, including
+// anything that it has subincluded itself.
+func (l *subincludeLinter) Symbols(label core.BuildLabel, annotation string) (map[string]bool, error) {
+	key := label.String() + "|" + annotation
+	if symbols, present := l.symbols[key]; present {
+		return symbols, nil
+	}
+	t := l.graph.Target(label)
+	if t == nil {
+		return nil, fmt.Errorf("unknown target %s", label)
+	}
+	outs := t.Outputs()
+	if annotation != "" {
+		outs = t.NamedOutputs(annotation)
+	}
+	symbols := map[string]bool{}
+	l.symbols[key] = symbols // Set this early in case of cycles.
+	for _, out := range outs {
+		stmts, err := l.parser.ParseFileOnly(filepath.Join(t.OutDir(), out))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range asp.DefinedNames(stmts) {
+			symbols[name] = true
+		}
+	}
+	for _, nested := range l.graph.NestedSubincludes(label) {
+		nestedSymbols, err := l.Symbols(nested, "")
+		if err != nil {
+			return nil, err
+		}
+		for name := range nestedSymbols {
+			symbols[name] = true
+		}
+	}
+	return symbols, nil
+}
+
+// packagesOf returns the packages containing the given targets, sorted by name.
+func packagesOf(graph *core.BuildGraph, labels []core.BuildLabel) []*core.Package {
+	seen := map[*core.Package]bool{}
+	var pkgs []*core.Package
+	for _, label := range labels {
+		if pkg := graph.PackageByLabel(label); pkg != nil && !seen[pkg] {
+			seen[pkg] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	sort.Slice(pkgs, func(i, j int) bool {
+		if pkgs[i].SubrepoName != pkgs[j].SubrepoName {
+			return pkgs[i].SubrepoName < pkgs[j].SubrepoName
+		}
+		return pkgs[i].Name < pkgs[j].Name
+	})
+	return pkgs
+}
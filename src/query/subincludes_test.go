@@ -0,0 +1,42 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestSubincludes(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	state.Config.Parse.PreloadSubincludes = []core.BuildLabel{core.ParseBuildLabel("//build_defs:preload", "")}
+	pkg := core.NewPackage("src/foo")
+	pkg.RegisterSubinclude(core.ParseBuildLabel("//build_defs:go", ""))
+	state.Graph.AddPackage(pkg)
+	state.Graph.AddNestedSubinclude(core.ParseBuildLabel("//build_defs:go", ""), core.ParseBuildLabel("//build_defs:common", ""))
+	state.Graph.AddNestedSubinclude(core.ParseBuildLabel("//build_defs:common", ""), core.ParseBuildLabel("//build_defs:go", ""))
+
+	var buf bytes.Buffer
+	Subincludes(&buf, state, []core.BuildLabel{core.ParseBuildLabel("//src/foo:all", "")})
+	assert.Equal(t, `//src/foo:all
+  //build_defs:go
+    //build_defs:common
+      //build_defs:go (cycle)
+  //build_defs:preload (preloaded)
+`, buf.String())
+}
+
+func TestUnusedSubincludes(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	pkg := core.NewPackage("src/query/test_data/subincludes")
+	pkg.Filename = "src/query/test_data/subincludes/BUILD_FILE"
+	l := newSubincludeLinter(state)
+	l.symbols["//build_defs:go|"] = map[string]bool{"go_library": true, "go_binary": true}
+	l.symbols["//build_defs:python|"] = map[string]bool{"python_library": true}
+	unused, err := l.Unused(pkg)
+	require.NoError(t, err)
+	assert.Equal(t, []core.BuildLabel{core.ParseBuildLabel("//build_defs:python", "")}, unused)
+}
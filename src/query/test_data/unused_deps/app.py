@@ -0,0 +1,10 @@
+import os, sys as system
+from src.query.test_data.unused_deps.lib import (
+    helper,
+    other_helper,
+)
+from . import local as loc  # Relative import of a module in the same package
+
+
+def main():
+    return helper.run(loc.value, os.getcwd(), system.argv)
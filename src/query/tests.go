@@ -0,0 +1,68 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// Tests prints the recorded history of previous test runs, slowest first.
+// If slowest is positive, only that many targets are printed. If cases is true, the
+// individual test cases of each target are printed too (again slowest first).
+func Tests(w io.Writer, history *core.TestHistory, labels []core.BuildLabel, slowest int, cases bool) {
+	entries := history.Slowest(labels)
+	if slowest > 0 && len(entries) > slowest {
+		entries = entries[:slowest]
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%10s  %s (%s)\n", formatTestDuration(entry.Duration), entry.Label, formatTestRuns(entry.Runs, entry.Failures))
+		if cases {
+			for _, name := range entry.SlowestCases() {
+				c := entry.Cases[name]
+				fmt.Fprintf(w, "%10s    %s (%s)\n", formatTestDuration(c.Duration), name, formatTestRuns(c.Runs, c.Failures))
+			}
+		}
+	}
+}
+
+// Flaky prints the flake rates of targets and test cases that have only passed on retry in previous runs,
+// flakiest first. Quarantined targets are marked as such.
+func Flaky(w io.Writer, config *core.Configuration, history *core.TestHistory, labels []core.BuildLabel) {
+	for _, entry := range history.Flakiest(labels) {
+		suffix := ""
+		if config.IsQuarantined(entry.Label) {
+			suffix = " [quarantined]"
+		}
+		fmt.Fprintf(w, "%5.1f%%  %s (%s)%s\n", 100*entry.FlakeRate(), entry.Label, formatFlakes(entry.Flakes, entry.Runs), suffix)
+		for _, name := range entry.FlakiestCases() {
+			c := entry.Cases[name]
+			fmt.Fprintf(w, "%5.1f%%    %s (%s)\n", 100*c.FlakeRate(), name, formatFlakes(c.Flakes, c.Runs))
+		}
+	}
+}
+
+func formatFlakes(flakes, runs int) string {
+	if flakes == 1 {
+		return fmt.Sprintf("1 flake in %d runs", runs)
+	}
+	return fmt.Sprintf("%d flakes in %d runs", flakes, runs)
+}
+
+func formatTestDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+func formatTestRuns(runs, failures int) string {
+	s := fmt.Sprintf("%d run", runs)
+	if runs != 1 {
+		s += "s"
+	}
+	if failures == 1 {
+		return s + ", 1 failure"
+	} else if failures > 1 {
+		return fmt.Sprintf("%s, %d failures", s, failures)
+	}
+	return s
+}
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestTests(t *testing.T) {
+	history := core.NewTestHistory()
+	d := 200 * time.Millisecond
+	history.Record(core.ParseBuildLabel("//src/query:fast_test", ""), core.TestSuite{
+		Duration: time.Second,
+		TestCases: core.TestCases{{
+			Name:       "TestFast",
+			Executions: []core.TestExecution{{Duration: &d}},
+		}},
+	}, 1, time.Now())
+	history.Record(core.ParseBuildLabel("//src/query:slow_test", ""), core.TestSuite{
+		Duration: 90 * time.Second,
+		TimedOut: true,
+	}, 1, time.Now())
+
+	var buf bytes.Buffer
+	Tests(&buf, history, nil, 0, false)
+	assert.Equal(t, `     1m30s  //src/query:slow_test (1 run, 1 failure)
+        1s  //src/query:fast_test (1 run)
+`, buf.String())
+
+	buf.Reset()
+	Tests(&buf, history, nil, 1, false)
+	assert.Equal(t, "     1m30s  //src/query:slow_test (1 run, 1 failure)\n", buf.String())
+
+	buf.Reset()
+	Tests(&buf, history, []core.BuildLabel{core.ParseBuildLabel("//src/query:fast_test", "")}, 0, true)
+	assert.Equal(t, `        1s  //src/query:fast_test (1 run)
+     200ms    TestFast (1 run)
+`, buf.String())
+}
+
+func TestFlaky(t *testing.T) {
+	history := core.NewTestHistory()
+	flaky := core.TestSuite{
+		TestCases: core.TestCases{{
+			Name: "TestFlaky",
+			Executions: []core.TestExecution{
+				{Failure: &core.TestResultFailure{Message: "failed"}},
+				{},
+			},
+		}},
+	}
+	stable := core.TestSuite{
+		TestCases: core.TestCases{{
+			Name:       "TestFlaky",
+			Executions: []core.TestExecution{{}},
+		}},
+	}
+	label := core.ParseBuildLabel("//src/query:flaky_test", "")
+	history.Record(label, flaky, 1, time.Now())
+	history.Record(label, stable, 1, time.Now())
+	history.Record(label, stable, 1, time.Now())
+	history.Record(label, stable, 1, time.Now())
+	history.Record(core.ParseBuildLabel("//src/query:stable_test", ""), stable, 1, time.Now())
+
+	config := core.DefaultConfiguration()
+	config.Test.Quarantine = []core.BuildLabel{label}
+	var buf bytes.Buffer
+	Flaky(&buf, config, history, nil)
+	assert.Equal(t, ` 25.0%  //src/query:flaky_test (1 flake in 4 runs) [quarantined]
+ 25.0%    TestFlaky (1 flake in 4 runs)
+`, buf.String())
+}
@@ -0,0 +1,375 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/please-build/buildtools/build"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/fs"
+)
+
+// An UnusedDep is a dependency that a target declares, but that none of its sources import.
+type UnusedDep struct {
+	Target, Dep core.BuildLabel
+}
+
+// UnusedDeps prints the dependencies of the given targets that aren't imported by any of their sources.
+// If fix is true, it removes them from the BUILD files instead. It returns the number it found.
+func UnusedDeps(out io.Writer, state *core.BuildState, labels []core.BuildLabel, fix bool) (int, error) {
+	unused, err := FindUnusedDeps(state, labels)
+	if err != nil {
+		return 0, err
+	} else if fix {
+		return len(unused), FixUnusedDeps(state, unused)
+	}
+	for _, u := range unused {
+		fmt.Fprintf(out, "%s has an unused dependency on %s\n", u.Target, u.Dep)
+	}
+	return len(unused), nil
+}
+
+// FindUnusedDeps finds the dependencies of the given targets that aren't imported by any of their sources.
+//
+// Currently this understands Go and Python. Go imports are mapped to targets using the go_package: labels
+// that go_library and friends record, or for Go libraries in this repo that don't have them, the Go.ImportPath
+// config setting; Python imports are mapped to python_library targets by their source files.
+// We can't tell what other kinds of dependency (e.g. those on non-library targets or third-party Python
+// ones) are used for, so they are never reported. Targets with generated sources are skipped, since we can't see what
+// they import without building them.
+func FindUnusedDeps(state *core.BuildState, labels []core.BuildLabel) ([]UnusedDep, error) {
+	f := &unusedDepFinder{
+		state:   state,
+		modules: map[*core.BuildTarget][]string{},
+	}
+	var ret []UnusedDep
+	for _, label := range labels {
+		target := state.Graph.TargetOrDie(label)
+		if target.Label.IsHidden() || target.Subrepo != nil {
+			continue
+		}
+		unused, err := f.UnusedDeps(target)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, unused...)
+	}
+	return ret, nil
+}
+
+// An unusedDepFinder finds unused dependencies, caching the Python modules of targets as it goes.
+type unusedDepFinder struct {
+	state   *core.BuildState
+	modules map[*core.BuildTarget][]string
+}
+
+// UnusedDeps returns the unused dependencies of a single target.
+func (f *unusedDepFinder) UnusedDeps(target *core.BuildTarget) ([]UnusedDep, error) {
+	srcs, ok := localSources(f.state.Graph, target)
+	if !ok {
+		return nil, nil
+	}
+	goImports := map[string]bool{}
+	var pyImports []string
+	for _, src := range srcs {
+		switch path.Ext(src) {
+		case ".go":
+			if err := goFileImports(src, goImports); err != nil {
+				return nil, err
+			}
+		case ".py":
+			imports, err := pythonFileImports(src)
+			if err != nil {
+				return nil, err
+			}
+			pyImports = append(pyImports, imports...)
+		}
+	}
+	if len(goImports) == 0 && len(pyImports) == 0 {
+		return nil, nil
+	}
+	var ret []UnusedDep
+	for _, label := range target.DeclaredDependenciesStrict() {
+		dep := f.state.Graph.Target(label)
+		if dep == nil || dep.Subrepo != nil || label.IsHidden() {
+			continue // Hidden dependencies are added by build macros, not written in BUILD files.
+		} else if importPaths := f.goImportPaths(dep); len(goImports) > 0 && len(importPaths) > 0 {
+			// An internal test is compiled as part of its library's package, so it uses it without importing it.
+			if !anyGoPackageImported(importPaths, goImports) && !(target.IsTest() && slices.Contains(importPaths, f.goPackageImportPath(target))) {
+				ret = append(ret, UnusedDep{Target: target.Label, Dep: label})
+			}
+		} else if modules := f.pythonModules(dep); len(pyImports) > 0 && len(modules) > 0 {
+			if !anyPythonModuleImported(modules, pyImports) {
+				ret = append(ret, UnusedDep{Target: target.Label, Dep: label})
+			}
+		}
+	}
+	return ret, nil
+}
+
+// goImportPaths returns the import paths of the Go packages that a target provides, which go_library and
+// friends record in its labels. If it doesn't have any but has Go sources in this repo, we assume it's a library
+// whose import path follows from Go.ImportPath like go_library's default. Otherwise it's empty and we can't tell
+// whether it's used; guessing from its label would be wrong for third-party modules.
+func (f *unusedDepFinder) goImportPaths(target *core.BuildTarget) []string {
+	if target.IsBinary || target.IsTest() {
+		return nil
+	} else if importPaths := target.PrefixedLabels("go_package:"); len(importPaths) > 0 {
+		return importPaths
+	} else if srcs, _ := localSources(f.state.Graph, target); !slices.ContainsFunc(srcs, func(src string) bool { return path.Ext(src) == ".go" }) {
+		return nil
+	}
+	// Like go_library, the import path is that of its package unless it's named differently, in which case that's appended.
+	importPath := f.goPackageImportPath(target)
+	if target.Label.Name != path.Base(target.Label.PackageName) {
+		importPath = path.Join(importPath, target.Label.Name)
+	}
+	return []string{importPath}
+}
+
+// goPackageImportPath returns the import path of the Go package in the directory of the given target.
+func (f *unusedDepFinder) goPackageImportPath(target *core.BuildTarget) string {
+	return path.Join(f.state.Config.Go.ImportPath, target.Label.PackageName)
+}
+
+// anyGoPackageImported returns true if any of the given packages (or anything within them) are imported.
+// Anything within them counts since a third-party module can record just the path of the module itself.
+func anyGoPackageImported(importPaths []string, imports map[string]bool) bool {
+	for _, importPath := range importPaths {
+		if imports[importPath] {
+			return true
+		}
+		for imp := range imports {
+			if strings.HasPrefix(imp, importPath+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pythonModules returns the names of the Python modules that a target provides.
+func (f *unusedDepFinder) pythonModules(target *core.BuildTarget) []string {
+	if modules, present := f.modules[target]; present {
+		return modules
+	}
+	var modules []string
+	if srcs, ok := localSources(f.state.Graph, target); ok && !target.IsBinary && !target.IsTest() {
+		for _, src := range srcs {
+			if module, ok := strings.CutSuffix(src, ".py"); ok {
+				module = strings.TrimSuffix(strings.TrimSuffix(module, "__init__"), "/")
+				modules = append(modules, strings.ReplaceAll(module, "/", "."))
+			}
+		}
+	}
+	f.modules[target] = modules
+	return modules
+}
+
+// anyPythonModuleImported returns true if any of the given modules (or anything within them) are imported.
+func anyPythonModuleImported(modules, imports []string) bool {
+	for _, module := range modules {
+		for _, imp := range imports {
+			if imp == module || strings.HasPrefix(imp, module+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// localSources returns the paths of all the source files of a target, including those of any of its
+// hidden children that it uses as sources. It returns false if any of them are generated by other rules.
+func localSources(graph *core.BuildGraph, target *core.BuildTarget) ([]string, bool) {
+	var ret []string
+	for _, src := range target.AllSources() {
+		if label, ok := src.Label(); !ok {
+			ret = append(ret, src.Paths(graph)...)
+		} else if child := graph.Target(label); child != nil && label.IsHidden() && label.Parent() == target.Label.Parent() && child != target {
+			srcs, ok := localSources(graph, child)
+			if !ok {
+				return nil, false
+			}
+			ret = append(ret, srcs...)
+		} else {
+			return nil, false
+		}
+	}
+	return ret, true
+}
+
+// goFileImports adds the import paths of a Go file to the given set.
+func goFileImports(filename string, imports map[string]bool) error {
+	f, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ImportsOnly)
+	if err != nil {
+		return err
+	}
+	for _, imp := range f.Imports {
+		if path, err := strconv.Unquote(imp.Path.Value); err == nil {
+			imports[path] = true
+		}
+	}
+	return nil
+}
+
+var pythonImportRegex = regexp.MustCompile(`^\s*import\s+(.+)$`)
+var pythonFromImportRegex = regexp.MustCompile(`^\s*from\s+(\.*)([\w.]*)\s+import\s+(.+)$`)
+
+// pythonFileImports returns the modules imported by a Python file. For 'from x import y' statements both x
+// and x.y are returned since we can't tell whether y is a module or something defined within x.
+// This is not a full Python parser, but it handles the common forms of import statement.
+func pythonFileImports(filename string) ([]string, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// This is the package that relative imports are relative to.
+	pkg := strings.Split(path.Dir(filename), "/")
+	var ret []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Join up continuation lines and parenthesised lists of names.
+		for (strings.HasSuffix(line, "\\") || strings.Count(line, "(") > strings.Count(line, ")")) && scanner.Scan() {
+			line = strings.TrimSuffix(line, "\\") + " " + scanner.Text()
+		}
+		line, _, _ = strings.Cut(line, "#")
+		if match := pythonImportRegex.FindStringSubmatch(line); match != nil {
+			ret = append(ret, splitPythonNames(match[1])...)
+		} else if match := pythonFromImportRegex.FindStringSubmatch(line); match != nil {
+			module := match[2]
+			if dots := len(match[1]); dots > 0 {
+				if dots > len(pkg) {
+					continue // Relative import beyond the top-level package; Python wouldn't allow this either.
+				}
+				module = strings.Trim(strings.Join(pkg[:len(pkg)-dots+1], ".")+"."+module, ".")
+			}
+			ret = append(ret, module)
+			for _, name := range splitPythonNames(strings.Trim(strings.TrimSpace(match[3]), "()")) {
+				ret = append(ret, module+"."+name)
+			}
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// splitPythonNames splits a comma-separated list of names in an import statement, removing any aliases.
+func splitPythonNames(s string) []string {
+	var ret []string
+	for _, name := range strings.Split(s, ",") {
+		if name, _, _ = strings.Cut(strings.TrimSpace(name), " "); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// FixUnusedDeps removes the given unused dependencies from the BUILD files that declare them.
+func FixUnusedDeps(state *core.BuildState, unused []UnusedDep) error {
+	byPackage := map[*core.Package]map[string][]core.BuildLabel{}
+	for _, u := range unused {
+		pkg := state.Graph.PackageOrDie(u.Target)
+		if byPackage[pkg] == nil {
+			byPackage[pkg] = map[string][]core.BuildLabel{}
+		}
+		byPackage[pkg][u.Target.Name] = append(byPackage[pkg][u.Target.Name], u.Dep)
+	}
+	pkgs := make([]*core.Package, 0, len(byPackage))
+	for pkg := range byPackage {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].Filename < pkgs[j].Filename })
+	for _, pkg := range pkgs {
+		log.Notice("Rewriting %s to remove unused dependencies...", pkg.Filename)
+		if err := removeDeps(pkg, byPackage[pkg]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeDeps rewrites a package's BUILD file to remove the given dependencies of each of its rules.
+// Like gc.RewriteFile, it only removes the affected parts of the file and leaves the rest of it alone.
+func removeDeps(pkg *core.Package, deps map[string][]core.BuildLabel) error {
+	b, err := os.ReadFile(pkg.Filename)
+	if err != nil {
+		return err
+	}
+	f, err := build.ParseBuild(pkg.Filename, b)
+	if err != nil {
+		return err
+	}
+	var edits [][2]int
+	for _, rule := range f.Rules("") {
+		remove, present := deps[rule.Name()]
+		if !present {
+			continue
+		}
+		list, ok := rule.Attr("deps").(*build.ListExpr)
+		if !ok {
+			log.Warning("Can't remove unused dependencies of %s; its deps aren't a literal list", rule.Name())
+			continue
+		}
+		for _, expr := range list.List {
+			if s, ok := expr.(*build.StringExpr); ok {
+				if label, err := core.TryParseBuildLabel(s.Value, pkg.Name, pkg.SubrepoName); err == nil && slices.Contains(remove, label) {
+					start, end := s.Span()
+					edits = append(edits, listElementExtent(b, start.Byte, end.Byte))
+				}
+			}
+		}
+	}
+	info, err := os.Stat(pkg.Filename)
+	if err != nil {
+		return err
+	}
+	return fs.WriteFile(bytes.NewReader(deleteExtents(b, edits)), pkg.Filename, info.Mode())
+}
+
+// listElementExtent returns the range of bytes to delete to remove the list element between start and end.
+// If it's on a line of its own, the whole line goes; otherwise just the element and the comma separating it from
+// its neighbours.
+func listElementExtent(b []byte, start, end int) [2]int {
+	lineStart := bytes.LastIndexByte(b[:start], '\n') + 1
+	rest := bytes.TrimLeft(b[end:], " \t")
+	rest = bytes.TrimLeft(bytes.TrimPrefix(rest, []byte{','}), " \t")
+	if len(bytes.TrimSpace(b[lineStart:start])) == 0 && (len(rest) == 0 || rest[0] == '\n' || rest[0] == '#') {
+		if lineEnd := bytes.IndexByte(rest, '\n'); lineEnd != -1 {
+			return [2]int{lineStart, len(b) - len(rest) + lineEnd + 1}
+		}
+		return [2]int{lineStart, len(b)}
+	}
+	if after := bytes.TrimLeft(b[end:], " \t"); len(after) > 0 && after[0] == ',' {
+		return [2]int{start, len(b) - len(rest)} // Take the following comma and whitespace with it.
+	}
+	if before := bytes.TrimRight(b[:start], " \t"); len(before) > 0 && before[len(before)-1] == ',' {
+		return [2]int{len(before) - 1, end} // It's the last element, so take the preceding comma instead.
+	}
+	return [2]int{start, end}
+}
+
+// deleteExtents returns the given file contents with the given ranges of bytes removed.
+func deleteExtents(b []byte, extents [][2]int) []byte {
+	sort.Slice(extents, func(i, j int) bool { return extents[i][0] < extents[j][0] })
+	ret := make([]byte, 0, len(b))
+	pos := 0
+	for _, extent := range extents {
+		if extent[0] > pos {
+			ret = append(ret, b[pos:extent[0]]...)
+		}
+		pos = max(pos, extent[1])
+	}
+	return append(ret, b[pos:]...)
+}
@@ -0,0 +1,227 @@
+package query
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestFindUnusedDepsPython(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	const dir = "src/query/test_data/unused_deps"
+	add := func(label string, srcs ...string) *core.BuildTarget {
+		target := addGraphTarget(state.Graph, label)
+		for _, src := range srcs {
+			target.AddSource(core.FileLabel{File: src, Package: target.Label.PackageName})
+		}
+		target.AddLabel("py")
+		return target
+	}
+	lib := add("//"+dir+"/lib:lib", "helper.py", "other_helper.py")
+	other := add("//"+dir+"/other:other", "other.py")
+	local := add("//"+dir+":local", "local.py")
+	data := add("//" + dir + ":data")
+	app := add("//"+dir+":app", "app.py")
+	app.IsBinary = true
+	for _, dep := range []*core.BuildTarget{lib, other, local, data} {
+		app.AddDependency(dep.Label)
+	}
+
+	unused, err := FindUnusedDeps(state, []core.BuildLabel{app.Label, lib.Label})
+	require.NoError(t, err)
+	assert.Equal(t, []UnusedDep{{Target: app.Label, Dep: other.Label}}, unused)
+}
+
+func TestFindUnusedDepsGo(t *testing.T) {
+	state, main := makeUnusedDepsGoState(t)
+	unused, err := FindUnusedDeps(state, []core.BuildLabel{main.Label})
+	require.NoError(t, err)
+	assert.Equal(t, []UnusedDep{{Target: main.Label, Dep: core.ParseBuildLabel("//src/fs", "")}}, unused)
+}
+
+func TestFindUnusedDepsGoImportPaths(t *testing.T) {
+	state, main := makeUnusedDepsGoState(t)
+	add := func(name string, labels ...string) *core.BuildTarget {
+		target := addGraphTarget(state.Graph, "//third_party/go:"+name)
+		for _, label := range labels {
+			target.AddLabel(label)
+		}
+		main.AddDependency(target.Label)
+		return target
+	}
+	// A third-party module whose package is imported is used, even though its label doesn't resemble its import path.
+	add("fmt_module", "go", "go_package:fmt")
+	// This has a different import path to the one we'd guess from its label; it isn't imported so it's unused.
+	lib := add("lib", "go", "go_package:example.com/something/else")
+	// We can't tell what this provides, so it's never reported.
+	add("unknown", "go")
+
+	unused, err := FindUnusedDeps(state, []core.BuildLabel{main.Label})
+	require.NoError(t, err)
+	assert.Equal(t, []UnusedDep{
+		{Target: main.Label, Dep: core.ParseBuildLabel("//src/fs", "")},
+		{Target: main.Label, Dep: lib.Label},
+	}, unused)
+}
+
+func TestFindUnusedDepsGoConfigImportPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import "github.com/thought-machine/please/src/cli"
+
+func main() {
+	cli.InitLogging(cli.MinVerbosity)
+}
+`), 0644))
+	state := core.NewDefaultBuildState()
+	state.Config.Go.ImportPath = "github.com/thought-machine/please"
+	// These don't record their import paths, but they have Go sources so we can work them out from the config.
+	add := func(label string) *core.BuildTarget {
+		target := addGraphTarget(state.Graph, label)
+		target.AddSource(core.FileLabel{File: "lib.go", Package: target.Label.PackageName})
+		return target
+	}
+	cli := add("//src/cli:cli")
+	logging := add("//src/cli:logging")
+	main := addGraphTarget(state.Graph, "//src/tools:main", cli, logging)
+	main.IsBinary = true
+	main.AddSource(core.FileLabel{File: "main.go", Package: dir})
+
+	unused, err := FindUnusedDeps(state, []core.BuildLabel{main.Label})
+	require.NoError(t, err)
+	assert.Equal(t, []UnusedDep{{Target: main.Label, Dep: logging.Label}}, unused)
+}
+
+func TestFindUnusedDepsGoInternalTest(t *testing.T) {
+	state, _ := makeUnusedDepsGoState(t)
+	state.Config.Go.ImportPath = "github.com/thought-machine/please"
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fs_test.go"), []byte(`package fs
+
+import "testing"
+
+func TestFS(t *testing.T) {}
+`), 0644))
+	fsLib := state.Graph.TargetOrDie(core.ParseBuildLabel("//src/fs:fs", ""))
+	test := addGraphTarget(state.Graph, "//src/fs:fs_test", fsLib)
+	test.Test = &core.TestFields{}
+	test.AddSource(core.FileLabel{File: "fs_test.go", Package: dir})
+
+	// The test is part of the package it tests, so it never imports it.
+	unused, err := FindUnusedDeps(state, []core.BuildLabel{test.Label})
+	require.NoError(t, err)
+	assert.Empty(t, unused)
+}
+
+func TestUnusedDepsFix(t *testing.T) {
+	state, main := makeUnusedDepsGoState(t)
+	var buf bytes.Buffer
+	n, err := UnusedDeps(&buf, state, []core.BuildLabel{main.Label}, true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "", buf.String())
+	b, err := os.ReadFile(state.Graph.PackageOrDie(main.Label).Filename)
+	require.NoError(t, err)
+	assert.Equal(t, `go_binary(
+    name = "main",
+    srcs = ["main.go"],
+    deps = [
+        ":data",
+        "//src/core",
+    ],
+)
+
+go_library(
+    name = "other",
+    srcs = ["other.go"],
+    deps = ["//src/fs"],
+)
+`, string(b))
+}
+
+// makeUnusedDepsGoState sets up a Go binary that depends on //src/core and //src/fs but only imports the former.
+func makeUnusedDepsGoState(t *testing.T) (*core.BuildState, *core.BuildTarget) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte(`package main
+
+import (
+	"fmt"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func main() {
+	fmt.Println(core.PleaseVersion)
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "BUILD"), []byte(`go_binary(
+    name = "main",
+    srcs = ["main.go"],
+    deps = [
+        ":data",
+        "//src/core",
+        "//src/fs",
+    ],
+)
+
+go_library(
+    name = "other",
+    srcs = ["other.go"],
+    deps = ["//src/fs"],
+)
+`), 0644))
+
+	state := core.NewDefaultBuildState()
+	coreLib := addGraphTarget(state.Graph, "//src/core:core")
+	coreLib.AddLabel("go")
+	coreLib.AddLabel("go_package:github.com/thought-machine/please/src/core")
+	fsLib := addGraphTarget(state.Graph, "//src/fs:fs")
+	fsLib.AddLabel("go")
+	fsLib.AddLabel("go_package:github.com/thought-machine/please/src/fs")
+	data := addGraphTarget(state.Graph, "//src/tools:data")
+	// The sources are on a hidden child of the binary, as some rules do.
+	srcs := addGraphTarget(state.Graph, "//src/tools:_main#srcs")
+	srcs.AddSource(core.FileLabel{File: "main.go", Package: dir})
+	main := addGraphTarget(state.Graph, "//src/tools:main", coreLib, fsLib, data, srcs)
+	main.IsBinary = true
+	main.AddSource(srcs.Label)
+	state.Graph.PackageOrDie(main.Label).Filename = filepath.Join(dir, "BUILD")
+	return state, main
+}
+
+func TestRemoveDepsInline(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "BUILD")
+	// The odd formatting elsewhere in the file should be left alone.
+	require.NoError(t, os.WriteFile(filename, []byte(`go_library(name = "lib", srcs = ["lib.go"],
+    deps = [":a", ":b", ":c"])
+
+go_library(
+    name="other",
+    deps = [":a", ":c"],  # Only these
+)
+`), 0644))
+	pkg := core.NewPackage("src/query")
+	pkg.Filename = filename
+	err := removeDeps(pkg, map[string][]core.BuildLabel{
+		"lib":   {core.ParseBuildLabel("//src/query:b", "")},
+		"other": {core.ParseBuildLabel("//src/query:a", ""), core.ParseBuildLabel("//src/query:c", "")},
+	})
+	require.NoError(t, err)
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, `go_library(name = "lib", srcs = ["lib.go"],
+    deps = [":a", ":c"])
+
+go_library(
+    name="other",
+    deps = [],  # Only these
+)
+`, string(b))
+}
@@ -0,0 +1,224 @@
+package query
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/thought-machine/please/src/build"
+	"github.com/thought-machine/please/src/core"
+)
+
+// A TargetWeight describes how much a single target contributes to the size and build time of the targets depending on it.
+// Sizes are in bytes and durations in nanoseconds when serialised to JSON.
+type TargetWeight struct {
+	Label core.BuildLabel `json:"label"`
+	// The total size of the target's outputs.
+	Size uint64 `json:"size"`
+	// How long the target took when it was last built.
+	Duration time.Duration `json:"duration"`
+	// The size & time that would go away if the target were no longer depended on, i.e. the totals for this target
+	// and all the ones that are only reachable through it.
+	ExclusiveSize     uint64        `json:"exclusive_size"`
+	ExclusiveDuration time.Duration `json:"exclusive_duration"`
+	// These are set if the target's size or build time couldn't be measured, e.g. because it was built remotely and
+	// its outputs weren't downloaded. Unknown values are reported as zero and aren't counted in any exclusive totals.
+	SizeUnknown     bool `json:"size_unknown,omitempty"`
+	DurationUnknown bool `json:"duration_unknown,omitempty"`
+}
+
+// Weight prints the size and build time of each of the transitive dependencies of the given targets,
+// along with the amount that would go away if it were no longer depended on, largest first.
+// The targets need to have been built already.
+func Weight(out io.Writer, graph *core.BuildGraph, labels []core.BuildLabel, sortBy string, hidden, outputJSON bool) {
+	weights := Weights(graph, labels, measureTarget)
+	if sortBy == "time" {
+		sort.SliceStable(weights, func(i, j int) bool { return weights[i].ExclusiveDuration > weights[j].ExclusiveDuration })
+	}
+	if !hidden {
+		visible := weights[:0]
+		for _, w := range weights {
+			if !w.Label.IsHidden() {
+				visible = append(visible, w)
+			}
+		}
+		weights = visible
+	}
+	if outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(weights); err != nil {
+			log.Fatalf("Failed to encode JSON: %s", err)
+		}
+		return
+	}
+	fmt.Fprintf(out, "%10s %10s %10s %10s  %s\n", "Exclusive", "Size", "Excl time", "Time", "Target")
+	for _, w := range weights {
+		size, duration := humanize.IBytes(w.Size), formatTestDuration(w.Duration)
+		if w.SizeUnknown {
+			size = "?"
+		}
+		if w.DurationUnknown {
+			duration = "?"
+		}
+		fmt.Fprintf(out, "%10s %10s %10s %10s  %s\n", humanize.IBytes(w.ExclusiveSize), size, formatTestDuration(w.ExclusiveDuration), duration, w.Label)
+	}
+}
+
+// measureTarget returns the size of a target's outputs on disk and how long its last build took.
+// Only the Size, Duration, SizeUnknown and DurationUnknown fields of the returned weight are set.
+func measureTarget(target *core.BuildTarget) TargetWeight {
+	var w TargetWeight
+	for _, out := range target.FullOutputs() {
+		if err := filepath.WalkDir(out, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			} else if !d.IsDir() {
+				if info, err := d.Info(); err == nil {
+					w.Size += uint64(info.Size())
+				}
+			}
+			return nil
+		}); errors.Is(err, fs.ErrNotExist) {
+			// This happens for remote builds when the outputs haven't been downloaded.
+			w.SizeUnknown = true
+		} else if err != nil {
+			log.Warning("Failed to measure outputs of %s: %s", target, err)
+		}
+	}
+	if w.SizeUnknown {
+		w.Size = 0
+	}
+	if md, err := build.LoadTargetMetadata(target); err == nil {
+		w.Duration = md.Duration
+	} else {
+		w.DurationUnknown = true
+	}
+	return w
+}
+
+// Weights returns the weights of all the transitive dependencies of the given targets (including themselves),
+// using the given function to measure each one. They're sorted by exclusive size, largest first.
+func Weights(graph *core.BuildGraph, labels []core.BuildLabel, measure func(*core.BuildTarget) TargetWeight) []TargetWeight {
+	// The exclusive weights are calculated from the dominator tree of the graph; a target dominates another if every
+	// path to it goes through the first one, so if it weren't depended on any more, all of those would go too.
+	// We use a virtual root at index 0 so there's a single one even if we're given multiple targets.
+	d := &dominators{graph: graph, indices: map[*core.BuildTarget]int{}}
+	d.targets = append(d.targets, nil)
+	d.preds = append(d.preds, nil)
+	d.postorder = append(d.postorder, 0)
+	for _, l := range labels {
+		d.visit(0, graph.TargetOrDie(l))
+	}
+	d.postorder[0] = len(d.order)
+	d.order = append(d.order, 0)
+	idom := d.Compute()
+
+	weights := make([]TargetWeight, len(d.targets))
+	for i, t := range d.targets[1:] {
+		w := &weights[i+1]
+		*w = measure(t)
+		w.Label = t.Label
+		w.ExclusiveSize, w.ExclusiveDuration = w.Size, w.Duration
+	}
+	// Every target comes after all those it dominates in postorder, so we can accumulate them in one pass.
+	for _, n := range d.order {
+		if n != 0 {
+			weights[idom[n]].ExclusiveSize += weights[n].ExclusiveSize
+			weights[idom[n]].ExclusiveDuration += weights[n].ExclusiveDuration
+		}
+	}
+	weights = weights[1:]
+	sort.SliceStable(weights, func(i, j int) bool {
+		if weights[i].ExclusiveSize != weights[j].ExclusiveSize {
+			return weights[i].ExclusiveSize > weights[j].ExclusiveSize
+		}
+		return weights[i].Label.Less(weights[j].Label)
+	})
+	return weights
+}
+
+// dominators computes the dominator tree of a build graph, using the algorithm from
+// "A Simple, Fast Dominance Algorithm" by Cooper, Harvey & Kennedy.
+type dominators struct {
+	graph   *core.BuildGraph
+	indices map[*core.BuildTarget]int
+	targets []*core.BuildTarget
+	// The predecessors (i.e. reverse dependencies) of each target.
+	preds [][]int
+	// The targets in postorder, and the postorder number of each one.
+	order     []int
+	postorder []int
+}
+
+// visit visits a target from the given predecessor, recursing into its dependencies if it hasn't been seen before.
+func (d *dominators) visit(pred int, target *core.BuildTarget) {
+	if idx, present := d.indices[target]; present {
+		d.preds[idx] = append(d.preds[idx], pred)
+		return
+	}
+	idx := len(d.targets)
+	d.indices[target] = idx
+	d.targets = append(d.targets, target)
+	d.preds = append(d.preds, []int{pred})
+	d.postorder = append(d.postorder, 0)
+	for _, l := range target.DeclaredDependencies() {
+		if dep := d.graph.Target(l); dep != nil {
+			for _, l := range dep.ProvideFor(target) {
+				d.visit(idx, d.graph.TargetOrDie(l))
+			}
+		}
+	}
+	d.postorder[idx] = len(d.order)
+	d.order = append(d.order, idx)
+}
+
+// Compute returns the immediate dominator of each target.
+func (d *dominators) Compute() []int {
+	idom := make([]int, len(d.targets))
+	for i := range idom {
+		idom[i] = -1
+	}
+	idom[0] = 0
+	for changed := true; changed; {
+		changed = false
+		// Iterate in reverse postorder, skipping the root.
+		for i := len(d.order) - 2; i >= 0; i-- {
+			n := d.order[i]
+			newIdom := -1
+			for _, p := range d.preds[n] {
+				if idom[p] == -1 {
+					continue
+				} else if newIdom == -1 {
+					newIdom = p
+				} else {
+					newIdom = d.intersect(idom, p, newIdom)
+				}
+			}
+			if idom[n] != newIdom {
+				idom[n] = newIdom
+				changed = true
+			}
+		}
+	}
+	return idom
+}
+
+func (d *dominators) intersect(idom []int, a, b int) int {
+	for a != b {
+		for d.postorder[a] < d.postorder[b] {
+			a = idom[a]
+		}
+		for d.postorder[b] < d.postorder[a] {
+			b = idom[b]
+		}
+	}
+	return a
+}
@@ -0,0 +1,80 @@
+package query
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestWeights(t *testing.T) {
+	graph := core.NewGraph()
+	bigdep := addGraphTarget(graph, "//src/query:bigdep")
+	big := addGraphTarget(graph, "//src/query:big", bigdep)
+	common := addGraphTarget(graph, "//src/query:common")
+	lib1 := addGraphTarget(graph, "//src/query:lib1", common, big)
+	lib2 := addGraphTarget(graph, "//src/query:lib2", common)
+	addGraphTarget(graph, "//src/query:bin", lib1, lib2)
+	sizes := map[string]uint64{"bigdep": 5000, "big": 1000, "common": 100, "lib1": 10, "lib2": 20, "bin": 1}
+
+	weights := Weights(graph, []core.BuildLabel{core.ParseBuildLabel("//src/query:bin", "")}, func(target *core.BuildTarget) TargetWeight {
+		size := sizes[target.Label.Name]
+		return TargetWeight{Size: size, Duration: time.Duration(size) * time.Millisecond}
+	})
+	weight := func(name string, size, exclusive uint64) TargetWeight {
+		return TargetWeight{
+			Label:             core.NewBuildLabel("src/query", name),
+			Size:              size,
+			Duration:          time.Duration(size) * time.Millisecond,
+			ExclusiveSize:     exclusive,
+			ExclusiveDuration: time.Duration(exclusive) * time.Millisecond,
+		}
+	}
+	assert.Equal(t, []TargetWeight{
+		weight("bin", 1, 6131),
+		weight("lib1", 10, 6010),
+		weight("big", 1000, 6000),
+		weight("bigdep", 5000, 5000),
+		weight("common", 100, 100),
+		weight("lib2", 20, 20),
+	}, weights)
+}
+
+func TestWeightsMultipleTargets(t *testing.T) {
+	graph := core.NewGraph()
+	common := addGraphTarget(graph, "//src/query:common")
+	bin1 := addGraphTarget(graph, "//src/query:bin1", common)
+	bin2 := addGraphTarget(graph, "//src/query:bin2", common)
+
+	weights := Weights(graph, []core.BuildLabel{bin1.Label, bin2.Label}, func(target *core.BuildTarget) TargetWeight {
+		return TargetWeight{Size: 1, Duration: time.Second}
+	})
+	// Neither binary dominates the common dependency, since it's reachable from either.
+	assert.Equal(t, []TargetWeight{
+		{Label: bin1.Label, Size: 1, Duration: time.Second, ExclusiveSize: 1, ExclusiveDuration: time.Second},
+		{Label: bin2.Label, Size: 1, Duration: time.Second, ExclusiveSize: 1, ExclusiveDuration: time.Second},
+		{Label: common.Label, Size: 1, Duration: time.Second, ExclusiveSize: 1, ExclusiveDuration: time.Second},
+	}, weights)
+}
+
+func TestWeightOutput(t *testing.T) {
+	graph := core.NewGraph()
+	target := addGraphTarget(graph, "//src/query:bin")
+
+	var buf bytes.Buffer
+	Weight(&buf, graph, []core.BuildLabel{target.Label}, "size", false, false)
+	// It hasn't been built, so there's no record of how long it took.
+	assert.Equal(t, " Exclusive       Size  Excl time       Time  Target\n       0 B        0 B         0s          ?  //src/query:bin\n", buf.String())
+}
+
+func TestMeasureTargetOutputsMissing(t *testing.T) {
+	target := core.NewBuildTarget(core.ParseBuildLabel("//src/query:remote", ""))
+	target.AddOutput("not_downloaded.txt")
+	w := measureTarget(target)
+	assert.True(t, w.SizeUnknown)
+	assert.True(t, w.DurationUnknown)
+	assert.EqualValues(t, 0, w.Size)
+}
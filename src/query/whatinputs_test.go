@@ -18,6 +18,23 @@ func addNewTarget(graph *core.BuildGraph, pkg *core.Package, targetName string,
 	return target
 }
 
+// addGraphTarget adds a new target with the given label to the graph, creating its package if needed,
+// and makes it depend on each of the given targets.
+func addGraphTarget(graph *core.BuildGraph, label string, deps ...*core.BuildTarget) *core.BuildTarget {
+	target := core.NewBuildTarget(core.ParseBuildLabel(label, ""))
+	pkg := graph.PackageByLabel(target.Label)
+	if pkg == nil {
+		pkg = core.NewPackage(target.Label.PackageName)
+		graph.AddPackage(pkg)
+	}
+	pkg.AddTarget(target)
+	graph.AddTarget(target)
+	for _, dep := range deps {
+		target.AddDependency(dep.Label)
+	}
+	return target
+}
+
 func TestWhatInputsSingleTarget(t *testing.T) {
 	graph := core.NewGraph()
 	pkg1 := core.NewPackage("package1")
@@ -158,8 +158,8 @@ func (c *Client) buildTestCommand(state *core.BuildState, target *core.BuildTarg
 		commandPrefix += `export TEST="$TEST_DIR/` + outs[0] + `" && `
 	}
 	cmd, err := core.ReplaceTestSequences(state, target, target.GetTestCommand(state))
-	if len(state.TestArgs) != 0 {
-		cmd += " " + strings.Join(state.TestArgs, " ")
+	if selection := state.TestSelection(target.Label); len(selection) != 0 {
+		cmd += " " + strings.Join(selection, " ")
 	}
 	return &pb.Command{
 		Platform: &pb.Platform{
@@ -382,6 +382,9 @@ func (c *Client) buildMetadata(target *core.BuildTarget, ar *pb.ActionResult, ne
 		Stdout: ar.StdoutRaw,
 		Stderr: ar.StderrRaw,
 	}
+	if md := ar.ExecutionMetadata; md != nil && md.ExecutionStartTimestamp != nil && md.ExecutionCompletedTimestamp != nil {
+		metadata.Duration = md.ExecutionCompletedTimestamp.AsTime().Sub(md.ExecutionStartTimestamp.AsTime())
+	}
 	if needStdout && len(metadata.Stdout) == 0 && ar.StdoutDigest != nil {
 		b, _, err := c.client.ReadBlob(context.Background(), digest.NewFromProtoUnvalidated(ar.StdoutDigest))
 		if err != nil {
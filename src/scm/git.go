@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -202,12 +203,28 @@ func (g *git) Remove(names []string) error {
 }
 
 func (g *git) ChangedLines() (map[string][]int, error) {
-	cmd := exec.Command("git", "diff", "origin/master", "--unified=0", "--no-color", "--no-ext-diff")
+	out, err := g.diff("origin/master")
+	if err != nil {
+		return nil, err
+	}
+	return g.parseChangedLines(out)
+}
+
+func (g *git) ChangedOriginalLinesIn(diffSpec string) (map[string][]int, error) {
+	out, err := g.diff(diffSpec)
+	if err != nil {
+		return nil, err
+	}
+	return g.parseChangedOriginalLines(out)
+}
+
+func (g *git) diff(diffSpec string) ([]byte, error) {
+	cmd := exec.Command("git", "diff", diffSpec, "--unified=0", "--no-color", "--no-ext-diff")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("git diff failed: %s\nOutput:\n%s", err, string(out))
 	}
-	return g.parseChangedLines(out)
+	return out, nil
 }
 
 func (g *git) parseChangedLines(input []byte) (map[string][]int, error) {
@@ -229,6 +246,46 @@ func (g *git) parseHunks(hunks []*diff.Hunk) []int {
 	return ret
 }
 
+func (g *git) parseChangedOriginalLines(input []byte) (map[string][]int, error) {
+	m := map[string][]int{}
+	fds, err := diff.ParseMultiFileDiff(input)
+	for _, fd := range fds {
+		oldName := strings.TrimPrefix(fd.OrigName, "a/")
+		newName := strings.TrimPrefix(fd.NewName, "b/")
+		if oldName == "/dev/null" {
+			// The file is new, so there's nothing we can say about its lines.
+			m[newName] = []int{}
+			continue
+		} else if newName != oldName && newName != "/dev/null" {
+			// It's been renamed; the new name is effectively a new file.
+			m[newName] = []int{}
+		}
+		m[oldName] = g.parseOriginalHunks(fd.Hunks)
+	}
+	return m, err
+}
+
+// parseOriginalHunks returns the lines of the original file that the given hunks touch.
+// Lines that were only inserted touch the original lines either side of them.
+func (g *git) parseOriginalHunks(hunks []*diff.Hunk) []int {
+	ret := []int{}
+	for _, hunk := range hunks {
+		if hunk.OrigLines == 0 {
+			// For a pure insertion, OrigStartLine is the line it comes after (or 0 at the start of the file).
+			if hunk.OrigStartLine > 0 {
+				ret = append(ret, int(hunk.OrigStartLine))
+			}
+			ret = append(ret, int(hunk.OrigStartLine)+1)
+			continue
+		}
+		for i := 0; i < int(hunk.OrigLines); i++ {
+			ret = append(ret, int(hunk.OrigStartLine)+i)
+		}
+	}
+	slices.Sort(ret)
+	return slices.Compact(ret)
+}
+
 func (g *git) Checkout(revision string) error {
 	if out, err := exec.Command("git", "checkout", revision).CombinedOutput(); err != nil {
 		return fmt.Errorf("git checkout of %s failed: %s\nOutput:\n%s", revision, err, string(out))
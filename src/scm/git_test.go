@@ -22,3 +22,21 @@ func TestParseChangedLines(t *testing.T) {
 		"tools/please_pex/behave.py":                                          {2, 3, 10, 11, 12, 13, 14, 15, 16, 17, 24, 25, 26, 27, 28, 29, 30, 31, 32},
 	}, m)
 }
+
+func TestParseChangedOriginalLines(t *testing.T) {
+	b, err := os.ReadFile("src/scm/test_data/git.diff")
+	assert.NoError(t, err)
+	g := git{}
+	m, err := g.parseChangedOriginalLines(b)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]int{
+		"test/python_rules/behave/BUILD": {8},
+		// Renamed; line 10 was inserted after line 9, so it touches both 9 & 10.
+		"test/python_rules/behave/features/behave_test.feature":               {1, 9, 10},
+		"test/python_rules/behave/features/behave_test3.feature":              {},
+		"test/python_rules/behave/features/steps/behave_test_steps.py":        {23, 24},
+		"test/python_rules/behave/features/test_suite_1/behave_test1.feature": {},
+		"test/python_rules/behave/features/test_suite_2/behave_test2.feature": {},
+		"tools/please_pex/behave.py":                                          {1, 2, 8, 15, 16},
+	}, m)
+}
@@ -32,6 +32,10 @@ type SCM interface {
 	// ChangedLines returns the set of lines that have been modified,
 	// as a map of filename -> affected line numbers.
 	ChangedLines() (map[string][]int, error)
+	// ChangedOriginalLinesIn returns the lines of the original version of each file (i.e. from before
+	// the changes in the given diffSpec) that the changes touch, as a map of filename -> line numbers.
+	// Added files and the new names of renamed ones have no original lines, so they map to an empty slice.
+	ChangedOriginalLinesIn(diffSpec string) (map[string][]int, error)
 	// Checkout checks out the given revision.
 	Checkout(revision string) error
 	// CurrentRevDate returns the commit date of the current revision, formatted according to the given format string.
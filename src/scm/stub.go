@@ -36,6 +36,10 @@ func (s *stub) ChangedLines() (map[string][]int, error) {
 	return nil, fmt.Errorf("unknown SCM, can't calculate changed lines")
 }
 
+func (s *stub) ChangedOriginalLinesIn(diffSpec string) (map[string][]int, error) {
+	return nil, fmt.Errorf("unknown SCM, can't calculate changed lines")
+}
+
 func (s *stub) Checkout(revision string) error {
 	return fmt.Errorf("unknown SCM, can't checkout")
 }
@@ -0,0 +1,132 @@
+// Code for writing coverage in the Cobertura XML format.
+// Unlike the XML coverage report, this sticks strictly to the Cobertura DTD (coverage-04.dtd) so it can be
+// consumed by tools that validate their input (e.g. the coverage views of various CI systems).
+
+package test
+
+import (
+	"encoding/xml"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// WriteCoberturaCoverageToFileOrDie writes the collected coverage data to a file in Cobertura format. Dies on failure.
+func WriteCoberturaCoverageToFileOrDie(coverage core.TestCoverage, filename string) {
+	if err := os.WriteFile(filename, coverageToCobertura(coverage, time.Now()), 0644); err != nil {
+		log.Fatalf("Failed to write coverage results to %s: %s", filename, err)
+	}
+}
+
+func coverageToCobertura(coverage core.TestCoverage, timestamp time.Time) []byte {
+	report := coberturaCoverage{
+		Version:   core.PleaseVersion,
+		Timestamp: timestamp.UnixMilli(),
+		Sources:   []string{core.RepoRoot},
+	}
+	pkgs := map[string]*coberturaPackage{}
+	for _, file := range coverage.OrderedFiles() {
+		lines, covered, total := coberturaLines(coverage.Files[file])
+		dir := path.Dir(file)
+		pkg, present := pkgs[dir]
+		if !present {
+			pkg = &coberturaPackage{Name: strings.ReplaceAll(dir, "/", ".")}
+			pkgs[dir] = pkg
+			report.Packages.Package = append(report.Packages.Package, pkg)
+		}
+		cls := coberturaClass{
+			Name:     strings.ReplaceAll(strings.TrimSuffix(file, path.Ext(file)), "/", "."),
+			Filename: file,
+			LineRate: lineRate(covered, total),
+		}
+		cls.Lines.Line = lines
+		pkg.Classes.Class = append(pkg.Classes.Class, cls)
+		pkg.covered += covered
+		pkg.total += total
+		report.LinesCovered += covered
+		report.LinesValid += total
+	}
+	for _, pkg := range report.Packages.Package {
+		pkg.LineRate = lineRate(pkg.covered, pkg.total)
+	}
+	report.LineRate = lineRate(report.LinesCovered, report.LinesValid)
+	b, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode xml: %s", err)
+	}
+	return []byte(xml.Header + coberturaDoctype + "\n" + string(b) + "\n")
+}
+
+// coberturaLines converts a file's coverage to Cobertura lines, which are 1-indexed and only include executable lines.
+func coberturaLines(coverage []core.LineCoverage) (lines []coberturaLine, covered, total int) {
+	for i, c := range coverage {
+		if c == core.Covered {
+			lines = append(lines, coberturaLine{Number: i + 1, Hits: 1, Branch: "false"})
+			covered++
+			total++
+		} else if c == core.Uncovered || c == core.Unreachable {
+			lines = append(lines, coberturaLine{Number: i + 1, Hits: 0, Branch: "false"})
+			total++
+		}
+	}
+	return lines, covered, total
+}
+
+func lineRate(covered, total int) float64 {
+	if total == 0 {
+		return 1.0 // Cobertura considers something with nothing to cover to be fully covered.
+	}
+	return formatFloatPrecision(float64(covered)/float64(total), 4)
+}
+
+const coberturaDoctype = `<!DOCTYPE coverage SYSTEM "http://cobertura.sourceforge.net/xml/coverage-04.dtd">`
+
+type coberturaCoverage struct {
+	XMLName         xml.Name `xml:"coverage"`
+	LineRate        float64  `xml:"line-rate,attr"`
+	BranchRate      float64  `xml:"branch-rate,attr"`
+	LinesCovered    int      `xml:"lines-covered,attr"`
+	LinesValid      int      `xml:"lines-valid,attr"`
+	BranchesCovered int      `xml:"branches-covered,attr"`
+	BranchesValid   int      `xml:"branches-valid,attr"`
+	Complexity      float64  `xml:"complexity,attr"`
+	Version         string   `xml:"version,attr"`
+	Timestamp       int64    `xml:"timestamp,attr"`
+	Sources         []string `xml:"sources>source"`
+	Packages        struct {
+		Package []*coberturaPackage `xml:"package"`
+	} `xml:"packages"`
+}
+
+type coberturaPackage struct {
+	Name       string  `xml:"name,attr"`
+	LineRate   float64 `xml:"line-rate,attr"`
+	BranchRate float64 `xml:"branch-rate,attr"`
+	Complexity float64 `xml:"complexity,attr"`
+	Classes    struct {
+		Class []coberturaClass `xml:"class"`
+	} `xml:"classes"`
+	covered int
+	total   int
+}
+
+type coberturaClass struct {
+	Name       string   `xml:"name,attr"`
+	Filename   string   `xml:"filename,attr"`
+	LineRate   float64  `xml:"line-rate,attr"`
+	BranchRate float64  `xml:"branch-rate,attr"`
+	Complexity float64  `xml:"complexity,attr"`
+	Methods    struct{} `xml:"methods"` // Required by the DTD, but we don't know anything about methods.
+	Lines      struct {
+		Line []coberturaLine `xml:"line"`
+	} `xml:"lines"`
+}
+
+type coberturaLine struct {
+	Number int    `xml:"number,attr"`
+	Hits   int    `xml:"hits,attr"`
+	Branch string `xml:"branch,attr"`
+}
@@ -37,6 +37,8 @@ func parseTestCoverage(target *core.BuildTarget, data []byte, run int) (*core.Te
 		return coverage, parseGcovCoverageResults(target, coverage, data)
 	} else if looksLikeIstanbulCoverageResults(data) {
 		return coverage, parseIstanbulCoverageResults(target, coverage, data, run)
+	} else if looksLikeLCOVCoverageResults(data) {
+		return coverage, parseLCOVCoverageResults(target, coverage, data, run)
 	} else {
 		return coverage, parseXMLCoverageResults(target, coverage, data)
 	}
@@ -48,7 +50,7 @@ func parseTestCoverage(target *core.BuildTarget, data []byte, run int) (*core.Te
 // tests, so it's important that we identify anything with zero coverage here.
 func AddOriginalTargetsToCoverage(state *core.BuildState, includeAllFiles bool) {
 	recordedCoverage := state.Coverage
-	state.Coverage = core.TestCoverage{Tests: recordedCoverage.Tests, Files: map[string][]core.LineCoverage{}}
+	state.Coverage = core.TestCoverage{Tests: recordedCoverage.Tests, Files: map[string][]core.LineCoverage{}, TestCases: recordedCoverage.TestCases}
 	mergeCoverage(state, recordedCoverage, collectCoverageFiles(state, includeAllFiles))
 }
 
@@ -1,7 +1,9 @@
 package test
 
 import (
+	"encoding/xml"
 	"testing"
+	"time"
 
 	"github.com/peterebden/tools/cover"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +21,7 @@ const (
 	gcovCoverageFile      = "src/test/test_data/gcov_coverage.gcov"
 	istanbulCoverageFile  = "src/test/test_data/istanbul_coverage.json"
 	istanbulCoverageFile2 = "src/test/test_data/istanbul_coverage_2.json"
+	lcovCoverageFile      = "src/test/test_data/lcov_coverage.info"
 )
 
 // Test that tests aren't required to produce coverage, ie. it's not an error if the file doesn't exist.
@@ -266,3 +269,106 @@ func TestGetDirectoryCoverage(t *testing.T) {
 	}
 	assert.Equal(t, expectedDirCoverage, dirCoverage)
 }
+
+func TestLCOVCoverage(t *testing.T) {
+	target := &core.BuildTarget{Label: core.BuildLabel{PackageName: "src/lib", Name: "lib_test"}}
+	coverage, err := parseTestCoverageFile(target, lcovCoverageFile, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(coverage.Files))
+	assert.Contains(t, coverage.Tests, target.Label)
+	lines := coverage.Files["src/lib/parser.rs"]
+	assert.Equal(t, 9, len(lines))
+	assertLine(t, lines, 1, core.NotExecutable)
+	assertLine(t, lines, 3, core.Covered)
+	assertLine(t, lines, 4, core.Covered)
+	// Uncovered in the first record but covered in the second, which should be merged together.
+	assertLine(t, lines, 5, core.Covered)
+	assertLine(t, lines, 6, core.NotExecutable)
+	assertLine(t, lines, 7, core.Covered)
+	assertLine(t, lines, 9, core.Uncovered)
+	lines = coverage.Files["src/lib/lexer.rs"]
+	assertLine(t, lines, 1, core.Covered)
+	assertLine(t, lines, 2, core.Uncovered)
+	// A single test name is just a name for the whole run, not a test case.
+	assert.Nil(t, coverage.TestCases)
+}
+
+func TestLCOVTestCaseCoverage(t *testing.T) {
+	target := &core.BuildTarget{Label: core.BuildLabel{PackageName: "src/lib", Name: "lib_test"}}
+	coverage, err := parseTestCoverage(target, []byte(`TN:test_parse
+SF:src/lib/parser.rs
+DA:1,1
+DA:2,0
+end_of_record
+TN:test_lex
+SF:src/lib/parser.rs
+DA:2,1
+end_of_record
+`), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string][]core.LineCoverage{
+		"test_parse": {"src/lib/parser.rs": {core.Covered, core.Uncovered}},
+		"test_lex":   {"src/lib/parser.rs": {core.NotExecutable, core.Covered}},
+	}, coverage.TestCases[target.Label])
+	assert.Equal(t, []core.LineCoverage{core.Covered, core.Covered}, coverage.Files["src/lib/parser.rs"])
+}
+
+func TestLCOVRoundTrip(t *testing.T) {
+	cov := core.TestCoverage{
+		Files: map[string][]core.LineCoverage{
+			"src/lib/parser.rs": {core.NotExecutable, core.Uncovered, core.Covered},
+			"src/lib/lexer.rs":  {core.Covered},
+		},
+	}
+	assert.Equal(t, `TN:
+SF:src/lib/lexer.rs
+DA:1,1
+LF:1
+LH:1
+end_of_record
+TN:
+SF:src/lib/parser.rs
+DA:2,0
+DA:3,1
+LF:2
+LH:1
+end_of_record
+`, string(coverageToLCOV(cov)))
+	coverage, err := parseTestCoverage(target, coverageToLCOV(cov), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, cov.Files, coverage.Files)
+}
+
+func TestCobertura(t *testing.T) {
+	cov := core.TestCoverage{
+		Files: map[string][]core.LineCoverage{
+			"src/lib/parser.rs": {core.NotExecutable, core.Uncovered, core.Covered, core.Covered},
+			"src/lib/lexer.rs":  {core.Covered},
+			"src/main.rs":       {core.NotExecutable},
+		},
+	}
+	report := coberturaCoverage{}
+	assert.NoError(t, xml.Unmarshal(coverageToCobertura(cov, time.Unix(1700000000, 0)), &report))
+	assert.Equal(t, 4, report.LinesValid)
+	assert.Equal(t, 3, report.LinesCovered)
+	assert.Equal(t, 0.75, report.LineRate)
+	assert.EqualValues(t, 1700000000000, report.Timestamp)
+	assert.Equal(t, 2, len(report.Packages.Package))
+	pkg := report.Packages.Package[1]
+	assert.Equal(t, "src", pkg.Name)
+	assert.Equal(t, 1.0, pkg.LineRate)
+	pkg = report.Packages.Package[0]
+	assert.Equal(t, "src.lib", pkg.Name)
+	assert.Equal(t, 0.75, pkg.LineRate)
+	assert.Equal(t, 2, len(pkg.Classes.Class))
+	cls := pkg.Classes.Class[1]
+	assert.Equal(t, "src.lib.parser", cls.Name)
+	assert.Equal(t, "src/lib/parser.rs", cls.Filename)
+	assert.Equal(t, 0.6667, cls.LineRate)
+	// Lines are 1-indexed and don't include non-executable ones.
+	assert.Equal(t, []coberturaLine{
+		{Number: 2, Hits: 0, Branch: "false"},
+		{Number: 3, Hits: 1, Branch: "false"},
+		{Number: 4, Hits: 1, Branch: "false"},
+	}, cls.Lines.Line)
+}
@@ -0,0 +1,105 @@
+// Parser for the Common Test Report Format (https://ctrf.io).
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// ctrfReport is the top level of a CTRF report.
+type ctrfReport struct {
+	Results *struct {
+		Summary struct {
+			Start int64 `json:"start"`
+			Stop  int64 `json:"stop"`
+		} `json:"summary"`
+		Tests []ctrfTest `json:"tests"`
+	} `json:"results"`
+}
+
+// ctrfTest is a single test within a CTRF report.
+type ctrfTest struct {
+	Name     string    `json:"name"`
+	Status   string    `json:"status"`
+	Duration float64   `json:"duration"` // in milliseconds
+	Message  string    `json:"message"`
+	Trace    string    `json:"trace"`
+	Suite    ctrfSuite `json:"suite"`
+	Retries  int       `json:"retries"`
+	Flaky    bool      `json:"flaky"`
+	Stdout   []string  `json:"stdout"`
+	Stderr   []string  `json:"stderr"`
+}
+
+// ctrfSuite is the suite a test belongs to. Older versions of the format have a single string,
+// newer ones have a list describing the hierarchy of suites, which we join together.
+type ctrfSuite string
+
+func (s *ctrfSuite) UnmarshalJSON(data []byte) error {
+	var suites []string
+	if err := json.Unmarshal(data, &suites); err == nil {
+		*s = ctrfSuite(strings.Join(suites, "."))
+		return nil
+	}
+	return json.Unmarshal(data, (*string)(s))
+}
+
+// looksLikeCTRFTestResults returns true if the given data looks like a CTRF report.
+func looksLikeCTRFTestResults(data []byte) bool {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte{'{'}) {
+		return false
+	}
+	report := ctrfReport{}
+	return json.Unmarshal(data, &report) == nil && report.Results != nil
+}
+
+// parseCTRFTestResults parses a CTRF report into a test suite.
+func parseCTRFTestResults(data []byte) (core.TestSuite, error) {
+	report := ctrfReport{}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return core.TestSuite{}, err
+	} else if report.Results == nil {
+		return core.TestSuite{}, nil
+	}
+	suite := core.TestSuite{}
+	if summary := report.Results.Summary; summary.Stop > summary.Start {
+		suite.Duration = time.Duration(summary.Stop-summary.Start) * time.Millisecond
+	}
+	for _, test := range report.Results.Tests {
+		duration := time.Duration(test.Duration * float64(time.Millisecond))
+		execution := core.TestExecution{
+			Duration: &duration,
+			Stdout:   strings.Join(test.Stdout, "\n"),
+			Stderr:   strings.Join(test.Stderr, "\n"),
+		}
+		failure := &core.TestResultFailure{Message: test.Message, Traceback: test.Trace, Type: "Failure"}
+		switch test.Status {
+		case "passed":
+		case "failed":
+			execution.Failure = failure
+		case "skipped", "pending":
+			execution.Skip = &core.TestResultSkip{Message: test.Message}
+		default:
+			failure.Type = "Error"
+			execution.Error = failure
+		}
+		testCase := core.TestCase{ClassName: string(test.Suite), Name: test.Name}
+		if test.Flaky && test.Status == "passed" {
+			// It passed after some retries, which we represent as failed executions before the successful one.
+			for i := 0; i < test.Retries; i++ {
+				testCase.Executions = append(testCase.Executions, core.TestExecution{Failure: &core.TestResultFailure{Type: "Failure"}})
+			}
+		}
+		testCase.Executions = append(testCase.Executions, execution)
+		suite.TestCases = append(suite.TestCases, testCase)
+		if report.Results.Summary.Stop <= report.Results.Summary.Start {
+			suite.Duration += duration
+		}
+	}
+	return suite, nil
+}
@@ -8,6 +8,7 @@ package test
 
 import (
 	"bytes"
+	"encoding/json"
 	"strings"
 
 	"github.com/jstemmer/go-junit-report/v2/gtr"
@@ -17,8 +18,23 @@ import (
 )
 
 func parseGoTestResults(data []byte) (core.TestSuite, error) {
-	parser := gotest.NewParser()
-	report, err := parser.Parse(bytes.NewReader(data))
+	return goReportToSuite(gotest.NewParser().Parse(bytes.NewReader(data)))
+}
+
+// parseGoJSONTestResults parses the output of `go test -json` (i.e. the format produced by test2json).
+func parseGoJSONTestResults(data []byte) (core.TestSuite, error) {
+	return goReportToSuite(gotest.NewJSONParser().Parse(bytes.NewReader(data)))
+}
+
+// looksLikeGoJSONTestResults returns true if the given data looks like the output of test2json.
+func looksLikeGoJSONTestResults(data []byte) bool {
+	line, _, _ := bytes.Cut(bytes.TrimSpace(data), []byte{'\n'})
+	event := struct{ Action string }{}
+	return json.Unmarshal(line, &event) == nil && event.Action != ""
+}
+
+// goReportToSuite converts a parsed report from either of the Go parsers to a test suite.
+func goReportToSuite(report gtr.Report, err error) (core.TestSuite, error) {
 	if err != nil {
 		return core.TestSuite{}, err
 	}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+// UpdateTestHistory records the results of all tests that ran in this build in the state's test history,
+// and writes it back to disk.
+func UpdateTestHistory(state *core.BuildState) {
+	now := time.Now()
+	for _, label := range state.ExpandOriginalLabels() {
+		target := state.Graph.TargetOrDie(label)
+		if !state.ShouldInclude(target) || !target.IsTest() || target.Test.Results == nil {
+			continue
+		} else if len(state.TestSelection(label)) > 0 {
+			state.TestHistory.RecordCases(label, *target.Test.Results)
+		} else {
+			state.TestHistory.Record(label, *target.Test.Results, int(state.NumTestRuns), now)
+		}
+	}
+	if err := state.TestHistory.Save(core.TestHistoryFile); err != nil {
+		log.Warning("Failed to save test history: %s", err)
+	}
+}
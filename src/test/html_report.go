@@ -0,0 +1,239 @@
+// Generates a static HTML report of test results and coverage.
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // needed to use //go:embed
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/fs"
+)
+
+//go:embed html_report.tmpl
+var htmlReportTemplateStr string
+
+var htmlReportTemplate = template.Must(template.New("html_report").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"percent": func(covered, total int) string {
+		if total == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.1f%%", 100.0*float64(covered)/float64(total))
+	},
+}).Parse(htmlReportTemplateStr))
+
+// An htmlPage is the data common to every page of the report.
+type htmlPage struct {
+	Title string
+	// Root is the relative path from this page to the root of the report.
+	Root string
+}
+
+type htmlIndex struct {
+	htmlPage
+	Targets               []*htmlTarget
+	Passed, Failed, Flaky int
+	Dirs                  []*htmlDir
+	Covered, Total        int
+}
+
+type htmlTarget struct {
+	Label, Path, Status, Duration  string
+	Tests, Failures, Skips, Flakes int
+	Cached, TimedOut, Quarantined  bool
+	Cases                          []htmlCase
+}
+
+type htmlCase struct {
+	Name, Status, Duration string
+	Executions             []htmlExecution
+}
+
+type htmlExecution struct {
+	core.TestExecution
+	Status, Duration string
+}
+
+type htmlDir struct {
+	Name           string
+	Files          []*htmlFile
+	Covered, Total int
+}
+
+type htmlFile struct {
+	Name, Path     string
+	Covered, Total int
+}
+
+type htmlLine struct {
+	Number      int
+	Text, Class string
+}
+
+// WriteHTMLReportOrDie writes a static HTML report of the test results and coverage in this build
+// to the given directory. It doesn't need anything external (e.g. scripts or stylesheets) to be viewed.
+func WriteHTMLReportOrDie(state *core.BuildState, dir string) {
+	if err := writeHTMLReport(state.Graph, state.Coverage, dir); err != nil {
+		log.Fatalf("Failed to write HTML report: %s", err)
+	}
+}
+
+func writeHTMLReport(graph *core.BuildGraph, coverage core.TestCoverage, dir string) error {
+	index := &htmlIndex{htmlPage: htmlPage{Title: "Test report"}}
+	for _, target := range graph.AllTargets() {
+		if !target.IsTest() || target.Test.Results == nil {
+			continue
+		}
+		t := newHTMLTarget(target)
+		index.Targets = append(index.Targets, t)
+		switch t.Status {
+		case "fail":
+			index.Failed++
+		case "flaky":
+			index.Flaky++
+		default:
+			index.Passed++
+		}
+		page := struct {
+			htmlPage
+			Target *htmlTarget
+		}{htmlPage: htmlPage{Title: t.Label, Root: relativeRoot(t.Path)}, Target: t}
+		if err := writeHTMLPage(dir, t.Path, "target", page); err != nil {
+			return err
+		}
+	}
+	dirs := map[string]*htmlDir{}
+	for _, filename := range coverage.OrderedFiles() {
+		lines := coverage.Files[filename]
+		covered, total := CountCoverage(lines)
+		f := &htmlFile{Name: path.Base(filename), Path: path.Join("coverage", filename+".html"), Covered: covered, Total: total}
+		d, present := dirs[path.Dir(filename)]
+		if !present {
+			d = &htmlDir{Name: path.Dir(filename)}
+			dirs[d.Name] = d
+			index.Dirs = append(index.Dirs, d)
+		}
+		d.Files = append(d.Files, f)
+		d.Covered += covered
+		d.Total += total
+		index.Covered += covered
+		index.Total += total
+		page := struct {
+			htmlPage
+			File  *htmlFile
+			Lines []htmlLine
+		}{htmlPage: htmlPage{Title: filename, Root: relativeRoot(f.Path)}, File: f, Lines: annotateSource(filename, lines)}
+		if err := writeHTMLPage(dir, f.Path, "file", page); err != nil {
+			return err
+		}
+	}
+	sort.Slice(index.Dirs, func(i, j int) bool { return index.Dirs[i].Name < index.Dirs[j].Name })
+	return writeHTMLPage(dir, "index.html", "index", index)
+}
+
+// newHTMLTarget converts a test target's results for the report.
+func newHTMLTarget(target *core.BuildTarget) *htmlTarget {
+	results := target.Test.Results
+	t := &htmlTarget{
+		Label:       target.Label.String(),
+		Path:        path.Join("tests", target.Label.Subrepo, target.Label.PackageName, target.Label.Name+".html"),
+		Status:      "pass",
+		Duration:    formatHTMLDuration(results.Duration),
+		Tests:       results.Tests(),
+		Failures:    results.Failures() + results.Errors(),
+		Skips:       results.Skips(),
+		Flakes:      results.FlakyPasses(),
+		Cached:      results.Cached,
+		TimedOut:    results.TimedOut,
+		Quarantined: results.Quarantined,
+	}
+	if t.Failures > 0 || t.TimedOut {
+		t.Status = "fail"
+	} else if t.Flakes > 0 {
+		t.Status = "flaky"
+	}
+	for _, testCase := range results.TestCases {
+		c := htmlCase{Name: testCase.Name, Status: TestCaseStatus(testCase)}
+		if testCase.ClassName != "" {
+			c.Name = testCase.ClassName + "." + testCase.Name
+		}
+		if d := testCase.Duration(); d != nil {
+			c.Duration = formatHTMLDuration(*d)
+		}
+		for _, execution := range testCase.Executions {
+			e := htmlExecution{TestExecution: execution, Status: testExecutionStatus(execution)}
+			if execution.Duration != nil {
+				e.Duration = formatHTMLDuration(*execution.Duration)
+			}
+			c.Executions = append(c.Executions, e)
+		}
+		t.Cases = append(t.Cases, c)
+	}
+	return t
+}
+
+// testExecutionStatus returns a short description of the result of a single execution of a test case.
+func testExecutionStatus(execution core.TestExecution) string {
+	if execution.Error != nil {
+		return "error"
+	} else if execution.Failure != nil {
+		return "fail"
+	} else if execution.Skip != nil {
+		return "skip"
+	}
+	return "pass"
+}
+
+func formatHTMLDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// annotateSource reads a source file and annotates each of its lines with its coverage.
+func annotateSource(filename string, coverage []core.LineCoverage) []htmlLine {
+	lines := []htmlLine{}
+	f, err := os.Open(filename)
+	if err != nil {
+		log.Warning("Failed to read %s for HTML report: %s", filename, err)
+		return lines
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for i := 0; scanner.Scan(); i++ {
+		line := htmlLine{Number: i + 1, Text: scanner.Text()}
+		if i < len(coverage) {
+			switch coverage[i] {
+			case core.Covered:
+				line.Class = "covered"
+			case core.Uncovered:
+				line.Class = "uncovered"
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// relativeRoot returns the relative path from a page at the given path back to the root of the report.
+func relativeRoot(pagePath string) string {
+	return strings.Repeat("../", strings.Count(pagePath, "/"))
+}
+
+// writeHTMLPage renders a single page of the report.
+func writeHTMLPage(dir, pagePath, tmpl string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := htmlReportTemplate.ExecuteTemplate(&buf, tmpl, data); err != nil {
+		return err
+	}
+	return fs.WriteFile(&buf, filepath.Join(dir, pagePath), 0644)
+}
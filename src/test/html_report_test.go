@@ -0,0 +1,64 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+	graph := core.NewGraph()
+	duration := 1500 * time.Millisecond
+	target := core.NewBuildTarget(core.ParseBuildLabel("//src/test:html_test", ""))
+	target.Test = &core.TestFields{Results: &core.TestSuite{
+		Duration: 3 * time.Second,
+		TestCases: core.TestCases{
+			{
+				Name:       "TestPasses",
+				Executions: []core.TestExecution{{Duration: &duration, Stdout: "<everything is fine>"}},
+			},
+			{
+				Name: "TestFlaky",
+				Executions: []core.TestExecution{
+					{Failure: &core.TestResultFailure{Type: "AssertionError", Message: "1 != 2"}},
+					{Duration: &duration},
+				},
+			},
+		},
+	}}
+	graph.AddTarget(target)
+	coverage := core.TestCoverage{Files: map[string][]core.LineCoverage{
+		"src/test/test_data/go_test_pass.txt": {core.Covered, core.Uncovered, core.NotExecutable},
+	}}
+	dir := t.TempDir()
+	require.NoError(t, writeHTMLReport(graph, coverage, dir))
+
+	index := readFile(t, filepath.Join(dir, "index.html"))
+	assert.Contains(t, index, `<a href="tests/src/test/html_test.html">//src/test:html_test</a>`)
+	assert.Contains(t, index, `<td class="flaky">flaky</td>`)
+	assert.Contains(t, index, `<summary>src/test/test_data</summary>`)
+	assert.Contains(t, index, `Total: 50.0% (1 / 2 lines)`)
+
+	page := readFile(t, filepath.Join(dir, "tests/src/test/html_test.html"))
+	assert.Contains(t, page, `<a href="../../../index.html">Summary</a>`)
+	assert.Contains(t, page, "&lt;everything is fine&gt;")
+	assert.Contains(t, page, "AssertionError: 1 != 2")
+	assert.Contains(t, page, `Run 2: <span class="pass">pass</span> in 1.5s`)
+
+	source := readFile(t, filepath.Join(dir, "coverage/src/test/test_data/go_test_pass.txt.html"))
+	assert.Contains(t, source, `<tr class="covered"><td class="line">1</td>`)
+	assert.Contains(t, source, `<tr class="uncovered"><td class="line">2</td>`)
+	assert.Contains(t, source, `<tr class=""><td class="line">3</td>`)
+}
+
+func readFile(t *testing.T, filename string) string {
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	return string(b)
+}
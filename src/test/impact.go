@@ -0,0 +1,238 @@
+package test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"slices"
+	"sort"
+
+	"github.com/thought-machine/please/src/core"
+	"github.com/thought-machine/please/src/fs"
+)
+
+// CoverageIndexFile is the file we store the coverage index in.
+const CoverageIndexFile = "plz-out/.coverage_index"
+
+// A CoverageIndex records which source lines each test covered, as of the last time it was run
+// under `plz cover`. It's used to work out which tests are affected by a change.
+type CoverageIndex struct {
+	// Tests maps each test to the lines it covered in each file.
+	Tests map[core.BuildLabel]map[string][]int
+	// Cases maps each test to the lines covered by each of its test cases, for tests whose coverage
+	// was broken down that way.
+	Cases map[core.BuildLabel]map[string]map[string][]int
+	// Files is the set of all files that we've got coverage information for.
+	// If a change touches a file that isn't in here, we can't say anything about which tests cover it.
+	Files map[string]bool
+}
+
+// NewCoverageIndex returns a new, empty, CoverageIndex.
+func NewCoverageIndex() *CoverageIndex {
+	return &CoverageIndex{
+		Tests: map[core.BuildLabel]map[string][]int{},
+		Cases: map[core.BuildLabel]map[string]map[string][]int{},
+		Files: map[string]bool{},
+	}
+}
+
+// LoadCoverageIndex loads the coverage index from the given file.
+// If it can't be read, an empty index is returned.
+func LoadCoverageIndex(filename string) *CoverageIndex {
+	idx := NewCoverageIndex()
+	f, err := os.Open(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Failed to read coverage index: %s", err)
+		}
+		return idx
+	}
+	defer f.Close()
+	if err := gob.NewDecoder(f).Decode(idx); err != nil {
+		log.Warning("Failed to decode coverage index: %s", err)
+		return NewCoverageIndex()
+	}
+	return idx
+}
+
+// Save writes the coverage index to the given file.
+func (idx *CoverageIndex) Save(filename string) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(idx); err != nil {
+		return err
+	}
+	return fs.WriteFile(&buf, filename, 0644)
+}
+
+// Update replaces the entries for each test in the given coverage with what it covered this time.
+// Tests for which partial returns true only ran some of their test cases, so their coverage is merged
+// into what we already had instead.
+func (idx *CoverageIndex) Update(coverage *core.TestCoverage, partial func(core.BuildLabel) bool) {
+	for label, files := range coverage.Tests {
+		for file := range files {
+			idx.Files[file] = true
+		}
+		cases := coverage.TestCases[label]
+		if !partial(label) {
+			idx.Tests[label] = coveredLines(files)
+			delete(idx.Cases, label)
+		} else if existing, present := idx.Tests[label]; present {
+			idx.Tests[label] = mergeCoveredLines(existing, coveredLines(files))
+		} else {
+			idx.Tests[label] = coveredLines(files)
+		}
+		if len(cases) > 0 && idx.Cases[label] == nil {
+			idx.Cases[label] = make(map[string]map[string][]int, len(cases))
+		}
+		for name, files := range cases {
+			idx.Cases[label][name] = coveredLines(files)
+		}
+	}
+}
+
+// ranAllCases returns true if all of the given test cases appear in the given results.
+func ranAllCases(results core.TestCases, cases map[string]map[string][]core.LineCoverage) bool {
+	for name := range cases {
+		if !results.Includes(name) {
+			return false
+		}
+	}
+	return true
+}
+
+// coveredLines returns the line numbers that are covered in each of the given files.
+func coveredLines(files map[string][]core.LineCoverage) map[string][]int {
+	covered := map[string][]int{}
+	for file, lines := range files {
+		for i, line := range lines {
+			if line == core.Covered {
+				covered[file] = append(covered[file], i+1) // +1 because lines are 1-indexed.
+			}
+		}
+	}
+	return covered
+}
+
+// Split splits a set of changes (a map of filename -> modified lines, as returned by scm.ChangedOriginalLinesIn)
+// into those to files that we have coverage for, and the names of files that we don't know anything about.
+func (idx *CoverageIndex) Split(changes map[string][]int) (known map[string][]int, unknown []string) {
+	known = map[string][]int{}
+	for file, lines := range changes {
+		if idx.Files[file] {
+			known[file] = lines
+		} else {
+			unknown = append(unknown, file)
+		}
+	}
+	sort.Strings(unknown)
+	return known, unknown
+}
+
+// Affected returns the subset of the given candidate tests that covered any of the given changes
+// (which should be limited to known files, as returned by Split).
+// The candidates should be the tests that depend on the changed files at the target level; any we don't
+// have coverage for are assumed to be affected.
+func (idx *CoverageIndex) Affected(candidates []core.BuildLabel, changes map[string][]int) []core.BuildLabel {
+	affected := []core.BuildLabel{}
+	for _, label := range candidates {
+		if covered, present := idx.Tests[label]; !present || coversChanges(covered, changes) {
+			affected = append(affected, label)
+		}
+	}
+	return affected
+}
+
+// mergeCoveredLines returns the union of two sets of covered lines.
+func mergeCoveredLines(a, b map[string][]int) map[string][]int {
+	merged := make(map[string][]int, len(a))
+	for file, lines := range a {
+		merged[file] = lines
+	}
+	for file, lines := range b {
+		merged[file] = append(slices.Clone(merged[file]), lines...)
+		slices.Sort(merged[file])
+		merged[file] = slices.Compact(merged[file])
+	}
+	return merged
+}
+
+// AffectedCases returns the test cases of the given test that covered any of the given changes, which should
+// be limited to known files as for Affected.
+// It returns nil if they should all be run; that's the case if we don't have coverage for individual test
+// cases, or if any of the changes were covered by the test outside of any one of them (e.g. during setup).
+func (idx *CoverageIndex) AffectedCases(label core.BuildLabel, changes map[string][]int) []string {
+	cases := idx.Cases[label]
+	if len(cases) == 0 {
+		return nil
+	}
+	affected := map[string]bool{}
+	for file, lines := range changes {
+		if len(lines) == 0 {
+			return nil // Only deletions; we can't tell which cases are affected.
+		}
+		for _, line := range lines {
+			change := map[string][]int{file: {line}}
+			if !coversChanges(idx.Tests[label], change) {
+				continue
+			}
+			found := false
+			for name, covered := range cases {
+				if coversChanges(covered, change) {
+					affected[name] = true
+					found = true
+				}
+			}
+			if !found {
+				return nil
+			}
+		}
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+	ret := make([]string, 0, len(affected))
+	for name := range affected {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// coversChanges returns true if any of the changed lines are in the given coverage.
+func coversChanges(covered map[string][]int, changes map[string][]int) bool {
+	for file, lines := range changes {
+		coveredLines := covered[file]
+		if len(coveredLines) == 0 {
+			continue
+		} else if len(lines) == 0 {
+			return true // We don't know which of its original lines changed; assume it's affected.
+		}
+		for _, line := range lines {
+			if i := sort.SearchInts(coveredLines, line); i < len(coveredLines) && coveredLines[i] == line {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpdateCoverageIndex updates the coverage index on disk with the coverage from this build.
+func UpdateCoverageIndex(state *core.BuildState) {
+	idx := LoadCoverageIndex(CoverageIndexFile)
+	// Only trust coverage of individual test cases if they're all ones the test actually ran.
+	coverage := state.Coverage
+	coverage.TestCases = map[core.BuildLabel]map[string]map[string][]core.LineCoverage{}
+	for label, cases := range state.Coverage.TestCases {
+		if target := state.Graph.Target(label); target != nil && target.Test != nil && target.Test.Results != nil && ranAllCases(target.Test.Results.TestCases, cases) {
+			coverage.TestCases[label] = cases
+		} else {
+			log.Debug("Ignoring coverage of individual test cases for %s, they don't match its test results", label)
+		}
+	}
+	idx.Update(&coverage, func(label core.BuildLabel) bool {
+		return len(state.TestSelection(label)) > 0
+	})
+	if err := idx.Save(CoverageIndexFile); err != nil {
+		log.Warning("Failed to save coverage index: %s", err)
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+var (
+	fooTest = core.ParseBuildLabel("//src/foo:foo_test", "")
+	barTest = core.ParseBuildLabel("//src/bar:bar_test", "")
+	newTest = core.ParseBuildLabel("//src/new:new_test", "")
+)
+
+func newTestCoverageIndex() *CoverageIndex {
+	const N, U, C = core.NotExecutable, core.Uncovered, core.Covered
+	idx := NewCoverageIndex()
+	idx.Update(&core.TestCoverage{
+		Tests: map[core.BuildLabel]map[string][]core.LineCoverage{
+			fooTest: {
+				"src/foo/foo.go": {N, C, C, U, N},
+				"src/lib/lib.go": {N, C, U, U},
+			},
+			barTest: {
+				"src/bar/bar.go": {C, C, C},
+				"src/lib/lib.go": {N, U, C, U},
+			},
+		},
+		TestCases: map[core.BuildLabel]map[string]map[string][]core.LineCoverage{
+			barTest: {
+				"TestOne": {"src/bar/bar.go": {C, C}},
+				"TestTwo": {"src/bar/bar.go": {C, N, C}},
+			},
+		},
+	}, noneSelected)
+	return idx
+}
+
+func noneSelected(core.BuildLabel) bool { return false }
+
+func TestCoverageIndexUpdate(t *testing.T) {
+	idx := newTestCoverageIndex()
+	assert.Equal(t, map[string][]int{
+		"src/foo/foo.go": {2, 3},
+		"src/lib/lib.go": {2},
+	}, idx.Tests[fooTest])
+	assert.Equal(t, map[string]bool{
+		"src/foo/foo.go": true,
+		"src/bar/bar.go": true,
+		"src/lib/lib.go": true,
+	}, idx.Files)
+
+	assert.Equal(t, map[string]map[string][]int{
+		"TestOne": {"src/bar/bar.go": {1, 2}},
+		"TestTwo": {"src/bar/bar.go": {1, 3}},
+	}, idx.Cases[barTest])
+
+	// A later run replaces the previous one.
+	idx.Update(&core.TestCoverage{
+		Tests: map[core.BuildLabel]map[string][]core.LineCoverage{
+			fooTest: {"src/foo/foo.go": {core.Covered}},
+			barTest: {"src/bar/bar.go": {core.Covered}},
+		},
+	}, noneSelected)
+	assert.Equal(t, map[string][]int{"src/foo/foo.go": {1}}, idx.Tests[fooTest])
+	assert.NotContains(t, idx.Cases, barTest)
+}
+
+func TestCoverageIndexUpdatePartial(t *testing.T) {
+	const N, C = core.NotExecutable, core.Covered
+	idx := newTestCoverageIndex()
+	// Only TestTwo ran this time, so what we knew about TestOne is kept.
+	idx.Update(&core.TestCoverage{
+		Tests: map[core.BuildLabel]map[string][]core.LineCoverage{
+			barTest: {"src/bar/bar.go": {N, N, C, C}},
+		},
+		TestCases: map[core.BuildLabel]map[string]map[string][]core.LineCoverage{
+			barTest: {"TestTwo": {"src/bar/bar.go": {N, N, C, C}}},
+		},
+	}, func(label core.BuildLabel) bool { return label == barTest })
+	assert.Equal(t, map[string][]int{
+		"src/bar/bar.go": {1, 2, 3, 4},
+		"src/lib/lib.go": {3},
+	}, idx.Tests[barTest])
+	assert.Equal(t, map[string]map[string][]int{
+		"TestOne": {"src/bar/bar.go": {1, 2}},
+		"TestTwo": {"src/bar/bar.go": {3, 4}},
+	}, idx.Cases[barTest])
+}
+
+func TestCoverageIndexSplit(t *testing.T) {
+	idx := newTestCoverageIndex()
+	known, unknown := idx.Split(map[string][]int{
+		"src/foo/foo.go": {1},
+		"src/foo/BUILD":  {3},
+		"README.md":      {1},
+	})
+	assert.Equal(t, map[string][]int{"src/foo/foo.go": {1}}, known)
+	assert.Equal(t, []string{"README.md", "src/foo/BUILD"}, unknown)
+}
+
+func TestCoverageIndexAffected(t *testing.T) {
+	idx := newTestCoverageIndex()
+	candidates := []core.BuildLabel{fooTest, barTest, newTest}
+	// Line 3 of lib.go is only covered by bar_test. We don't know anything about new_test so it must be run.
+	assert.Equal(t, []core.BuildLabel{barTest, newTest}, idx.Affected(candidates, map[string][]int{"src/lib/lib.go": {3}}))
+	// Line 2 is covered by foo_test.
+	assert.Equal(t, []core.BuildLabel{fooTest, newTest}, idx.Affected(candidates, map[string][]int{"src/lib/lib.go": {2}}))
+	// Line 4 isn't covered by anything.
+	assert.Equal(t, []core.BuildLabel{newTest}, idx.Affected(candidates, map[string][]int{"src/lib/lib.go": {4}}))
+	// If lines were only deleted, we assume anything that covered the file is affected.
+	assert.Equal(t, []core.BuildLabel{fooTest, barTest, newTest}, idx.Affected(candidates, map[string][]int{"src/lib/lib.go": {}}))
+}
+
+func TestCoverageIndexAffectedCases(t *testing.T) {
+	idx := newTestCoverageIndex()
+	// Line 2 of bar.go is only covered by TestOne.
+	assert.Equal(t, []string{"TestOne"}, idx.AffectedCases(barTest, map[string][]int{"src/bar/bar.go": {2}}))
+	assert.Equal(t, []string{"TestOne", "TestTwo"}, idx.AffectedCases(barTest, map[string][]int{"src/bar/bar.go": {1, 3}}))
+	// Line 3 of lib.go is covered by bar_test, but not by any individual test case, so they all need to run.
+	assert.Nil(t, idx.AffectedCases(barTest, map[string][]int{"src/lib/lib.go": {3}, "src/bar/bar.go": {2}}))
+	// We don't know anything about foo_test's test cases.
+	assert.Nil(t, idx.AffectedCases(fooTest, map[string][]int{"src/foo/foo.go": {2}}))
+	// Nor can we tell which ones are affected by deletions.
+	assert.Nil(t, idx.AffectedCases(barTest, map[string][]int{"src/bar/bar.go": {}}))
+}
+
+func TestCoverageIndexSaveAndLoad(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "coverage_index")
+	idx := newTestCoverageIndex()
+	require.NoError(t, idx.Save(filename))
+	assert.Equal(t, idx, LoadCoverageIndex(filename))
+	assert.Equal(t, NewCoverageIndex(), LoadCoverageIndex(filepath.Join(t.TempDir(), "nope")))
+}
+
+func TestRanAllCases(t *testing.T) {
+	results := core.TestCases{{ClassName: "lib", Name: "test_parse"}, {Name: "test_lex"}}
+	assert.True(t, ranAllCases(results, map[string]map[string][]core.LineCoverage{"test_parse": nil, "test_lex": nil}))
+	// A name for the whole run isn't one of the test cases.
+	assert.False(t, ranAllCases(results, map[string]map[string][]core.LineCoverage{"lib_test": nil}))
+}
@@ -0,0 +1,104 @@
+// Code for parsing and writing LCOV tracefiles (as produced by e.g. lcov, grcov, llvm-cov or c8).
+// The format is described in the geninfo(1) man page; we're only interested in the line data.
+// Normally a tracefile has a single test name (the TN: line), if any, for the whole run. If it has several,
+// we take them to be the test cases that each record was collected for and record their coverage separately.
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+func looksLikeLCOVCoverageResults(results []byte) bool {
+	results = bytes.TrimSpace(results)
+	return bytes.HasPrefix(results, []byte("TN:")) || bytes.HasPrefix(results, []byte("SF:"))
+}
+
+func parseLCOVCoverageResults(target *core.BuildTarget, coverage *core.TestCoverage, data []byte, run int) error {
+	filename, testName := "", ""
+	var lines []core.LineCoverage
+	cases := map[string]map[string][]core.LineCoverage{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "TN:") {
+			testName = strings.TrimPrefix(line, "TN:")
+		} else if strings.HasPrefix(line, "SF:") {
+			filename = sanitiseFileName(target, strings.TrimPrefix(strings.TrimPrefix(line, "SF:"), core.RepoRoot+"/"), run)
+			lines = nil
+		} else if strings.HasPrefix(line, "DA:") {
+			// DA:<line number>,<execution count>[,<checksum>]
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(fields) < 2 {
+				return fmt.Errorf("Bad line data on line %d: %s", lineno, line)
+			}
+			n, err := strconv.Atoi(fields[0])
+			if err != nil || n < 1 {
+				return fmt.Errorf("Bad line number on line %d: %s", lineno, line)
+			}
+			for len(lines) < n {
+				lines = append(lines, core.NotExecutable)
+			}
+			// Some tools write a count of -1 for lines that aren't executable.
+			if count, _ := strconv.ParseInt(fields[1], 10, 64); count > 0 {
+				lines[n-1] = core.Covered
+			} else if count == 0 && lines[n-1] != core.Covered {
+				lines[n-1] = core.Uncovered
+			}
+		} else if line == "end_of_record" {
+			if filename == "" {
+				return fmt.Errorf("Record without a source file ending on line %d", lineno)
+			}
+			// The same file can appear in multiple records, so merge them together.
+			coverage.Files[filename] = core.MergeCoverageLines(coverage.Files[filename], lines)
+			if testName != "" {
+				if cases[testName] == nil {
+					cases[testName] = map[string][]core.LineCoverage{}
+				}
+				cases[testName][filename] = core.MergeCoverageLines(cases[testName][filename], lines)
+			}
+			filename = ""
+			lines = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	coverage.Tests[target.Label] = coverage.Files
+	if len(cases) > 1 {
+		coverage.TestCases = map[core.BuildLabel]map[string]map[string][]core.LineCoverage{target.Label: cases}
+	}
+	return nil
+}
+
+// WriteLCOVCoverageToFileOrDie writes the collected coverage data to a file in LCOV format. Dies on failure.
+// We don't track execution counts, so covered lines are always recorded as having been executed once.
+func WriteLCOVCoverageToFileOrDie(coverage core.TestCoverage, filename string) {
+	if err := os.WriteFile(filename, coverageToLCOV(coverage), 0644); err != nil {
+		log.Fatalf("Failed to write coverage results to %s: %s", filename, err)
+	}
+}
+
+func coverageToLCOV(coverage core.TestCoverage) []byte {
+	var buf bytes.Buffer
+	for _, file := range coverage.OrderedFiles() {
+		fmt.Fprintf(&buf, "TN:\nSF:%s\n", file)
+		for i, line := range coverage.Files[file] {
+			if line == core.Covered {
+				fmt.Fprintf(&buf, "DA:%d,1\n", i+1)
+			} else if line != core.NotExecutable {
+				fmt.Fprintf(&buf, "DA:%d,0\n", i+1)
+			}
+		}
+		covered, total := CountCoverage(coverage.Files[file])
+		fmt.Fprintf(&buf, "LF:%d\nLH:%d\nend_of_record\n", total, covered)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,25 @@
+// The built-in formats that we can parse test results in.
+
+package test
+
+import "github.com/thought-machine/please/src/core"
+
+func init() {
+	// More specific formats come first since we use the first one that detects the results.
+	// Go's plain text output can't be detected, so it's the fallback if nothing else matches.
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "junit", Detect: looksLikeJUnitXMLTestResults, Parse: parseJUnitXMLTestSuite})
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "go_json", Detect: looksLikeGoJSONTestResults, Parse: parseGoJSONTestResults})
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "ctrf", Detect: looksLikeCTRFTestResults, Parse: parseCTRFTestResults})
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "tap", Detect: looksLikeTAPTestResults, Parse: parseTAPTestResults})
+	core.RegisterTestResultsFormat(core.TestResultsFormat{Name: "go", Parse: parseGoTestResults})
+}
+
+// parseJUnitXMLTestSuite parses JUnit XML results, collapsing them into a single suite.
+func parseJUnitXMLTestSuite(data []byte) (core.TestSuite, error) {
+	testSuites, err := parseJUnitXMLTestResults(data)
+	testSuite := core.TestSuite{}
+	for _, suite := range testSuites.TestSuites {
+		testSuite.Collapse(suite)
+	}
+	return testSuite, err
+}
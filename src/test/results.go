@@ -12,10 +12,11 @@ import (
 	"github.com/thought-machine/please/src/fs"
 )
 
-func parseTestResults(data [][]byte) (core.TestSuite, error) {
+// parseTestResults parses test results in the given format (or detects the format if it's empty).
+func parseTestResults(data [][]byte, format string) (core.TestSuite, error) {
 	suite := core.TestSuite{}
 	for _, datum := range data {
-		newSuite, err := parseTestResultDatum(datum)
+		newSuite, err := parseTestResultDatum(datum, format)
 		if err != nil {
 			return suite, err
 		}
@@ -24,27 +25,23 @@ func parseTestResults(data [][]byte) (core.TestSuite, error) {
 	return suite, nil
 }
 
-func parseTestResultsFile(file string) (core.TestSuite, error) {
+func parseTestResultsFile(file, format string) (core.TestSuite, error) {
 	data, err := readTestResultsDir(file)
 	if err != nil {
 		return core.TestSuite{}, err
 	}
-	return parseTestResults(data)
+	return parseTestResults(data, format)
 }
 
-func parseTestResultDatum(data []byte) (core.TestSuite, error) {
+func parseTestResultDatum(data []byte, format string) (core.TestSuite, error) {
 	if len(data) == 0 {
 		return core.TestSuite{}, fmt.Errorf("No results")
-	} else if looksLikeJUnitXMLTestResults(data) {
-		testSuites, err := parseJUnitXMLTestResults(data)
-		testSuite := core.TestSuite{}
-		for _, suite := range testSuites.TestSuites {
-			testSuite.Collapse(suite)
-		}
-		return testSuite, err
-	} else {
-		return parseGoTestResults(data)
 	}
+	f, err := core.FindTestResultsFormat(format, data)
+	if err != nil {
+		return core.TestSuite{}, err
+	}
+	return f.Parse(data)
 }
 
 func readTestResultsDir(outputDir string) ([][]byte, error) {
@@ -66,8 +63,9 @@ func readTestResultsDir(outputDir string) ([][]byte, error) {
 }
 
 // LoadPreviousFailures loads any failed tests from the given results file.
-// It returns the set of targets that should be run and any arguments for them.
-func LoadPreviousFailures(filename string) ([]core.BuildLabel, []string) {
+// It returns the set of targets that should be run and the test cases to run for each of them;
+// targets that failed without any individual test cases failing aren't in the latter and should be run in full.
+func LoadPreviousFailures(filename string) ([]core.BuildLabel, map[core.BuildLabel][]string) {
 	f, err := os.Open(filename)
 	if err != nil {
 		log.Fatalf("Failed to read previous test results: %s", err)
@@ -80,17 +78,79 @@ func LoadPreviousFailures(filename string) ([]core.BuildLabel, []string) {
 		log.Fatalf("Failed to read previous test results: %s", err)
 	}
 	labels := []core.BuildLabel{}
-	args := []string{}
+	selections := map[core.BuildLabel][]string{}
 	for _, suite := range junit.TestSuites {
-		if suite.Failures > 0 {
-			labels = append(labels, core.NewBuildLabel(
-				strings.ReplaceAll(suite.Package, ".", "/"), suite.Name))
+		if suite.Failures > 0 || suite.Errors > 0 {
+			label := suiteLabel(suite.Package, suite.Name)
+			labels = append(labels, label)
 			for _, c := range suite.TestCases {
 				if c.Failure != nil || c.Error != nil {
-					args = append(args, c.Name)
+					selections[label] = append(selections[label], c.Name)
 				}
 			}
 		}
 	}
-	return labels, args
+	return labels, selections
+}
+
+// LoadPreviousResults loads the results of each target from the given results file.
+// It returns an empty map if the file doesn't exist or can't be read.
+func LoadPreviousResults(filename string) map[core.BuildLabel]core.TestSuite {
+	results := map[core.BuildLabel]core.TestSuite{}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warning("Failed to read previous test results: %s", err)
+		}
+		return results
+	}
+	suites, err := parseJUnitXMLTestResults(data)
+	if err != nil {
+		log.Warning("Failed to parse previous test results: %s", err)
+		return results
+	}
+	for _, suite := range suites.TestSuites {
+		// We fill in the class name when writing the results if it was empty; undo that so they match new results.
+		for i, testCase := range suite.TestCases {
+			if testCase.ClassName == suite.JavaStyleName() {
+				suite.TestCases[i].ClassName = ""
+			}
+		}
+		results[suiteLabel(suite.Package, suite.Name)] = suite
+	}
+	return results
+}
+
+// MergePreviousResults merges the results of any targets that only ran some of their test cases into
+// their results from a previous run, so the results for them are still complete.
+func MergePreviousResults(state *core.BuildState, previous map[core.BuildLabel]core.TestSuite) {
+	for _, label := range state.ExpandOriginalLabels() {
+		target := state.Graph.TargetOrDie(label)
+		if !target.IsTest() || target.Test.Results == nil || len(state.TestSelection(label)) == 0 {
+			continue
+		} else if suite, present := previous[label]; present {
+			suite.Merge(*target.Test.Results)
+			target.Test.Results = &suite
+		}
+	}
+}
+
+// suiteLabel returns the label of the target that a test suite in our results file came from.
+func suiteLabel(pkg, name string) core.BuildLabel {
+	return core.NewBuildLabel(strings.ReplaceAll(pkg, ".", "/"), name)
+}
+
+// TestCaseStatus returns a short description of the overall result of a test case.
+func TestCaseStatus(testCase core.TestCase) string {
+	if testCase.Success() != nil {
+		if testCase.FlakyPass() {
+			return "flaky"
+		}
+		return "pass"
+	} else if testCase.Skip() != nil {
+		return "skip"
+	} else if len(testCase.Errors()) > 0 {
+		return "error"
+	}
+	return "fail"
 }
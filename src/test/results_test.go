@@ -2,6 +2,7 @@ package test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -10,7 +11,7 @@ import (
 )
 
 func TestGoFailure(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_failure.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_failure.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 4, len(results.TestCases))
 	assert.Equal(t, 2, results.Passes())
@@ -19,7 +20,7 @@ func TestGoFailure(t *testing.T) {
 }
 
 func TestGoPassed(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_pass.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_pass.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 4, len(results.TestCases))
 	assert.Equal(t, 4, results.Passes())
@@ -28,7 +29,7 @@ func TestGoPassed(t *testing.T) {
 }
 
 func TestGoMultipleFailure(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_multiple_failure.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_multiple_failure.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(results.TestCases))
 	assert.Equal(t, 0, results.Passes())
@@ -37,7 +38,7 @@ func TestGoMultipleFailure(t *testing.T) {
 }
 
 func TestGoSkipped(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_skip.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_skip.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 4, len(results.TestCases))
 	assert.Equal(t, 3, results.Passes())
@@ -47,7 +48,7 @@ func TestGoSkipped(t *testing.T) {
 
 // TestGoSkipped tests the skip messages in versions of Go prior to 1.14.
 func TestGoSkippedMessage(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_skip.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_skip.txt", "")
 	require.NoError(t, err)
 
 	var skippedTC = getFirstSkippedTestCase(results)
@@ -56,7 +57,7 @@ func TestGoSkippedMessage(t *testing.T) {
 
 // Go 1.14 changes the ordering of skipped messages in Go tests
 func TestGoSkippedMessage114(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_skip_1_14.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_skip_1_14.txt", "")
 	require.NoError(t, err)
 
 	var skippedTC = getFirstSkippedTestCase(results)
@@ -74,7 +75,7 @@ func getFirstSkippedTestCase(ts core.TestSuite) *core.TestCase {
 
 // TestGoFailedMessage tests the location of failed test output in versions of Go prior to 1.14.
 func TestGoFailedTraceback(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_failure.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_failure.txt", "")
 	require.NoError(t, err)
 
 	var failedTC = getFirstFailedTestCase(results)
@@ -83,7 +84,7 @@ func TestGoFailedTraceback(t *testing.T) {
 
 // Go 1.14 changes the ordering of failed messages in Go tests
 func TestGoFailedTracebackGo114(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_fail_1_14.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_fail_1_14.txt", "")
 	require.NoError(t, err)
 
 	var failedTC = getFirstFailedTestCase(results)
@@ -100,7 +101,7 @@ func getFirstFailedTestCase(ts core.TestSuite) *core.TestCase {
 }
 
 func TestGoSubtests(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_subtests.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_subtests.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 7, len(results.TestCases))
 	assert.Equal(t, 7, results.Passes())
@@ -108,7 +109,7 @@ func TestGoSubtests(t *testing.T) {
 
 func TestBuckXML(t *testing.T) {
 	t.Skip("This format matches nothing we generate or care about")
-	results, err := parseTestResultsFile("src/test/test_data/junit.xml")
+	results, err := parseTestResultsFile("src/test/test_data/junit.xml", "")
 	require.NoError(t, err)
 	assert.Equal(t, 4, len(results.TestCases))
 	assert.Equal(t, 4, results.Passes())
@@ -117,7 +118,7 @@ func TestBuckXML(t *testing.T) {
 }
 
 func TestJUnitXML(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/xmlrunner-junit.xml")
+	results, err := parseTestResultsFile("src/test/test_data/xmlrunner-junit.xml", "")
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(results.TestCases))
 	assert.Equal(t, 1, results.Passes())
@@ -126,7 +127,7 @@ func TestJUnitXML(t *testing.T) {
 }
 
 func TestKarmaXML(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/karma-junit.xml")
+	results, err := parseTestResultsFile("src/test/test_data/karma-junit.xml", "")
 	require.NoError(t, err)
 	assert.Equal(t, 10, len(results.TestCases))
 	assert.Equal(t, 10, results.Passes())
@@ -135,7 +136,7 @@ func TestKarmaXML(t *testing.T) {
 }
 
 func TestUnitTestXML(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/unittest.xml")
+	results, err := parseTestResultsFile("src/test/test_data/unittest.xml", "")
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(results.TestCases))
 	assert.Equal(t, 0, results.Passes())
@@ -144,7 +145,7 @@ func TestUnitTestXML(t *testing.T) {
 }
 
 func TestSkip(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/xmlrunner-skipped.xml")
+	results, err := parseTestResultsFile("src/test/test_data/xmlrunner-skipped.xml", "")
 	require.NoError(t, err)
 	assert.Equal(t, 2, len(results.TestCases))
 	assert.Equal(t, 1, results.Passes())
@@ -152,7 +153,7 @@ func TestSkip(t *testing.T) {
 }
 
 func TestGoSuite(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_suite.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_suite.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 7, len(results.TestCases))
 	assert.Equal(t, 5, results.Passes())
@@ -161,7 +162,7 @@ func TestGoSuite(t *testing.T) {
 }
 
 func TestGoIgnoreUnknownOutput(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_ignore_logs.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_ignore_logs.txt", "")
 	require.NoError(t, err)
 	assert.Equal(t, 4, len(results.TestCases))
 	assert.Equal(t, 4, results.Passes())
@@ -170,14 +171,138 @@ func TestGoIgnoreUnknownOutput(t *testing.T) {
 }
 
 func TestParseGoFileWithNoTests(t *testing.T) {
-	_, err := parseTestResultsFile("src/test/test_data/go_empty_test.txt")
+	_, err := parseTestResultsFile("src/test/test_data/go_empty_test.txt", "")
 	assert.NoError(t, err)
 }
 
 func TestParseGoFileWithLogging(t *testing.T) {
-	results, err := parseTestResultsFile("src/test/test_data/go_test_logging.txt")
+	results, err := parseTestResultsFile("src/test/test_data/go_test_logging.txt", "")
 	assert.NoError(t, err)
 	assert.Equal(t, 3, len(results.TestCases))
 	assert.Equal(t, 3, results.Passes())
 	assert.Equal(t, 0, results.Failures())
 }
+
+func TestGoJSON(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/go_test_json.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(results.TestCases))
+	assert.Equal(t, 1, results.Passes())
+	assert.Equal(t, 1, results.Failures())
+	assert.Equal(t, 1, results.Skips())
+	assert.Equal(t, "TestFails", results.TestCases[1].Name)
+	assert.Contains(t, results.TestCases[1].Executions[0].Failure.Message, "expected 1, got 2")
+}
+
+func TestTAP13(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/tap13.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(results.TestCases))
+	assert.Equal(t, 3, results.Passes())
+	assert.Equal(t, 1, results.Failures())
+	assert.Equal(t, 2, results.Skips())
+	assert.Equal(t, "parses the config", results.TestCases[0].Name)
+	failure := results.TestCases[1].Executions[0].Failure
+	require.NotNil(t, failure)
+	assert.Equal(t, "expected ENOENT", failure.Message)
+	assert.Equal(t, "at Object.<anonymous> (test/config.js:12:3)\nat run (node_modules/tape/index.js:88:5)", failure.Traceback)
+	assert.Equal(t, 12500*time.Microsecond, *results.TestCases[1].Duration())
+	assert.Equal(t, "escaped # hash", results.TestCases[2].Name)
+	assert.Equal(t, "not supported on this platform", results.TestCases[3].Skip().Skip.Message)
+	assert.Equal(t, "implement me", results.TestCases[4].Skip().Skip.Message)
+	assert.Equal(t, "test 6", results.TestCases[5].Name)
+}
+
+func TestTAP14Subtests(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/tap14_subtests.txt", "")
+	require.NoError(t, err)
+	// The subtests are summarised by their parent, so we only see the top-level tests.
+	assert.Equal(t, 2, len(results.TestCases))
+	assert.Equal(t, "widgets", results.TestCases[0].Name)
+	assert.Equal(t, 1, results.Failures())
+	assert.Equal(t, 1, results.Passes())
+}
+
+func TestTAPBailOut(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/tap_bail_out.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(results.TestCases))
+	assert.Equal(t, 1, results.Errors())
+	assert.Equal(t, "Database went away", results.TestCases[1].Executions[0].Error.Message)
+}
+
+func TestTAPPlanMismatch(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/tap_plan_mismatch.txt", "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(results.TestCases))
+	assert.Equal(t, 2, results.Passes())
+	assert.Equal(t, 1, results.Failures())
+	assert.Equal(t, "Planned 3 tests but ran 2", results.TestCases[2].Executions[0].Failure.Message)
+}
+
+func TestCTRF(t *testing.T) {
+	results, err := parseTestResultsFile("src/test/test_data/ctrf.json", "")
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(results.TestCases))
+	assert.Equal(t, 1, results.Passes()) // The flaky one doesn't count as a normal pass.
+	assert.Equal(t, 1, results.Failures())
+	assert.Equal(t, 1, results.Skips())
+	assert.Equal(t, 1, results.Errors())
+	assert.Equal(t, 1, results.FlakyPasses())
+	assert.Equal(t, 1508*time.Millisecond, results.Duration)
+	assert.Equal(t, "App", results.TestCases[0].ClassName)
+	assert.Equal(t, "App.buttons", results.TestCases[1].ClassName)
+	assert.Equal(t, "expected true to be false", results.TestCases[1].Executions[0].Failure.Message)
+	assert.Equal(t, "loading\ndone", results.TestCases[2].Success().Stdout)
+}
+
+func TestExplicitResultsFormat(t *testing.T) {
+	// This would be detected as Go output, but we can force it to be interpreted as TAP.
+	data := []byte("ok 1 - first\nnot ok 2 - second\n")
+	results, err := parseTestResults([][]byte{data}, "tap")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(results.TestCases))
+	assert.Equal(t, 1, results.Failures())
+
+	_, err = parseTestResults([][]byte{data}, "nope")
+	assert.Error(t, err)
+}
+
+func TestLoadPreviousFailures(t *testing.T) {
+	labels, selections := LoadPreviousFailures("src/test/test_data/previous_results.xml")
+	coreTest := core.ParseBuildLabel("//src/core:core_test", "")
+	timeoutTest := core.ParseBuildLabel("//src/fs:timeout_test", "")
+	assert.Equal(t, []core.BuildLabel{coreTest, timeoutTest}, labels)
+	// The timeout test has no failing test cases so it should be run in full.
+	assert.Equal(t, map[core.BuildLabel][]string{coreTest: {"TestFails"}}, selections)
+}
+
+func TestLoadPreviousResults(t *testing.T) {
+	results := LoadPreviousResults("src/test/test_data/previous_results.xml")
+	assert.Equal(t, 3, len(results))
+	suite := results[core.ParseBuildLabel("//src/core:core_test", "")]
+	require.Equal(t, 3, len(suite.TestCases))
+	assert.Equal(t, "", suite.TestCases[0].ClassName)
+	assert.Equal(t, "CoreTest", suite.TestCases[2].ClassName)
+	assert.Equal(t, 2, suite.Passes())
+	assert.Equal(t, 1, suite.Failures())
+	assert.Equal(t, 0, len(LoadPreviousResults("src/test/test_data/nope.xml")))
+}
+
+func TestMergePreviousResults(t *testing.T) {
+	state := core.NewDefaultBuildState()
+	label := core.ParseBuildLabel("//src/core:core_test", "")
+	target := core.NewBuildTarget(label)
+	target.Test = new(core.TestFields)
+	target.Test.Results = &core.TestSuite{
+		Package:   "src.core",
+		Name:      "core_test",
+		TestCases: core.TestCases{{Name: "TestFails", Executions: []core.TestExecution{{}}}},
+	}
+	state.Graph.AddTarget(target)
+	state.AddOriginalTarget(label, true)
+	state.TestSelections = map[core.BuildLabel][]string{label: {"TestFails"}}
+	MergePreviousResults(state, LoadPreviousResults("src/test/test_data/previous_results.xml"))
+	assert.Equal(t, 3, len(target.Test.Results.TestCases))
+	assert.Equal(t, 3, target.Test.Results.Passes())
+}
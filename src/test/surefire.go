@@ -13,7 +13,9 @@ func CopySurefireXMLFilesToDir(state *core.BuildState, surefireDir string) {
 	for _, label := range state.ExpandOriginalLabels() {
 		target := state.Graph.TargetOrDie(label)
 		if state.ShouldInclude(target) && target.IsTest() && !target.Test.NoOutput {
-			copySurefireXMLtoDir(target.TestResultsFile(), surefireDir)
+			for shard := 0; shard < target.NumTestShards(); shard++ {
+				copySurefireXMLtoDir(target.ShardResultsFile(shard), surefireDir)
+			}
 		}
 	}
 }
@@ -0,0 +1,217 @@
+// Parser for the Test Anything Protocol (https://testanything.org), versions 13 and 14.
+//
+// We only look at the top-level test points; subtests (which are indented) are summarised
+// by the test point that follows them, so we don't need to descend into them.
+
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thought-machine/please/src/core"
+)
+
+var (
+	tapVersion   = regexp.MustCompile(`^TAP version (1[34])$`)
+	tapPlan      = regexp.MustCompile(`^1\.\.(\d+)(?:\s*#\s*(.*))?$`)
+	tapTestPoint = regexp.MustCompile(`^(not ok|ok)\b\s*(\d+)?\s*(?:-\s*)?(.*)$`)
+	tapDirective = regexp.MustCompile(`(?i)^(skip|todo)\S*\s*(.*)$`)
+)
+
+// looksLikeTAPTestResults returns true if the given data looks like TAP output.
+// TAP output must have a plan either at the start or the end, which we use to identify it
+// (test points alone look too much like Go's output).
+func looksLikeTAPTestResults(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	first, _, _ := bytes.Cut(data, []byte{'\n'})
+	first = bytes.TrimSpace(first)
+	last := bytes.TrimSpace(data[bytes.LastIndexByte(data, '\n')+1:])
+	return tapVersion.Match(first) || tapPlan.Match(first) || tapPlan.Match(last)
+}
+
+// parseTAPTestResults parses TAP output into a test suite.
+func parseTAPTestResults(data []byte) (core.TestSuite, error) {
+	suite := core.TestSuite{}
+	planned := -1
+	var diagnostics []string // Comment lines since the last test point, which usually describe a failure.
+	var yamlLines []string
+	inYAML := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if inYAML {
+			if line == "  ..." {
+				inYAML = false
+				applyTAPYAML(&suite, yamlLines)
+				yamlLines = nil
+			} else {
+				yamlLines = append(yamlLines, line)
+			}
+			continue
+		} else if line == "  ---" && len(suite.TestCases) > 0 {
+			// A YAML block is indented by two spaces after the test point it belongs to (deeper ones are for subtests).
+			inYAML = true
+			continue
+		} else if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue // Part of a subtest.
+		} else if tapVersion.MatchString(line) || line == "" {
+			continue
+		} else if strings.HasPrefix(line, "Bail out!") {
+			suite.TestCases = append(suite.TestCases, core.TestCase{
+				Name: "Bail out!",
+				Executions: []core.TestExecution{{
+					Error: &core.TestResultFailure{
+						Message: strings.TrimSpace(strings.TrimPrefix(line, "Bail out!")),
+						Type:    "BailOut",
+					},
+				}},
+			})
+			return suite, nil
+		} else if m := tapPlan.FindStringSubmatch(line); m != nil {
+			planned, _ = strconv.Atoi(m[1])
+		} else if m := tapTestPoint.FindStringSubmatch(line); m != nil {
+			suite.TestCases = append(suite.TestCases, tapTestCase(m[1] == "ok", m[2], m[3], len(suite.TestCases)+1, diagnostics))
+			diagnostics = nil
+		} else if strings.HasPrefix(line, "#") {
+			diagnostics = append(diagnostics, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return suite, err
+	} else if inYAML {
+		return suite, fmt.Errorf("Unterminated YAML block in TAP output")
+	} else if planned >= 0 && len(suite.TestCases) != planned {
+		suite.TestCases = append(suite.TestCases, core.TestCase{
+			Name: "Plan",
+			Executions: []core.TestExecution{{
+				Failure: &core.TestResultFailure{
+					Message: fmt.Sprintf("Planned %d tests but ran %d", planned, len(suite.TestCases)),
+					Type:    "PlanMismatch",
+				},
+			}},
+		})
+	}
+	for _, tc := range suite.TestCases {
+		if d := tc.Duration(); d != nil {
+			suite.Duration += *d
+		}
+	}
+	return suite, nil
+}
+
+// tapTestCase creates a test case from a single TAP test point.
+func tapTestCase(ok bool, number, description string, index int, diagnostics []string) core.TestCase {
+	description, directive := splitTAPDirective(description)
+	if description == "" {
+		if number == "" {
+			number = strconv.Itoa(index)
+		}
+		description = "test " + number
+	}
+	execution := core.TestExecution{}
+	if m := tapDirective.FindStringSubmatch(directive); m != nil {
+		if strings.EqualFold(m[1], "skip") || !ok {
+			// A failing TODO test is expected to fail, so we treat it like a skip.
+			execution.Skip = &core.TestResultSkip{Message: m[2]}
+		}
+	} else if !ok {
+		execution.Failure = &core.TestResultFailure{
+			Message:   "Test failed",
+			Type:      "Failure",
+			Traceback: strings.Join(diagnostics, "\n"),
+		}
+	}
+	return core.TestCase{Name: description, Executions: []core.TestExecution{execution}}
+}
+
+// splitTAPDirective splits a test point's description from any directive (i.e. # SKIP or # TODO) after it.
+// Hashes in the description can be escaped with a backslash.
+func splitTAPDirective(description string) (string, string) {
+	for i := 0; i < len(description); i++ {
+		if description[i] == '\\' {
+			i++
+		} else if description[i] == '#' {
+			return unescapeTAP(strings.TrimSpace(description[:i])), strings.TrimSpace(description[i+1:])
+		}
+	}
+	return unescapeTAP(description), ""
+}
+
+func unescapeTAP(s string) string {
+	return strings.NewReplacer(`\#`, `#`, `\\`, `\`).Replace(s)
+}
+
+// applyTAPYAML applies a YAML diagnostics block to the most recent test case.
+func applyTAPYAML(suite *core.TestSuite, lines []string) {
+	info := parseTAPYAML(lines)
+	execution := &suite.TestCases[len(suite.TestCases)-1].Executions[0]
+	if ms, err := strconv.ParseFloat(info["duration_ms"], 64); err == nil {
+		duration := time.Duration(ms * float64(time.Millisecond))
+		execution.Duration = &duration
+	}
+	if execution.Failure != nil {
+		if msg := info["message"]; msg != "" {
+			execution.Failure.Message = msg
+		}
+		if stack := info["stack"]; stack != "" {
+			execution.Failure.Traceback = stack
+		} else if execution.Failure.Traceback == "" {
+			execution.Failure.Traceback = strings.Join(lines, "\n")
+		}
+	}
+}
+
+// parseTAPYAML does a minimal parse of the top-level keys of a YAML diagnostics block.
+// We don't need anything more complex than strings, and block scalars, for the keys we understand.
+func parseTAPYAML(lines []string) map[string]string {
+	indent := -1
+	for _, line := range lines {
+		if trimmed := strings.TrimLeft(line, " "); trimmed != "" && (indent == -1 || len(line)-len(trimmed) < indent) {
+			indent = len(line) - len(trimmed)
+		}
+	}
+	info := map[string]string{}
+	if indent == -1 {
+		return info
+	}
+	key := ""
+	var block []string
+	flush := func() {
+		if key != "" && block != nil {
+			info[key] = strings.TrimRight(strings.Join(block, "\n"), "\n")
+		}
+		key = ""
+		block = nil
+	}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		} else if line[indent] == ' ' {
+			if key != "" {
+				block = append(block, strings.TrimSpace(line))
+			}
+			continue
+		}
+		flush()
+		k, v, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		if v == "|" || v == "|-" || v == ">" || v == ">-" {
+			key = k
+			block = []string{}
+		} else {
+			info[k] = strings.Trim(v, `"'`)
+		}
+	}
+	flush()
+	return info
+}
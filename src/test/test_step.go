@@ -13,6 +13,7 @@ import (
 
 	"github.com/thought-machine/please/src/build"
 	"github.com/thought-machine/please/src/cli/logging"
+	"github.com/thought-machine/please/src/cmap"
 	"github.com/thought-machine/please/src/core"
 	"github.com/thought-machine/please/src/fs"
 	"github.com/thought-machine/please/src/process"
@@ -33,7 +34,9 @@ var numUploadFailures int64
 const maxUploadFailures int64 = 10
 
 // Test runs the tests for a single target.
-func Test(state *core.BuildState, target *core.BuildTarget, remote bool, run int) {
+// The given limiter is the one the caller acquired a worker from; it's released while the test waits
+// for any resources it needs.
+func Test(state *core.BuildState, target *core.BuildTarget, remote bool, run int, limiter cmap.Limiter) {
 	// Defer this so that no matter what happens in this test run, we always call target.CompleteRun
 	defer func() {
 		runsAllCompleted := target.CompleteRun(state)
@@ -51,11 +54,11 @@ func Test(state *core.BuildState, target *core.BuildTarget, remote bool, run int
 	}()
 
 	state.LogTestRunning(target, run, core.TargetTesting, "Testing...")
-	test(state.ForTarget(target), target.Label, target, remote, run)
+	test(state.ForTarget(target), target.Label, target, remote, run, limiter)
 }
 
-func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarget, runRemotely bool, run int) {
-	target.StartTestSuite()
+func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarget, runRemotely bool, run int, limiter cmap.Limiter) {
+	target.StartTestSuite(state.Config.IsQuarantined(target.Label))
 
 	hash, err := runtimeHash(state, target, runRemotely, run)
 	if err != nil {
@@ -66,6 +69,10 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 	outputFile := filepath.Join(target.TestDir(run), core.TestResultsFile)
 	coverageFile := filepath.Join(target.TestDir(run), core.CoverageFile)
 	needCoverage := target.NeedCoverage(state)
+	// Each shard of a sharded test is cached separately.
+	shard := target.TestShard(run)
+	resultsFile := target.ShardResultsFile(shard)
+	cachedCoverageFile := target.ShardCoverageFile(shard)
 
 	// If the user passed --shell then just prepare the directory.
 	if state.PrepareOnly {
@@ -75,8 +82,8 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 
 	cachedTestResults := func() *core.TestSuite {
 		log.Debug("Not re-running test %s; got cached results.", label)
-		coverage := parseCoverageFile(state, target, target.CoverageFile(), run)
-		results, err := parseTestResultsFile(target.TestResultsFile())
+		coverage := parseCoverageFile(state, target, cachedCoverageFile, run)
+		results, err := parseTestResultsFile(resultsFile, target.Test.ResultsFormat)
 		results.Package = strings.ReplaceAll(target.Label.PackageName, "/", ".")
 		results.Name = target.Label.Name
 		results.Cached = true
@@ -95,37 +102,32 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 	}
 
 	moveAndCacheOutputFiles := func(results *core.TestSuite, coverage *core.TestCoverage) bool {
-		// Never cache test results when given arguments; the results may be incomplete.
-		if len(state.TestArgs) > 0 {
-			log.Debug("Not caching results for %s, we passed it arguments", label)
-			return true
-		}
 		// Never cache test results if there were failures (usually flaky tests).
 		if results.Failures() > 0 {
 			log.Debug("Not caching results for %s, test had failures", label)
 			return true
 		}
-		outs := []string{filepath.Base(target.TestResultsFile())}
-		if err := moveOutputFile(state, hash, outputFile, target.TestResultsFile(), dummyOutput); err != nil {
+		outs := []string{filepath.Base(resultsFile)}
+		if err := moveOutputFile(state, hash, outputFile, resultsFile, dummyOutput); err != nil {
 			state.LogTestResult(target, run, core.TargetTestFailed, results, coverage, err, "Failed to move test output file")
 			return false
 		}
 
 		if needCoverage || core.PathExists(coverageFile) {
-			if err := moveOutputFile(state, hash, coverageFile, target.CoverageFile(), dummyCoverage); err != nil {
+			if err := moveOutputFile(state, hash, coverageFile, cachedCoverageFile, dummyCoverage); err != nil {
 				state.LogTestResult(target, run, core.TargetTestFailed, results, coverage, err, "Failed to move test coverage file")
 				return false
 			}
-			outs = append(outs, filepath.Base(target.CoverageFile()))
+			outs = append(outs, filepath.Base(cachedCoverageFile))
 		}
 		for _, output := range target.Test.Outputs {
 			tmpFile := filepath.Join(target.TestDir(run), output)
-			outFile := filepath.Join(target.OutDir(), output)
+			outFile := target.ShardTestOutput(output, shard)
 			if err := moveOutputFile(state, hash, tmpFile, outFile, ""); err != nil {
 				state.LogTestResult(target, run, core.TargetTestFailed, results, coverage, err, "Failed to move test output file")
 				return false
 			}
-			outs = append(outs, output)
+			outs = append(outs, strings.TrimPrefix(outFile, target.OutDir()+"/"))
 		}
 		if state.Cache != nil && !runRemotely {
 			state.Cache.Store(target, hash, outs)
@@ -138,23 +140,25 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 			return true
 		}
 
-		if s := target.State(); (s == core.Unchanged || s == core.Reused) && core.PathExists(target.TestResultsFile()) {
+		if s := target.State(); (s == core.Unchanged || s == core.Reused) && core.PathExists(resultsFile) {
 			// Output file exists already and appears to be valid. We might still need to rerun though
 			// if the coverage files aren't available.
-			if needCoverage && !verifyHash(state, target.CoverageFile(), hash) {
-				log.Debug("Rerunning %s, coverage file doesn't exist or has wrong hash", target.Label)
-				return true
-			} else if !verifyHash(state, target.TestResultsFile(), hash) {
-				log.Debug("Rerunning %s, results file has incorrect hash", target.Label)
-				return true
+			// The results may also be from a run of a different selection of test cases, in which case
+			// the cache might still have what we want.
+			if needCoverage && !verifyHash(state, cachedCoverageFile, hash) {
+				log.Debug("Coverage file for %s doesn't exist or has wrong hash", target.Label)
+			} else if !verifyHash(state, resultsFile, hash) {
+				log.Debug("Results file for %s has incorrect hash", target.Label)
+			} else {
+				return false
 			}
-			return false
+		} else {
+			log.Debug("Output file %s does not exist for %s", resultsFile, target.Label)
 		}
-		log.Debug("Output file %s does not exist for %s", target.TestResultsFile(), target.Label)
 		// Check the cache for these artifacts.
-		files := []string{filepath.Base(target.TestResultsFile())}
+		files := []string{filepath.Base(resultsFile)}
 		if needCoverage {
-			files = append(files, filepath.Base(target.CoverageFile()))
+			files = append(files, filepath.Base(cachedCoverageFile))
 		}
 		return !retrieveFromCache(state, target, hash, files)
 	}
@@ -168,13 +172,18 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 	// Don't cache when doing multiple runs, presumably the user explicitly wants to check it.
 	if state.NumTestRuns == 1 && !runRemotely && !needToRun() {
 		if cachedResults := cachedTestResults(); cachedResults != nil {
-			target.Test.Results = cachedResults
+			if target.NumTestShards() > 1 {
+				// Other shards may have added their results already.
+				target.AddTestResults(*cachedResults)
+			} else {
+				target.Test.Results = cachedResults
+			}
 			return
 		}
 	}
 
 	// Remove any cached test result file.
-	if err := RemoveTestOutputs(target); err != nil {
+	if err := removeShardOutputs(target, shard); err != nil {
 		state.LogBuildError(label, core.TargetTestFailed, err, "Failed to remove test output files")
 		return
 	}
@@ -182,6 +191,11 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 		state.LogBuildError(label, core.TargetTestFailed, err, "Failed to verify worker not needed")
 		return
 	}
+	// Wait until any resources this test shares with other tests are free.
+	if len(target.Test.Resources) > 0 {
+		state.LogTestRunning(target, run, core.TargetTesting, "Waiting for test resources...")
+		defer state.AcquireTestResources(target, limiter)()
+	}
 
 	coverage := &core.TestCoverage{}
 	if state.NumTestRuns == 1 {
@@ -194,10 +208,11 @@ func test(state *core.BuildState, label core.BuildLabel, target *core.BuildTarge
 			moveAndCacheOutputFiles(target.Test.Results, coverage)
 		}
 	} else if state.TestSequentially {
-		for run := 1; run <= int(state.NumTestRuns); run++ {
-			state.LogTestRunning(target, run, core.TargetTesting, "Testing...")
+		// Sequential tests re-use the test dir of their first run (which is one per shard).
+		for i := 0; i < int(state.NumTestRuns); i++ {
+			state.LogTestRunning(target, run+i*target.NumTestShards(), core.TargetTesting, "Testing...")
 			var results core.TestSuite
-			results, coverage = doTest(state, target, runRemotely, 1) // Sequential tests re-use run 1's test dir
+			results, coverage = doTest(state, target, runRemotely, run)
 			target.AddTestResults(results)
 		}
 	} else {
@@ -239,7 +254,7 @@ func doFlakeRun(state *core.BuildState, target *core.BuildTarget, run int, runRe
 	for flakes := 1; flakes <= int(target.Test.Flakiness); flakes++ {
 		state.LogTestRunning(target, run, core.TargetTesting, getFlakeStatus(flakes, int(target.Test.Flakiness)))
 
-		testSuite, cov := doTest(state, target, runRemotely, 1) // If we're running flakes, numRuns must be 1
+		testSuite, cov := doTest(state, target, runRemotely, run) // If we're running flakes, numRuns must be 1 so this is only ever a different shard
 
 		results.TimedOut = results.TimedOut || testSuite.TimedOut
 		results.Properties = testSuite.Properties
@@ -296,6 +311,11 @@ func logTargetResults(state *core.BuildState, target *core.BuildTarget, coverage
 		}
 		logTestSuccess(state, target, run, target.Test.Results, coverage)
 		return
+	} else if target.Test.Results.Quarantined {
+		// Quarantined tests don't fail the build, but we still want people to know about it.
+		log.Warning("%s failed, but it's quarantined so won't fail the build", target.Label)
+		state.LogTestResult(target, run, core.TargetTested, target.Test.Results, coverage, nil, "Tests failed (quarantined)")
+		return
 	}
 	var resultErr error
 	var resultMsg string
@@ -343,8 +363,8 @@ func pluralise(word string, quantity int) string {
 func testCommandAndEnv(state *core.BuildState, target *core.BuildTarget, run int) (string, core.BuildEnv, error) {
 	replacedCmd, err := core.ReplaceTestSequences(state, target, target.GetTestCommand(state))
 	env := core.TestEnvironment(state, target, filepath.Join(core.RepoRoot, target.TestDir(run)), run)
-	if len(state.TestArgs) > 0 {
-		replacedCmd += " " + strings.Join(state.TestArgs, " ")
+	if selection := state.TestSelection(target.Label); len(selection) > 0 {
+		replacedCmd += " " + strings.Join(selection, " ")
 	}
 	return replacedCmd, env, err
 }
@@ -388,6 +408,9 @@ func doTestResults(state *core.BuildState, target *core.BuildTarget, runRemotely
 		var stdout []byte
 		stdout, err = prepareAndRunTest(state, target, run)
 		metadata = &core.BuildMetadata{Stdout: stdout}
+		if err == nil && !supportsSharding(target, run) {
+			log.Warning("%s is split into %d shards, but its test runner didn't touch $TEST_SHARD_STATUS_FILE to indicate that it supports sharding; each shard may have run all of its tests", target.Label, target.NumTestShards())
+		}
 	}
 
 	coverage := parseCoverageFile(state, target, filepath.Join(target.TestDir(run), core.CoverageFile), run)
@@ -408,6 +431,12 @@ func doTestResults(state *core.BuildState, target *core.BuildTarget, runRemotely
 	return metadata, data, coverage, err
 }
 
+// supportsSharding returns false if a sharded test didn't touch $TEST_SHARD_STATUS_FILE, which test runners
+// do to indicate that they support sharding. If it didn't, every shard has probably run all the tests.
+func supportsSharding(target *core.BuildTarget, run int) bool {
+	return target.NumTestShards() <= 1 || fs.PathExists(filepath.Join(target.TestDir(run), core.TestShardStatusFile))
+}
+
 // prepareAndRunTest sets up a test directory and runs the test.
 func prepareAndRunTest(state *core.BuildState, target *core.BuildTarget, run int) (stdout []byte, err error) {
 	if err = core.PrepareRuntimeDir(state, target, target.TestDir(run)); err != nil {
@@ -482,7 +511,7 @@ func parseTestOutput(stdout string, stderr string, runError error, duration time
 		return failSuite("Test failed", "TestFailed", runError.Error())
 	}
 
-	results, parseError := parseTestResults(resultsData)
+	results, parseError := parseTestResults(resultsData, target.Test.ResultsFormat)
 	if parseError != nil {
 		// Output fails to parse with execution error - SYNTHETIC ERROR + EXECUTION ERROR - Failed to parse output
 		if runError != nil {
@@ -517,13 +546,23 @@ func parseCoverageFile(state *core.BuildState, target *core.BuildTarget, coverag
 
 // RemoveTestOutputs removes any cached test or coverage result files for a target.
 func RemoveTestOutputs(target *core.BuildTarget) error {
-	if err := fs.RemoveAll(target.TestResultsFile()); err != nil {
+	for shard := 0; shard < target.NumTestShards(); shard++ {
+		if err := removeShardOutputs(target, shard); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeShardOutputs removes any cached test or coverage result files for a single shard of a target.
+func removeShardOutputs(target *core.BuildTarget, shard int) error {
+	if err := fs.RemoveAll(target.ShardResultsFile(shard)); err != nil {
 		return err
-	} else if err := fs.RemoveAll(target.CoverageFile()); err != nil {
+	} else if err := fs.RemoveAll(target.ShardCoverageFile(shard)); err != nil {
 		return err
 	}
 	for _, output := range target.Test.Outputs {
-		if err := fs.RemoveAll(filepath.Join(target.OutDir(), output)); err != nil {
+		if err := fs.RemoveAll(target.ShardTestOutput(output, shard)); err != nil {
 			return err
 		}
 	}
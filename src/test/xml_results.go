@@ -283,6 +283,7 @@ type jUnitXMLTestSuite struct {
 
 	Errors    int    `xml:"errors,attr,omitempty"`
 	Failures  int    `xml:"failures,attr,omitempty"`
+	Flakes    int    `xml:"flakes,attr,omitempty"`
 	HostName  string `xml:"hostname,attr,omitempty"`
 	Skipped   int    `xml:"skipped,attr,omitempty"`
 	Package   string `xml:"package,attr,omitempty"`
@@ -487,6 +488,7 @@ func mustSerialiseResults(graph *core.BuildGraph, storeOutputOnSuccess bool) []b
 					suite.Tests += xmlTestSuite.Tests
 					suite.Errors += xmlTestSuite.Errors
 					suite.Failures += xmlTestSuite.Failures
+					suite.Flakes += xmlTestSuite.Flakes
 					suite.Skipped += xmlTestSuite.Skipped
 					suite.timed.Time += xmlTestSuite.timed.Time
 					suite.TestCases = append(suite.TestCases, xmlTestSuite.TestCases...)
@@ -532,6 +534,7 @@ func toXMLTestSuite(testSuite *core.TestSuite, storeOutputOnSuccess bool) *jUnit
 		Tests:      testSuite.Tests(),
 		Errors:     testSuite.Errors(),
 		Failures:   testSuite.Failures(),
+		Flakes:     testSuite.FlakyPasses(),
 		Skipped:    testSuite.Skips(),
 		timed:      timed{testSuite.Duration.Seconds()},
 		Properties: toXMLProperties(testSuite.Properties, testSuite.Cached),
@@ -558,6 +561,9 @@ func toXMLTestCase(result core.TestCase, storeOutputOnSuccess bool) jUnitXMLTest
 	if success != nil {
 		// We passed but we might have had flakes
 		testcase.Time = success.Duration.Seconds()
+		if result.FlakyPass() {
+			testcase.Status = "flaky"
+		}
 
 		if storeOutputOnSuccess {
 			testcase.Stderr = success.Stderr
@@ -122,9 +122,9 @@ func xmlTestScenario() *core.BuildTarget {
 }
 
 const expected = `<testsuites name="//src/core:lock_test" time="1">
-    <testsuite name="lock_test" tests="3" failures="1" package="src.core" time="1">
+    <testsuite name="lock_test" tests="3" failures="1" flakes="1" package="src.core" time="1">
         <properties></properties>
-        <testcase name="TestAcquireRepoLock" classname="src.core.lock_test" time="0.5">
+        <testcase name="TestAcquireRepoLock" classname="src.core.lock_test" status="flaky" time="0.5">
             <flakyFailure type="">
                 <system-out>failure out</system-out>
             </flakyFailure>
@@ -138,9 +138,9 @@ const expected = `<testsuites name="//src/core:lock_test" time="1">
 </testsuites>`
 
 const expectedWithSuccessOutput = `<testsuites name="//src/core:lock_test" time="1">
-    <testsuite name="lock_test" tests="3" failures="1" package="src.core" time="1">
+    <testsuite name="lock_test" tests="3" failures="1" flakes="1" package="src.core" time="1">
         <properties></properties>
-        <testcase name="TestAcquireRepoLock" classname="src.core.lock_test" time="0.5">
+        <testcase name="TestAcquireRepoLock" classname="src.core.lock_test" status="flaky" time="0.5">
             <flakyFailure type="">
                 <system-out>failure out</system-out>
             </flakyFailure>
@@ -118,10 +118,32 @@ func (h *Handler) findLabel(currentPath, label string) lsp.Location {
 		}
 		return true
 	})
-
+	if loc.Range == (lsp.Range{}) {
+		// It wasn't declared with a literal name in the BUILD file, e.g. because it was created by a macro.
+		if decl := h.findDeclaration(l); decl.URI != "" {
+			return decl
+		}
+	}
 	return loc
 }
 
+// findDeclaration returns the location of the call that declared the given target, if it was recorded while parsing.
+func (h *Handler) findDeclaration(label core.BuildLabel) lsp.Location {
+	target := h.state.Graph.Target(label)
+	if target == nil {
+		return lsp.Location{}
+	}
+	call, ok := target.DeclaredAt()
+	if !ok {
+		return lsp.Location{}
+	}
+	p := asp.FilePosition{Line: call.Line, Column: 1}
+	return lsp.Location{
+		URI:   lsp.DocumentURI("file://" + filepath.Join(h.root, call.Filename)),
+		Range: rng(p, p),
+	}
+}
+
 // findName finds the name arguments to a function call. The name must be a simple string lit as we don't evaluate the
 // package to deal with more complex expressions.
 func findName(args []asp.CallArgument) string {
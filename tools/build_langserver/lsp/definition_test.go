@@ -7,6 +7,8 @@ import (
 
 	"github.com/sourcegraph/go-lsp"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/thought-machine/please/src/core"
 )
 
 func TestDefinition(t *testing.T) {
@@ -122,3 +124,21 @@ func TestDefinitionFileInput(t *testing.T) {
 		},
 	}, locs)
 }
+
+func TestFindDeclaration(t *testing.T) {
+	h := NewHandler()
+	h.root = "/repo"
+	h.state = core.NewDefaultBuildState()
+	target := core.NewBuildTarget(core.ParseBuildLabel("//src/core:_core#srcs", ""))
+	h.state.Graph.AddTarget(target)
+	assert.Equal(t, lsp.Location{}, h.findDeclaration(target.Label))
+
+	target.CallStack = []core.CallSite{
+		{Function: "build_rule", Filename: "build_defs/go.build_defs", Line: 40},
+		{Function: "go_library", Filename: "src/core/BUILD", Line: 3},
+	}
+	assert.Equal(t, lsp.Location{
+		URI:   lsp.DocumentURI("file:///repo/src/core/BUILD"),
+		Range: xrng(2, 0, 2, 0),
+	}, h.findDeclaration(target.Label))
+}
@@ -195,6 +195,8 @@ func (h *Handler) initialize(params *lsp.InitializeParams) (*lsp.InitializeResul
 	}
 	h.state = core.NewBuildState(config)
 	h.state.NeedBuild = false
+	// Record where targets came from so we can go to the definitions of ones that macros created.
+	h.state.RecordProvenance = true
 	// We need an unwrapped parser instance as well for raw access.
 	h.parser = asp.NewParser(h.state)
 	// Parse everything in the repo up front.